@@ -1,18 +1,10 @@
 package main
 
 import (
+	"auth/internal/app"
 	"auth/internal/config"
-	"auth/internal/handler"
-	v1 "auth/internal/handler/v1"
-	"auth/internal/repository"
-	"auth/internal/repository/postgres"
-	"auth/internal/repository/redis"
-	"auth/internal/service"
 	"context"
-	"fmt"
-	"github.com/Linkify-Company/common_utils/errify"
 	"github.com/Linkify-Company/common_utils/logger"
-	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
@@ -23,44 +15,19 @@ func main() {
 
 	log := logger.GetLogger(cfg.Application.Env)
 
-	pool, err := postgres.New(context.Background(), log, cfg.Application.Debug)
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer stop()
+
+	application, err := app.New(ctx, cfg, log)
 	if err != nil {
 		log.Error(err)
 		panic(err)
 	}
-
-	redisClient, err := redis.New()
-	if err != nil {
+	if err := application.Run(ctx); err != nil {
 		log.Error(err)
-		panic(err)
+		stop()
+		os.Exit(1)
 	}
 
-	authService := service.NewService(
-		log,
-		pool,
-		redisClient,
-		repository.NewRepository(),
-		&cfg.EmailService,
-	)
-
-	router := handler.Run(
-		log,
-		v1.NewHandler(&cfg.Handler, &cfg.Token, log, authService),
-	)
-
-	go func() {
-		if err := http.ListenAndServe(fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.Port), router); err != nil {
-			log.Error(errify.NewInternalServerError(err.Error(), "main/ListenAndServe"))
-			panic(err)
-		}
-	}()
-
-	log.Infof("server listening on port %d", cfg.Server.Port)
-
-	stop := make(chan os.Signal, 1)
-	signal.Notify(stop, syscall.SIGTERM, syscall.SIGINT)
-
-	<-stop
-
 	log.Infof("application stopped")
 }
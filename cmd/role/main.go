@@ -0,0 +1,103 @@
+package main
+
+import (
+	"auth/internal/config"
+	"auth/internal/domain"
+	"auth/internal/repository"
+	"auth/internal/repository/postgres"
+	"auth/internal/repository/redis"
+	"auth/internal/service"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/Linkify-Company/common_utils/errify"
+	"github.com/Linkify-Company/common_utils/logger"
+	"os"
+	"time"
+)
+
+func main() {
+	var (
+		userID int
+		email  string
+		role   int
+		reason string
+	)
+	flag.IntVar(&userID, "user-id", 0, "id of the user whose role is changed")
+	flag.StringVar(&email, "email", "", "email of the user whose role is changed")
+	flag.IntVar(&role, "role", -1, "new role: 0 - admin, 1 - moderator, 2 - user")
+	flag.StringVar(&reason, "reason", "", "reason written to the audit log")
+
+	// Флаги объявлены до MustLoad, так как он сам разбирает командную строку
+	cfg := config.MustLoad()
+
+	if err := run(cfg, userID, email, domain.RoleUpdate{Role: (*domain.Role)(&role), Reason: reason}); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
+
+// run Изменяет роль пользователя. Сервисы и репозитории создаются только те, что нужны для SetRole,
+// поэтому утилите не нужны настройки LDAP, SAML и других провайдеров входа
+func run(cfg *config.Config, userID int, email string, update domain.RoleUpdate) error {
+	if userID <= 0 && email == "" {
+		return errors.New("user-id or email is required")
+	}
+	if err := update.Valid(); err != nil {
+		return err
+	}
+
+	log := logger.GetLogger(cfg.Application.Env)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*30)
+	defer cancel()
+
+	pool, e := postgres.New(ctx, log, cfg.Application.Debug)
+	if e != nil {
+		return e
+	}
+	defer pool.Close()
+
+	redisClient, e := redis.New()
+	if e != nil {
+		return e
+	}
+	defer redisClient.Close()
+
+	transaction := repository.NewTransactionsRepos(pool, redisClient)
+	userRepos := repository.NewUserRepos()
+	admin := service.NewAdminService(log, transaction, userRepos, repository.NewRoleRepos(), repository.NewAuthRepo())
+
+	if userID <= 0 {
+		id, e := userIDByEmail(ctx, transaction, userRepos, email)
+		if e != nil {
+			return e
+		}
+		userID = id
+	}
+
+	user, e := admin.SetRole(ctx, userID, &update, nil)
+	if e != nil {
+		return e
+	}
+	fmt.Printf("role of user %d (%s) set to %d\n", user.ID, user.Email, user.Role)
+	return nil
+}
+
+func userIDByEmail(ctx context.Context, transaction repository.Transaction, userRepos repository.User, email string) (int, errify.IError) {
+	tx, err := transaction.Begin(ctx)
+	if err != nil {
+		return 0, errify.NewInternalServerError(err.Error(), "userIDByEmail/Begin")
+	}
+	defer transaction.Rollback(ctx, tx)
+
+	user, err := userRepos.UserByEmail(ctx, tx, email)
+	if err != nil {
+		if errors.Is(err, repository.UserNotExist) {
+			return 0, errify.NewBadRequestError(err.Error(), service.UserNotExist.Error(), "userIDByEmail/UserByEmail")
+		}
+		return 0, errify.NewInternalServerError(err.Error(), "userIDByEmail/UserByEmail")
+	}
+	return user.ID, nil
+}
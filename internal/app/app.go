@@ -0,0 +1,301 @@
+// Package app Жизненный цикл сервиса: создание зависимостей, запуск серверов и фоновых задач и их остановка
+package app
+
+import (
+	"auth/internal/config"
+	"auth/internal/handler"
+	grpchandler "auth/internal/handler/grpc"
+	v1 "auth/internal/handler/v1"
+	"auth/internal/metrics"
+	"auth/internal/repository"
+	"auth/internal/repository/postgres"
+	"auth/internal/repository/redis"
+	"auth/internal/service"
+	"auth/internal/tracing"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Linkify-Company/common_utils/errify"
+	"github.com/Linkify-Company/common_utils/logger"
+	goredis "github.com/go-redis/redis"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"google.golang.org/grpc"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+type App struct {
+	cfg *config.Config
+	log logger.Logger
+
+	pool        *pgxpool.Pool
+	redisClient *goredis.Client
+	service     *service.Service
+
+	httpServer *http.Server
+	grpcServer *grpc.Server
+
+	// stopTracing Отправляет накопленные спаны, вызывается последним, чтобы в них попала остановка сервиса
+	stopTracing func(ctx context.Context) error
+
+	// workers Фоновые задачи, которые останавливаются после серверов
+	workers sync.WaitGroup
+}
+
+// New Подключается к Postgres и Redis, загружает политики авторизации и создает серверы. Серверы не запускаются
+func New(ctx context.Context, cfg *config.Config, log logger.Logger) (*App, errify.IError) {
+	a := &App{
+		cfg: cfg,
+		log: log,
+	}
+	var err errify.IError
+
+	a.stopTracing, err = tracing.New(ctx, cfg.Tracing)
+	if err != nil {
+		return nil, err.JoinLoc("New")
+	}
+	a.pool, err = postgres.New(ctx, log, cfg.Application.Debug)
+	if err != nil {
+		return nil, err.JoinLoc("New")
+	}
+	a.redisClient, err = redis.New()
+	if err != nil {
+		a.close()
+		return nil, err.JoinLoc("New")
+	}
+	repos, err := repository.NewRepository(a.redisClient, &cfg.Authz, &cfg.OAuth, &cfg.LDAP, &cfg.SAML)
+	if err != nil {
+		a.close()
+		return nil, err.JoinLoc("New")
+	}
+
+	a.service = service.NewService(
+		log,
+		a.pool,
+		a.redisClient,
+		repos,
+		&cfg.EmailService,
+		&cfg.Security,
+		&cfg.Organization,
+		&cfg.OAuth,
+		&cfg.SAML,
+		&cfg.SCIM,
+		&cfg.Cookie,
+		&cfg.CSRF,
+		&cfg.Token,
+		&cfg.Health,
+	)
+
+	err = a.service.ReloadPolicy(ctx)
+	if err != nil {
+		a.close()
+		return nil, err.JoinLoc("New")
+	}
+
+	if cfg.Metrics.Enabled {
+		err = a.registerMetrics()
+		if err != nil {
+			a.close()
+			return nil, err.JoinLoc("New")
+		}
+	}
+
+	tlsConfig, err := newTLSConfig(cfg.Server.TLS, log)
+	if err != nil {
+		a.close()
+		return nil, err.JoinLoc("New")
+	}
+
+	router := handler.Run(
+		log,
+		&cfg.CORS,
+		&cfg.Server.TLS,
+		&cfg.Metrics,
+		a.service.Health,
+		v1.NewHandler(&cfg.Handler, &cfg.Token, &cfg.Security, &cfg.ForwardAuth, log, a.service),
+	)
+	a.httpServer = &http.Server{
+		Addr:         fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.Port),
+		Handler:      router,
+		ReadTimeout:  cfg.Server.ReadTimeout,
+		WriteTimeout: cfg.Server.WriteTimeout,
+		IdleTimeout:  cfg.Server.IdleTimeout,
+		TLSConfig:    tlsConfig,
+	}
+	if cfg.GRPC.Port != 0 {
+		a.grpcServer = grpchandler.NewServer(&cfg.GRPC, &cfg.Handler, &cfg.Server.TLS, tlsConfig, log, a.service)
+	}
+	return a, nil
+}
+
+// Run Запускает серверы и фоновые задачи и работает до отмены ctx или ошибки сервера, после чего останавливает сервис
+func (a *App) Run(ctx context.Context) errify.IError {
+	workersCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+
+	a.workers.Add(1)
+	go func() {
+		defer a.workers.Done()
+		a.service.WatchPolicy(workersCtx, a.cfg.Authz.ReloadInterval)
+	}()
+
+	errCh := make(chan errify.IError, 2)
+
+	listener, e := net.Listen("tcp", a.httpServer.Addr)
+	if e != nil {
+		a.shutdown(stopWorkers)
+		return errify.NewInternalServerError(e.Error(), "Run/Listen")
+	}
+	// Сертификаты берутся из TLSConfig сервера, поэтому пути к файлам здесь не нужны
+	serve := a.httpServer.Serve
+	if a.httpServer.TLSConfig != nil {
+		serve = func(l net.Listener) error {
+			return a.httpServer.ServeTLS(l, "", "")
+		}
+	}
+	go func() {
+		if e := serve(listener); e != nil && !errors.Is(e, http.ErrServerClosed) {
+			errCh <- errify.NewInternalServerError(e.Error(), "Run/Serve")
+		}
+	}()
+	a.log.Infof("server listening on port %d", a.cfg.Server.Port)
+
+	if a.grpcServer != nil {
+		listener, e := net.Listen("tcp", fmt.Sprintf("%s:%d", a.cfg.GRPC.Host, a.cfg.GRPC.Port))
+		if e != nil {
+			a.shutdown(stopWorkers)
+			return errify.NewInternalServerError(e.Error(), "Run/Listen")
+		}
+		go func() {
+			if e := a.grpcServer.Serve(listener); e != nil && !errors.Is(e, grpc.ErrServerStopped) {
+				errCh <- errify.NewInternalServerError(e.Error(), "Run/grpc.Serve")
+			}
+		}()
+		a.log.Infof("gRPC server listening on port %d", a.cfg.GRPC.Port)
+	}
+
+	var err errify.IError
+	select {
+	case <-ctx.Done():
+		a.log.Infof("shutdown signal received")
+	case err = <-errCh:
+		a.log.Error(err)
+	}
+	if e := a.shutdown(stopWorkers); e != nil && err == nil {
+		err = e
+	}
+	return err
+}
+
+// shutdown Останавливает сервис в течение ServerConfig.ShutdownTimeout: снимает его с балансировки, дожидается
+// текущих запросов, останавливает фоновые задачи и отправку писем и закрывает соединения с базами
+func (a *App) shutdown(stopWorkers context.CancelFunc) errify.IError {
+	ctx, cancel := context.WithTimeout(context.Background(), a.cfg.Server.ShutdownTimeout)
+	defer cancel()
+
+	var err errify.IError
+	fail := func(e errify.IError) {
+		a.log.Error(e)
+		err = e
+	}
+
+	a.service.SetDraining()
+	select {
+	case <-ctx.Done():
+	case <-time.After(a.cfg.Server.DrainDelay):
+	}
+
+	var servers sync.WaitGroup
+	servers.Add(1)
+	go func() {
+		defer servers.Done()
+		if e := a.httpServer.Shutdown(ctx); e != nil {
+			fail(errify.NewInternalServerError(e.Error(), "shutdown/Shutdown"))
+		}
+	}()
+	if a.grpcServer != nil {
+		servers.Add(1)
+		go func() {
+			defer servers.Done()
+			a.stopGRPC(ctx)
+		}()
+	}
+	servers.Wait()
+
+	stopWorkers()
+	if e := wait(ctx, &a.workers); e != nil {
+		fail(errify.NewInternalServerError(e.Error(), "shutdown/workers"))
+	}
+	if e := a.service.Wait(ctx); e != nil {
+		fail(e.JoinLoc("shutdown"))
+	}
+	a.close()
+	if e := a.stopTracing(ctx); e != nil {
+		fail(errify.NewInternalServerError(e.Error(), "shutdown/stopTracing"))
+	}
+	return err
+}
+
+// registerMetrics Добавляет метрики пула Postgres, команд Redis и активных сессий
+func (a *App) registerMetrics() errify.IError {
+	metrics.InstrumentRedis(a.redisClient)
+
+	if e := metrics.Register(metrics.NewPoolCollector(a.pool)); e != nil {
+		return errify.NewInternalServerError(e.Error(), "registerMetrics/NewPoolCollector")
+	}
+	sessions := metrics.NewSessionsCollector(func(ctx context.Context) (int64, error) {
+		count, err := a.service.ActiveSessions(ctx)
+		if err != nil {
+			return 0, err
+		}
+		return count, nil
+	}, a.cfg.Metrics.SessionsTimeout)
+	if e := metrics.Register(sessions); e != nil {
+		return errify.NewInternalServerError(e.Error(), "registerMetrics/NewSessionsCollector")
+	}
+	return nil
+}
+
+// stopGRPC Ждет завершения вызовов, а когда время вышло, прерывает оставшиеся
+func (a *App) stopGRPC(ctx context.Context) {
+	stopped := make(chan struct{})
+	go func() {
+		a.grpcServer.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		a.grpcServer.Stop()
+		<-stopped
+	}
+}
+
+func (a *App) close() {
+	if a.redisClient != nil {
+		if e := a.redisClient.Close(); e != nil {
+			a.log.Error(errify.NewInternalServerError(e.Error(), "close/redis.Close"))
+		}
+	}
+	if a.pool != nil {
+		a.pool.Close()
+	}
+}
+
+func wait(ctx context.Context, wg *sync.WaitGroup) error {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-done:
+		return nil
+	}
+}
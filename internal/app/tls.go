@@ -0,0 +1,139 @@
+package app
+
+import (
+	"auth/internal/config"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"github.com/Linkify-Company/common_utils/errify"
+	"github.com/Linkify-Company/common_utils/logger"
+	"os"
+	"sync"
+	"time"
+)
+
+// certReloader Держит сертификат сервиса и центр сертификатов клиентов и перечитывает их, когда файлы изменились.
+// Проверка выполняется при рукопожатии не чаще ReloadInterval, поэтому отдельная горутина не нужна
+type certReloader struct {
+	cfg config.TLSConfig
+	log logger.Logger
+
+	mx        sync.Mutex
+	checkedAt time.Time
+	modTime   time.Time
+	config    *tls.Config
+}
+
+// newTLSConfig TLS для серверов или nil, если сертификат не задан
+func newTLSConfig(cfg config.TLSConfig, log logger.Logger) (*tls.Config, errify.IError) {
+	if cfg.CertPath == "" {
+		return nil, nil
+	}
+	if cfg.KeyPath == "" {
+		return nil, errify.NewInternalServerError("tls key path is empty", "newTLSConfig")
+	}
+	r := &certReloader{cfg: cfg, log: log}
+
+	modTime, err := r.filesModTime()
+	if err != nil {
+		return nil, errify.NewInternalServerError(err.Error(), "newTLSConfig/filesModTime")
+	}
+	r.config, err = r.load()
+	if err != nil {
+		return nil, errify.NewInternalServerError(err.Error(), "newTLSConfig/load")
+	}
+	r.modTime = modTime
+	r.checkedAt = time.Now()
+
+	return &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		NextProtos:         []string{"h2", "http/1.1"},
+		GetConfigForClient: r.configForClient,
+		// Не используется при GetConfigForClient, но без сертификата в базовой конфигурации ServeTLS требует файлы
+		GetCertificate: r.certificate,
+	}, nil
+}
+
+func (r *certReloader) certificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	config, err := r.configForClient(hello)
+	if err != nil {
+		return nil, err
+	}
+	return &config.Certificates[0], nil
+}
+
+func (r *certReloader) configForClient(*tls.ClientHelloInfo) (*tls.Config, error) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+
+	if time.Since(r.checkedAt) >= r.cfg.ReloadInterval {
+		r.checkedAt = time.Now()
+		r.reload()
+	}
+	return r.config, nil
+}
+
+// reload При ошибке продолжает действовать предыдущий сертификат, например пока файлы заменены не полностью
+func (r *certReloader) reload() {
+	modTime, err := r.filesModTime()
+	if err != nil {
+		r.log.Error(errify.NewInternalServerError(err.Error(), "reload/filesModTime"))
+		return
+	}
+	if !modTime.After(r.modTime) {
+		return
+	}
+	config, err := r.load()
+	if err != nil {
+		r.log.Error(errify.NewInternalServerError(err.Error(), "reload/load"))
+		return
+	}
+	r.config = config
+	r.modTime = modTime
+	r.log.Infof("tls certificates reloaded")
+}
+
+func (r *certReloader) load() (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(r.cfg.CertPath, r.cfg.KeyPath)
+	if err != nil {
+		return nil, err
+	}
+	config := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		NextProtos:   []string{"h2", "http/1.1"},
+		Certificates: []tls.Certificate{cert},
+	}
+	if r.cfg.ClientCAPath != "" {
+		pem, err := os.ReadFile(r.cfg.ClientCAPath)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, errors.New("client ca certificates not found")
+		}
+		// Сертификат нужен не на всех адресах, поэтому при рукопожатии он только проверяется, если передан
+		config.ClientCAs = pool
+		config.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+	return config, nil
+}
+
+// filesModTime Время последнего изменения среди файлов сертификатов
+func (r *certReloader) filesModTime() (time.Time, error) {
+	var latest time.Time
+	for _, path := range []string{r.cfg.CertPath, r.cfg.KeyPath, r.cfg.ClientCAPath} {
+		if path == "" {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("filesModTime/Stat: %w", err)
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/ilyakaznacheev/cleanenv"
 	"github.com/joho/godotenv"
 	"os"
+	"slices"
 	"time"
 )
 
@@ -36,8 +37,23 @@ type (
 		Application  ApplicationConfig  `yaml:"application" env-required:"true"`
 		Handler      HandlerConfig      `yaml:"handler" env-required:"true"`
 		Token        TokenConfig        `yaml:"token" env-required:"true"`
+		Cookie       CookieConfig       `yaml:"cookie"`
+		CSRF         CSRFConfig         `yaml:"csrf"`
 		Server       ServerConfig       `yaml:"server" env-required:"true"`
+		CORS         CORSConfig         `yaml:"cors"`
+		Health       HealthConfig       `yaml:"health"`
+		Metrics      MetricsConfig      `yaml:"metrics"`
+		Tracing      TracingConfig      `yaml:"tracing"`
+		GRPC         GRPCConfig         `yaml:"grpc"`
 		EmailService EmailServiceConfig `yaml:"email_service" env-required:"true"`
+		Security     SecurityConfig     `yaml:"security"`
+		Authz        AuthzConfig        `yaml:"authz"`
+		Organization OrganizationConfig `yaml:"organization"`
+		OAuth        OAuthConfig        `yaml:"oauth"`
+		LDAP         LDAPConfig         `yaml:"ldap"`
+		SAML         SAMLConfig         `yaml:"saml"`
+		SCIM         SCIMConfig         `yaml:"scim"`
+		ForwardAuth  ForwardAuthConfig  `yaml:"forward_auth"`
 	}
 
 	ApplicationConfig struct {
@@ -54,6 +70,78 @@ type (
 		Host         string        `yaml:"host" env-required:"true"`
 		WriteTimeout time.Duration `yaml:"write-timeout" env-required:"true"`
 		ReadTimeout  time.Duration `yaml:"read-timeout" env-required:"true"`
+		IdleTimeout  time.Duration `yaml:"idle-timeout" env-default:"60s"`
+		// DrainDelay Время между переходом /readyz в состояние draining и остановкой серверов,
+		// за которое балансировщик успевает перестать направлять запросы
+		DrainDelay time.Duration `yaml:"drain-delay" env-default:"5s"`
+		// ShutdownTimeout Общее ограничение на остановку: завершение запросов, фоновых задач и закрытие соединений
+		ShutdownTimeout time.Duration `yaml:"shutdown-timeout" env-default:"20s"`
+		TLS             TLSConfig     `yaml:"tls"`
+	}
+
+	// TLSConfig TLS на стороне сервиса, общий для HTTP и gRPC серверов. Без CertPath сервис работает без TLS
+	TLSConfig struct {
+		CertPath string `yaml:"cert_path"`
+		KeyPath  string `yaml:"key_path"`
+		// ReloadInterval Как часто проверять, не заменены ли файлы сертификатов, чтобы подхватить их без перезапуска
+		ReloadInterval time.Duration `yaml:"reload_interval" env-default:"1m"`
+		// ClientCAPath Центр, которым подписаны сертификаты клиентов. Сертификат клиента проверяется, если он передан
+		ClientCAPath string `yaml:"client_ca_path"`
+		// ClientCertPaths Префиксы адресов, доступных только с проверенным сертификатом клиента, например /srv-auth/api/v1/admin
+		ClientCertPaths []string `yaml:"client_cert_paths"`
+		// ClientIdentities Имя сервиса по субъекту сертификата: полному (CN=billing,O=Linkify), CN или URI из SAN.
+		// Если список задан, на ClientCertPaths допускаются только перечисленные клиенты
+		ClientIdentities map[string]string `yaml:"client_identities"`
+	}
+
+	CORSConfig struct {
+		// AllowedOrigins Источники вида https://app.example.com. * заменяет любую часть адреса, например https://*.example.com.
+		// Пустой список отключает CORS
+		AllowedOrigins []string `yaml:"allowed_origins"`
+		AllowedMethods []string `yaml:"allowed_methods" env-default:"GET,POST,PUT,PATCH,DELETE"`
+		AllowedHeaders []string `yaml:"allowed_headers" env-default:"Authorization,Content-Type,X-CSRF-Token"`
+		// ExposedHeaders Заголовки ответа, доступные скрипту, например продленный токен
+		ExposedHeaders []string `yaml:"exposed_headers" env-default:"Authorization,X-Request-ID"`
+		// AllowCredentials Разрешает запросы с cookie. Несовместим с источником * в AllowedOrigins
+		AllowCredentials bool `yaml:"allow_credentials"`
+		// MaxAge Время, на которое браузер запоминает ответ на предварительный запрос
+		MaxAge time.Duration `yaml:"max_age" env-default:"10m"`
+	}
+
+	HealthConfig struct {
+		// Timeout Ограничение на проверку одной зависимости в /readyz
+		Timeout time.Duration `yaml:"timeout" env-default:"2s"`
+		// CheckSMTP Проверять доступность почтового сервера. Без него сервис не может отправлять коды, но остается готов
+		CheckSMTP bool `yaml:"check_smtp"`
+	}
+
+	MetricsConfig struct {
+		Enabled bool `yaml:"enabled"`
+		// Path Адрес метрик. Он не входит в /srv-auth, поэтому к нему не применяются CORS и сертификаты клиентов
+		Path string `yaml:"path" env-default:"/metrics"`
+		// SessionsTimeout Ограничение на подсчет активных сессий в Redis при каждом запросе метрик
+		SessionsTimeout time.Duration `yaml:"sessions_timeout" env-default:"2s"`
+	}
+
+	TracingConfig struct {
+		// Exporter Куда отправлять спаны: otlp - в коллектор по OTLP/gRPC, stdout - в стандартный вывод.
+		// Пустое значение отключает запись спанов
+		Exporter string `yaml:"exporter"`
+		// Endpoint Адрес коллектора для otlp
+		Endpoint string `yaml:"endpoint" env-default:"localhost:4317"`
+		// Insecure Подключаться к коллектору без TLS, например к локальному
+		Insecure    bool   `yaml:"insecure"`
+		ServiceName string `yaml:"service_name" env-default:"srv-auth"`
+		// SampleRatio Доля записываемых трасс, начатых в сервисе. Для трасс вызывающего действует его решение
+		SampleRatio float64 `yaml:"sample_ratio" env-default:"1"`
+	}
+
+	GRPCConfig struct {
+		// Port Порт gRPC сервера, 0 - сервер не запускается
+		Port int    `yaml:"port"`
+		Host string `yaml:"host"`
+		// Reflection Регистрирует сервис рефлексии, чтобы grpcurl и подобные клиенты могли получить схему
+		Reflection bool `yaml:"reflection"`
 	}
 
 	TokenConfig struct {
@@ -61,9 +149,168 @@ type (
 		RefreshTTL time.Duration `yaml:"refresh_ttl" env-required:"true"`
 	}
 
+	// CookieConfig Cookie с токеном для браузерных клиентов. Cookie живет RefreshTTL, пока действует сессия:
+	// токен в ней истекает через AccessTTL и заменяется продленным в /auth/check
+	CookieConfig struct {
+		Name string `yaml:"name" env-default:"Authorization"`
+		// OAuthStateName Cookie, которая связывает вход через провайдера с браузером, начавшим его
+		OAuthStateName string `yaml:"oauth_state_name" env-default:"oauth_state"`
+		Domain         string `yaml:"domain"`
+		Path           string `yaml:"path" env-default:"/"`
+		Secure         bool   `yaml:"secure" env-default:"true"`
+		// SameSite lax, strict или none. none требует Secure
+		SameSite string `yaml:"same_site" env-default:"lax"`
+		// HostPrefix Добавляет к именам префикс __Host-: cookie только Secure, без Domain и с путем /
+		HostPrefix bool `yaml:"host_prefix"`
+	}
+
+	// CSRFConfig Защита изменяющих запросов, авторизованных cookie: токен из CSRF cookie повторяется в заголовке,
+	// а Origin или Referer должен совпадать с адресом сервиса или быть в TrustedOrigins
+	CSRFConfig struct {
+		Enabled    bool   `yaml:"enabled" env-default:"true"`
+		CookieName string `yaml:"cookie_name" env-default:"csrf_token"`
+		HeaderName string `yaml:"header_name" env-default:"X-CSRF-Token"`
+		// TrustedOrigins Источники вида https://app.example.com, с которых разрешены запросы
+		TrustedOrigins []string `yaml:"trusted_origins"`
+	}
+
+	SecurityConfig struct {
+		// EnumerationSafe Не раскрывать, зарегистрирована ли почта, в ответах регистрации и авторизации
+		EnumerationSafe bool `yaml:"enumeration_safe" env-default:"true"`
+		// ProtectUserLookup Поиск пользователя в GET /user/{value} требует авторизации, а чужие записи - разрешения users:read.
+		// Выключен по умолчанию, так как без него адрес остается публичным, как раньше
+		ProtectUserLookup bool `yaml:"protect_user_lookup"`
+	}
+
+	AuthzConfig struct {
+		// PolicyPath Путь к файлу политик авторизации, без него все запросы запрещаются
+		PolicyPath string `yaml:"policy_path"`
+		// ReloadInterval Период проверки изменения файла политик
+		ReloadInterval time.Duration `yaml:"reload_interval" env-default:"10s"`
+	}
+
+	OrganizationConfig struct {
+		// InvitationTTL Время, в течение которого можно принять приглашение в организацию
+		InvitationTTL time.Duration `yaml:"invitation_ttl" env-default:"168h"`
+	}
+
+	OAuthConfig struct {
+		// StateTTL Время, за которое пользователь должен вернуться от провайдера
+		StateTTL time.Duration `yaml:"state_ttl" env-default:"10m"`
+		// Providers Внешние провайдеры входа по имени, которое используется в адресе /oauth/{provider}
+		Providers map[string]OAuthProviderConfig `yaml:"providers"`
+	}
+
+	// OAuthProviderConfig Настройки провайдера OIDC или OAuth2.
+	// Для OIDC достаточно Issuer, адреса берутся из discovery; для OAuth2 задаются AuthURL, TokenURL и UserInfoURL
+	OAuthProviderConfig struct {
+		Issuer      string `yaml:"issuer"`
+		AuthURL     string `yaml:"auth_url"`
+		TokenURL    string `yaml:"token_url"`
+		UserInfoURL string `yaml:"userinfo_url"`
+		ClientID    string `yaml:"client_id"`
+		// ClientSecretEnv Переменная окружения, в которой хранится секрет клиента
+		ClientSecretEnv string   `yaml:"client_secret_env"`
+		RedirectURL     string   `yaml:"redirect_url"`
+		Scopes          []string `yaml:"scopes"`
+		// Поля ответа провайдера с идентификатором, почтой и признаком ее подтверждения
+		SubjectField       string `yaml:"subject_field"`
+		EmailField         string `yaml:"email_field"`
+		EmailVerifiedField string `yaml:"email_verified_field"`
+		// TrustEmail Провайдер отдает только подтвержденные почты, признак подтверждения не проверяется
+		TrustEmail bool `yaml:"trust_email"`
+	}
+
+	LDAPConfig struct {
+		// Directories Каталоги LDAP. Пользователи с почтой из доменов каталога входят только через него
+		Directories []LDAPDirectoryConfig `yaml:"directories"`
+	}
+
+	LDAPDirectoryConfig struct {
+		Name    string   `yaml:"name"`
+		Domains []string `yaml:"domains"`
+		// URL Адрес сервера вида ldap://host:389 или ldaps://host:636
+		URL      string `yaml:"url"`
+		StartTLS bool   `yaml:"start_tls"`
+		// CACertPath Сертификат центра, которым подписан сертификат сервера, без него используются системные
+		CACertPath         string `yaml:"ca_cert_path"`
+		InsecureSkipVerify bool   `yaml:"insecure_skip_verify"`
+		// BindDN Служебная учетная запись для поиска пользователя, пароль берется из переменной BindPasswordEnv
+		BindDN          string `yaml:"bind_dn"`
+		BindPasswordEnv string `yaml:"bind_password_env"`
+		BaseDN          string `yaml:"base_dn"`
+		// UserFilter Фильтр поиска пользователя, %s заменяется экранированной почтой
+		UserFilter     string `yaml:"user_filter"`
+		GroupAttribute string `yaml:"group_attribute"`
+		// GroupRoles Роль по DN группы. Если пользователь состоит в нескольких группах, выбирается старшая роль
+		GroupRoles map[string]int `yaml:"group_roles"`
+		// DefaultRole Роль пользователя вне групп из GroupRoles, по умолчанию обычный пользователь
+		DefaultRole *int          `yaml:"default_role"`
+		Timeout     time.Duration `yaml:"timeout"`
+	}
+
+	SAMLConfig struct {
+		// RequestTTL Время, за которое пользователь должен вернуться от провайдера с ответом на запрос
+		RequestTTL time.Duration `yaml:"request_ttl" env-default:"10m"`
+		// IdentityProviders Провайдеры SAML по имени, которое используется в адресе /saml/{idp}
+		IdentityProviders map[string]SAMLIdentityProviderConfig `yaml:"identity_providers"`
+	}
+
+	// SAMLIdentityProviderConfig Настройки сервиса как SP для одного провайдера SAML.
+	// Метаданные провайдера берутся из файла IdPMetadataPath или по адресу IdPMetadataURL
+	SAMLIdentityProviderConfig struct {
+		// EntityID Идентификатор сервиса у провайдера, по умолчанию MetadataURL
+		EntityID    string `yaml:"entity_id"`
+		MetadataURL string `yaml:"metadata_url"`
+		ACSURL      string `yaml:"acs_url"`
+		// CertPath и KeyPath Ключевая пара сервиса (RSA) для подписи запросов и расшифровки утверждений
+		CertPath        string `yaml:"cert_path"`
+		KeyPath         string `yaml:"key_path"`
+		IdPMetadataPath string `yaml:"idp_metadata_path"`
+		IdPMetadataURL  string `yaml:"idp_metadata_url"`
+		// Binding Способ отправки запроса провайдеру: redirect или post
+		Binding      string `yaml:"binding"`
+		SignRequests bool   `yaml:"sign_requests"`
+		// AllowIdPInitiated Разрешает вход, начатый на стороне провайдера, без запроса сервиса
+		AllowIdPInitiated bool `yaml:"allow_idp_initiated"`
+		// Атрибуты утверждения с идентификатором и почтой, по умолчанию используется NameID
+		SubjectAttribute string `yaml:"subject_attribute"`
+		EmailAttribute   string `yaml:"email_attribute"`
+		// TrustEmail Провайдер отдает только подтвержденные почты. Без этого пользователь не создается и не связывается по почте
+		TrustEmail bool `yaml:"trust_email"`
+		// RoleAttribute Атрибут со значениями, по которым назначается роль. Пустой - роль не синхронизируется
+		RoleAttribute string `yaml:"role_attribute"`
+		// AttributeRoles Роль по значению RoleAttribute. При нескольких значениях выбирается старшая роль
+		AttributeRoles map[string]int `yaml:"attribute_roles"`
+		// DefaultRole Роль пользователя без значений из AttributeRoles, по умолчанию обычный пользователь
+		DefaultRole *int `yaml:"default_role"`
+	}
+
+	SCIMConfig struct {
+		// GroupRoles Роль в организации по имени группы SCIM (1 - администратор, 2 - участник).
+		// Участник получает старшую роль среди своих групп, роль владельца через SCIM не меняется
+		GroupRoles map[string]int `yaml:"group_roles"`
+	}
+
+	ForwardAuthConfig struct {
+		// LoginURL Страница входа, на которую /auth/verify перенаправляет HTML запросы без действительного токена.
+		// Пустой - такие запросы получают 401, как и остальные
+		LoginURL string `yaml:"login_url"`
+		// ReturnParam Параметр LoginURL, в котором передается адрес исходного запроса
+		ReturnParam string `yaml:"return_param" env-default:"rd"`
+	}
+
 	EmailServiceConfig struct {
 		SmtpServer string `yaml:"smtp_server" env-required:"true"`
 		SmtpPort   int    `yaml:"smtp_port" env-required:"true"`
+		// CodeTTL Количество времени, за которое должны подтвердить почту
+		CodeTTL time.Duration `yaml:"code_ttl" env-default:"10m"`
+		// CodeAttempts Максимальное количество попыток на подтверждение email
+		CodeAttempts int `yaml:"code_attempts" env-default:"3"`
+		// ResendCooldown Минимальный интервал между отправками кода на один адрес
+		ResendCooldown time.Duration `yaml:"resend_cooldown" env-default:"1m"`
+		// DailyLimit Максимальное количество отправок кода на один адрес за сутки
+		DailyLimit int `yaml:"daily_limit" env-default:"10"`
 	}
 )
 
@@ -94,9 +341,17 @@ func MustLoad() *Config {
 		panic("the env is not specified correctly: " + cfg.Application.Env)
 	}
 	checkEnv()
+	checkCORS(cfg.CORS)
 	return &cfg
 }
 
+// checkCORS Источник * вместе с cookie открыл бы сессии пользователей любому сайту
+func checkCORS(cfg CORSConfig) {
+	if cfg.AllowCredentials && slices.Contains(cfg.AllowedOrigins, "*") {
+		panic("cors: allowed_origins * can not be used with allow_credentials")
+	}
+}
+
 func fetchConfigPath() string {
 	var res string
 	flag.StringVar(&res, "config", "", "path to config file")
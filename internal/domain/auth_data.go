@@ -4,4 +4,12 @@ type AuthData struct {
 	ID    int
 	Email string
 	Role  Role
+	// Permissions Разрешения всех ролей пользователя
+	Permissions []Permission
+	// OrgID Активная организация, 0 - личное пространство пользователя
+	OrgID int
+	// OrgRole Роль в активной организации
+	OrgRole OrgRole
+	// PersonalTokenID Персональный токен, которым выполнен запрос, 0 - обычная сессия
+	PersonalTokenID int
 }
@@ -0,0 +1,11 @@
+package domain
+
+// DirectoryUser Пользователь, прошедший проверку в каталоге LDAP
+type DirectoryUser struct {
+	// Directory Имя каталога из конфигурации
+	Directory string
+	DN        string
+	Email     string
+	Groups    []string
+	Role      Role
+}
@@ -0,0 +1,25 @@
+package domain
+
+const (
+	HealthOK       = "ok"
+	HealthFail     = "fail"
+	HealthDraining = "draining"
+)
+
+// DependencyHealth Состояние зависимости сервиса и время ее проверки
+type DependencyHealth struct {
+	Name      string  `json:"name"`
+	Status    string  `json:"status"`
+	LatencyMs float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// Readiness Готовность сервиса принимать запросы. Во время остановки сервис не готов, даже если зависимости доступны
+type Readiness struct {
+	Status       string              `json:"status"`
+	Dependencies []*DependencyHealth `json:"dependencies"`
+}
+
+func (r *Readiness) Ready() bool {
+	return r.Status == HealthOK
+}
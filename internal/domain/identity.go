@@ -0,0 +1,32 @@
+package domain
+
+import "time"
+
+// ExternalIdentity Учетная запись пользователя у внешнего провайдера входа
+type ExternalIdentity struct {
+	Provider      string
+	Subject       string
+	Email         string
+	EmailVerified bool
+}
+
+// UserIdentity Привязка внешней учетной записи к пользователю
+type UserIdentity struct {
+	ID          int        `json:"id"`
+	UserID      int        `json:"user_id"`
+	Provider    string     `json:"provider"`
+	Subject     string     `json:"subject"`
+	Email       string     `json:"email"`
+	CreatedAt   time.Time  `json:"created_at"`
+	LastLoginAt *time.Time `json:"last_login_at"`
+}
+
+// OAuthState Данные входа через провайдера, сохраняемые до возврата пользователя по state
+type OAuthState struct {
+	Provider string `json:"provider"`
+	Nonce    string `json:"nonce"`
+	// Verifier Секрет PKCE, передается провайдеру при обмене кода
+	Verifier string `json:"verifier"`
+	// LinkUserID Пользователь, к которому привязывается учетная запись, 0 - вход
+	LinkUserID int `json:"link_user_id,omitempty"`
+}
@@ -0,0 +1,110 @@
+package domain
+
+import (
+	"errors"
+	"github.com/go-playground/validator/v10"
+	"regexp"
+	"time"
+	"unicode/utf8"
+)
+
+// OrgRole Роль пользователя внутри организации
+type OrgRole int
+
+const (
+	OrgRoleOwner OrgRole = iota
+	OrgRoleAdmin
+	OrgRoleMember
+)
+
+func (r OrgRole) Valid() bool {
+	switch r {
+	case OrgRoleOwner, OrgRoleAdmin, OrgRoleMember:
+		return true
+	}
+	return false
+}
+
+// CanManage Может ли роль управлять участниками и приглашениями организации
+func (r OrgRole) CanManage() bool {
+	return r == OrgRoleOwner || r == OrgRoleAdmin
+}
+
+// InvitationStatus Состояние приглашения в организацию
+type InvitationStatus string
+
+const (
+	InvitationPending  InvitationStatus = "pending"
+	InvitationAccepted InvitationStatus = "accepted"
+	InvitationDeclined InvitationStatus = "declined"
+	InvitationRevoked  InvitationStatus = "revoked"
+)
+
+// Organization Организация (рабочее пространство) с участниками
+type Organization struct {
+	ID        int       `json:"id"`
+	Name      string    `json:"name"`
+	Slug      string    `json:"slug"`
+	OwnerID   int       `json:"owner_id"`
+	CreatedAt time.Time `json:"created_at"`
+	// Role Роль текущего пользователя в организации
+	Role *OrgRole `json:"role,omitempty"`
+}
+
+var slugRegexp = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{1,62}$`)
+
+func (o *Organization) Valid() error {
+	if o == nil {
+		return errors.New("organization empty")
+	}
+	length := utf8.RuneCountInString(o.Name)
+	if length < 1 || length > 100 {
+		return errors.New("name not valid")
+	}
+	if !slugRegexp.MatchString(o.Slug) {
+		return errors.New("slug not valid")
+	}
+	return nil
+}
+
+type OrgMember struct {
+	OrgID  int     `json:"org_id"`
+	UserID int     `json:"user_id"`
+	Email  string  `json:"email"`
+	Role   OrgRole `json:"role"`
+	// Active Участник, отключенный через SCIM, не получает доступ к организации
+	Active     bool    `json:"active"`
+	ExternalID *string `json:"external_id,omitempty"`
+	// Provisioned Учетная запись пользователя создана организацией через SCIM
+	Provisioned bool       `json:"provisioned"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   *time.Time `json:"updated_at,omitempty"`
+}
+
+type OrgInvitation struct {
+	ID        int              `json:"id"`
+	OrgID     int              `json:"org_id"`
+	Email     string           `json:"email" validate:"required,email"`
+	Role      OrgRole          `json:"role"`
+	InvitedBy int              `json:"invited_by"`
+	Status    InvitationStatus `json:"status"`
+	ExpiresAt time.Time        `json:"expires_at"`
+	CreatedAt time.Time        `json:"created_at"`
+	// TokenHash Хеш токена приглашения, сам токен отправляется только на почту
+	TokenHash string `json:"-"`
+}
+
+func (i *OrgInvitation) Valid() error {
+	if i == nil {
+		return errors.New("invitation empty")
+	}
+	if !i.Role.Valid() || i.Role == OrgRoleOwner {
+		return errors.New("role not valid")
+	}
+	vl := validator.New()
+	err := vl.Struct(*i)
+	if err != nil {
+		return err.(validator.ValidationErrors)[0]
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package domain
+
+import (
+	"errors"
+	"regexp"
+)
+
+// Permission Именованное разрешение вида "ресурс:действие"
+type Permission string
+
+const (
+	// PermissionAll Дает все разрешения, назначено встроенной роли администратора
+	PermissionAll Permission = "*"
+
+	PermissionUsersRead  Permission = "users:read"
+	PermissionUsersWrite Permission = "users:write"
+	PermissionRolesRead  Permission = "roles:read"
+	PermissionRolesWrite Permission = "roles:write"
+	PermissionLinksRead  Permission = "links:read"
+	PermissionLinksWrite Permission = "links:write"
+	// PermissionAuthzCheck Запрос решений об авторизации через /authz/check
+	PermissionAuthzCheck Permission = "authz:check"
+
+	// Разрешения на действия пользователя со своей учетной записью. Они есть у каждого пользователя
+	// и нужны как области персонального токена
+	PermissionOrgsRead        Permission = "orgs:read"
+	PermissionOrgsWrite       Permission = "orgs:write"
+	PermissionIdentitiesRead  Permission = "identities:read"
+	PermissionIdentitiesWrite Permission = "identities:write"
+	PermissionTokensRead      Permission = "tokens:read"
+	PermissionTokensWrite     Permission = "tokens:write"
+)
+
+// SelfServicePermissions Разрешения, которые есть у каждого пользователя независимо от его ролей
+var SelfServicePermissions = []Permission{
+	PermissionOrgsRead,
+	PermissionOrgsWrite,
+	PermissionIdentitiesRead,
+	PermissionIdentitiesWrite,
+	PermissionTokensRead,
+	PermissionTokensWrite,
+}
+
+var permissionRegexp = regexp.MustCompile(`^[a-z][a-z0-9_-]*:[a-z][a-z0-9_-]*$`)
+
+func (p Permission) Valid() bool {
+	return p == PermissionAll || permissionRegexp.MatchString(string(p))
+}
+
+// PermissionInfo Разрешение, хранящееся в базе
+type PermissionInfo struct {
+	ID          int        `json:"id"`
+	Name        Permission `json:"name"`
+	Description string     `json:"description"`
+}
+
+func (p *PermissionInfo) Valid() error {
+	if p == nil {
+		return errors.New("permission empty")
+	}
+	if p.Name == PermissionAll || !p.Name.Valid() {
+		return errors.New("permission name not valid")
+	}
+	return nil
+}
+
+// AccessRole Роль как набор разрешений. Пользователю может быть назначено несколько ролей
+type AccessRole struct {
+	ID          int          `json:"id"`
+	Name        string       `json:"name"`
+	Description string       `json:"description"`
+	Builtin     bool         `json:"builtin"`
+	Permissions []Permission `json:"permissions"`
+}
+
+var roleNameRegexp = regexp.MustCompile(`^[a-z][a-z0-9_-]{1,63}$`)
+
+func (r *AccessRole) Valid() error {
+	if r == nil {
+		return errors.New("role empty")
+	}
+	if !roleNameRegexp.MatchString(r.Name) {
+		return errors.New("role name not valid")
+	}
+	return ValidPermissions(r.Permissions)
+}
+
+func ValidPermissions(permissions []Permission) error {
+	for _, p := range permissions {
+		if !p.Valid() {
+			return errors.New("permission not valid: " + string(p))
+		}
+	}
+	return nil
+}
+
+// HasPermission Проверяет, есть ли у пользователя все перечисленные разрешения
+func (a *AuthData) HasPermission(permissions ...Permission) bool {
+	if a == nil {
+		return false
+	}
+	for _, required := range permissions {
+		if !hasPermission(a.Permissions, required) {
+			return false
+		}
+	}
+	return true
+}
+
+func hasPermission(granted []Permission, required Permission) bool {
+	for _, p := range granted {
+		if p == PermissionAll || p == required {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,80 @@
+package domain
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// PersonalTokenPrefix Префикс, по которому персональный токен отличается от JWT
+const PersonalTokenPrefix = "lpat_"
+
+// IsPersonalToken Является ли токен персональным токеном доступа
+func IsPersonalToken(token string) bool {
+	return strings.HasPrefix(token, PersonalTokenPrefix)
+}
+
+// PersonalToken Долгоживущий токен доступа для скриптов и интеграций
+type PersonalToken struct {
+	ID     int    `json:"id"`
+	UserID int    `json:"user_id"`
+	Name   string `json:"name"`
+	// Prefix Начало токена, по которому его можно узнать в списке
+	Prefix     string       `json:"prefix"`
+	Scopes     []Permission `json:"scopes"`
+	ExpiresAt  *time.Time   `json:"expires_at"`
+	LastUsedAt *time.Time   `json:"last_used_at"`
+	RevokedAt  *time.Time   `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time    `json:"created_at"`
+	TokenHash  string       `json:"-"`
+}
+
+// Active Не отозван ли токен и не истек ли его срок
+func (t *PersonalToken) Active(now time.Time) bool {
+	return t.RevokedAt == nil && (t.ExpiresAt == nil || now.Before(*t.ExpiresAt))
+}
+
+// Permissions Разрешения токена: области токена, которые есть у пользователя.
+// Действия со своей учетной записью доступны любому пользователю
+func (t *PersonalToken) Permissions(granted []Permission) []Permission {
+	permissions := make([]Permission, 0, len(t.Scopes))
+	for _, scope := range t.Scopes {
+		if hasPermission(granted, scope) || slices.Contains(SelfServicePermissions, scope) {
+			permissions = append(permissions, scope)
+		}
+	}
+	return permissions
+}
+
+// PersonalTokenCreate Запрос на создание персонального токена
+type PersonalTokenCreate struct {
+	Name   string       `json:"name"`
+	Scopes []Permission `json:"scopes"`
+	// ExpiresAt Время истечения токена, без него токен бессрочный
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+func (c *PersonalTokenCreate) Valid() error {
+	if c == nil {
+		return errors.New("token empty")
+	}
+	length := utf8.RuneCountInString(c.Name)
+	if length < 1 || length > 100 {
+		return errors.New("name not valid")
+	}
+	if len(c.Scopes) == 0 {
+		return errors.New("scopes empty")
+	}
+	if c.ExpiresAt != nil && !c.ExpiresAt.After(time.Now()) {
+		return errors.New("expires_at must be in the future")
+	}
+	return ValidPermissions(c.Scopes)
+}
+
+// PersonalTokenCreated Созданный токен. Значение Token возвращается только один раз
+type PersonalTokenCreated struct {
+	*PersonalToken
+	Token string `json:"token"`
+}
@@ -0,0 +1,357 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"reflect"
+	"strings"
+)
+
+// PolicyEffect Результат применения правила
+type PolicyEffect string
+
+const (
+	EffectAllow PolicyEffect = "allow"
+	EffectDeny  PolicyEffect = "deny"
+)
+
+// Операторы условий правил
+const (
+	OperatorEq        = "eq"
+	OperatorNe        = "ne"
+	OperatorIn        = "in"
+	OperatorNotIn     = "not_in"
+	OperatorContains  = "contains"
+	OperatorExists    = "exists"
+	OperatorNotExists = "not_exists"
+	OperatorGt        = "gt"
+	OperatorGte       = "gte"
+	OperatorLt        = "lt"
+	OperatorLte       = "lte"
+)
+
+// Policy Набор декларативных правил авторизации.
+// Запрещающее правило имеет приоритет над разрешающим, если не подошло ни одно правило, применяется Default
+type Policy struct {
+	Default PolicyEffect  `yaml:"default" json:"default"`
+	Rules   []*PolicyRule `yaml:"rules" json:"rules"`
+}
+
+type PolicyRule struct {
+	Name        string       `yaml:"name" json:"name"`
+	Description string       `yaml:"description" json:"description,omitempty"`
+	Effect      PolicyEffect `yaml:"effect" json:"effect"`
+	// Actions Шаблоны действий, например "links:*"
+	Actions []string `yaml:"actions" json:"actions"`
+	// Resources Шаблоны типа ресурса (атрибут resource.type), пустой список подходит для любого ресурса
+	Resources  []string           `yaml:"resources" json:"resources,omitempty"`
+	Conditions []*PolicyCondition `yaml:"conditions" json:"conditions,omitempty"`
+}
+
+// PolicyCondition Условие над атрибутом запроса.
+// Attribute и Ref задаются путем вида "subject.id", "resource.owner_id", "context.ip"
+type PolicyCondition struct {
+	Attribute string `yaml:"attribute" json:"attribute"`
+	Operator  string `yaml:"operator" json:"operator"`
+	// Value Значение для сравнения
+	Value any `yaml:"value" json:"value,omitempty"`
+	// Ref Путь к другому атрибуту, с которым сравнивается Attribute, вместо Value
+	Ref string `yaml:"ref" json:"ref,omitempty"`
+}
+
+// AuthzRequest Запрос решения об авторизации
+type AuthzRequest struct {
+	// Subject Атрибуты субъекта заполняются сервисом по токену и не принимаются от вызывающего
+	Subject  map[string]any `json:"-"`
+	Action   string         `json:"action"`
+	Resource map[string]any `json:"resource"`
+	Context  map[string]any `json:"context"`
+	// Token Токен пользователя, из которого заполняются атрибуты subject. Без него субъектом является вызывающий
+	Token string `json:"token,omitempty"`
+	// Explain Вернуть результат проверки каждого правила
+	Explain bool `json:"explain"`
+	// Policy Политика для пробного вычисления (dry-run) вместо активной
+	Policy *Policy `json:"policy,omitempty"`
+}
+
+func (r *AuthzRequest) Valid() error {
+	if r == nil {
+		return errors.New("request empty")
+	}
+	if r.Action == "" {
+		return errors.New("action empty")
+	}
+	if r.Policy != nil {
+		return r.Policy.Valid()
+	}
+	return nil
+}
+
+// AuthzDecision Решение об авторизации
+type AuthzDecision struct {
+	Allow  bool         `json:"allow"`
+	Effect PolicyEffect `json:"effect"`
+	// Rule Правило, определившее решение; пустое, если применено решение по умолчанию
+	Rule   string       `json:"rule,omitempty"`
+	DryRun bool         `json:"dry_run,omitempty"`
+	Trace  []*RuleTrace `json:"trace,omitempty"`
+}
+
+// RuleTrace Результат проверки одного правила в режиме explain
+type RuleTrace struct {
+	Rule    string       `json:"rule"`
+	Effect  PolicyEffect `json:"effect"`
+	Matched bool         `json:"matched"`
+	Reason  string       `json:"reason,omitempty"`
+}
+
+// SetDefaults Заполняет незаданные поля политики при загрузке: без Default запрещено все, что не разрешено явно
+func (p *Policy) SetDefaults() {
+	if p.Default == "" {
+		p.Default = EffectDeny
+	}
+}
+
+func (p *Policy) Valid() error {
+	if p == nil {
+		return errors.New("policy empty")
+	}
+	switch p.Default {
+	case "", EffectAllow, EffectDeny:
+	default:
+		return errors.New("policy default effect not valid")
+	}
+	names := make(map[string]struct{}, len(p.Rules))
+	for i, rule := range p.Rules {
+		if rule == nil || rule.Name == "" {
+			return fmt.Errorf("rule %d: name empty", i)
+		}
+		if _, ok := names[rule.Name]; ok {
+			return fmt.Errorf("rule %s: duplicate name", rule.Name)
+		}
+		names[rule.Name] = struct{}{}
+
+		if rule.Effect != EffectAllow && rule.Effect != EffectDeny {
+			return fmt.Errorf("rule %s: effect not valid", rule.Name)
+		}
+		if len(rule.Actions) == 0 {
+			return fmt.Errorf("rule %s: actions empty", rule.Name)
+		}
+		for _, pattern := range append(append([]string{}, rule.Actions...), rule.Resources...) {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("rule %s: pattern %q not valid", rule.Name, pattern)
+			}
+		}
+		for _, c := range rule.Conditions {
+			if err := c.valid(); err != nil {
+				return fmt.Errorf("rule %s: %w", rule.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (c *PolicyCondition) valid() error {
+	if c == nil || c.Attribute == "" {
+		return errors.New("condition attribute empty")
+	}
+	switch c.Operator {
+	case OperatorExists, OperatorNotExists:
+		return nil
+	case OperatorEq, OperatorNe, OperatorIn, OperatorNotIn, OperatorContains,
+		OperatorGt, OperatorGte, OperatorLt, OperatorLte:
+	default:
+		return fmt.Errorf("condition operator %q not valid", c.Operator)
+	}
+	if c.Ref == "" && c.Value == nil {
+		return fmt.Errorf("condition on %s: value or ref required", c.Attribute)
+	}
+	return nil
+}
+
+// Evaluate Вычисляет решение по политике. При explain проверяются все правила и возвращается трассировка
+func (p *Policy) Evaluate(req *AuthzRequest, explain bool) *AuthzDecision {
+	var deny, allow *PolicyRule
+	var trace []*RuleTrace
+
+	for _, rule := range p.Rules {
+		matched, reason := rule.match(req)
+		if explain {
+			trace = append(trace, &RuleTrace{
+				Rule:    rule.Name,
+				Effect:  rule.Effect,
+				Matched: matched,
+				Reason:  reason,
+			})
+		}
+		if !matched {
+			continue
+		}
+		if rule.Effect == EffectDeny && deny == nil {
+			deny = rule
+			if !explain {
+				break
+			}
+		}
+		if rule.Effect == EffectAllow && allow == nil {
+			allow = rule
+		}
+	}
+
+	decision := &AuthzDecision{Effect: p.Default, Trace: trace}
+	switch {
+	case deny != nil:
+		decision.Effect, decision.Rule = EffectDeny, deny.Name
+	case allow != nil:
+		decision.Effect, decision.Rule = EffectAllow, allow.Name
+	}
+	decision.Allow = decision.Effect == EffectAllow
+	return decision
+}
+
+func (r *PolicyRule) match(req *AuthzRequest) (bool, string) {
+	if !matchAny(r.Actions, req.Action) {
+		return false, "action does not match"
+	}
+	if len(r.Resources) > 0 {
+		resourceType, _ := req.Resource["type"].(string)
+		if !matchAny(r.Resources, resourceType) {
+			return false, "resource type does not match"
+		}
+	}
+	for _, c := range r.Conditions {
+		if !c.match(req) {
+			return false, fmt.Sprintf("condition %s %s failed", c.Attribute, c.Operator)
+		}
+	}
+	return true, ""
+}
+
+func (c *PolicyCondition) match(req *AuthzRequest) bool {
+	actual, exists := req.attribute(c.Attribute)
+	switch c.Operator {
+	case OperatorExists:
+		return exists
+	case OperatorNotExists:
+		return !exists
+	}
+	if !exists {
+		return false
+	}
+
+	expected := c.Value
+	if c.Ref != "" {
+		var ok bool
+		expected, ok = req.attribute(c.Ref)
+		if !ok {
+			return false
+		}
+	}
+
+	switch c.Operator {
+	case OperatorEq:
+		return equalValues(actual, expected)
+	case OperatorNe:
+		return !equalValues(actual, expected)
+	case OperatorIn:
+		return containsValue(expected, actual)
+	case OperatorNotIn:
+		return !containsValue(expected, actual)
+	case OperatorContains:
+		return containsValue(actual, expected)
+	case OperatorGt, OperatorGte, OperatorLt, OperatorLte:
+		a, ok1 := toFloat(actual)
+		b, ok2 := toFloat(expected)
+		if !ok1 || !ok2 {
+			return false
+		}
+		switch c.Operator {
+		case OperatorGt:
+			return a > b
+		case OperatorGte:
+			return a >= b
+		case OperatorLt:
+			return a < b
+		default:
+			return a <= b
+		}
+	}
+	return false
+}
+
+// attribute Возвращает значение атрибута по пути вида "subject.id"
+func (r *AuthzRequest) attribute(name string) (any, bool) {
+	parts := strings.Split(name, ".")
+	var value any
+	switch parts[0] {
+	case "subject":
+		value = r.Subject
+	case "resource":
+		value = r.Resource
+	case "context":
+		value = r.Context
+	case "action":
+		return r.Action, len(parts) == 1
+	default:
+		return nil, false
+	}
+	for _, part := range parts[1:] {
+		m, ok := value.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		value, ok = m[part]
+		if !ok {
+			return nil, false
+		}
+	}
+	return value, value != nil
+}
+
+func matchAny(patterns []string, value string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, value); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func equalValues(a, b any) bool {
+	fa, ok1 := toFloat(a)
+	fb, ok2 := toFloat(b)
+	if ok1 && ok2 {
+		return fa == fb
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func containsValue(list any, value any) bool {
+	v := reflect.ValueOf(list)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return false
+	}
+	for i := 0; i < v.Len(); i++ {
+		if equalValues(v.Index(i).Interface(), value) {
+			return true
+		}
+	}
+	return false
+}
+
+func toFloat(v any) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	}
+	return 0, false
+}
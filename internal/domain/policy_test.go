@@ -0,0 +1,139 @@
+package domain
+
+import (
+	"testing"
+)
+
+func TestPolicyEvaluate(t *testing.T) {
+	policy := &Policy{
+		Default: EffectDeny,
+		Rules: []*PolicyRule{
+			{Name: "read-links", Effect: EffectAllow, Actions: []string{"links:read"}},
+			{Name: "write-own-links", Effect: EffectAllow, Actions: []string{"links:*"}, Resources: []string{"link*"},
+				Conditions: []*PolicyCondition{{Attribute: "resource.owner_id", Operator: OperatorEq, Ref: "subject.id"}}},
+			{Name: "deny-locked", Effect: EffectDeny, Actions: []string{"*"},
+				Conditions: []*PolicyCondition{{Attribute: "subject.locked", Operator: OperatorEq, Value: true}}},
+			{Name: "deny-nested", Effect: EffectDeny, Actions: []string{"links/*"}},
+		},
+	}
+	tests := []struct {
+		name     string
+		policy   *Policy
+		action   string
+		subject  map[string]any
+		resource map[string]any
+		want     PolicyEffect
+		wantRule string
+	}{
+		{name: "exact action", action: "links:read", subject: map[string]any{"id": 1}, want: EffectAllow, wantRule: "read-links"},
+		{name: "action pattern and owner ref", action: "links:delete", subject: map[string]any{"id": 1},
+			resource: map[string]any{"type": "link", "owner_id": float64(1)}, want: EffectAllow, wantRule: "write-own-links"},
+		{name: "resource pattern with suffix", action: "links:update", subject: map[string]any{"id": 1},
+			resource: map[string]any{"type": "links", "owner_id": 1}, want: EffectAllow, wantRule: "write-own-links"},
+		{name: "resource pattern mismatch", action: "links:update", subject: map[string]any{"id": 1},
+			resource: map[string]any{"type": "page", "owner_id": 1}, want: EffectDeny},
+		{name: "owner mismatch", action: "links:delete", subject: map[string]any{"id": 1},
+			resource: map[string]any{"type": "link", "owner_id": 2}, want: EffectDeny},
+		{name: "deny overrides allow", action: "links:read", subject: map[string]any{"id": 1, "locked": true},
+			want: EffectDeny, wantRule: "deny-locked"},
+		{name: "star does not cross slash", action: "links/a/b", subject: map[string]any{"id": 1}, want: EffectDeny},
+		{name: "star matches one segment", action: "links/a", subject: map[string]any{"id": 1}, want: EffectDeny, wantRule: "deny-nested"},
+		{name: "default deny", action: "users:read", subject: map[string]any{"id": 1}, want: EffectDeny},
+		{name: "default allow", policy: &Policy{Default: EffectAllow, Rules: policy.Rules}, action: "users:read",
+			subject: map[string]any{"id": 1}, want: EffectAllow},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := policy
+			if tt.policy != nil {
+				p = tt.policy
+			}
+			req := &AuthzRequest{Action: tt.action, Subject: tt.subject, Resource: tt.resource}
+			for _, explain := range []bool{false, true} {
+				decision := p.Evaluate(req, explain)
+				if decision.Effect != tt.want || decision.Rule != tt.wantRule || decision.Allow != (tt.want == EffectAllow) {
+					t.Fatalf("Evaluate(explain=%v) = %s %q, want %s %q", explain, decision.Effect, decision.Rule, tt.want, tt.wantRule)
+				}
+				if !explain && decision.Trace != nil {
+					t.Fatalf("Evaluate(explain=false) returned trace")
+				}
+			}
+		})
+	}
+}
+
+func TestPolicyEvaluateExplain(t *testing.T) {
+	policy := &Policy{
+		Default: EffectDeny,
+		Rules: []*PolicyRule{
+			{Name: "deny-first", Effect: EffectDeny, Actions: []string{"links:*"}},
+			{Name: "allow-after", Effect: EffectAllow, Actions: []string{"links:read"}},
+			{Name: "other-resource", Effect: EffectAllow, Actions: []string{"*"}, Resources: []string{"page"}},
+			{Name: "condition", Effect: EffectAllow, Actions: []string{"*"},
+				Conditions: []*PolicyCondition{{Attribute: "subject.role", Operator: OperatorLte, Value: 1}}},
+		},
+	}
+	req := &AuthzRequest{Action: "links:read", Subject: map[string]any{"role": 2}, Resource: map[string]any{"type": "link"}}
+
+	decision := policy.Evaluate(req, true)
+	if decision.Effect != EffectDeny || decision.Rule != "deny-first" {
+		t.Fatalf("Evaluate() = %s %q, want deny %q", decision.Effect, decision.Rule, "deny-first")
+	}
+	want := []RuleTrace{
+		{Rule: "deny-first", Effect: EffectDeny, Matched: true},
+		{Rule: "allow-after", Effect: EffectAllow, Matched: true},
+		{Rule: "other-resource", Effect: EffectAllow, Reason: "resource type does not match"},
+		{Rule: "condition", Effect: EffectAllow, Reason: "condition subject.role lte failed"},
+	}
+	if len(decision.Trace) != len(want) {
+		t.Fatalf("trace has %d rules, want %d", len(decision.Trace), len(want))
+	}
+	for i, trace := range decision.Trace {
+		if *trace != want[i] {
+			t.Errorf("trace[%d] = %+v, want %+v", i, *trace, want[i])
+		}
+	}
+}
+
+func TestPolicyValid(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  *Policy
+		wantErr bool
+	}{
+		{name: "empty default", policy: &Policy{}},
+		{name: "unknown default", policy: &Policy{Default: "maybe"}, wantErr: true},
+		{name: "bad pattern", policy: &Policy{Rules: []*PolicyRule{{Name: "a", Effect: EffectAllow, Actions: []string{"["}}}}, wantErr: true},
+		{name: "duplicate name", policy: &Policy{Rules: []*PolicyRule{
+			{Name: "a", Effect: EffectAllow, Actions: []string{"*"}},
+			{Name: "a", Effect: EffectDeny, Actions: []string{"*"}},
+		}}, wantErr: true},
+		{name: "condition without value", policy: &Policy{Rules: []*PolicyRule{{Name: "a", Effect: EffectAllow, Actions: []string{"*"},
+			Conditions: []*PolicyCondition{{Attribute: "subject.id", Operator: OperatorEq}}}}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			def := tt.policy.Default
+			err := tt.policy.Valid()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Valid() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.policy.Default != def {
+				t.Fatalf("Valid() changed default from %q to %q", def, tt.policy.Default)
+			}
+		})
+	}
+}
+
+func TestPolicySetDefaults(t *testing.T) {
+	p := &Policy{}
+	p.SetDefaults()
+	if p.Default != EffectDeny {
+		t.Fatalf("SetDefaults() default = %q, want %q", p.Default, EffectDeny)
+	}
+	p = &Policy{Default: EffectAllow}
+	p.SetDefaults()
+	if p.Default != EffectAllow {
+		t.Fatalf("SetDefaults() default = %q, want %q", p.Default, EffectAllow)
+	}
+}
@@ -1,5 +1,11 @@
 package domain
 
+import (
+	"errors"
+	"strconv"
+	"time"
+)
+
 type Role int
 
 const (
@@ -8,6 +14,69 @@ const (
 	RoleUser
 )
 
+var roleNames = map[Role]string{
+	RoleAdmin:     "admin",
+	RoleModerator: "moderator",
+	RoleUser:      "user",
+}
+
+// ParseRole Роль по имени (admin, moderator, user) или по номеру
+func ParseRole(value string) (Role, error) {
+	for role, name := range roleNames {
+		if name == value {
+			return role, nil
+		}
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || !Role(n).Valid() {
+		return 0, errors.New("role not valid")
+	}
+	return Role(n), nil
+}
+
+func (r Role) String() string {
+	if name, ok := roleNames[r]; ok {
+		return name
+	}
+	return strconv.Itoa(int(r))
+}
+
 func (r *Role) SetDefault() {
 	*r = RoleUser
 }
+
+func (r Role) Valid() bool {
+	switch r {
+	case RoleAdmin, RoleModerator, RoleUser:
+		return true
+	}
+	return false
+}
+
+// RoleChange Запись аудита изменения роли пользователя
+type RoleChange struct {
+	ID      int  `json:"id"`
+	UserID  int  `json:"user_id"`
+	OldRole Role `json:"old_role"`
+	NewRole Role `json:"new_role"`
+	// ChangedBy Администратор, изменивший роль; nil, если роль изменена через CLI
+	ChangedBy *int      `json:"changed_by,omitempty"`
+	Reason    string    `json:"reason,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// RoleUpdate Запрос на изменение роли пользователя
+type RoleUpdate struct {
+	Role   *Role  `json:"role"`
+	Reason string `json:"reason"`
+}
+
+func (u *RoleUpdate) Valid() error {
+	if u == nil || u.Role == nil {
+		return errors.New("role empty")
+	}
+	if !u.Role.Valid() {
+		return errors.New("role not valid")
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+package domain
+
+import "time"
+
+// SAMLIdentityPrefix Префикс провайдера в привязках учетных записей SAML, чтобы имена не пересекались с OAuth
+const SAMLIdentityPrefix = "saml:"
+
+// SAMLAuthnRequest Запрос аутентификации к провайдеру SAML
+type SAMLAuthnRequest struct {
+	ID string
+	// RedirectURL Адрес провайдера с запросом для привязки HTTP-Redirect
+	RedirectURL string
+	// PostForm HTML форма с запросом для привязки HTTP-POST
+	PostForm []byte
+}
+
+// SAMLAssertion Проверенное утверждение провайдера SAML
+type SAMLAssertion struct {
+	ID       string
+	Identity *ExternalIdentity
+	// Role Роль из атрибутов утверждения, nil - роль не синхронизируется
+	Role *Role
+	// ExpiresAt Время, после которого утверждение не принимается
+	ExpiresAt time.Time
+}
+
+// SAMLState Данные запроса аутентификации, сохраняемые до возврата пользователя по RelayState
+type SAMLState struct {
+	IdP       string `json:"idp"`
+	RequestID string `json:"request_id"`
+}
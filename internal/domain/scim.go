@@ -0,0 +1,492 @@
+package domain
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/go-playground/validator/v10"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// SCIMTokenPrefix Префикс токена, которым система управления учетными записями организации обращается к SCIM API
+	SCIMTokenPrefix = "lscim_"
+
+	SCIMUserSchema  = "urn:ietf:params:scim:schemas:core:2.0:User"
+	SCIMGroupSchema = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	SCIMListSchema  = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	SCIMPatchSchema = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	SCIMErrorSchema = "urn:ietf:params:scim:api:messages:2.0:Error"
+
+	DefaultSCIMCount = 100
+	MaxSCIMCount     = 200
+)
+
+// SCIMToken Токен SCIM организации. Все операции по токену выполняются в пределах его организации
+type SCIMToken struct {
+	ID    int    `json:"id"`
+	OrgID int    `json:"org_id"`
+	Name  string `json:"name"`
+	// Prefix Начало токена, по которому его можно узнать в списке
+	Prefix     string     `json:"prefix"`
+	CreatedBy  int        `json:"created_by"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	TokenHash  string     `json:"-"`
+}
+
+type SCIMTokenCreate struct {
+	Name string `json:"name"`
+}
+
+func (c *SCIMTokenCreate) Valid() error {
+	if c == nil {
+		return errors.New("token empty")
+	}
+	length := utf8.RuneCountInString(c.Name)
+	if length < 1 || length > 100 {
+		return errors.New("name not valid")
+	}
+	return nil
+}
+
+// SCIMTokenCreated Созданный токен. Значение Token возвращается только один раз
+type SCIMTokenCreated struct {
+	*SCIMToken
+	Token string `json:"token"`
+}
+
+// OrgGroup Группа организации, которой управляет система управления учетными записями
+type OrgGroup struct {
+	ID          int
+	OrgID       int
+	DisplayName string
+	ExternalID  *string
+	Members     []*OrgMember
+	CreatedAt   time.Time
+	UpdatedAt   *time.Time
+}
+
+// SCIMFilter Фильтр вида `attribute eq "value"`, другие операторы не поддерживаются
+type SCIMFilter struct {
+	Attribute string
+	Value     string
+}
+
+var scimFilterRegexp = regexp.MustCompile(`(?i)^\s*([A-Za-z][A-Za-z0-9.]*)\s+eq\s+("(?:[^"\\]|\\.)*")\s*$`)
+
+func ParseSCIMFilter(filter string) (*SCIMFilter, error) {
+	match := scimFilterRegexp.FindStringSubmatch(filter)
+	if match == nil {
+		return nil, errors.New("filter not supported")
+	}
+	value, err := strconv.Unquote(match[2])
+	if err != nil {
+		return nil, errors.New("filter value not valid")
+	}
+	return &SCIMFilter{Attribute: match[1], Value: value}, nil
+}
+
+// SCIMQuery Параметры выборки списка ресурсов. StartIndex считается с 1
+type SCIMQuery struct {
+	Filter     *SCIMFilter
+	StartIndex int
+	Count      int
+}
+
+// Valid Проверяет параметры и приводит имя атрибута фильтра к одному из attributes
+func (q *SCIMQuery) Valid(attributes ...string) error {
+	if q == nil {
+		return errors.New("query empty")
+	}
+	if q.StartIndex < 1 {
+		q.StartIndex = 1
+	}
+	if q.Count < 0 {
+		q.Count = 0
+	}
+	if q.Count > MaxSCIMCount {
+		q.Count = MaxSCIMCount
+	}
+	if q.Filter == nil {
+		return nil
+	}
+	for _, attribute := range attributes {
+		if strings.EqualFold(q.Filter.Attribute, attribute) {
+			q.Filter.Attribute = attribute
+			return nil
+		}
+	}
+	return errors.New("filter attribute not supported")
+}
+
+type SCIMMeta struct {
+	ResourceType string     `json:"resourceType"`
+	Created      time.Time  `json:"created"`
+	LastModified *time.Time `json:"lastModified,omitempty"`
+}
+
+type SCIMEmail struct {
+	Value   string `json:"value"`
+	Type    string `json:"type,omitempty"`
+	Primary bool   `json:"primary,omitempty"`
+}
+
+// SCIMMemberRef Ссылка на участника группы или на группу пользователя
+type SCIMMemberRef struct {
+	Value   string `json:"value"`
+	Display string `json:"display,omitempty"`
+}
+
+// SCIMUser Ресурс User. userName - почта пользователя, id - идентификатор пользователя
+type SCIMUser struct {
+	Schemas    []string        `json:"schemas"`
+	ID         string          `json:"id,omitempty"`
+	ExternalID string          `json:"externalId,omitempty"`
+	UserName   string          `json:"userName"`
+	Active     *bool           `json:"active,omitempty"`
+	Emails     []SCIMEmail     `json:"emails,omitempty"`
+	Groups     []SCIMMemberRef `json:"groups,omitempty"`
+	Meta       *SCIMMeta       `json:"meta,omitempty"`
+}
+
+func NewSCIMUser(member *OrgMember, groups []*OrgGroup) *SCIMUser {
+	active := member.Active
+	user := &SCIMUser{
+		Schemas:  []string{SCIMUserSchema},
+		ID:       strconv.Itoa(member.UserID),
+		UserName: member.Email,
+		Active:   &active,
+		Emails:   []SCIMEmail{{Value: member.Email, Type: "work", Primary: true}},
+		Groups:   make([]SCIMMemberRef, 0, len(groups)),
+		Meta: &SCIMMeta{
+			ResourceType: "User",
+			Created:      member.CreatedAt,
+			LastModified: member.UpdatedAt,
+		},
+	}
+	if member.ExternalID != nil {
+		user.ExternalID = *member.ExternalID
+	}
+	for _, group := range groups {
+		user.Groups = append(user.Groups, SCIMMemberRef{Value: strconv.Itoa(group.ID), Display: group.DisplayName})
+	}
+	return user
+}
+
+// Email Почта пользователя: userName, если это почта, иначе основная почта из emails
+func (u *SCIMUser) Email() string {
+	vl := validator.New()
+	if vl.Var(u.UserName, "email") == nil {
+		return strings.ToLower(u.UserName)
+	}
+	for _, email := range u.Emails {
+		if email.Primary && vl.Var(email.Value, "email") == nil {
+			return strings.ToLower(email.Value)
+		}
+	}
+	return ""
+}
+
+// IsActive Пользователь без атрибута active считается активным
+func (u *SCIMUser) IsActive() bool {
+	return u.Active == nil || *u.Active
+}
+
+func (u *SCIMUser) Valid() error {
+	if u == nil {
+		return errors.New("user empty")
+	}
+	if u.Email() == "" {
+		return errors.New("userName not valid")
+	}
+	if utf8.RuneCountInString(u.ExternalID) > 255 {
+		return errors.New("externalId not valid")
+	}
+	return nil
+}
+
+// Patch Применяет операции PATCH к ресурсу. Атрибуты, которые не хранятся, игнорируются
+func (u *SCIMUser) Patch(operations []SCIMPatchOperation) error {
+	for _, operation := range operations {
+		op := strings.ToLower(operation.Op)
+		if op != "add" && op != "replace" && op != "remove" {
+			return errors.New("patch op not valid")
+		}
+		if operation.Path == "" {
+			if op == "remove" {
+				return errors.New("patch path required")
+			}
+			var values map[string]json.RawMessage
+			err := json.Unmarshal(operation.Value, &values)
+			if err != nil {
+				return errors.New("patch value not valid")
+			}
+			for path, value := range values {
+				err = u.patchAttribute(op, path, value)
+				if err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		err := u.patchAttribute(op, operation.Path, operation.Value)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (u *SCIMUser) patchAttribute(op string, path string, value json.RawMessage) error {
+	switch strings.ToLower(path) {
+	case "active":
+		if op == "remove" {
+			return errors.New("active can not be removed")
+		}
+		active, err := scimBool(value)
+		if err != nil {
+			return err
+		}
+		u.Active = &active
+	case "username":
+		if op == "remove" {
+			return errors.New("userName can not be removed")
+		}
+		return scimString(value, &u.UserName)
+	case "externalid":
+		if op == "remove" {
+			u.ExternalID = ""
+			return nil
+		}
+		return scimString(value, &u.ExternalID)
+	}
+	return nil
+}
+
+// SCIMGroup Ресурс Group, участники указываются идентификаторами пользователей
+type SCIMGroup struct {
+	Schemas     []string        `json:"schemas"`
+	ID          string          `json:"id,omitempty"`
+	ExternalID  string          `json:"externalId,omitempty"`
+	DisplayName string          `json:"displayName"`
+	Members     []SCIMMemberRef `json:"members"`
+	Meta        *SCIMMeta       `json:"meta,omitempty"`
+}
+
+func NewSCIMGroup(group *OrgGroup) *SCIMGroup {
+	scimGroup := &SCIMGroup{
+		Schemas:     []string{SCIMGroupSchema},
+		ID:          strconv.Itoa(group.ID),
+		DisplayName: group.DisplayName,
+		Members:     make([]SCIMMemberRef, 0, len(group.Members)),
+		Meta: &SCIMMeta{
+			ResourceType: "Group",
+			Created:      group.CreatedAt,
+			LastModified: group.UpdatedAt,
+		},
+	}
+	if group.ExternalID != nil {
+		scimGroup.ExternalID = *group.ExternalID
+	}
+	for _, member := range group.Members {
+		scimGroup.Members = append(scimGroup.Members, SCIMMemberRef{Value: strconv.Itoa(member.UserID), Display: member.Email})
+	}
+	return scimGroup
+}
+
+func (g *SCIMGroup) Valid() error {
+	if g == nil {
+		return errors.New("group empty")
+	}
+	length := utf8.RuneCountInString(g.DisplayName)
+	if length < 1 || length > 255 {
+		return errors.New("displayName not valid")
+	}
+	if utf8.RuneCountInString(g.ExternalID) > 255 {
+		return errors.New("externalId not valid")
+	}
+	_, err := g.MemberIDs()
+	return err
+}
+
+// MemberIDs Идентификаторы пользователей - участников группы без повторов
+func (g *SCIMGroup) MemberIDs() ([]int, error) {
+	ids := make([]int, 0, len(g.Members))
+	seen := make(map[int]bool, len(g.Members))
+	for _, member := range g.Members {
+		id, err := strconv.Atoi(member.Value)
+		if err != nil || id <= 0 {
+			return nil, errors.New("member value not valid")
+		}
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// Patch Применяет операции PATCH к группе, включая пути вида members[value eq "id"]
+func (g *SCIMGroup) Patch(operations []SCIMPatchOperation) error {
+	for _, operation := range operations {
+		op := strings.ToLower(operation.Op)
+		if op != "add" && op != "replace" && op != "remove" {
+			return errors.New("patch op not valid")
+		}
+		if operation.Path == "" {
+			if op == "remove" {
+				return errors.New("patch path required")
+			}
+			var values map[string]json.RawMessage
+			err := json.Unmarshal(operation.Value, &values)
+			if err != nil {
+				return errors.New("patch value not valid")
+			}
+			for path, value := range values {
+				err = g.patchAttribute(op, path, value)
+				if err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		err := g.patchAttribute(op, operation.Path, operation.Value)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var scimMemberPathRegexp = regexp.MustCompile(`(?i)^members\[(.+)\]$`)
+
+func (g *SCIMGroup) patchAttribute(op string, path string, value json.RawMessage) error {
+	if match := scimMemberPathRegexp.FindStringSubmatch(path); match != nil {
+		filter, err := ParseSCIMFilter(match[1])
+		if err != nil || !strings.EqualFold(filter.Attribute, "value") || op != "remove" {
+			return errors.New("patch path not supported")
+		}
+		g.removeMembers([]SCIMMemberRef{{Value: filter.Value}})
+		return nil
+	}
+
+	switch strings.ToLower(path) {
+	case "displayname":
+		if op == "remove" {
+			return errors.New("displayName can not be removed")
+		}
+		return scimString(value, &g.DisplayName)
+	case "externalid":
+		if op == "remove" {
+			g.ExternalID = ""
+			return nil
+		}
+		return scimString(value, &g.ExternalID)
+	case "members":
+		var members []SCIMMemberRef
+		if len(value) != 0 {
+			err := json.Unmarshal(value, &members)
+			if err != nil {
+				return errors.New("members not valid")
+			}
+		}
+		switch op {
+		case "add":
+			g.Members = append(g.Members, members...)
+		case "replace":
+			g.Members = members
+		case "remove":
+			if len(value) == 0 {
+				g.Members = nil
+			} else {
+				g.removeMembers(members)
+			}
+		}
+	}
+	return nil
+}
+
+func (g *SCIMGroup) removeMembers(members []SCIMMemberRef) {
+	remove := make(map[string]bool, len(members))
+	for _, member := range members {
+		remove[member.Value] = true
+	}
+	kept := g.Members[:0]
+	for _, member := range g.Members {
+		if !remove[member.Value] {
+			kept = append(kept, member)
+		}
+	}
+	g.Members = kept
+}
+
+type SCIMPatch struct {
+	Schemas    []string             `json:"schemas"`
+	Operations []SCIMPatchOperation `json:"Operations"`
+}
+
+func (p *SCIMPatch) Valid() error {
+	if p == nil || len(p.Operations) == 0 {
+		return errors.New("operations empty")
+	}
+	return nil
+}
+
+type SCIMPatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+type SCIMListResponse struct {
+	Schemas      []string `json:"schemas"`
+	TotalResults int      `json:"totalResults"`
+	StartIndex   int      `json:"startIndex"`
+	ItemsPerPage int      `json:"itemsPerPage"`
+	Resources    any      `json:"Resources"`
+}
+
+func NewSCIMListResponse(resources any, count int, total int, startIndex int) *SCIMListResponse {
+	return &SCIMListResponse{
+		Schemas:      []string{SCIMListSchema},
+		TotalResults: total,
+		StartIndex:   startIndex,
+		ItemsPerPage: count,
+		Resources:    resources,
+	}
+}
+
+type SCIMError struct {
+	Schemas  []string `json:"schemas"`
+	Status   string   `json:"status"`
+	ScimType string   `json:"scimType,omitempty"`
+	Detail   string   `json:"detail,omitempty"`
+}
+
+// scimBool Разбирает логическое значение. Некоторые системы передают его строкой "True"/"False"
+func scimBool(value json.RawMessage) (bool, error) {
+	var b bool
+	if json.Unmarshal(value, &b) == nil {
+		return b, nil
+	}
+	var s string
+	if json.Unmarshal(value, &s) == nil {
+		if b, err := strconv.ParseBool(strings.ToLower(s)); err == nil {
+			return b, nil
+		}
+	}
+	return false, errors.New("boolean value not valid")
+}
+
+func scimString(value json.RawMessage, dst *string) error {
+	err := json.Unmarshal(value, dst)
+	if err != nil {
+		return errors.New("string value not valid")
+	}
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"github.com/go-playground/validator/v10"
 	"regexp"
+	"time"
 	"unicode/utf8"
 )
 
@@ -59,4 +60,9 @@ type UserFromDB struct {
 	Email        string
 	HashPassword []byte
 	Role         Role
+	Verified     bool
+	Locked       bool
+	CreatedAt    time.Time
+	UpdatedAt    *time.Time
+	DeletedAt    *time.Time
 }
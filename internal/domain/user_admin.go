@@ -0,0 +1,118 @@
+package domain
+
+import (
+	"errors"
+	"github.com/go-playground/validator/v10"
+	"time"
+)
+
+// UserSort Поле, по которому сортируется список пользователей
+type UserSort string
+
+const (
+	UserSortID        UserSort = "id"
+	UserSortEmail     UserSort = "email"
+	UserSortCreatedAt UserSort = "created_at"
+
+	DefaultUsersLimit = 20
+	MaxUsersLimit     = 100
+)
+
+// UserFilter Параметры выборки пользователей для администрирования
+type UserFilter struct {
+	Role        *Role
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+	Verified    *bool
+	Locked      *bool
+	Deleted     *bool
+	// EmailPrefix Поиск по началу email без учета регистра
+	EmailPrefix string
+	Sort        UserSort
+	Desc        bool
+	// Cursor Непрозрачный курсор, полученный в UserPage.NextCursor
+	Cursor string
+	Limit  int
+}
+
+func (f *UserFilter) Valid() error {
+	if f == nil {
+		return errors.New("filter empty")
+	}
+	switch f.Sort {
+	case "":
+		f.Sort = UserSortID
+	case UserSortID, UserSortEmail, UserSortCreatedAt:
+	default:
+		return errors.New("sort not valid")
+	}
+	if f.Limit == 0 {
+		f.Limit = DefaultUsersLimit
+	}
+	if f.Limit < 0 || f.Limit > MaxUsersLimit {
+		return errors.New("limit not valid")
+	}
+	if f.Role != nil && !f.Role.Valid() {
+		return errors.New("role not valid")
+	}
+	if f.CreatedFrom != nil && f.CreatedTo != nil && f.CreatedFrom.After(*f.CreatedTo) {
+		return errors.New("created range not valid")
+	}
+	return nil
+}
+
+// UserInfo Данные пользователя, доступные администратору
+type UserInfo struct {
+	ID        int        `json:"id"`
+	Email     string     `json:"email"`
+	Role      Role       `json:"role"`
+	Verified  bool       `json:"verified"`
+	Locked    bool       `json:"locked"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+func NewUserInfo(user *UserFromDB) *UserInfo {
+	return &UserInfo{
+		ID:        user.ID,
+		Email:     user.Email,
+		Role:      user.Role,
+		Verified:  user.Verified,
+		Locked:    user.Locked,
+		CreatedAt: user.CreatedAt,
+		UpdatedAt: user.UpdatedAt,
+		DeletedAt: user.DeletedAt,
+	}
+}
+
+// UserPage Страница списка пользователей
+type UserPage struct {
+	Users []*UserInfo `json:"users"`
+	// NextCursor Курсор следующей страницы, пустой на последней странице
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// UserUpdate Изменяемые администратором поля пользователя, nil означает "не менять"
+type UserUpdate struct {
+	Email    *string `json:"email"`
+	Verified *bool   `json:"verified"`
+	Locked   *bool   `json:"locked"`
+}
+
+func (u *UserUpdate) Valid() error {
+	if u == nil {
+		return errors.New("update empty")
+	}
+	if u.Email == nil && u.Verified == nil && u.Locked == nil {
+		return errors.New("nothing to update")
+	}
+	if u.Email != nil {
+		vl := validator.New()
+		err := vl.Var(*u.Email, "required,email")
+		if err != nil {
+			return err.(validator.ValidationErrors)[0]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package handler
+
+import (
+	"auth/internal/requestid"
+	"bytes"
+	"context"
+	"encoding/json"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// requestInfo Сведения о запросе, которые становятся известны внутренним обработчикам, но нужны в записи лога доступа
+type requestInfo struct {
+	userID int
+}
+
+// SetUserID Запоминает пользователя, выполнившего запрос, для записи в лог доступа
+func SetUserID(ctx context.Context, userID int) {
+	if info, ok := ctx.Value(requestInfoKey).(*requestInfo); ok {
+		info.userID = userID
+	}
+}
+
+// requestMiddleware Принимает идентификатор запроса из X-Request-ID или создает новый, возвращает его в заголовке ответа
+// и в теле ответа с ошибкой и пишет по одной записи в лог доступа на каждый запрос
+func (h *handler) requestMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		id := requestid.FromHeader(r.Header.Get(requestid.Header))
+		w.Header().Set(requestid.Header, id)
+
+		info := &requestInfo{}
+		ctx := requestid.NewContext(r.Context(), id)
+		ctx = context.WithValue(ctx, requestInfoKey, info)
+
+		writer := &requestWriter{statusRecorder: statusRecorder{ResponseWriter: w}, requestID: id}
+		next.ServeHTTP(writer, r.WithContext(ctx))
+		writer.flushError()
+
+		h.accessLog(r, id, writer.code(), time.Since(start), info.userID)
+	})
+}
+
+// accessLog Запись в формате ключ=значение, чтобы ее можно было разобрать сборщиком логов.
+// Частые проверки состояния и метрик пишутся на уровне debug
+func (h *handler) accessLog(r *http.Request, id string, status int, duration time.Duration, userID int) {
+	user := "-"
+	if userID != 0 {
+		user = strconv.Itoa(userID)
+	}
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+	forwarded := r.Header.Get("X-Forwarded-For")
+	if forwarded == "" {
+		forwarded = "-"
+	}
+	route := routeTemplate(r)
+	write := h.log.Infof
+	if h.probe(route) {
+		write = h.log.Debugf
+	}
+	write("access request_id=%s method=%s route=%s status=%d duration_ms=%.3f user_id=%s ip=%s forwarded_for=%q",
+		id, r.Method, route, status, float64(duration.Microseconds())/1000, user, ip, forwarded)
+}
+
+// requestWriter Задерживает тело ответа с ошибкой, чтобы добавить в него идентификатор запроса
+type requestWriter struct {
+	statusRecorder
+	requestID string
+	errorBody bytes.Buffer
+}
+
+func (w *requestWriter) WriteHeader(status int) {
+	if w.status != 0 {
+		return
+	}
+	if status < http.StatusBadRequest {
+		w.statusRecorder.WriteHeader(status)
+		return
+	}
+	w.status = status
+}
+
+func (w *requestWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.status < http.StatusBadRequest {
+		return w.ResponseWriter.Write(b)
+	}
+	return w.errorBody.Write(b)
+}
+
+// Unwrap Дает http.ResponseController доступ к исходному ResponseWriter
+func (w *requestWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// flushError Отправляет задержанный ответ с ошибкой. Поле request_id добавляется только в стандартный ответ application/json,
+// остальные ответы, например SCIM со своей схемой ошибки, отправляются без изменений и несут идентификатор только в заголовке
+func (w *requestWriter) flushError() {
+	if w.status < http.StatusBadRequest {
+		return
+	}
+	body := w.errorBody.Bytes()
+	if mediaType, _, err := mime.ParseMediaType(w.Header().Get("Content-Type")); err == nil && mediaType == "application/json" {
+		var fields map[string]json.RawMessage
+		if json.Unmarshal(body, &fields) == nil && fields != nil {
+			if _, ok := fields["request_id"]; !ok {
+				fields["request_id"], _ = json.Marshal(w.requestID)
+				if b, err := json.Marshal(fields); err == nil {
+					body = b
+				}
+			}
+		}
+	}
+	w.Header().Del("Content-Length")
+	w.ResponseWriter.WriteHeader(w.status)
+	_, _ = w.ResponseWriter.Write(body)
+}
@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"net/http"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// corsMiddleware Добавляет заголовки CORS к ответам на запросы с разрешенных источников
+func (h *handler) corsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin != "" {
+			w.Header().Add("Vary", "Origin")
+			if h.allowedOrigin(origin) {
+				h.setOrigin(w, origin)
+				if len(h.corsCfg.ExposedHeaders) != 0 {
+					w.Header().Set("Access-Control-Expose-Headers", strings.Join(h.corsCfg.ExposedHeaders, ", "))
+				}
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// preflight Отвечает на предварительный запрос браузера. Запрос с неразрешенного источника или с неразрешенным методом
+// получает 403 без заголовков CORS
+func (h *handler) preflight(w http.ResponseWriter, r *http.Request) {
+	origin := r.Header.Get("Origin")
+	method := r.Header.Get("Access-Control-Request-Method")
+	if origin == "" || method == "" {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	w.Header().Add("Vary", "Access-Control-Request-Method")
+	w.Header().Add("Vary", "Access-Control-Request-Headers")
+
+	if !h.allowedOrigin(origin) || !slices.Contains(h.corsCfg.AllowedMethods, strings.ToUpper(method)) {
+		// Заголовки, которые добавил corsMiddleware, не должны попасть в отказ
+		w.Header().Del("Access-Control-Allow-Origin")
+		w.Header().Del("Access-Control-Allow-Credentials")
+		w.Header().Del("Access-Control-Expose-Headers")
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	h.setOrigin(w, origin)
+	w.Header().Set("Access-Control-Allow-Methods", strings.Join(h.corsCfg.AllowedMethods, ", "))
+	if len(h.corsCfg.AllowedHeaders) != 0 {
+		w.Header().Set("Access-Control-Allow-Headers", strings.Join(h.corsCfg.AllowedHeaders, ", "))
+	}
+	if h.corsCfg.MaxAge > 0 {
+		w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(h.corsCfg.MaxAge.Seconds())))
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *handler) setOrigin(w http.ResponseWriter, origin string) {
+	if slices.Contains(h.corsCfg.AllowedOrigins, "*") {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		return
+	}
+	w.Header().Set("Access-Control-Allow-Origin", origin)
+	if h.corsCfg.AllowCredentials {
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+	}
+}
+
+// allowedOrigin Источник совпадает с одним из AllowedOrigins с учетом * без учета регистра.
+// Любой источник не разрешается при AllowCredentials, даже если конфигурация не была проверена при загрузке
+func (h *handler) allowedOrigin(origin string) bool {
+	origin = strings.ToLower(origin)
+	for _, pattern := range h.corsCfg.AllowedOrigins {
+		if pattern == "*" {
+			if h.corsCfg.AllowCredentials {
+				continue
+			}
+			return true
+		}
+		if ok, err := path.Match(strings.ToLower(pattern), origin); err == nil && ok {
+			return true
+		}
+	}
+	return false
+}
@@ -1,5 +1,7 @@
 package handler
 
 const (
-	ValidationError = "Validation error"
+	ValidationError      = "Validation error"
+	ClientCertRequired   = "Client certificate required"
+	ClientCertNotAllowed = "Client certificate is not allowed"
 )
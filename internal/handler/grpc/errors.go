@@ -0,0 +1,34 @@
+package grpc
+
+import (
+	"auth/internal/repository"
+	"auth/internal/service"
+	"context"
+	"errors"
+	"github.com/Linkify-Company/common_utils/errify"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const internalError = "internal error"
+
+// statusError Переводит ошибку сервиса в статус gRPC. Подробности внутренних ошибок только логируются
+func (h *handler) statusError(ctx context.Context, err errify.IError) error {
+	switch err.(type) {
+	case *service.ForbiddenError:
+		return status.Error(codes.PermissionDenied, service.ErrAccessDenied.Error())
+	case *errify.UnauthorizedError:
+		if errors.Is(err, service.ErrTokenExpired) {
+			return status.Error(codes.Unauthenticated, service.ErrTokenExpired.Error())
+		}
+		return status.Error(codes.Unauthenticated, service.ErrInvalidCredentials.Error())
+	case *errify.BadRequestError:
+		if err.Error() == repository.UserNotExist.Error() {
+			return status.Error(codes.NotFound, service.UserNotExist.Error())
+		}
+		return status.Error(codes.InvalidArgument, err.Error())
+	default:
+		h.logger(ctx).Error(err)
+		return status.Error(codes.Internal, internalError)
+	}
+}
@@ -0,0 +1,135 @@
+package grpc
+
+import (
+	"auth/internal/config"
+	"auth/internal/domain"
+	hr "auth/internal/handler"
+	"auth/internal/service"
+	authv1 "auth/pkg/api/auth/v1"
+	"context"
+	"github.com/Linkify-Company/common_utils/errify"
+	"github.com/Linkify-Company/common_utils/logger"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"math"
+)
+
+// handler Реализация authv1.AuthServiceServer поверх того же слоя сервисов, что и HTTP обработчики
+type handler struct {
+	authv1.UnimplementedAuthServiceServer
+
+	cfg     *config.HandlerConfig
+	tlsCfg  *config.TLSConfig
+	log     logger.Logger
+	service *service.Service
+}
+
+func (h *handler) CheckAuthorization(ctx context.Context, req *authv1.CheckAuthorizationRequest) (*authv1.CheckAuthorizationResponse, error) {
+	if req.GetAccessToken() == "" {
+		return nil, status.Error(codes.Unauthenticated, service.ErrInvalidCredentials.Error())
+	}
+	ctx, cancel := context.WithTimeout(ctx, h.cfg.ContextTimeout)
+	defer cancel()
+
+	user, err := h.service.CheckAuthorization(ctx, req.GetAccessToken())
+	if err != nil {
+		return nil, h.statusError(ctx, err.JoinLoc("CheckAuthorization"))
+	}
+	permissions := make([]string, 0, len(user.Permissions))
+	for _, permission := range user.Permissions {
+		permissions = append(permissions, string(permission))
+	}
+	return &authv1.CheckAuthorizationResponse{
+		UserId:          int64(user.ID),
+		Email:           user.Email,
+		Role:            int32(user.Role),
+		Permissions:     permissions,
+		OrgId:           int64(user.OrgID),
+		OrgRole:         int32(user.OrgRole),
+		PersonalTokenId: int64(user.PersonalTokenID),
+	}, nil
+}
+
+func (h *handler) GetUser(ctx context.Context, req *authv1.GetUserRequest) (*authv1.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, h.cfg.ContextTimeout)
+	defer cancel()
+
+	var user *domain.User
+	var err errify.IError
+
+	switch lookup := req.GetLookup().(type) {
+	case *authv1.GetUserRequest_Id:
+		id, ok := userID(lookup.Id)
+		if !ok {
+			return nil, status.Error(codes.InvalidArgument, hr.ValidationError)
+		}
+		user, err = h.service.GetUserByID(ctx, id)
+	case *authv1.GetUserRequest_Email:
+		if lookup.Email == "" {
+			return nil, status.Error(codes.InvalidArgument, hr.ValidationError)
+		}
+		user, err = h.service.GetUserByEmail(ctx, lookup.Email)
+	default:
+		return nil, status.Error(codes.InvalidArgument, hr.ValidationError)
+	}
+	if err != nil {
+		return nil, h.statusError(ctx, err.JoinLoc("GetUser"))
+	}
+	return newUser(user), nil
+}
+
+func (h *handler) GetUsers(ctx context.Context, req *authv1.GetUsersRequest) (*authv1.GetUsersResponse, error) {
+	if len(req.GetIds()) == 0 || len(req.GetIds()) > domain.MaxUsersLimit {
+		return nil, status.Error(codes.InvalidArgument, hr.ValidationError)
+	}
+	ids := make([]int, 0, len(req.GetIds()))
+	for _, value := range req.GetIds() {
+		id, ok := userID(value)
+		if !ok {
+			return nil, status.Error(codes.InvalidArgument, hr.ValidationError)
+		}
+		ids = append(ids, id)
+	}
+	ctx, cancel := context.WithTimeout(ctx, h.cfg.ContextTimeout)
+	defer cancel()
+
+	users, err := h.service.GetUsersByIDs(ctx, ids)
+	if err != nil {
+		return nil, h.statusError(ctx, err.JoinLoc("GetUsers"))
+	}
+	res := &authv1.GetUsersResponse{Users: make([]*authv1.User, 0, len(users))}
+	for _, user := range users {
+		res.Users = append(res.Users, newUser(user))
+	}
+	return res, nil
+}
+
+func (h *handler) RevokeSessions(ctx context.Context, req *authv1.RevokeSessionsRequest) (*authv1.RevokeSessionsResponse, error) {
+	id, ok := userID(req.GetUserId())
+	if !ok {
+		return nil, status.Error(codes.InvalidArgument, hr.ValidationError)
+	}
+	ctx, cancel := context.WithTimeout(ctx, h.cfg.ContextTimeout)
+	defer cancel()
+
+	err := h.service.RevokeSessions(ctx, id)
+	if err != nil {
+		return nil, h.statusError(ctx, err.JoinLoc("RevokeSessions"))
+	}
+	return &authv1.RevokeSessionsResponse{}, nil
+}
+
+func userID(value int64) (int, bool) {
+	if value <= 0 || value > math.MaxInt32 {
+		return 0, false
+	}
+	return int(value), true
+}
+
+func newUser(user *domain.User) *authv1.User {
+	return &authv1.User{
+		Id:    int64(user.ID),
+		Email: user.Email,
+		Role:  int32(user.Role),
+	}
+}
@@ -0,0 +1,160 @@
+package grpc
+
+import (
+	"auth/internal/config"
+	"auth/internal/domain"
+	hr "auth/internal/handler"
+	"auth/internal/requestid"
+	"auth/internal/service"
+	authv1 "auth/pkg/api/auth/v1"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"github.com/Linkify-Company/common_utils/errify"
+	"github.com/Linkify-Company/common_utils/logger"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+	"strings"
+)
+
+// methodPermissions Разрешения, которые нужны вызывающему для метода. Методы вне списка доступны без токена
+var methodPermissions = map[string][]domain.Permission{
+	authv1.AuthService_GetUser_FullMethodName:        {domain.PermissionUsersRead},
+	authv1.AuthService_GetUsers_FullMethodName:       {domain.PermissionUsersRead},
+	authv1.AuthService_RevokeSessions_FullMethodName: {domain.PermissionUsersWrite},
+}
+
+// NewServer Создает gRPC сервер с API авторизации, проверкой состояния и, если включено, рефлексией.
+// С tlsConfig сервер принимает только TLS соединения, а методы из TLSConfig.ClientCertPaths требуют сертификат клиента
+func NewServer(
+	cfg *config.GRPCConfig,
+	handlerCfg *config.HandlerConfig,
+	tlsCfg *config.TLSConfig,
+	tlsConfig *tls.Config,
+	log logger.Logger,
+	service *service.Service,
+) *grpc.Server {
+	h := &handler{
+		cfg:     handlerCfg,
+		tlsCfg:  tlsCfg,
+		log:     log,
+		service: service,
+	}
+	opts := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(h.requestIDInterceptor, h.panicInterceptor, h.clientCertInterceptor, h.authInterceptor),
+	}
+	if tlsConfig != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
+	server := grpc.NewServer(opts...)
+
+	authv1.RegisterAuthServiceServer(server, h)
+
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus(authv1.AuthService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(server, healthServer)
+
+	if cfg.Reflection {
+		reflection.Register(server)
+	}
+	for name := range server.GetServiceInfo() {
+		log.Debugf("gRPC %s", name)
+	}
+	return server
+}
+
+// requestIDInterceptor Берет идентификатор запроса из метаданных x-request-id или создает новый
+// и возвращает его в заголовке ответа, как это делает HTTP сервер
+func (h *handler) requestIDInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, next grpc.UnaryHandler) (any, error) {
+	var value string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(requestid.Header); len(values) > 0 {
+			value = values[0]
+		}
+	}
+	id := requestid.FromHeader(value)
+	_ = grpc.SetHeader(ctx, metadata.Pairs(requestid.Header, id))
+
+	return next(requestid.NewContext(ctx, id), req)
+}
+
+// logger Логгер вызова, записи которого содержат идентификатор запроса
+func (h *handler) logger(ctx context.Context) logger.Logger {
+	return requestid.Logger(ctx, h.log)
+}
+
+func (h *handler) panicInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, next grpc.UnaryHandler) (resp any, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			h.logger(ctx).Error(errify.NewInternalServerError(fmt.Sprint(r), info.FullMethod).SetDetails("There was a panic in the gRPC server"))
+			err = status.Error(codes.Internal, internalError)
+		}
+	}()
+
+	return next(ctx, req)
+}
+
+// clientCertInterceptor Пропускает вызовы методов из TLSConfig.ClientCertPaths только с проверенным сертификатом клиента
+func (h *handler) clientCertInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, next grpc.UnaryHandler) (any, error) {
+	if !hr.RequiresClientCert(h.tlsCfg, info.FullMethod) {
+		return next(ctx, req)
+	}
+	var state *tls.ConnectionState
+	if p, ok := peer.FromContext(ctx); ok {
+		if tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo); ok {
+			state = &tlsInfo.State
+		}
+	}
+	identity, err := hr.ClientIdentity(h.tlsCfg, state)
+	if err != nil {
+		if err.Error() == hr.ClientCertNotAllowed {
+			return nil, status.Error(codes.PermissionDenied, hr.ClientCertNotAllowed)
+		}
+		return nil, status.Error(codes.Unauthenticated, hr.ClientCertRequired)
+	}
+	h.logger(ctx).Debugf("gRPC %s called by %s", info.FullMethod, identity)
+	return next(ctx, req)
+}
+
+// authInterceptor Проверяет токен из метаданных authorization и разрешения вызывающего для методов из methodPermissions
+func (h *handler) authInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, next grpc.UnaryHandler) (any, error) {
+	permissions, ok := methodPermissions[info.FullMethod]
+	if !ok {
+		return next(ctx, req)
+	}
+	token := bearerToken(ctx)
+	if token == "" {
+		return nil, status.Error(codes.Unauthenticated, service.ErrInvalidCredentials.Error())
+	}
+	authCtx, cancel := context.WithTimeout(ctx, h.cfg.ContextTimeout)
+	defer cancel()
+
+	user, err := h.service.CheckAuthorization(authCtx, token)
+	if err != nil {
+		return nil, h.statusError(ctx, err.JoinLoc("authInterceptor"))
+	}
+	if !user.HasPermission(permissions...) {
+		return nil, status.Error(codes.PermissionDenied, service.ErrAccessDenied.Error())
+	}
+	return next(ctx, req)
+}
+
+func bearerToken(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	for _, value := range md.Get("authorization") {
+		if token, ok := strings.CutPrefix(value, "Bearer "); ok {
+			return strings.TrimSpace(token)
+		}
+	}
+	return ""
+}
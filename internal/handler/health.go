@@ -0,0 +1,35 @@
+package handler
+
+import (
+	"auth/internal/domain"
+	"encoding/json"
+	"github.com/Linkify-Company/common_utils/errify"
+	"net/http"
+)
+
+// healthz Процесс жив и обрабатывает запросы. Зависимости не проверяются, чтобы их сбой не приводил к перезапуску
+func (h *handler) healthz(w http.ResponseWriter, r *http.Request) {
+	h.writeHealth(w, r, http.StatusOK, map[string]string{"status": domain.HealthOK})
+}
+
+// readyz Готовность принимать трафик: доступность зависимостей с временем проверки. 503, если зависимость недоступна
+// или сервис останавливается
+func (h *handler) readyz(w http.ResponseWriter, r *http.Request) {
+	readiness := h.health.Readiness(r.Context())
+
+	status := http.StatusOK
+	if !readiness.Ready() {
+		status = http.StatusServiceUnavailable
+	}
+	h.writeHealth(w, r, status, readiness)
+}
+
+// writeHealth Ответ проверок без общей обертки, чтобы его могли разбирать оркестраторы и балансировщики
+func (h *handler) writeHealth(w http.ResponseWriter, r *http.Request, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		h.logger(r).Error(errify.NewInternalServerError(err.Error(), "writeHealth/Encode"))
+	}
+}
@@ -1,6 +1,10 @@
 package handler
 
 import (
+	"auth/internal/config"
+	"auth/internal/metrics"
+	"auth/internal/requestid"
+	"auth/internal/service"
 	"github.com/Linkify-Company/common_utils/errify"
 	"github.com/Linkify-Company/common_utils/logger"
 	"github.com/Linkify-Company/common_utils/response"
@@ -12,17 +16,43 @@ type Handler interface {
 	Init(router *mux.Router)
 }
 
-func Run(log logger.Logger, handlers ...Handler) *mux.Router {
+func Run(
+	log logger.Logger,
+	corsCfg *config.CORSConfig,
+	tlsCfg *config.TLSConfig,
+	metricsCfg *config.MetricsConfig,
+	health service.Health,
+	handlers ...Handler,
+) *mux.Router {
 	var router = mux.NewRouter()
-	var hr = handler{log: log}
+	var hr = handler{log: log, corsCfg: corsCfg, tlsCfg: tlsCfg, metricsCfg: metricsCfg, health: health}
 
-	router.Use()
+	router.Use(hr.requestMiddleware, hr.tracingMiddleware)
+	// Запросы без маршрута не проходят через middleware роутера, но тоже попадают в лог доступа
+	router.NotFoundHandler = hr.requestMiddleware(http.NotFoundHandler())
+	router.MethodNotAllowedHandler = hr.requestMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}))
+	if metricsCfg.Enabled {
+		router.Use(hr.metricsMiddleware)
+		router.Handle(metricsCfg.Path, metrics.Handler()).Methods(http.MethodGet)
+	}
 
 	main := router.PathPrefix("/srv-auth").Subrouter()
 
+	if len(corsCfg.AllowedOrigins) != 0 {
+		main.Use(hr.corsMiddleware)
+		// Предварительные запросы не совпадают с маршрутами по методу, поэтому обрабатываются отдельным маршрутом
+		main.PathPrefix("/").Methods(http.MethodOptions).HandlerFunc(hr.preflight)
+	}
 	main.HandleFunc("/ping", hr.ping).Methods(http.MethodGet)
+	main.HandleFunc("/healthz", hr.healthz).Methods(http.MethodGet)
+	main.HandleFunc("/readyz", hr.readyz).Methods(http.MethodGet)
 
 	api := main.PathPrefix("/api").Subrouter()
+	if len(tlsCfg.ClientCertPaths) != 0 {
+		api.Use(hr.clientCertMiddleware)
+	}
 
 	for _, h := range handlers {
 		h.Init(api)
@@ -32,8 +62,24 @@ func Run(log logger.Logger, handlers ...Handler) *mux.Router {
 	return router
 }
 
+type ctxKey int
+
+const (
+	clientIdentityKey ctxKey = iota
+	requestInfoKey
+)
+
 type handler struct {
-	log logger.Logger
+	log        logger.Logger
+	corsCfg    *config.CORSConfig
+	tlsCfg     *config.TLSConfig
+	metricsCfg *config.MetricsConfig
+	health     service.Health
+}
+
+// logger Логгер запроса, записи которого содержат его идентификатор
+func (h *handler) logger(r *http.Request) logger.Logger {
+	return requestid.Logger(r.Context(), h.log)
 }
 
 func (h *handler) registeredEndpoints(router *mux.Router) {
@@ -49,6 +95,11 @@ func (h *handler) registeredEndpoints(router *mux.Router) {
 				return err
 			}
 			h.log.Debugf("%s %s", methods, t)
+			if h.metricsCfg.Enabled {
+				for _, method := range methods {
+					metrics.InitRoute(method, t)
+				}
+			}
 		}
 		return nil
 	})
@@ -58,5 +109,5 @@ func (h *handler) registeredEndpoints(router *mux.Router) {
 }
 
 func (h *handler) ping(w http.ResponseWriter, r *http.Request) {
-	response.Ok(w, response.NewSend("", "pong", http.StatusOK), h.log)
+	response.Ok(w, response.NewSend("", "pong", http.StatusOK), h.logger(r))
 }
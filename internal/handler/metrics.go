@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"auth/internal/metrics"
+	"github.com/gorilla/mux"
+	"net/http"
+	"time"
+)
+
+// statusRecorder Запоминает код ответа, который записал обработчик
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusRecorder) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusRecorder) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// code Код ответа. Если обработчик ничего не записал, сервер ответит 200
+func (w *statusRecorder) code() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
+
+// Unwrap Дает http.ResponseController доступ к исходному ResponseWriter
+func (w *statusRecorder) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// metricsMiddleware Учитывает запрос по шаблону маршрута, а не по пути, чтобы идентификаторы в пути не порождали новые ряды
+func (h *handler) metricsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w}
+
+		next.ServeHTTP(recorder, r)
+
+		metrics.ObserveRequest(r.Method, routeTemplate(r), recorder.code(), time.Since(start))
+	})
+}
+
+// routeTemplate Шаблон маршрута, с которым совпал запрос, например /srv-auth/api/v1/user/{id}
+func routeTemplate(r *http.Request) string {
+	if current := mux.CurrentRoute(r); current != nil {
+		if t, err := current.GetPathTemplate(); err == nil {
+			return t
+		}
+	}
+	return "unknown"
+}
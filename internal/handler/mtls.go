@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"auth/internal/config"
+	"context"
+	"crypto/tls"
+	"github.com/Linkify-Company/common_utils/errify"
+	"github.com/Linkify-Company/common_utils/response"
+	"net/http"
+	"strings"
+)
+
+// RequiresClientCert Адрес или метод gRPC доступен только с проверенным сертификатом клиента
+func RequiresClientCert(cfg *config.TLSConfig, path string) bool {
+	for _, prefix := range cfg.ClientCertPaths {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientIdentity Имя сервиса по проверенному сертификату клиента. Без ClientIdentities именем служит CN сертификата
+func ClientIdentity(cfg *config.TLSConfig, state *tls.ConnectionState) (string, errify.IError) {
+	if state == nil || len(state.VerifiedChains) == 0 || len(state.PeerCertificates) == 0 {
+		return "", errify.NewUnauthorizedError(ClientCertRequired, ClientCertRequired, "ClientIdentity")
+	}
+	cert := state.PeerCertificates[0]
+	if len(cfg.ClientIdentities) == 0 {
+		return cert.Subject.CommonName, nil
+	}
+	subjects := []string{cert.Subject.String(), cert.Subject.CommonName}
+	for _, uri := range cert.URIs {
+		subjects = append(subjects, uri.String())
+	}
+	for _, subject := range subjects {
+		if identity, ok := cfg.ClientIdentities[subject]; ok && subject != "" {
+			return identity, nil
+		}
+	}
+	return "", errify.NewUnauthorizedError(ClientCertNotAllowed, ClientCertNotAllowed, "ClientIdentity")
+}
+
+// ClientIdentityFromContext Имя сервиса, сертификат которого проверил clientCertMiddleware
+func ClientIdentityFromContext(ctx context.Context) string {
+	identity, _ := ctx.Value(clientIdentityKey).(string)
+	return identity
+}
+
+// clientCertMiddleware Пропускает на адреса из ClientCertPaths только клиентов с проверенным сертификатом
+func (h *handler) clientCertMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !RequiresClientCert(h.tlsCfg, r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		identity, err := ClientIdentity(h.tlsCfg, r.TLS)
+		if err != nil {
+			response.Error(w, err.JoinLoc("clientCertMiddleware"), h.logger(r))
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), clientIdentityKey, identity)))
+	})
+}
@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"auth/internal/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+	"net/http"
+)
+
+// tracingMiddleware Продолжает трассу из заголовков traceparent и tracestate или начинает новую и записывает запрос спаном.
+// Проверки состояния и метрики не записываются, чтобы частые опросы не вытесняли трассы запросов
+func (h *handler) tracingMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := routeTemplate(r)
+		if h.probe(route) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := tracing.Start(ctx, r.Method+" "+route,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(r.Method),
+				semconv.HTTPRoute(route),
+				semconv.URLPath(r.URL.Path),
+			),
+		)
+		defer span.End()
+
+		recorder := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(recorder, r.WithContext(ctx))
+
+		status := recorder.code()
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+		// Ответы 4xx - ошибки клиента, а не сервиса
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	})
+}
+
+// probe Маршруты проверок состояния и метрик, которые опрашиваются оркестратором и Prometheus
+func (h *handler) probe(route string) bool {
+	switch route {
+	case "/srv-auth/ping", "/srv-auth/healthz", "/srv-auth/readyz", h.metricsCfg.Path:
+		return true
+	}
+	return false
+}
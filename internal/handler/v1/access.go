@@ -0,0 +1,223 @@
+package v1
+
+import (
+	"auth/internal/domain"
+	hr "auth/internal/handler"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/Linkify-Company/common_utils/errify"
+	"github.com/Linkify-Company/common_utils/response"
+	"github.com/gorilla/mux"
+	"net/http"
+	"strconv"
+)
+
+func initAccess(h *handler, admin *mux.Router) {
+	rolesRead := h.requirePermission(domain.PermissionRolesRead)
+	rolesWrite := h.requirePermission(domain.PermissionRolesWrite)
+
+	admin.HandleFunc("/permissions", rolesRead(h.Permissions)).Methods(http.MethodGet)
+	admin.HandleFunc("/permissions", rolesWrite(h.AddPermission)).Methods(http.MethodPost)
+
+	admin.HandleFunc("/roles", rolesRead(h.AccessRoles)).Methods(http.MethodGet)
+	admin.HandleFunc("/roles", rolesWrite(h.AddAccessRole)).Methods(http.MethodPost)
+	admin.HandleFunc("/roles/{id}/permissions", rolesWrite(h.SetAccessRolePermissions)).Methods(http.MethodPut)
+	admin.HandleFunc("/roles/{id}", rolesWrite(h.DeleteAccessRole)).Methods(http.MethodDelete)
+
+	admin.HandleFunc("/users/{id}/roles", rolesRead(h.UserAccessRoles)).Methods(http.MethodGet)
+	admin.HandleFunc("/users/{id}/roles", rolesWrite(h.SetUserAccessRoles)).Methods(http.MethodPut)
+	admin.HandleFunc("/users/{id}/permissions", rolesRead(h.UserPermissions)).Methods(http.MethodGet)
+}
+
+func (h *handler) Permissions(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), h.cfg.ContextTimeout)
+	defer cancel()
+
+	permissions, err := h.service.Permissions(ctx)
+	if err != nil {
+		response.Error(w, err.JoinLoc("Permissions"), h.logger(r))
+		return
+	}
+	response.Ok(w, response.NewSend(permissions, "Get permissions successfully", http.StatusOK), h.logger(r))
+}
+
+func (h *handler) AddPermission(w http.ResponseWriter, r *http.Request) {
+	var req domain.PermissionInfo
+	e := json.NewDecoder(r.Body).Decode(&req)
+	if e != nil {
+		response.Error(w, errify.NewBadRequestError(e.Error(), hr.ValidationError, "AddPermission").
+			JoinLoc("NewDecoder"), h.logger(r))
+		return
+	}
+	e = req.Valid()
+	if e != nil {
+		response.Error(w, errify.NewBadRequestError(e.Error(), hr.ValidationError, "AddPermission").
+			JoinLoc("Valid"), h.logger(r))
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), h.cfg.ContextTimeout)
+	defer cancel()
+
+	id, err := h.service.AddPermission(ctx, &req)
+	if err != nil {
+		response.Error(w, err.JoinLoc("AddPermission"), h.logger(r))
+		return
+	}
+	response.Ok(w, response.NewSend(id, "Create permission successfully", http.StatusCreated), h.logger(r))
+}
+
+func (h *handler) AccessRoles(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), h.cfg.ContextTimeout)
+	defer cancel()
+
+	roles, err := h.service.AccessRoles(ctx)
+	if err != nil {
+		response.Error(w, err.JoinLoc("AccessRoles"), h.logger(r))
+		return
+	}
+	response.Ok(w, response.NewSend(roles, "Get roles successfully", http.StatusOK), h.logger(r))
+}
+
+func (h *handler) AddAccessRole(w http.ResponseWriter, r *http.Request) {
+	var req domain.AccessRole
+	e := json.NewDecoder(r.Body).Decode(&req)
+	if e != nil {
+		response.Error(w, errify.NewBadRequestError(e.Error(), hr.ValidationError, "AddAccessRole").
+			JoinLoc("NewDecoder"), h.logger(r))
+		return
+	}
+	e = req.Valid()
+	if e != nil {
+		response.Error(w, errify.NewBadRequestError(e.Error(), hr.ValidationError, "AddAccessRole").
+			JoinLoc("Valid"), h.logger(r))
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), h.cfg.ContextTimeout)
+	defer cancel()
+
+	role, err := h.service.AddAccessRole(ctx, &req)
+	if err != nil {
+		response.Error(w, err.JoinLoc("AddAccessRole"), h.logger(r))
+		return
+	}
+	response.Ok(w, response.NewSend(role, "Create role successfully", http.StatusCreated), h.logger(r))
+}
+
+func (h *handler) SetAccessRolePermissions(w http.ResponseWriter, r *http.Request) {
+	id, e := strconv.Atoi(mux.Vars(r)["id"])
+	if e != nil || id <= 0 {
+		response.Error(w, errify.NewBadRequestError(hr.ValidationError, hr.ValidationError, "SetAccessRolePermissions").
+			JoinLoc("Atoi"), h.logger(r))
+		return
+	}
+	var req struct {
+		Permissions []domain.Permission `json:"permissions"`
+	}
+	e = json.NewDecoder(r.Body).Decode(&req)
+	if e != nil {
+		response.Error(w, errify.NewBadRequestError(e.Error(), hr.ValidationError, "SetAccessRolePermissions").
+			JoinLoc("NewDecoder"), h.logger(r))
+		return
+	}
+	e = domain.ValidPermissions(req.Permissions)
+	if e != nil {
+		response.Error(w, errify.NewBadRequestError(e.Error(), hr.ValidationError, "SetAccessRolePermissions").
+			JoinLoc("ValidPermissions"), h.logger(r))
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), h.cfg.ContextTimeout)
+	defer cancel()
+
+	role, err := h.service.SetAccessRolePermissions(ctx, id, req.Permissions)
+	if err != nil {
+		response.Error(w, err.JoinLoc("SetAccessRolePermissions"), h.logger(r))
+		return
+	}
+	response.Ok(w, response.NewSend(role, "Set role permissions successfully", http.StatusOK), h.logger(r))
+}
+
+func (h *handler) DeleteAccessRole(w http.ResponseWriter, r *http.Request) {
+	id, e := strconv.Atoi(mux.Vars(r)["id"])
+	if e != nil || id <= 0 {
+		response.Error(w, errify.NewBadRequestError(hr.ValidationError, hr.ValidationError, "DeleteAccessRole").
+			JoinLoc("Atoi"), h.logger(r))
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), h.cfg.ContextTimeout)
+	defer cancel()
+
+	err := h.service.DeleteAccessRole(ctx, id)
+	if err != nil {
+		response.Error(w, err.JoinLoc("DeleteAccessRole"), h.logger(r))
+		return
+	}
+	response.Ok(w, response.NewSend("", "Delete role successfully", http.StatusOK), h.logger(r))
+}
+
+func (h *handler) UserAccessRoles(w http.ResponseWriter, r *http.Request) {
+	id, e := strconv.Atoi(mux.Vars(r)["id"])
+	if e != nil || id <= 0 {
+		response.Error(w, errify.NewBadRequestError(hr.ValidationError, hr.ValidationError, "UserAccessRoles").
+			JoinLoc("Atoi"), h.logger(r))
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), h.cfg.ContextTimeout)
+	defer cancel()
+
+	roles, err := h.service.UserAccessRoles(ctx, id)
+	if err != nil {
+		response.Error(w, err.JoinLoc("UserAccessRoles"), h.logger(r))
+		return
+	}
+	response.Ok(w, response.NewSend(roles, "Get user roles successfully", http.StatusOK), h.logger(r))
+}
+
+func (h *handler) SetUserAccessRoles(w http.ResponseWriter, r *http.Request) {
+	id, e := strconv.Atoi(mux.Vars(r)["id"])
+	if e != nil || id <= 0 {
+		response.Error(w, errify.NewBadRequestError(hr.ValidationError, hr.ValidationError, "SetUserAccessRoles").
+			JoinLoc("Atoi"), h.logger(r))
+		return
+	}
+	var req struct {
+		Roles []int `json:"roles"`
+	}
+	e = json.NewDecoder(r.Body).Decode(&req)
+	if e == nil && len(req.Roles) == 0 {
+		e = errors.New("roles empty")
+	}
+	if e != nil {
+		response.Error(w, errify.NewBadRequestError(e.Error(), hr.ValidationError, "SetUserAccessRoles").
+			JoinLoc("NewDecoder"), h.logger(r))
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), h.cfg.ContextTimeout)
+	defer cancel()
+
+	admin := authDataFromContext(r.Context())
+	roles, err := h.service.SetUserAccessRoles(ctx, id, req.Roles, &admin.ID)
+	if err != nil {
+		response.Error(w, err.JoinLoc("SetUserAccessRoles"), h.logger(r))
+		return
+	}
+	response.Ok(w, response.NewSend(roles, "Set user roles successfully", http.StatusOK), h.logger(r))
+}
+
+func (h *handler) UserPermissions(w http.ResponseWriter, r *http.Request) {
+	id, e := strconv.Atoi(mux.Vars(r)["id"])
+	if e != nil || id <= 0 {
+		response.Error(w, errify.NewBadRequestError(hr.ValidationError, hr.ValidationError, "UserPermissions").
+			JoinLoc("Atoi"), h.logger(r))
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), h.cfg.ContextTimeout)
+	defer cancel()
+
+	permissions, err := h.service.UserPermissions(ctx, id)
+	if err != nil {
+		response.Error(w, err.JoinLoc("UserPermissions"), h.logger(r))
+		return
+	}
+	response.Ok(w, response.NewSend(permissions, "Get user permissions successfully", http.StatusOK), h.logger(r))
+}
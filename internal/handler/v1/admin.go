@@ -0,0 +1,218 @@
+package v1
+
+import (
+	"auth/internal/domain"
+	hr "auth/internal/handler"
+	"context"
+	"encoding/json"
+	"github.com/Linkify-Company/common_utils/errify"
+	"github.com/Linkify-Company/common_utils/response"
+	"github.com/gorilla/mux"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+func initAdmin(h *handler, router *mux.Router) {
+	admin := router.PathPrefix("/admin").Subrouter()
+	admin.Use(h.authMiddleware)
+
+	usersRead := h.requirePermission(domain.PermissionUsersRead)
+	usersWrite := h.requirePermission(domain.PermissionUsersWrite)
+	rolesRead := h.requirePermission(domain.PermissionRolesRead)
+	rolesWrite := h.requirePermission(domain.PermissionRolesWrite)
+
+	admin.HandleFunc("/users", usersRead(h.Users)).Methods(http.MethodGet)
+	admin.HandleFunc("/users/{id}", usersWrite(h.UpdateUser)).Methods(http.MethodPatch)
+	admin.HandleFunc("/users/{id}", usersWrite(h.DeleteUser)).Methods(http.MethodDelete)
+	admin.HandleFunc("/users/{id}/role", rolesWrite(h.SetRole)).Methods(http.MethodPut)
+	admin.HandleFunc("/users/{id}/role/audit", rolesRead(h.RoleChanges)).Methods(http.MethodGet)
+
+	initAccess(h, admin)
+}
+
+func (h *handler) Users(w http.ResponseWriter, r *http.Request) {
+	filter, e := userFilter(r.URL.Query())
+	if e == nil {
+		e = filter.Valid()
+	}
+	if e != nil {
+		response.Error(w, errify.NewBadRequestError(e.Error(), hr.ValidationError, "Users").
+			JoinLoc("userFilter"), h.logger(r))
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), h.cfg.ContextTimeout)
+	defer cancel()
+
+	page, err := h.service.Users(ctx, filter)
+	if err != nil {
+		response.Error(w, err.JoinLoc("Users"), h.logger(r))
+		return
+	}
+	response.Ok(w, response.NewSend(page, "Get users successfully", http.StatusOK), h.logger(r))
+}
+
+func (h *handler) UpdateUser(w http.ResponseWriter, r *http.Request) {
+	id, e := strconv.Atoi(mux.Vars(r)["id"])
+	if e != nil || id <= 0 {
+		response.Error(w, errify.NewBadRequestError(hr.ValidationError, hr.ValidationError, "UpdateUser").
+			JoinLoc("Atoi"), h.logger(r))
+		return
+	}
+	var req domain.UserUpdate
+	e = json.NewDecoder(r.Body).Decode(&req)
+	if e != nil {
+		response.Error(w, errify.NewBadRequestError(e.Error(), hr.ValidationError, "UpdateUser").
+			JoinLoc("NewDecoder"), h.logger(r))
+		return
+	}
+	e = req.Valid()
+	if e != nil {
+		response.Error(w, errify.NewBadRequestError(e.Error(), hr.ValidationError, "UpdateUser").
+			JoinLoc("Valid"), h.logger(r))
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), h.cfg.ContextTimeout)
+	defer cancel()
+
+	user, err := h.service.UpdateUser(ctx, id, &req)
+	if err != nil {
+		response.Error(w, err.JoinLoc("UpdateUser"), h.logger(r))
+		return
+	}
+	response.Ok(w, response.NewSend(user, "Update user successfully", http.StatusOK), h.logger(r))
+}
+
+func (h *handler) DeleteUser(w http.ResponseWriter, r *http.Request) {
+	id, e := strconv.Atoi(mux.Vars(r)["id"])
+	if e != nil || id <= 0 {
+		response.Error(w, errify.NewBadRequestError(hr.ValidationError, hr.ValidationError, "DeleteUser").
+			JoinLoc("Atoi"), h.logger(r))
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), h.cfg.ContextTimeout)
+	defer cancel()
+
+	err := h.service.DeleteUser(ctx, id)
+	if err != nil {
+		response.Error(w, err.JoinLoc("DeleteUser"), h.logger(r))
+		return
+	}
+	response.Ok(w, response.NewSend("", "Delete user successfully", http.StatusOK), h.logger(r))
+}
+
+func (h *handler) SetRole(w http.ResponseWriter, r *http.Request) {
+	id, e := strconv.Atoi(mux.Vars(r)["id"])
+	if e != nil || id <= 0 {
+		response.Error(w, errify.NewBadRequestError(hr.ValidationError, hr.ValidationError, "SetRole").
+			JoinLoc("Atoi"), h.logger(r))
+		return
+	}
+	var req domain.RoleUpdate
+	e = json.NewDecoder(r.Body).Decode(&req)
+	if e != nil {
+		response.Error(w, errify.NewBadRequestError(e.Error(), hr.ValidationError, "SetRole").
+			JoinLoc("NewDecoder"), h.logger(r))
+		return
+	}
+	e = req.Valid()
+	if e != nil {
+		response.Error(w, errify.NewBadRequestError(e.Error(), hr.ValidationError, "SetRole").
+			JoinLoc("Valid"), h.logger(r))
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), h.cfg.ContextTimeout)
+	defer cancel()
+
+	admin := authDataFromContext(r.Context())
+	user, err := h.service.SetRole(ctx, id, &req, &admin.ID)
+	if err != nil {
+		response.Error(w, err.JoinLoc("SetRole"), h.logger(r))
+		return
+	}
+	response.Ok(w, response.NewSend(user, "Set role successfully", http.StatusOK), h.logger(r))
+}
+
+func (h *handler) RoleChanges(w http.ResponseWriter, r *http.Request) {
+	id, e := strconv.Atoi(mux.Vars(r)["id"])
+	if e != nil || id <= 0 {
+		response.Error(w, errify.NewBadRequestError(hr.ValidationError, hr.ValidationError, "RoleChanges").
+			JoinLoc("Atoi"), h.logger(r))
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), h.cfg.ContextTimeout)
+	defer cancel()
+
+	changes, err := h.service.RoleChanges(ctx, id)
+	if err != nil {
+		response.Error(w, err.JoinLoc("RoleChanges"), h.logger(r))
+		return
+	}
+	response.Ok(w, response.NewSend(changes, "Get role changes successfully", http.StatusOK), h.logger(r))
+}
+
+// userFilter Разбирает параметры запроса списка пользователей
+func userFilter(query url.Values) (*domain.UserFilter, error) {
+	var filter = domain.UserFilter{
+		EmailPrefix: query.Get("email"),
+		Sort:        domain.UserSort(query.Get("sort")),
+		Desc:        query.Get("order") == "desc",
+		Cursor:      query.Get("cursor"),
+	}
+	var err error
+
+	if v := query.Get("limit"); v != "" {
+		filter.Limit, err = strconv.Atoi(v)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if v := query.Get("role"); v != "" {
+		role, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, err
+		}
+		filter.Role = (*domain.Role)(&role)
+	}
+	if filter.CreatedFrom, err = timeParam(query, "created_from"); err != nil {
+		return nil, err
+	}
+	if filter.CreatedTo, err = timeParam(query, "created_to"); err != nil {
+		return nil, err
+	}
+	if filter.Verified, err = boolParam(query, "verified"); err != nil {
+		return nil, err
+	}
+	if filter.Locked, err = boolParam(query, "locked"); err != nil {
+		return nil, err
+	}
+	if filter.Deleted, err = boolParam(query, "deleted"); err != nil {
+		return nil, err
+	}
+	return &filter, nil
+}
+
+func timeParam(query url.Values, key string) (*time.Time, error) {
+	v := query.Get(key)
+	if v == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func boolParam(query url.Values, key string) (*bool, error) {
+	v := query.Get(key)
+	if v == "" {
+		return nil, nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
@@ -22,8 +22,10 @@ func initAuth(h *handler, router *mux.Router) {
 	auth := router.PathPrefix("/auth").Subrouter()
 
 	auth.HandleFunc("/login", h.Login).Methods(http.MethodPost)
-	auth.HandleFunc("/logout", h.Logout).Methods(http.MethodDelete)
+	auth.Handle("/logout", h.csrfMiddleware(http.HandlerFunc(h.Logout))).Methods(http.MethodDelete)
 	auth.HandleFunc("/check", h.CheckAuth).Methods(http.MethodGet)
+	auth.HandleFunc("/verify", h.Verify).Methods(http.MethodGet, http.MethodHead)
+	auth.HandleFunc("/csrf", h.CSRFToken).Methods(http.MethodGet)
 }
 
 func (h *handler) Login(w http.ResponseWriter, r *http.Request) {
@@ -31,13 +33,13 @@ func (h *handler) Login(w http.ResponseWriter, r *http.Request) {
 	e := json.NewDecoder(r.Body).Decode(&req)
 	if e != nil {
 		response.Error(w, errify.NewBadRequestError(e.Error(), hr.ValidationError, "Login").
-			JoinLoc("NewDecoder"), h.log)
+			JoinLoc("NewDecoder"), h.logger(r))
 		return
 	}
 	e = req.Valid()
 	if e != nil {
 		response.Error(w, errify.NewBadRequestError(e.Error(), hr.ValidationError, "Login").
-			JoinLoc("Valid"), h.log)
+			JoinLoc("Valid"), h.logger(r))
 		return
 	}
 	ctx, cancel := context.WithTimeout(r.Context(), h.cfg.ContextTimeout)
@@ -45,19 +47,19 @@ func (h *handler) Login(w http.ResponseWriter, r *http.Request) {
 
 	token, err := h.service.Authorization(ctx, &req, *h.tokenCfg)
 	if err != nil {
-		response.Error(w, err.JoinLoc("Authorization"), h.log)
+		response.Error(w, err.JoinLoc("Authorization"), h.logger(r))
 		return
 	}
 	h.service.SetToken(w, token)
 
-	response.Ok(w, response.NewSend(token, "Authorization successfully", http.StatusOK), h.log)
+	response.Ok(w, response.NewSend(token, "Authorization successfully", http.StatusOK), h.logger(r))
 }
 
 func (h *handler) CheckAuth(w http.ResponseWriter, r *http.Request) {
 	req, e := h.service.GetToken(r)
 	if e != nil || req == "" {
-		response.Error(w, errify.NewUnauthorizedError(e.Error(),
-			service.ErrInvalidCredentials.Error(), "CheckAuth"), h.log)
+		response.Error(w, errify.NewUnauthorizedError(service.ErrInvalidCredentials.Error(),
+			service.ErrInvalidCredentials.Error(), "CheckAuth"), h.logger(r))
 		return
 	}
 	ctx, cancel := context.WithTimeout(r.Context(), h.cfg.ContextTimeout)
@@ -69,42 +71,57 @@ func (h *handler) CheckAuth(w http.ResponseWriter, r *http.Request) {
 			token, err := h.service.RenewAuthorization(ctx, req, *h.tokenCfg)
 			if err != nil {
 				if err, ok := err.(*errify.InternalServerError); ok {
-					response.Error(w, err.JoinLoc("CheckAuth"), h.log)
+					response.Error(w, err.JoinLoc("CheckAuth"), h.logger(r))
 					return
 				}
 				response.Error(w, errify.NewInternalServerError(err.Error(), "CheckAuth").
-					JoinLoc("RenewAuthorization").JoinLoc(err.Location()), h.log)
+					JoinLoc("RenewAuthorization").JoinLoc(err.Location()), h.logger(r))
 				return
 			}
-			user, err := h.service.CheckAuthorization(ctx, req)
+			user, err := h.service.CheckAuthorization(ctx, token)
 			if err != nil {
 				if err, ok := err.(*errify.InternalServerError); ok {
-					response.Error(w, err.JoinLoc("CheckAuth"), h.log)
+					response.Error(w, err.JoinLoc("CheckAuth"), h.logger(r))
 					return
 				}
 				response.Error(w, errify.NewInternalServerError(err.Error(), "CheckAuth").
-					JoinLoc("RenewAuthorization").JoinLoc(err.Location()), h.log)
+					JoinLoc("RenewAuthorization").JoinLoc(err.Location()), h.logger(r))
 				return
 			}
+			hr.SetUserID(ctx, user.ID)
+			// Браузерный клиент получает продленную сессию в cookie, как после входа
+			h.service.SetToken(w, token)
 			response.Ok(w, response.NewSend(CheckAuthResponse{
 				AuthData: user,
 				Token:    token,
-			}, "Authorization successfully", http.StatusOK), h.log)
+			}, "Authorization successfully", http.StatusOK), h.logger(r))
 			return
 		}
-		response.Error(w, err.JoinLoc("CheckAuth"), h.log)
+		response.Error(w, err.JoinLoc("CheckAuth"), h.logger(r))
 		return
 	}
+	hr.SetUserID(ctx, user.ID)
 	response.Ok(w, response.NewSend(CheckAuthResponse{
 		AuthData: user,
-	}, "Authorization successfully", http.StatusOK), h.log)
+	}, "Authorization successfully", http.StatusOK), h.logger(r))
+}
+
+// CSRFToken Выдает CSRF токен сессии, которая была открыта до его появления. Значение нужно передавать в заголовке
+// изменяющих запросов, авторизованных cookie
+func (h *handler) CSRFToken(w http.ResponseWriter, r *http.Request) {
+	token, e := h.service.CSRFToken(w, r)
+	if e != nil {
+		response.Error(w, errify.NewInternalServerError(e.Error(), "CSRFToken"), h.logger(r))
+		return
+	}
+	response.Ok(w, response.NewSend(token, "Get csrf token successfully", http.StatusOK), h.logger(r))
 }
 
 func (h *handler) Logout(w http.ResponseWriter, r *http.Request) {
 	req, e := h.service.GetToken(r)
 	if e != nil || req == "" {
-		response.Error(w, errify.NewUnauthorizedError(e.Error(),
-			service.ErrInvalidCredentials.Error(), "CheckAuth"), h.log)
+		response.Error(w, errify.NewUnauthorizedError(service.ErrInvalidCredentials.Error(),
+			service.ErrInvalidCredentials.Error(), "Logout"), h.logger(r))
 		return
 	}
 	ctx, cancel := context.WithTimeout(r.Context(), h.cfg.ContextTimeout)
@@ -112,8 +129,10 @@ func (h *handler) Logout(w http.ResponseWriter, r *http.Request) {
 
 	err := h.service.Logout(ctx, req)
 	if err != nil {
-		response.Error(w, err.JoinLoc("Logout"), h.log)
+		response.Error(w, err.JoinLoc("Logout"), h.logger(r))
 		return
 	}
-	response.Ok(w, response.NewSend("", "Logout successfully", http.StatusOK), h.log)
+	h.service.ClearToken(w)
+
+	response.Ok(w, response.NewSend("", "Logout successfully", http.StatusOK), h.logger(r))
 }
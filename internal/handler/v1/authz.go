@@ -0,0 +1,77 @@
+package v1
+
+import (
+	"auth/internal/domain"
+	hr "auth/internal/handler"
+	"auth/internal/service"
+	"context"
+	"encoding/json"
+	"github.com/Linkify-Company/common_utils/errify"
+	"github.com/Linkify-Company/common_utils/response"
+	"github.com/gorilla/mux"
+	"net/http"
+)
+
+func initAuthz(h *handler, router *mux.Router) {
+	authz := router.PathPrefix("/authz").Subrouter()
+	authz.Use(h.authMiddleware)
+
+	authz.HandleFunc("/check", h.requirePermission(domain.PermissionAuthzCheck)(h.CheckAccess)).Methods(http.MethodPost)
+	authz.HandleFunc("/reload", h.requirePermission(domain.PermissionRolesWrite)(h.ReloadPolicy)).Methods(http.MethodPost)
+}
+
+func (h *handler) CheckAccess(w http.ResponseWriter, r *http.Request) {
+	var req domain.AuthzRequest
+	e := json.NewDecoder(r.Body).Decode(&req)
+	if e != nil {
+		response.Error(w, errify.NewBadRequestError(e.Error(), hr.ValidationError, "CheckAccess").
+			JoinLoc("NewDecoder"), h.logger(r))
+		return
+	}
+	if req.Policy != nil {
+		req.Policy.SetDefaults()
+	}
+	e = req.Valid()
+	if e != nil {
+		response.Error(w, errify.NewBadRequestError(e.Error(), hr.ValidationError, "CheckAccess").
+			JoinLoc("Valid"), h.logger(r))
+		return
+	}
+	caller := authDataFromContext(r.Context())
+	// Пробная политика может разрешить что угодно, поэтому ее вычисляют только те, кто управляет политиками
+	if req.Policy != nil && !caller.HasPermission(domain.PermissionRolesWrite) {
+		h.responseError(w, r, service.NewForbiddenError(service.ErrAccessDenied.Error(), "CheckAccess/Policy"))
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), h.cfg.ContextTimeout)
+	defer cancel()
+
+	subject := caller
+	if req.Token != "" {
+		user, err := h.service.CheckAuthorization(ctx, req.Token)
+		if err != nil {
+			response.Error(w, err.JoinLoc("CheckAccess"), h.logger(r))
+			return
+		}
+		subject = user
+	}
+
+	decision, err := h.service.CheckAccess(ctx, subject, &req)
+	if err != nil {
+		response.Error(w, err.JoinLoc("CheckAccess"), h.logger(r))
+		return
+	}
+	response.Ok(w, response.NewSend(decision, "Check access successfully", http.StatusOK), h.logger(r))
+}
+
+func (h *handler) ReloadPolicy(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), h.cfg.ContextTimeout)
+	defer cancel()
+
+	err := h.service.ReloadPolicy(ctx)
+	if err != nil {
+		response.Error(w, err.JoinLoc("ReloadPolicy"), h.logger(r))
+		return
+	}
+	response.Ok(w, response.NewSend("", "Reload policy successfully", http.StatusOK), h.logger(r))
+}
@@ -2,6 +2,7 @@ package v1
 
 import (
 	hr "auth/internal/handler"
+	"auth/internal/service"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -9,7 +10,9 @@ import (
 	"github.com/Linkify-Company/common_utils/response"
 	"github.com/go-playground/validator/v10"
 	"github.com/gorilla/mux"
+	"math"
 	"net/http"
+	"strconv"
 )
 
 func initEmail(h *handler, router *mux.Router) {
@@ -23,21 +26,26 @@ func (h *handler) PushCodeInEmail(w http.ResponseWriter, r *http.Request) {
 	}{}
 	err := json.NewDecoder(r.Body).Decode(&email)
 	if err != nil {
-		response.Error(w, errify.NewBadRequestError(err.Error(), hr.ValidationError, "PushCodeInEmail/Decode"), h.log)
+		response.Error(w, errify.NewBadRequestError(err.Error(), hr.ValidationError, "PushCodeInEmail/Decode"), h.logger(r))
 		return
 	}
 	err = validator.New().Var(email.Email, "required,email")
 	if err != nil {
-		response.Error(w, errify.NewBadRequestError(err.Error(), hr.ValidationError, "PushCodeInEmail/Var"), h.log)
+		response.Error(w, errify.NewBadRequestError(err.Error(), hr.ValidationError, "PushCodeInEmail/Var"), h.logger(r))
 		return
 	}
 	ctx, cancel := context.WithTimeout(r.Context(), h.cfg.ContextTimeout)
 	defer cancel()
 
 	e := h.service.User.PushCodeInEmail(ctx, h.service.Email, email.Email)
+	if limit, ok := e.(*service.TooManyRequestsError); ok {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(limit.RetryAfter.Seconds()))))
+		h.statusError(w, r, http.StatusTooManyRequests, service.ErrTooManyRequests.Error(), e.JoinLoc("PushCodeInEmail"))
+		return
+	}
 	if e != nil {
-		response.Error(w, e.JoinLoc("PushCodeInEmail"), h.log)
+		response.Error(w, e.JoinLoc("PushCodeInEmail"), h.logger(r))
 		return
 	}
-	response.Ok(w, response.NewSend("", fmt.Sprintf("the confirmation code has been sent to the email: %s", email.Email), http.StatusOK), h.log)
+	response.Ok(w, response.NewSend("", fmt.Sprintf("the confirmation code has been sent to the email: %s", email.Email), http.StatusOK), h.logger(r))
 }
@@ -2,33 +2,44 @@ package v1
 
 import (
 	"auth/internal/config"
+	"auth/internal/domain"
 	hr "auth/internal/handler"
+	"auth/internal/requestid"
 	"auth/internal/service"
+	"context"
 	"fmt"
 	"github.com/Linkify-Company/common_utils/errify"
 	"github.com/Linkify-Company/common_utils/logger"
+	"github.com/Linkify-Company/common_utils/response"
 	"github.com/gorilla/mux"
 	"net/http"
+	"strings"
 )
 
 type handler struct {
-	cfg      *config.HandlerConfig
-	tokenCfg *config.TokenConfig
-	log      logger.Logger
-	service  *service.Service
+	cfg            *config.HandlerConfig
+	tokenCfg       *config.TokenConfig
+	securityCfg    *config.SecurityConfig
+	forwardAuthCfg *config.ForwardAuthConfig
+	log            logger.Logger
+	service        *service.Service
 }
 
 func NewHandler(
 	cfg *config.HandlerConfig,
 	tokenCfg *config.TokenConfig,
+	securityCfg *config.SecurityConfig,
+	forwardAuthCfg *config.ForwardAuthConfig,
 	log logger.Logger,
 	service *service.Service,
 ) hr.Handler {
 	return &handler{
-		cfg:      cfg,
-		log:      log,
-		service:  service,
-		tokenCfg: tokenCfg,
+		cfg:            cfg,
+		log:            log,
+		service:        service,
+		tokenCfg:       tokenCfg,
+		securityCfg:    securityCfg,
+		forwardAuthCfg: forwardAuthCfg,
 	}
 }
 
@@ -41,6 +52,13 @@ func (h *handler) Init(router *mux.Router) {
 	initUser(h, version)
 	initAuth(h, version)
 	initEmail(h, version)
+	initAdmin(h, version)
+	initAuthz(h, version)
+	initOrganization(h, version)
+	initPersonalToken(h, version)
+	initOAuth(h, version)
+	initSAML(h, version)
+	initSCIM(h, version)
 }
 
 func (h *handler) panicMiddleware(next http.Handler) http.Handler {
@@ -48,10 +66,162 @@ func (h *handler) panicMiddleware(next http.Handler) http.Handler {
 		defer func() {
 			if err := recover(); err != nil {
 				w.WriteHeader(http.StatusInternalServerError)
-				h.log.Error(errify.NewInternalServerError(fmt.Sprint(err), r.RequestURI).SetDetails("There was a panic in the router under version No. 1"))
+				h.logger(r).Error(errify.NewInternalServerError(fmt.Sprint(err), r.RequestURI).SetDetails("There was a panic in the router under version No. 1"))
 			}
 		}()
 
 		next.ServeHTTP(w, r)
 	})
 }
+
+// logger Логгер запроса, записи которого содержат его идентификатор
+func (h *handler) logger(r *http.Request) logger.Logger {
+	return requestid.Logger(r.Context(), h.log)
+}
+
+// statusError Отвечает ошибкой с кодом, для которого в errify нет типа. Тело ответа такое же, как у остальных
+// ответов пакета response, клиент получает только message
+func (h *handler) statusError(w http.ResponseWriter, r *http.Request, status int, message string, err errify.IError) {
+	h.logger(r).Error(err)
+	response.Ok(w, response.NewSend("", message, status), h.logger(r))
+}
+
+// responseError Отвечает ошибкой сервиса. Запрет доступа передается кодом 403, остальное - как в response.Error
+func (h *handler) responseError(w http.ResponseWriter, r *http.Request, err errify.IError) {
+	if _, ok := err.(*service.ForbiddenError); ok {
+		h.statusError(w, r, http.StatusForbidden, service.ErrAccessDenied.Error(), err)
+		return
+	}
+	response.Error(w, err, h.logger(r))
+}
+
+type ctxKey int
+
+const (
+	authDataKey ctxKey = iota
+	scimOrgKey
+)
+
+// authDataFromContext Возвращает данные пользователя, сохраненные middleware авторизации
+func authDataFromContext(ctx context.Context) *domain.AuthData {
+	user, _ := ctx.Value(authDataKey).(*domain.AuthData)
+	return user
+}
+
+// authData Возвращает данные авторизованного пользователя, выполнившего запрос
+func (h *handler) authData(ctx context.Context, r *http.Request) (*domain.AuthData, errify.IError) {
+	token, e := h.service.GetToken(r)
+	if e != nil || token == "" {
+		return nil, errify.NewUnauthorizedError(service.ErrInvalidCredentials.Error(),
+			service.ErrInvalidCredentials.Error(), "authData/GetToken")
+	}
+	user, err := h.service.CheckAuthorization(ctx, token)
+	if err != nil {
+		return nil, err.JoinLoc("authData")
+	}
+	hr.SetUserID(ctx, user.ID)
+	return user, nil
+}
+
+// authMiddleware Пропускает только авторизованные запросы и сохраняет данные пользователя в контексте
+func (h *handler) authMiddleware(next http.Handler) http.Handler {
+	return h.csrfMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), h.cfg.ContextTimeout)
+		defer cancel()
+
+		user, err := h.authData(ctx, r)
+		if err != nil {
+			response.Error(w, err.JoinLoc("authMiddleware"), h.logger(r))
+			return
+		}
+		if user.PersonalTokenID != 0 {
+			scope, ok := personalTokenScope(r)
+			if !ok || !user.HasPermission(scope) {
+				h.responseError(w, r, service.NewForbiddenError(service.ErrAccessDenied.Error(), "authMiddleware/personalTokenScope"))
+				return
+			}
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), authDataKey, user)))
+	}))
+}
+
+// personalTokenScopes Область персонального токена, которая нужна для маршрута под authMiddleware.
+// Ключ - метод и шаблон пути после /v1. Маршруты вне списка персональным токеном недоступны
+var personalTokenScopes = map[string]domain.Permission{
+	"GET /admin/users":                              domain.PermissionUsersRead,
+	"PATCH /admin/users/{id}":                       domain.PermissionUsersWrite,
+	"DELETE /admin/users/{id}":                      domain.PermissionUsersWrite,
+	"PUT /admin/users/{id}/role":                    domain.PermissionRolesWrite,
+	"GET /admin/users/{id}/role/audit":              domain.PermissionRolesRead,
+	"GET /admin/permissions":                        domain.PermissionRolesRead,
+	"POST /admin/permissions":                       domain.PermissionRolesWrite,
+	"GET /admin/roles":                              domain.PermissionRolesRead,
+	"POST /admin/roles":                             domain.PermissionRolesWrite,
+	"PUT /admin/roles/{id}/permissions":             domain.PermissionRolesWrite,
+	"DELETE /admin/roles/{id}":                      domain.PermissionRolesWrite,
+	"GET /admin/users/{id}/roles":                   domain.PermissionRolesRead,
+	"PUT /admin/users/{id}/roles":                   domain.PermissionRolesWrite,
+	"GET /admin/users/{id}/permissions":             domain.PermissionRolesRead,
+	"POST /authz/check":                             domain.PermissionAuthzCheck,
+	"POST /authz/reload":                            domain.PermissionRolesWrite,
+	"GET /orgs":                                     domain.PermissionOrgsRead,
+	"POST /orgs":                                    domain.PermissionOrgsWrite,
+	"POST /orgs/invitations/accept":                 domain.PermissionOrgsWrite,
+	"POST /orgs/invitations/decline":                domain.PermissionOrgsWrite,
+	"GET /orgs/{id}/members":                        domain.PermissionOrgsRead,
+	"PATCH /orgs/{id}/members/{user_id}":            domain.PermissionOrgsWrite,
+	"DELETE /orgs/{id}/members/{user_id}":           domain.PermissionOrgsWrite,
+	"GET /orgs/{id}/invitations":                    domain.PermissionOrgsRead,
+	"POST /orgs/{id}/invitations":                   domain.PermissionOrgsWrite,
+	"DELETE /orgs/{id}/invitations/{invitation_id}": domain.PermissionOrgsWrite,
+	"GET /orgs/{id}/scim/tokens":                    domain.PermissionOrgsRead,
+	"DELETE /orgs/{id}/scim/tokens/{token_id}":      domain.PermissionOrgsWrite,
+	"GET /oauth/identities":                         domain.PermissionIdentitiesRead,
+	"DELETE /oauth/identities/{provider}":           domain.PermissionIdentitiesWrite,
+	"GET /tokens":                                   domain.PermissionTokensRead,
+	"DELETE /tokens/{id}":                           domain.PermissionTokensWrite,
+}
+
+// personalTokenScope Область, которая нужна персональному токену для запроса
+func personalTokenScope(r *http.Request) (domain.Permission, bool) {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return "", false
+	}
+	template, err := route.GetPathTemplate()
+	if err != nil {
+		return "", false
+	}
+	_, path, ok := strings.Cut(template, "/v1")
+	if !ok {
+		return "", false
+	}
+	scope, ok := personalTokenScopes[r.Method+" "+path]
+	return scope, ok
+}
+
+// csrfMiddleware Отклоняет изменяющие запросы, авторизованные cookie, без CSRF токена или с чужого источника
+func (h *handler) csrfMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		err := h.service.CheckCSRF(r)
+		if err != nil {
+			response.Error(w, err.JoinLoc("csrfMiddleware"), h.logger(r))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requirePermission Пропускает запросы пользователей, у которых есть все перечисленные разрешения.
+// Используется на маршрутах под authMiddleware
+func (h *handler) requirePermission(permissions ...domain.Permission) func(http.HandlerFunc) http.HandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if !authDataFromContext(r.Context()).HasPermission(permissions...) {
+				h.responseError(w, r, service.NewForbiddenError(service.ErrAccessDenied.Error(), "requirePermission"))
+				return
+			}
+			next(w, r)
+		}
+	}
+}
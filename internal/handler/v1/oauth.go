@@ -0,0 +1,103 @@
+package v1
+
+import (
+	hr "auth/internal/handler"
+	"context"
+	"github.com/Linkify-Company/common_utils/errify"
+	"github.com/Linkify-Company/common_utils/response"
+	"github.com/gorilla/mux"
+	"net/http"
+)
+
+func initOAuth(h *handler, router *mux.Router) {
+	oauth := router.PathPrefix("/oauth").Subrouter()
+
+	oauth.Handle("/identities", h.authMiddleware(http.HandlerFunc(h.UserIdentities))).Methods(http.MethodGet)
+	oauth.Handle("/identities/{provider}", h.authMiddleware(http.HandlerFunc(h.RemoveIdentity))).Methods(http.MethodDelete)
+
+	oauth.HandleFunc("/{provider}/login", h.OAuthLogin).Methods(http.MethodGet)
+	oauth.Handle("/{provider}/link", h.authMiddleware(http.HandlerFunc(h.OAuthLink))).Methods(http.MethodGet)
+	oauth.HandleFunc("/{provider}/callback", h.OAuthCallback).Methods(http.MethodGet)
+}
+
+func (h *handler) OAuthLogin(w http.ResponseWriter, r *http.Request) {
+	h.oauthRedirect(w, r, 0)
+}
+
+func (h *handler) OAuthLink(w http.ResponseWriter, r *http.Request) {
+	h.oauthRedirect(w, r, authDataFromContext(r.Context()).ID)
+}
+
+// oauthRedirect Перенаправляет пользователя на страницу входа провайдера
+func (h *handler) oauthRedirect(w http.ResponseWriter, r *http.Request, linkUserID int) {
+	ctx, cancel := context.WithTimeout(r.Context(), h.cfg.ContextTimeout)
+	defer cancel()
+
+	url, state, err := h.service.OAuthLoginURL(ctx, mux.Vars(r)["provider"], linkUserID)
+	if err != nil {
+		response.Error(w, err.JoinLoc("oauthRedirect"), h.logger(r))
+		return
+	}
+	h.service.SetOAuthState(w, state)
+	http.Redirect(w, r, url, http.StatusFound)
+}
+
+func (h *handler) OAuthCallback(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if e := query.Get("error"); e != "" {
+		response.Error(w, errify.NewBadRequestError(e, hr.ValidationError, "OAuthCallback").
+			SetDetails(query.Get("error_description")), h.logger(r))
+		return
+	}
+	state, code := query.Get("state"), query.Get("code")
+	if state == "" || code == "" {
+		response.Error(w, errify.NewBadRequestError(hr.ValidationError, hr.ValidationError, "OAuthCallback").
+			JoinLoc("Query"), h.logger(r))
+		return
+	}
+	err := h.service.CheckOAuthState(w, r, state)
+	if err != nil {
+		response.Error(w, err.JoinLoc("OAuthCallback"), h.logger(r))
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), h.cfg.ContextTimeout)
+	defer cancel()
+
+	// Привязку учетной записи может завершить только пользователь, который ее начал
+	var userID int
+	if user, err := h.authData(ctx, r); err == nil {
+		userID = user.ID
+	}
+	token, err := h.service.OAuthCallback(ctx, mux.Vars(r)["provider"], state, code, userID, *h.tokenCfg)
+	if err != nil {
+		response.Error(w, err.JoinLoc("OAuthCallback"), h.logger(r))
+		return
+	}
+	h.service.SetToken(w, token)
+
+	response.Ok(w, response.NewSend(token, "Authorization successfully", http.StatusOK), h.logger(r))
+}
+
+func (h *handler) UserIdentities(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), h.cfg.ContextTimeout)
+	defer cancel()
+
+	identities, err := h.service.UserIdentities(ctx, authDataFromContext(r.Context()).ID)
+	if err != nil {
+		response.Error(w, err.JoinLoc("UserIdentities"), h.logger(r))
+		return
+	}
+	response.Ok(w, response.NewSend(identities, "Get linked accounts successfully", http.StatusOK), h.logger(r))
+}
+
+func (h *handler) RemoveIdentity(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), h.cfg.ContextTimeout)
+	defer cancel()
+
+	err := h.service.RemoveIdentity(ctx, authDataFromContext(r.Context()).ID, mux.Vars(r)["provider"])
+	if err != nil {
+		response.Error(w, err.JoinLoc("RemoveIdentity"), h.logger(r))
+		return
+	}
+	response.Ok(w, response.NewSend("", "Remove linked account successfully", http.StatusOK), h.logger(r))
+}
@@ -0,0 +1,302 @@
+package v1
+
+import (
+	"auth/internal/domain"
+	hr "auth/internal/handler"
+	"auth/internal/service"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/Linkify-Company/common_utils/errify"
+	"github.com/Linkify-Company/common_utils/response"
+	"github.com/gorilla/mux"
+	"net/http"
+	"strconv"
+)
+
+func initOrganization(h *handler, router *mux.Router) {
+	orgs := router.PathPrefix("/orgs").Subrouter()
+	orgs.Use(h.authMiddleware)
+
+	orgs.HandleFunc("", h.AddOrganization).Methods(http.MethodPost)
+	orgs.HandleFunc("", h.Organizations).Methods(http.MethodGet)
+	orgs.HandleFunc("/switch", h.SwitchOrganization).Methods(http.MethodPost)
+	orgs.HandleFunc("/invitations/accept", h.AcceptInvitation).Methods(http.MethodPost)
+	orgs.HandleFunc("/invitations/decline", h.DeclineInvitation).Methods(http.MethodPost)
+
+	orgs.HandleFunc("/{id}/members", h.OrgMembers).Methods(http.MethodGet)
+	orgs.HandleFunc("/{id}/members/{user_id}", h.SetOrgMemberRole).Methods(http.MethodPatch)
+	orgs.HandleFunc("/{id}/members/{user_id}", h.RemoveOrgMember).Methods(http.MethodDelete)
+	orgs.HandleFunc("/{id}/invitations", h.InviteMember).Methods(http.MethodPost)
+	orgs.HandleFunc("/{id}/invitations", h.OrgInvitations).Methods(http.MethodGet)
+	orgs.HandleFunc("/{id}/invitations/{invitation_id}", h.RevokeInvitation).Methods(http.MethodDelete)
+	orgs.HandleFunc("/{id}/scim/tokens", h.AddSCIMToken).Methods(http.MethodPost)
+	orgs.HandleFunc("/{id}/scim/tokens", h.SCIMTokens).Methods(http.MethodGet)
+	orgs.HandleFunc("/{id}/scim/tokens/{token_id}", h.RevokeSCIMToken).Methods(http.MethodDelete)
+}
+
+func (h *handler) AddOrganization(w http.ResponseWriter, r *http.Request) {
+	var req domain.Organization
+	e := json.NewDecoder(r.Body).Decode(&req)
+	if e != nil {
+		response.Error(w, errify.NewBadRequestError(e.Error(), hr.ValidationError, "AddOrganization").
+			JoinLoc("NewDecoder"), h.logger(r))
+		return
+	}
+	e = req.Valid()
+	if e != nil {
+		response.Error(w, errify.NewBadRequestError(e.Error(), hr.ValidationError, "AddOrganization").
+			JoinLoc("Valid"), h.logger(r))
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), h.cfg.ContextTimeout)
+	defer cancel()
+
+	org, err := h.service.AddOrganization(ctx, authDataFromContext(r.Context()).ID, &req)
+	if err != nil {
+		h.responseError(w, r, err.JoinLoc("AddOrganization"))
+		return
+	}
+	response.Ok(w, response.NewSend(org, "Create organization successfully", http.StatusCreated), h.logger(r))
+}
+
+func (h *handler) Organizations(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), h.cfg.ContextTimeout)
+	defer cancel()
+
+	orgs, err := h.service.UserOrganizations(ctx, authDataFromContext(r.Context()).ID)
+	if err != nil {
+		h.responseError(w, r, err.JoinLoc("Organizations"))
+		return
+	}
+	response.Ok(w, response.NewSend(orgs, "Get organizations successfully", http.StatusOK), h.logger(r))
+}
+
+func (h *handler) SwitchOrganization(w http.ResponseWriter, r *http.Request) {
+	user := authDataFromContext(r.Context())
+	if user.PersonalTokenID != 0 {
+		response.Error(w, errify.NewUnauthorizedError(service.ErrPersonalToken.Error(),
+			service.ErrPersonalToken.Error(), "SwitchOrganization"), h.logger(r))
+		return
+	}
+	var req struct {
+		// OrgID 0 - переключение в личное пространство
+		OrgID int `json:"org_id"`
+	}
+	e := json.NewDecoder(r.Body).Decode(&req)
+	if e == nil && req.OrgID < 0 {
+		e = errors.New("org_id not valid")
+	}
+	if e != nil {
+		response.Error(w, errify.NewBadRequestError(e.Error(), hr.ValidationError, "SwitchOrganization").
+			JoinLoc("NewDecoder"), h.logger(r))
+		return
+	}
+	accessToken, e := h.service.GetToken(r)
+	if e != nil {
+		response.Error(w, errify.NewBadRequestError(e.Error(), hr.ValidationError, "SwitchOrganization").
+			JoinLoc("GetToken"), h.logger(r))
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), h.cfg.ContextTimeout)
+	defer cancel()
+
+	token, err := h.service.SwitchOrganization(ctx, accessToken, user, req.OrgID, *h.tokenCfg)
+	if err != nil {
+		h.responseError(w, r, err.JoinLoc("SwitchOrganization"))
+		return
+	}
+	h.service.SetToken(w, token)
+
+	response.Ok(w, response.NewSend(token, "Switch organization successfully", http.StatusOK), h.logger(r))
+}
+
+func (h *handler) AcceptInvitation(w http.ResponseWriter, r *http.Request) {
+	h.respondInvitation(w, r, true)
+}
+
+func (h *handler) DeclineInvitation(w http.ResponseWriter, r *http.Request) {
+	h.respondInvitation(w, r, false)
+}
+
+func (h *handler) respondInvitation(w http.ResponseWriter, r *http.Request, accept bool) {
+	var req struct {
+		Token string `json:"token"`
+	}
+	e := json.NewDecoder(r.Body).Decode(&req)
+	if e == nil && req.Token == "" {
+		e = errors.New("token empty")
+	}
+	if e != nil {
+		response.Error(w, errify.NewBadRequestError(e.Error(), hr.ValidationError, "respondInvitation").
+			JoinLoc("NewDecoder"), h.logger(r))
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), h.cfg.ContextTimeout)
+	defer cancel()
+
+	org, err := h.service.RespondInvitation(ctx, authDataFromContext(r.Context()), req.Token, accept)
+	if err != nil {
+		h.responseError(w, r, err.JoinLoc("respondInvitation"))
+		return
+	}
+	if !accept {
+		response.Ok(w, response.NewSend("", "Decline invitation successfully", http.StatusOK), h.logger(r))
+		return
+	}
+	response.Ok(w, response.NewSend(org, "Accept invitation successfully", http.StatusOK), h.logger(r))
+}
+
+func (h *handler) OrgMembers(w http.ResponseWriter, r *http.Request) {
+	orgID, e := strconv.Atoi(mux.Vars(r)["id"])
+	if e != nil || orgID <= 0 {
+		response.Error(w, errify.NewBadRequestError(hr.ValidationError, hr.ValidationError, "OrgMembers").
+			JoinLoc("Atoi"), h.logger(r))
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), h.cfg.ContextTimeout)
+	defer cancel()
+
+	members, err := h.service.Members(ctx, orgID, authDataFromContext(r.Context()).ID)
+	if err != nil {
+		h.responseError(w, r, err.JoinLoc("OrgMembers"))
+		return
+	}
+	response.Ok(w, response.NewSend(members, "Get members successfully", http.StatusOK), h.logger(r))
+}
+
+func (h *handler) SetOrgMemberRole(w http.ResponseWriter, r *http.Request) {
+	orgID, memberID, e := orgMemberVars(r)
+	if e != nil {
+		response.Error(w, errify.NewBadRequestError(e.Error(), hr.ValidationError, "SetOrgMemberRole").
+			JoinLoc("orgMemberVars"), h.logger(r))
+		return
+	}
+	var req struct {
+		Role *domain.OrgRole `json:"role"`
+	}
+	e = json.NewDecoder(r.Body).Decode(&req)
+	if e == nil && (req.Role == nil || !req.Role.Valid()) {
+		e = errors.New("role not valid")
+	}
+	if e != nil {
+		response.Error(w, errify.NewBadRequestError(e.Error(), hr.ValidationError, "SetOrgMemberRole").
+			JoinLoc("NewDecoder"), h.logger(r))
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), h.cfg.ContextTimeout)
+	defer cancel()
+
+	member, err := h.service.SetMemberRole(ctx, orgID, authDataFromContext(r.Context()).ID, memberID, *req.Role)
+	if err != nil {
+		h.responseError(w, r, err.JoinLoc("SetOrgMemberRole"))
+		return
+	}
+	response.Ok(w, response.NewSend(member, "Set member role successfully", http.StatusOK), h.logger(r))
+}
+
+func (h *handler) RemoveOrgMember(w http.ResponseWriter, r *http.Request) {
+	orgID, memberID, e := orgMemberVars(r)
+	if e != nil {
+		response.Error(w, errify.NewBadRequestError(e.Error(), hr.ValidationError, "RemoveOrgMember").
+			JoinLoc("orgMemberVars"), h.logger(r))
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), h.cfg.ContextTimeout)
+	defer cancel()
+
+	err := h.service.RemoveMember(ctx, orgID, authDataFromContext(r.Context()).ID, memberID)
+	if err != nil {
+		h.responseError(w, r, err.JoinLoc("RemoveOrgMember"))
+		return
+	}
+	response.Ok(w, response.NewSend("", "Remove member successfully", http.StatusOK), h.logger(r))
+}
+
+func (h *handler) InviteMember(w http.ResponseWriter, r *http.Request) {
+	orgID, e := strconv.Atoi(mux.Vars(r)["id"])
+	if e != nil || orgID <= 0 {
+		response.Error(w, errify.NewBadRequestError(hr.ValidationError, hr.ValidationError, "InviteMember").
+			JoinLoc("Atoi"), h.logger(r))
+		return
+	}
+	var req domain.OrgInvitation
+	e = json.NewDecoder(r.Body).Decode(&req)
+	if e != nil {
+		response.Error(w, errify.NewBadRequestError(e.Error(), hr.ValidationError, "InviteMember").
+			JoinLoc("NewDecoder"), h.logger(r))
+		return
+	}
+	e = req.Valid()
+	if e != nil {
+		response.Error(w, errify.NewBadRequestError(e.Error(), hr.ValidationError, "InviteMember").
+			JoinLoc("Valid"), h.logger(r))
+		return
+	}
+	req.OrgID = orgID
+
+	ctx, cancel := context.WithTimeout(r.Context(), h.cfg.ContextTimeout)
+	defer cancel()
+
+	invitation, err := h.service.Invite(ctx, h.service.Email, authDataFromContext(r.Context()).ID, &req)
+	if err != nil {
+		h.responseError(w, r, err.JoinLoc("InviteMember"))
+		return
+	}
+	response.Ok(w, response.NewSend(invitation, "Send invitation successfully", http.StatusCreated), h.logger(r))
+}
+
+func (h *handler) OrgInvitations(w http.ResponseWriter, r *http.Request) {
+	orgID, e := strconv.Atoi(mux.Vars(r)["id"])
+	if e != nil || orgID <= 0 {
+		response.Error(w, errify.NewBadRequestError(hr.ValidationError, hr.ValidationError, "OrgInvitations").
+			JoinLoc("Atoi"), h.logger(r))
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), h.cfg.ContextTimeout)
+	defer cancel()
+
+	invitations, err := h.service.Invitations(ctx, orgID, authDataFromContext(r.Context()).ID)
+	if err != nil {
+		h.responseError(w, r, err.JoinLoc("OrgInvitations"))
+		return
+	}
+	response.Ok(w, response.NewSend(invitations, "Get invitations successfully", http.StatusOK), h.logger(r))
+}
+
+func (h *handler) RevokeInvitation(w http.ResponseWriter, r *http.Request) {
+	orgID, e := strconv.Atoi(mux.Vars(r)["id"])
+	if e != nil || orgID <= 0 {
+		response.Error(w, errify.NewBadRequestError(hr.ValidationError, hr.ValidationError, "RevokeInvitation").
+			JoinLoc("Atoi"), h.logger(r))
+		return
+	}
+	id, e := strconv.Atoi(mux.Vars(r)["invitation_id"])
+	if e != nil || id <= 0 {
+		response.Error(w, errify.NewBadRequestError(hr.ValidationError, hr.ValidationError, "RevokeInvitation").
+			JoinLoc("Atoi"), h.logger(r))
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), h.cfg.ContextTimeout)
+	defer cancel()
+
+	err := h.service.RevokeInvitation(ctx, orgID, authDataFromContext(r.Context()).ID, id)
+	if err != nil {
+		h.responseError(w, r, err.JoinLoc("RevokeInvitation"))
+		return
+	}
+	response.Ok(w, response.NewSend("", "Revoke invitation successfully", http.StatusOK), h.logger(r))
+}
+
+func orgMemberVars(r *http.Request) (int, int, error) {
+	vars := mux.Vars(r)
+	orgID, err := strconv.Atoi(vars["id"])
+	if err != nil || orgID <= 0 {
+		return 0, 0, errors.New("id not valid")
+	}
+	memberID, err := strconv.Atoi(vars["user_id"])
+	if err != nil || memberID <= 0 {
+		return 0, 0, errors.New("user_id not valid")
+	}
+	return orgID, memberID, nil
+}
@@ -0,0 +1,85 @@
+package v1
+
+import (
+	"auth/internal/domain"
+	hr "auth/internal/handler"
+	"auth/internal/service"
+	"context"
+	"encoding/json"
+	"github.com/Linkify-Company/common_utils/errify"
+	"github.com/Linkify-Company/common_utils/response"
+	"github.com/gorilla/mux"
+	"net/http"
+	"strconv"
+)
+
+func initPersonalToken(h *handler, router *mux.Router) {
+	tokens := router.PathPrefix("/tokens").Subrouter()
+	tokens.Use(h.authMiddleware)
+
+	tokens.HandleFunc("", h.AddPersonalToken).Methods(http.MethodPost)
+	tokens.HandleFunc("", h.PersonalTokens).Methods(http.MethodGet)
+	tokens.HandleFunc("/{id}", h.RevokePersonalToken).Methods(http.MethodDelete)
+}
+
+func (h *handler) AddPersonalToken(w http.ResponseWriter, r *http.Request) {
+	user := authDataFromContext(r.Context())
+	// Новый токен выпускается только из сессии, чтобы утекший токен нельзя было продлить
+	if user.PersonalTokenID != 0 {
+		response.Error(w, errify.NewUnauthorizedError(service.ErrPersonalToken.Error(),
+			service.ErrPersonalToken.Error(), "AddPersonalToken"), h.logger(r))
+		return
+	}
+	var req domain.PersonalTokenCreate
+	e := json.NewDecoder(r.Body).Decode(&req)
+	if e != nil {
+		response.Error(w, errify.NewBadRequestError(e.Error(), hr.ValidationError, "AddPersonalToken").
+			JoinLoc("NewDecoder"), h.logger(r))
+		return
+	}
+	e = req.Valid()
+	if e != nil {
+		response.Error(w, errify.NewBadRequestError(e.Error(), hr.ValidationError, "AddPersonalToken").
+			JoinLoc("Valid"), h.logger(r))
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), h.cfg.ContextTimeout)
+	defer cancel()
+
+	token, err := h.service.AddPersonalToken(ctx, user.ID, &req)
+	if err != nil {
+		response.Error(w, err.JoinLoc("AddPersonalToken"), h.logger(r))
+		return
+	}
+	response.Ok(w, response.NewSend(token, "Create personal access token successfully", http.StatusCreated), h.logger(r))
+}
+
+func (h *handler) PersonalTokens(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), h.cfg.ContextTimeout)
+	defer cancel()
+
+	tokens, err := h.service.PersonalTokens(ctx, authDataFromContext(r.Context()).ID)
+	if err != nil {
+		response.Error(w, err.JoinLoc("PersonalTokens"), h.logger(r))
+		return
+	}
+	response.Ok(w, response.NewSend(tokens, "Get personal access tokens successfully", http.StatusOK), h.logger(r))
+}
+
+func (h *handler) RevokePersonalToken(w http.ResponseWriter, r *http.Request) {
+	id, e := strconv.Atoi(mux.Vars(r)["id"])
+	if e != nil || id <= 0 {
+		response.Error(w, errify.NewBadRequestError(hr.ValidationError, hr.ValidationError, "RevokePersonalToken").
+			JoinLoc("Atoi"), h.logger(r))
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), h.cfg.ContextTimeout)
+	defer cancel()
+
+	err := h.service.RevokePersonalToken(ctx, authDataFromContext(r.Context()).ID, id)
+	if err != nil {
+		response.Error(w, err.JoinLoc("RevokePersonalToken"), h.logger(r))
+		return
+	}
+	response.Ok(w, response.NewSend("", "Revoke personal access token successfully", http.StatusOK), h.logger(r))
+}
@@ -0,0 +1,74 @@
+package v1
+
+import (
+	hr "auth/internal/handler"
+	"context"
+	"github.com/Linkify-Company/common_utils/errify"
+	"github.com/Linkify-Company/common_utils/response"
+	"github.com/gorilla/mux"
+	"net/http"
+)
+
+func initSAML(h *handler, router *mux.Router) {
+	saml := router.PathPrefix("/saml/{idp}").Subrouter()
+
+	saml.HandleFunc("/metadata", h.SAMLMetadata).Methods(http.MethodGet)
+	saml.HandleFunc("/login", h.SAMLLogin).Methods(http.MethodGet)
+	saml.HandleFunc("/acs", h.SAMLAssertionConsumer).Methods(http.MethodPost)
+}
+
+func (h *handler) SAMLMetadata(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), h.cfg.ContextTimeout)
+	defer cancel()
+
+	metadata, err := h.service.SAMLMetadata(ctx, mux.Vars(r)["idp"])
+	if err != nil {
+		response.Error(w, err.JoinLoc("SAMLMetadata"), h.logger(r))
+		return
+	}
+	w.Header().Set("Content-Type", "application/samlmetadata+xml")
+	_, _ = w.Write(metadata)
+}
+
+// SAMLLogin Отправляет пользователя к провайдеру: перенаправлением или автоматически отправляемой формой
+func (h *handler) SAMLLogin(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), h.cfg.ContextTimeout)
+	defer cancel()
+
+	request, err := h.service.SAMLLogin(ctx, mux.Vars(r)["idp"])
+	if err != nil {
+		response.Error(w, err.JoinLoc("SAMLLogin"), h.logger(r))
+		return
+	}
+	if request.RedirectURL != "" {
+		http.Redirect(w, r, request.RedirectURL, http.StatusFound)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	_, _ = w.Write(request.PostForm)
+}
+
+func (h *handler) SAMLAssertionConsumer(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		response.Error(w, errify.NewBadRequestError(err.Error(), hr.ValidationError, "SAMLAssertionConsumer/ParseForm"), h.logger(r))
+		return
+	}
+	samlResponse := r.PostForm.Get("SAMLResponse")
+	if samlResponse == "" {
+		response.Error(w, errify.NewBadRequestError(hr.ValidationError, hr.ValidationError, "SAMLAssertionConsumer").
+			JoinLoc("PostForm"), h.logger(r))
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), h.cfg.ContextTimeout)
+	defer cancel()
+
+	token, err := h.service.SAMLAssertionConsumer(ctx, mux.Vars(r)["idp"], samlResponse, r.PostForm.Get("RelayState"), *h.tokenCfg)
+	if err != nil {
+		response.Error(w, err.JoinLoc("SAMLAssertionConsumer"), h.logger(r))
+		return
+	}
+	h.service.SetToken(w, token)
+
+	response.Ok(w, response.NewSend(token, "Authorization successfully", http.StatusOK), h.logger(r))
+}
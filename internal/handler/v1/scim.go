@@ -0,0 +1,469 @@
+package v1
+
+import (
+	"auth/internal/domain"
+	hr "auth/internal/handler"
+	"auth/internal/service"
+	"context"
+	"encoding/json"
+	"github.com/Linkify-Company/common_utils/errify"
+	"github.com/Linkify-Company/common_utils/response"
+	"github.com/gorilla/mux"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const scimContentType = "application/scim+json"
+
+func initSCIM(h *handler, router *mux.Router) {
+	scim := router.PathPrefix("/scim/v2").Subrouter()
+	scim.Use(h.scimMiddleware)
+
+	scim.HandleFunc("/Users", h.SCIMUsers).Methods(http.MethodGet)
+	scim.HandleFunc("/Users", h.AddSCIMUser).Methods(http.MethodPost)
+	scim.HandleFunc("/Users/{id}", h.SCIMUser).Methods(http.MethodGet)
+	scim.HandleFunc("/Users/{id}", h.ReplaceSCIMUser).Methods(http.MethodPut)
+	scim.HandleFunc("/Users/{id}", h.PatchSCIMUser).Methods(http.MethodPatch)
+	scim.HandleFunc("/Users/{id}", h.DeleteSCIMUser).Methods(http.MethodDelete)
+
+	scim.HandleFunc("/Groups", h.SCIMGroups).Methods(http.MethodGet)
+	scim.HandleFunc("/Groups", h.AddSCIMGroup).Methods(http.MethodPost)
+	scim.HandleFunc("/Groups/{id}", h.SCIMGroup).Methods(http.MethodGet)
+	scim.HandleFunc("/Groups/{id}", h.ReplaceSCIMGroup).Methods(http.MethodPut)
+	scim.HandleFunc("/Groups/{id}", h.PatchSCIMGroup).Methods(http.MethodPatch)
+	scim.HandleFunc("/Groups/{id}", h.DeleteSCIMGroup).Methods(http.MethodDelete)
+}
+
+// scimMiddleware Пропускает запросы с действующим токеном SCIM и сохраняет организацию токена в контексте.
+// Токен передается только в заголовке Authorization, cookie сессии не принимается
+func (h *handler) scimMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || token == "" {
+			h.scimError(w, r, errify.NewUnauthorizedError(service.ErrInvalidCredentials.Error(),
+				service.ErrInvalidCredentials.Error(), "scimMiddleware/Authorization"))
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), h.cfg.ContextTimeout)
+		defer cancel()
+
+		orgID, err := h.service.SCIMTenant(ctx, strings.TrimSpace(token))
+		if err != nil {
+			h.scimError(w, r, err.JoinLoc("scimMiddleware"))
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), scimOrgKey, orgID)))
+	})
+}
+
+// scimOrgFromContext Возвращает организацию, сохраненную middleware SCIM
+func scimOrgFromContext(ctx context.Context) int {
+	orgID, _ := ctx.Value(scimOrgKey).(int)
+	return orgID
+}
+
+func (h *handler) SCIMUsers(w http.ResponseWriter, r *http.Request) {
+	query, e := scimQuery(r, "userName", "externalId")
+	if e != nil {
+		h.scimError(w, r, errify.NewBadRequestError(e.Error(), hr.ValidationError, "SCIMUsers").
+			JoinLoc("scimQuery"))
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), h.cfg.ContextTimeout)
+	defer cancel()
+
+	users, err := h.service.SCIMUsers(ctx, scimOrgFromContext(r.Context()), query)
+	if err != nil {
+		h.scimError(w, r, err.JoinLoc("SCIMUsers"))
+		return
+	}
+	h.scimResponse(w, r, http.StatusOK, users)
+}
+
+func (h *handler) SCIMUser(w http.ResponseWriter, r *http.Request) {
+	id, ok := scimID(r)
+	if !ok {
+		h.scimError(w, r, scimNotExist("SCIMUser"))
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), h.cfg.ContextTimeout)
+	defer cancel()
+
+	user, err := h.service.SCIMUser(ctx, scimOrgFromContext(r.Context()), id)
+	if err != nil {
+		h.scimError(w, r, err.JoinLoc("SCIMUser"))
+		return
+	}
+	h.scimResponse(w, r, http.StatusOK, user)
+}
+
+func (h *handler) AddSCIMUser(w http.ResponseWriter, r *http.Request) {
+	var req domain.SCIMUser
+	e := json.NewDecoder(r.Body).Decode(&req)
+	if e == nil {
+		e = req.Valid()
+	}
+	if e != nil {
+		h.scimError(w, r, errify.NewBadRequestError(e.Error(), hr.ValidationError, "AddSCIMUser").
+			JoinLoc("Valid"))
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), h.cfg.ContextTimeout)
+	defer cancel()
+
+	user, err := h.service.AddSCIMUser(ctx, scimOrgFromContext(r.Context()), &req)
+	if err != nil {
+		h.scimError(w, r, err.JoinLoc("AddSCIMUser"))
+		return
+	}
+	h.scimResponse(w, r, http.StatusCreated, user)
+}
+
+func (h *handler) ReplaceSCIMUser(w http.ResponseWriter, r *http.Request) {
+	id, ok := scimID(r)
+	if !ok {
+		h.scimError(w, r, scimNotExist("ReplaceSCIMUser"))
+		return
+	}
+	var req domain.SCIMUser
+	e := json.NewDecoder(r.Body).Decode(&req)
+	if e == nil {
+		e = req.Valid()
+	}
+	if e != nil {
+		h.scimError(w, r, errify.NewBadRequestError(e.Error(), hr.ValidationError, "ReplaceSCIMUser").
+			JoinLoc("Valid"))
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), h.cfg.ContextTimeout)
+	defer cancel()
+
+	user, err := h.service.ReplaceSCIMUser(ctx, scimOrgFromContext(r.Context()), id, &req)
+	if err != nil {
+		h.scimError(w, r, err.JoinLoc("ReplaceSCIMUser"))
+		return
+	}
+	h.scimResponse(w, r, http.StatusOK, user)
+}
+
+func (h *handler) PatchSCIMUser(w http.ResponseWriter, r *http.Request) {
+	id, ok := scimID(r)
+	if !ok {
+		h.scimError(w, r, scimNotExist("PatchSCIMUser"))
+		return
+	}
+	var req domain.SCIMPatch
+	e := json.NewDecoder(r.Body).Decode(&req)
+	if e == nil {
+		e = req.Valid()
+	}
+	if e != nil {
+		h.scimError(w, r, errify.NewBadRequestError(e.Error(), hr.ValidationError, "PatchSCIMUser").
+			JoinLoc("Valid"))
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), h.cfg.ContextTimeout)
+	defer cancel()
+
+	user, err := h.service.PatchSCIMUser(ctx, scimOrgFromContext(r.Context()), id, &req)
+	if err != nil {
+		h.scimError(w, r, err.JoinLoc("PatchSCIMUser"))
+		return
+	}
+	h.scimResponse(w, r, http.StatusOK, user)
+}
+
+func (h *handler) DeleteSCIMUser(w http.ResponseWriter, r *http.Request) {
+	id, ok := scimID(r)
+	if !ok {
+		h.scimError(w, r, scimNotExist("DeleteSCIMUser"))
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), h.cfg.ContextTimeout)
+	defer cancel()
+
+	err := h.service.DeleteSCIMUser(ctx, scimOrgFromContext(r.Context()), id)
+	if err != nil {
+		h.scimError(w, r, err.JoinLoc("DeleteSCIMUser"))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *handler) SCIMGroups(w http.ResponseWriter, r *http.Request) {
+	query, e := scimQuery(r, "displayName", "externalId")
+	if e != nil {
+		h.scimError(w, r, errify.NewBadRequestError(e.Error(), hr.ValidationError, "SCIMGroups").
+			JoinLoc("scimQuery"))
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), h.cfg.ContextTimeout)
+	defer cancel()
+
+	groups, err := h.service.SCIMGroups(ctx, scimOrgFromContext(r.Context()), query)
+	if err != nil {
+		h.scimError(w, r, err.JoinLoc("SCIMGroups"))
+		return
+	}
+	h.scimResponse(w, r, http.StatusOK, groups)
+}
+
+func (h *handler) SCIMGroup(w http.ResponseWriter, r *http.Request) {
+	id, ok := scimID(r)
+	if !ok {
+		h.scimError(w, r, scimNotExist("SCIMGroup"))
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), h.cfg.ContextTimeout)
+	defer cancel()
+
+	group, err := h.service.SCIMGroup(ctx, scimOrgFromContext(r.Context()), id)
+	if err != nil {
+		h.scimError(w, r, err.JoinLoc("SCIMGroup"))
+		return
+	}
+	h.scimResponse(w, r, http.StatusOK, group)
+}
+
+func (h *handler) AddSCIMGroup(w http.ResponseWriter, r *http.Request) {
+	var req domain.SCIMGroup
+	e := json.NewDecoder(r.Body).Decode(&req)
+	if e == nil {
+		e = req.Valid()
+	}
+	if e != nil {
+		h.scimError(w, r, errify.NewBadRequestError(e.Error(), hr.ValidationError, "AddSCIMGroup").
+			JoinLoc("Valid"))
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), h.cfg.ContextTimeout)
+	defer cancel()
+
+	group, err := h.service.AddSCIMGroup(ctx, scimOrgFromContext(r.Context()), &req)
+	if err != nil {
+		h.scimError(w, r, err.JoinLoc("AddSCIMGroup"))
+		return
+	}
+	h.scimResponse(w, r, http.StatusCreated, group)
+}
+
+func (h *handler) ReplaceSCIMGroup(w http.ResponseWriter, r *http.Request) {
+	id, ok := scimID(r)
+	if !ok {
+		h.scimError(w, r, scimNotExist("ReplaceSCIMGroup"))
+		return
+	}
+	var req domain.SCIMGroup
+	e := json.NewDecoder(r.Body).Decode(&req)
+	if e == nil {
+		e = req.Valid()
+	}
+	if e != nil {
+		h.scimError(w, r, errify.NewBadRequestError(e.Error(), hr.ValidationError, "ReplaceSCIMGroup").
+			JoinLoc("Valid"))
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), h.cfg.ContextTimeout)
+	defer cancel()
+
+	group, err := h.service.ReplaceSCIMGroup(ctx, scimOrgFromContext(r.Context()), id, &req)
+	if err != nil {
+		h.scimError(w, r, err.JoinLoc("ReplaceSCIMGroup"))
+		return
+	}
+	h.scimResponse(w, r, http.StatusOK, group)
+}
+
+func (h *handler) PatchSCIMGroup(w http.ResponseWriter, r *http.Request) {
+	id, ok := scimID(r)
+	if !ok {
+		h.scimError(w, r, scimNotExist("PatchSCIMGroup"))
+		return
+	}
+	var req domain.SCIMPatch
+	e := json.NewDecoder(r.Body).Decode(&req)
+	if e == nil {
+		e = req.Valid()
+	}
+	if e != nil {
+		h.scimError(w, r, errify.NewBadRequestError(e.Error(), hr.ValidationError, "PatchSCIMGroup").
+			JoinLoc("Valid"))
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), h.cfg.ContextTimeout)
+	defer cancel()
+
+	group, err := h.service.PatchSCIMGroup(ctx, scimOrgFromContext(r.Context()), id, &req)
+	if err != nil {
+		h.scimError(w, r, err.JoinLoc("PatchSCIMGroup"))
+		return
+	}
+	h.scimResponse(w, r, http.StatusOK, group)
+}
+
+func (h *handler) DeleteSCIMGroup(w http.ResponseWriter, r *http.Request) {
+	id, ok := scimID(r)
+	if !ok {
+		h.scimError(w, r, scimNotExist("DeleteSCIMGroup"))
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), h.cfg.ContextTimeout)
+	defer cancel()
+
+	err := h.service.DeleteSCIMGroup(ctx, scimOrgFromContext(r.Context()), id)
+	if err != nil {
+		h.scimError(w, r, err.JoinLoc("DeleteSCIMGroup"))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *handler) AddSCIMToken(w http.ResponseWriter, r *http.Request) {
+	user := authDataFromContext(r.Context())
+	if user.PersonalTokenID != 0 {
+		response.Error(w, errify.NewUnauthorizedError(service.ErrPersonalToken.Error(),
+			service.ErrPersonalToken.Error(), "AddSCIMToken"), h.logger(r))
+		return
+	}
+	orgID, e := strconv.Atoi(mux.Vars(r)["id"])
+	if e != nil || orgID <= 0 {
+		response.Error(w, errify.NewBadRequestError(hr.ValidationError, hr.ValidationError, "AddSCIMToken").
+			JoinLoc("Atoi"), h.logger(r))
+		return
+	}
+	var req domain.SCIMTokenCreate
+	e = json.NewDecoder(r.Body).Decode(&req)
+	if e != nil {
+		response.Error(w, errify.NewBadRequestError(e.Error(), hr.ValidationError, "AddSCIMToken").
+			JoinLoc("NewDecoder"), h.logger(r))
+		return
+	}
+	e = req.Valid()
+	if e != nil {
+		response.Error(w, errify.NewBadRequestError(e.Error(), hr.ValidationError, "AddSCIMToken").
+			JoinLoc("Valid"), h.logger(r))
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), h.cfg.ContextTimeout)
+	defer cancel()
+
+	token, err := h.service.AddSCIMToken(ctx, orgID, user.ID, &req)
+	if err != nil {
+		h.responseError(w, r, err.JoinLoc("AddSCIMToken"))
+		return
+	}
+	response.Ok(w, response.NewSend(token, "Create SCIM token successfully", http.StatusCreated), h.logger(r))
+}
+
+func (h *handler) SCIMTokens(w http.ResponseWriter, r *http.Request) {
+	orgID, e := strconv.Atoi(mux.Vars(r)["id"])
+	if e != nil || orgID <= 0 {
+		response.Error(w, errify.NewBadRequestError(hr.ValidationError, hr.ValidationError, "SCIMTokens").
+			JoinLoc("Atoi"), h.logger(r))
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), h.cfg.ContextTimeout)
+	defer cancel()
+
+	tokens, err := h.service.SCIMTokens(ctx, orgID, authDataFromContext(r.Context()).ID)
+	if err != nil {
+		h.responseError(w, r, err.JoinLoc("SCIMTokens"))
+		return
+	}
+	response.Ok(w, response.NewSend(tokens, "Get SCIM tokens successfully", http.StatusOK), h.logger(r))
+}
+
+func (h *handler) RevokeSCIMToken(w http.ResponseWriter, r *http.Request) {
+	orgID, e := strconv.Atoi(mux.Vars(r)["id"])
+	if e != nil || orgID <= 0 {
+		response.Error(w, errify.NewBadRequestError(hr.ValidationError, hr.ValidationError, "RevokeSCIMToken").
+			JoinLoc("Atoi"), h.logger(r))
+		return
+	}
+	id, e := strconv.Atoi(mux.Vars(r)["token_id"])
+	if e != nil || id <= 0 {
+		response.Error(w, errify.NewBadRequestError(hr.ValidationError, hr.ValidationError, "RevokeSCIMToken").
+			JoinLoc("Atoi"), h.logger(r))
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), h.cfg.ContextTimeout)
+	defer cancel()
+
+	err := h.service.RevokeSCIMToken(ctx, orgID, authDataFromContext(r.Context()).ID, id)
+	if err != nil {
+		h.responseError(w, r, err.JoinLoc("RevokeSCIMToken"))
+		return
+	}
+	response.Ok(w, response.NewSend("", "Revoke SCIM token successfully", http.StatusOK), h.logger(r))
+}
+
+// scimQuery Разбирает параметры filter, startIndex и count списка ресурсов
+func scimQuery(r *http.Request, attributes ...string) (*domain.SCIMQuery, error) {
+	values := r.URL.Query()
+	query := &domain.SCIMQuery{StartIndex: 1, Count: domain.DefaultSCIMCount}
+	var err error
+	if value := values.Get("startIndex"); value != "" {
+		if query.StartIndex, err = strconv.Atoi(value); err != nil {
+			return nil, err
+		}
+	}
+	if value := values.Get("count"); value != "" {
+		if query.Count, err = strconv.Atoi(value); err != nil {
+			return nil, err
+		}
+	}
+	if value := values.Get("filter"); value != "" {
+		if query.Filter, err = domain.ParseSCIMFilter(value); err != nil {
+			return nil, err
+		}
+	}
+	return query, query.Valid(attributes...)
+}
+
+// scimID Идентификатор ресурса из пути. Ресурс с неразборчивым идентификатором считается несуществующим
+func scimID(r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	return id, err == nil && id > 0
+}
+
+func scimNotExist(loc string) errify.IError {
+	return errify.NewBadRequestError(service.ErrSCIMResourceNotExist.Error(), service.ErrSCIMResourceNotExist.Error(), loc).
+		JoinLoc("scimID")
+}
+
+func (h *handler) scimResponse(w http.ResponseWriter, r *http.Request, status int, v any) {
+	w.Header().Set("Content-Type", scimContentType)
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		h.logger(r).Error(errify.NewInternalServerError(err.Error(), "scimResponse/Encode"))
+	}
+}
+
+// scimError Отвечает ошибкой в формате SCIM. Отсутствие ресурса и конфликт передаются сервисом
+// как BadRequestError с соответствующим сообщением
+func (h *handler) scimError(w http.ResponseWriter, r *http.Request, err errify.IError) {
+	h.logger(r).Error(err)
+
+	res := &domain.SCIMError{Schemas: []string{domain.SCIMErrorSchema}}
+	status := http.StatusInternalServerError
+	switch err.(type) {
+	case *errify.UnauthorizedError:
+		status = http.StatusUnauthorized
+		res.Detail = service.ErrInvalidCredentials.Error()
+	case *errify.BadRequestError:
+		status = http.StatusBadRequest
+		res.Detail = err.Error()
+		switch err.Error() {
+		case service.ErrSCIMResourceNotExist.Error():
+			status = http.StatusNotFound
+		case service.ErrSCIMResourceConflict.Error():
+			status = http.StatusConflict
+			res.ScimType = "uniqueness"
+		default:
+			res.ScimType = "invalidValue"
+		}
+	}
+	res.Status = strconv.Itoa(status)
+	h.scimResponse(w, r, status, res)
+}
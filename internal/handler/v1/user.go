@@ -3,6 +3,7 @@ package v1
 import (
 	"auth/internal/domain"
 	hr "auth/internal/handler"
+	"auth/internal/service"
 	"context"
 	"encoding/json"
 	"github.com/Linkify-Company/common_utils/errify"
@@ -10,6 +11,7 @@ import (
 	"github.com/gorilla/mux"
 	"net/http"
 	"strconv"
+	"strings"
 )
 
 func initUser(h *handler, router *mux.Router) {
@@ -24,13 +26,13 @@ func (h *handler) AddUser(w http.ResponseWriter, r *http.Request) {
 	e := json.NewDecoder(r.Body).Decode(&req)
 	if e != nil {
 		response.Error(w, errify.NewBadRequestError(e.Error(), hr.ValidationError, "AddUser").
-			JoinLoc("NewDecoder"), h.log)
+			JoinLoc("NewDecoder"), h.logger(r))
 		return
 	}
 	e = req.Valid()
 	if e != nil {
 		response.Error(w, errify.NewBadRequestError(e.Error(), hr.ValidationError, "AddUser").
-			JoinLoc("Valid"), h.log)
+			JoinLoc("Valid"), h.logger(r))
 		return
 	}
 
@@ -39,10 +41,10 @@ func (h *handler) AddUser(w http.ResponseWriter, r *http.Request) {
 
 	id, err := h.service.AddUser(ctx, h.service.Email, &req)
 	if err != nil {
-		response.Error(w, err.JoinLoc("AddUser"), h.log)
+		response.Error(w, err.JoinLoc("AddUser"), h.logger(r))
 		return
 	}
-	response.Ok(w, response.NewSend(id, "Create user successfully", http.StatusCreated), h.log)
+	response.Ok(w, response.NewSend(id, "Create user successfully", http.StatusCreated), h.logger(r))
 }
 
 func (h *handler) GetUser(w http.ResponseWriter, r *http.Request) {
@@ -50,12 +52,25 @@ func (h *handler) GetUser(w http.ResponseWriter, r *http.Request) {
 	id, _ := strconv.Atoi(mux.Vars(r)["value"])
 	if email == "" && id <= 0 {
 		response.Error(w, errify.NewBadRequestError(hr.ValidationError, hr.ValidationError, "GetUser").
-			JoinLoc("Atoi"), h.log)
+			JoinLoc("Atoi"), h.logger(r))
 		return
 	}
 	ctx, cancel := context.WithTimeout(r.Context(), h.cfg.ContextTimeout)
 	defer cancel()
 
+	if h.securityCfg.ProtectUserLookup {
+		requester, err := h.authData(ctx, r)
+		if err != nil {
+			response.Error(w, err.JoinLoc("GetUser"), h.logger(r))
+			return
+		}
+		// Чужие записи доступны только с разрешением users:read, для остальных ответ одинаков независимо от наличия пользователя
+		if !requester.HasPermission(domain.PermissionUsersRead) && requester.ID != id && !strings.EqualFold(requester.Email, email) {
+			response.Error(w, errify.NewBadRequestError(service.UserNotExist.Error(), service.UserNotExist.Error(), "GetUser"), h.logger(r))
+			return
+		}
+	}
+
 	var err errify.IError
 	var user *domain.User
 
@@ -65,8 +80,8 @@ func (h *handler) GetUser(w http.ResponseWriter, r *http.Request) {
 		user, err = h.service.GetUserByEmail(ctx, email)
 	}
 	if err != nil {
-		response.Error(w, err.JoinLoc("GetUser"), h.log)
+		response.Error(w, err.JoinLoc("GetUser"), h.logger(r))
 		return
 	}
-	response.Ok(w, response.NewSend(user, "Get user successfully", http.StatusOK), h.log)
+	response.Ok(w, response.NewSend(user, "Get user successfully", http.StatusOK), h.logger(r))
 }
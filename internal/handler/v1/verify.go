@@ -0,0 +1,165 @@
+package v1
+
+import (
+	"auth/internal/domain"
+	hr "auth/internal/handler"
+	"auth/internal/service"
+	"context"
+	"errors"
+	"github.com/Linkify-Company/common_utils/errify"
+	"github.com/Linkify-Company/common_utils/response"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	headerUserID    = "X-User-Id"
+	headerUserEmail = "X-User-Email"
+	headerUserRole  = "X-User-Role"
+)
+
+// Verify Проверка запроса для nginx auth_request и Traefik ForwardAuth.
+// Прокси смотрит только на код ответа: 200 с данными пользователя в заголовках X-User-*, 401 без действительного токена,
+// 403, если не выполнены требования из параметров role (любая из ролей) и permission (все разрешения).
+// HTML запросы без токена перенаправляются на ForwardAuthConfig.LoginURL. nginx не передает клиенту ответ подзапроса,
+// поэтому для него перенаправление настраивается через error_page 401, а адрес берется из заголовка Location
+func (h *handler) Verify(w http.ResponseWriter, r *http.Request) {
+	roles, permissions, e := verifyRequirements(r.URL.Query())
+	if e != nil {
+		response.Error(w, errify.NewBadRequestError(e.Error(), hr.ValidationError, "Verify").
+			JoinLoc("verifyRequirements"), h.logger(r))
+		return
+	}
+	token, e := h.service.GetToken(r)
+	if e != nil || token == "" {
+		h.verifyUnauthorized(w, r)
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), h.cfg.ContextTimeout)
+	defer cancel()
+
+	user, err := h.service.CheckAuthorization(ctx, token)
+	if err != nil && errors.Is(err, service.ErrTokenExpired) {
+		token, err = h.service.RenewAuthorization(ctx, token, *h.tokenCfg)
+		if err == nil {
+			h.service.SetToken(w, token)
+			user, err = h.service.CheckAuthorization(ctx, token)
+		}
+	}
+	if err != nil {
+		if _, ok := err.(*errify.UnauthorizedError); ok {
+			h.verifyUnauthorized(w, r)
+			return
+		}
+		response.Error(w, err.JoinLoc("Verify"), h.logger(r))
+		return
+	}
+	hr.SetUserID(ctx, user.ID)
+	hasRole, err := h.verifyRole(ctx, user, roles)
+	if err != nil {
+		response.Error(w, err.JoinLoc("Verify"), h.logger(r))
+		return
+	}
+	if !hasRole || !user.HasPermission(permissions...) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	w.Header().Set(headerUserID, strconv.Itoa(user.ID))
+	w.Header().Set(headerUserEmail, user.Email)
+	w.Header().Set(headerUserRole, user.Role.String())
+	w.WriteHeader(http.StatusOK)
+}
+
+// verifyRole Есть ли у пользователя любая из ролей. Персональный токен ограничен своими областями, поэтому роль
+// владельца засчитывается ему, только если области покрывают все разрешения встроенной роли с тем же именем
+func (h *handler) verifyRole(ctx context.Context, user *domain.AuthData, roles []domain.Role) (bool, errify.IError) {
+	if len(roles) == 0 {
+		return true, nil
+	}
+	if user.PersonalTokenID == 0 {
+		return slices.Contains(roles, user.Role), nil
+	}
+	accessRoles, err := h.service.AccessRoles(ctx)
+	if err != nil {
+		return false, err.JoinLoc("verifyRole")
+	}
+	for _, accessRole := range accessRoles {
+		if !accessRole.Builtin || !slices.ContainsFunc(roles, func(role domain.Role) bool {
+			return role.String() == accessRole.Name
+		}) {
+			continue
+		}
+		if user.HasPermission(accessRole.Permissions...) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// verifyUnauthorized Отвечает 401, а браузеру, если задана страница входа, перенаправлением на нее с адресом возврата
+func (h *handler) verifyUnauthorized(w http.ResponseWriter, r *http.Request) {
+	if h.forwardAuthCfg.LoginURL == "" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	login, e := url.Parse(h.forwardAuthCfg.LoginURL)
+	if e != nil {
+		response.Error(w, errify.NewInternalServerError(e.Error(), "verifyUnauthorized/Parse"), h.logger(r))
+		return
+	}
+	if returnURL := forwardedURL(r); returnURL != "" {
+		query := login.Query()
+		query.Set(h.forwardAuthCfg.ReturnParam, returnURL)
+		login.RawQuery = query.Encode()
+	}
+	w.Header().Set("Location", login.String())
+
+	if !strings.Contains(r.Header.Get("Accept"), "text/html") {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	w.WriteHeader(http.StatusFound)
+}
+
+// forwardedURL Адрес исходного запроса из заголовков прокси: X-Original-URL (nginx) или X-Forwarded-* (Traefik)
+func forwardedURL(r *http.Request) string {
+	if original := r.Header.Get("X-Original-URL"); original != "" {
+		return original
+	}
+	uri := r.Header.Get("X-Forwarded-Uri")
+	if uri == "" {
+		uri = r.Header.Get("X-Original-URI")
+	}
+	host := r.Header.Get("X-Forwarded-Host")
+	if host == "" {
+		return uri
+	}
+	proto := r.Header.Get("X-Forwarded-Proto")
+	if proto == "" {
+		proto = "https"
+	}
+	return proto + "://" + host + uri
+}
+
+// verifyRequirements Разбирает требуемые роли и разрешения из параметров запроса
+func verifyRequirements(query url.Values) ([]domain.Role, []domain.Permission, error) {
+	roles := make([]domain.Role, 0, len(query["role"]))
+	for _, value := range query["role"] {
+		role, err := domain.ParseRole(value)
+		if err != nil {
+			return nil, nil, err
+		}
+		roles = append(roles, role)
+	}
+	permissions := make([]domain.Permission, 0, len(query["permission"]))
+	for _, value := range query["permission"] {
+		if value == "" {
+			return nil, nil, errors.New("permission empty")
+		}
+		permissions = append(permissions, domain.Permission(value))
+	}
+	return roles, permissions, nil
+}
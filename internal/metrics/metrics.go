@@ -0,0 +1,154 @@
+// Package metrics Метрики сервиса в формате Prometheus. Метрики регистрируются в собственном реестре,
+// который отдает Handler, поэтому сторонние библиотеки не добавляют в него свои
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const namespace = "auth"
+
+// Способы входа
+const (
+	LoginPassword = "password"
+	LoginOAuth    = "oauth"
+	LoginSAML     = "saml"
+)
+
+// Причины отказа во входе
+const (
+	ReasonInvalidCredentials = "invalid_credentials"
+	ReasonLocked             = "locked"
+	ReasonError              = "error"
+)
+
+// Результаты операций
+const (
+	ResultSuccess = "success"
+	ResultFailure = "failure"
+)
+
+var registry = prometheus.NewRegistry()
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "HTTP requests by route template, method and status code.",
+	}, []string{"method", "route", "status"})
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "HTTP request latency by route template and method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	logins = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "logins_total",
+		Help:      "Login attempts by method, result and failure reason.",
+	}, []string{"method", "result", "reason"})
+	renewals = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "token_renewals_total",
+		Help:      "Access token renewals by result.",
+	}, []string{"result"})
+
+	emails = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "email",
+		Name:      "sends_total",
+		Help:      "Email sends by result.",
+	}, []string{"result"})
+	emailDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "email",
+		Name:      "send_duration_seconds",
+		Help:      "Email send latency including the SMTP handshake.",
+		Buckets:   []float64{.1, .25, .5, 1, 2.5, 5, 10, 30, 60},
+	})
+
+	redisDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "redis",
+		Name:      "command_duration_seconds",
+		Help:      "Redis command latency by command. Pipelines and transactions are observed as a whole.",
+		Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+	}, []string{"command"})
+	redisErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "redis",
+		Name:      "command_errors_total",
+		Help:      "Redis commands that failed, a missing key is not an error.",
+	}, []string{"command"})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests,
+		httpDuration,
+		logins,
+		renewals,
+		emails,
+		emailDuration,
+		redisDuration,
+		redisErrors,
+	)
+}
+
+// Handler Отдает метрики реестра сервиса. Ошибка одного сборщика не мешает отдать остальные метрики
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{
+		Registry:      registry,
+		ErrorHandling: promhttp.ContinueOnError,
+	})
+}
+
+// Register Добавляет в реестр сервиса сборщик, значения которого вычисляются при запросе метрик
+func Register(collector prometheus.Collector) error {
+	return registry.Register(collector)
+}
+
+// InitRoute Создает ряды маршрута заранее, чтобы маршрут без запросов был виден с нулевыми значениями
+func InitRoute(method string, route string) {
+	httpDuration.WithLabelValues(method, route)
+}
+
+func ObserveRequest(method string, route string, status int, duration time.Duration) {
+	httpRequests.WithLabelValues(method, route, strconv.Itoa(status)).Inc()
+	httpDuration.WithLabelValues(method, route).Observe(duration.Seconds())
+}
+
+// Login Учитывает попытку входа. Пустая причина означает успешный вход
+func Login(method string, reason string) {
+	if reason == "" {
+		logins.WithLabelValues(method, ResultSuccess, "").Inc()
+		return
+	}
+	logins.WithLabelValues(method, ResultFailure, reason).Inc()
+}
+
+func Renewal(ok bool) {
+	renewals.WithLabelValues(result(ok)).Inc()
+}
+
+func EmailSend(ok bool, duration time.Duration) {
+	emails.WithLabelValues(result(ok)).Inc()
+	emailDuration.Observe(duration.Seconds())
+}
+
+func result(ok bool) string {
+	if ok {
+		return ResultSuccess
+	}
+	return ResultFailure
+}
@@ -0,0 +1,34 @@
+package metrics
+
+import (
+	"github.com/go-redis/redis"
+	"strings"
+	"time"
+)
+
+// InstrumentRedis Измеряет время команд клиента. Копии клиента из WithContext наследуют измерение
+func InstrumentRedis(client *redis.Client) {
+	client.WrapProcess(func(next func(cmd redis.Cmder) error) func(cmd redis.Cmder) error {
+		return func(cmd redis.Cmder) error {
+			start := time.Now()
+			err := next(cmd)
+			observeRedis(strings.ToLower(cmd.Name()), start, err)
+			return err
+		}
+	})
+	client.WrapProcessPipeline(func(next func(cmds []redis.Cmder) error) func(cmds []redis.Cmder) error {
+		return func(cmds []redis.Cmder) error {
+			start := time.Now()
+			err := next(cmds)
+			observeRedis("pipeline", start, err)
+			return err
+		}
+	})
+}
+
+func observeRedis(command string, start time.Time, err error) {
+	redisDuration.WithLabelValues(command).Observe(time.Since(start).Seconds())
+	if err != nil && err != redis.Nil {
+		redisErrors.WithLabelValues(command).Inc()
+	}
+}
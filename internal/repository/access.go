@@ -0,0 +1,232 @@
+package repository
+
+import (
+	"auth/internal/domain"
+	"auth/internal/repository/postgres"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+type AccessRepos struct{}
+
+func NewAccessRepos() Access {
+	return &AccessRepos{}
+}
+
+func (m *AccessRepos) Permissions(ctx context.Context, tx pgx.Tx) ([]*domain.PermissionInfo, error) {
+	rows, err := tx.Query(ctx, `SELECT id, name, description FROM permission ORDER BY name`)
+	if err != nil {
+		return nil, fmt.Errorf("Permissions/Query: %w", err)
+	}
+	defer rows.Close()
+
+	permissions := make([]*domain.PermissionInfo, 0)
+	for rows.Next() {
+		var p domain.PermissionInfo
+		err = rows.Scan(&p.ID, &p.Name, &p.Description)
+		if err != nil {
+			return nil, fmt.Errorf("Permissions/Scan: %w", err)
+		}
+		permissions = append(permissions, &p)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("Permissions/Rows: %w", err)
+	}
+	return permissions, nil
+}
+
+func (m *AccessRepos) AddPermission(ctx context.Context, tx pgx.Tx, permission *domain.PermissionInfo) (int, error) {
+	row := tx.QueryRow(ctx, `INSERT INTO permission (name, description) VALUES ($1, $2) RETURNING id`,
+		permission.Name, permission.Description)
+	var id int
+	err := row.Scan(&id)
+	if err != nil {
+		if err, ok := err.(*pgconn.PgError); ok && err.Code == postgres.ErrUniqueViolation {
+			return 0, PermissionAlreadyExist
+		}
+		return 0, fmt.Errorf("AddPermission/Scan: %w", err)
+	}
+	return id, nil
+}
+
+const accessRoleQuery = `SELECT r.id, r.name, r.description, r.builtin,
+	COALESCE(array_agg(p.name ORDER BY p.name) FILTER (WHERE p.name IS NOT NULL), '{}')
+FROM access_role r
+LEFT JOIN access_role_permission rp ON rp.role_id = r.id
+LEFT JOIN permission p ON p.id = rp.permission_id`
+
+func (m *AccessRepos) AccessRoles(ctx context.Context, tx pgx.Tx) ([]*domain.AccessRole, error) {
+	rows, err := tx.Query(ctx, accessRoleQuery+` GROUP BY r.id ORDER BY r.id`)
+	if err != nil {
+		return nil, fmt.Errorf("AccessRoles/Query: %w", err)
+	}
+	return scanAccessRoles(rows)
+}
+
+func (m *AccessRepos) AccessRole(ctx context.Context, tx pgx.Tx, id int) (*domain.AccessRole, error) {
+	rows, err := tx.Query(ctx, accessRoleQuery+` WHERE r.id = $1 GROUP BY r.id`, id)
+	if err != nil {
+		return nil, fmt.Errorf("AccessRole/Query: %w", err)
+	}
+	roles, err := scanAccessRoles(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(roles) == 0 {
+		return nil, RoleNotExist
+	}
+	return roles[0], nil
+}
+
+func (m *AccessRepos) AddAccessRole(ctx context.Context, tx pgx.Tx, role *domain.AccessRole) (int, error) {
+	row := tx.QueryRow(ctx, `INSERT INTO access_role (name, description) VALUES ($1, $2) RETURNING id`,
+		role.Name, role.Description)
+	var id int
+	err := row.Scan(&id)
+	if err != nil {
+		if err, ok := err.(*pgconn.PgError); ok && err.Code == postgres.ErrUniqueViolation {
+			return 0, RoleAlreadyExist
+		}
+		return 0, fmt.Errorf("AddAccessRole/Scan: %w", err)
+	}
+	return id, nil
+}
+
+func (m *AccessRepos) SetAccessRolePermissions(ctx context.Context, tx pgx.Tx, roleID int, permissions []domain.Permission) error {
+	_, err := tx.Exec(ctx, `DELETE FROM access_role_permission WHERE role_id = $1`, roleID)
+	if err != nil {
+		return fmt.Errorf("SetAccessRolePermissions/Exec: %w", err)
+	}
+	if len(permissions) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(permissions))
+	for _, p := range permissions {
+		names = append(names, string(p))
+	}
+	tag, err := tx.Exec(ctx, `INSERT INTO access_role_permission (role_id, permission_id)
+		SELECT $1, id FROM permission WHERE name = ANY($2) ON CONFLICT DO NOTHING`, roleID, names)
+	if err != nil {
+		if err, ok := err.(*pgconn.PgError); ok && err.Code == postgres.ErrForeignKeyViolation {
+			return RoleNotExist
+		}
+		return fmt.Errorf("SetAccessRolePermissions/Exec: %w", err)
+	}
+	if int(tag.RowsAffected()) != len(uniqueStrings(names)) {
+		return PermissionNotExist
+	}
+	return nil
+}
+
+func (m *AccessRepos) DeleteAccessRole(ctx context.Context, tx pgx.Tx, id int) error {
+	var builtin bool
+	err := tx.QueryRow(ctx, `SELECT builtin FROM access_role WHERE id = $1`, id).Scan(&builtin)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return RoleNotExist
+		}
+		return fmt.Errorf("DeleteAccessRole/Scan: %w", err)
+	}
+	if builtin {
+		return RoleBuiltin
+	}
+	_, err = tx.Exec(ctx, `DELETE FROM access_role WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("DeleteAccessRole/Exec: %w", err)
+	}
+	return nil
+}
+
+func (m *AccessRepos) UserAccessRoles(ctx context.Context, tx pgx.Tx, userID int) ([]*domain.AccessRole, error) {
+	rows, err := tx.Query(ctx, accessRoleQuery+` JOIN user_access_role ur ON ur.role_id = r.id
+		WHERE ur.user_id = $1 GROUP BY r.id ORDER BY r.id`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("UserAccessRoles/Query: %w", err)
+	}
+	return scanAccessRoles(rows)
+}
+
+func (m *AccessRepos) SetUserAccessRoles(ctx context.Context, tx pgx.Tx, userID int, roleIDs []int) error {
+	_, err := tx.Exec(ctx, `DELETE FROM user_access_role WHERE user_id = $1`, userID)
+	if err != nil {
+		return fmt.Errorf("SetUserAccessRoles/Exec: %w", err)
+	}
+	for _, roleID := range roleIDs {
+		_, err = tx.Exec(ctx, `INSERT INTO user_access_role (user_id, role_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`,
+			userID, roleID)
+		if err != nil {
+			if err, ok := err.(*pgconn.PgError); ok && err.Code == postgres.ErrForeignKeyViolation {
+				if err.ConstraintName == "user_access_role_user_id_fkey" {
+					return UserNotExist
+				}
+				return RoleNotExist
+			}
+			return fmt.Errorf("SetUserAccessRoles/Exec: %w", err)
+		}
+	}
+	return nil
+}
+
+func (m *AccessRepos) UserPermissions(ctx context.Context, tx pgx.Tx, userID int) ([]domain.Permission, error) {
+	rows, err := tx.Query(ctx, `SELECT DISTINCT p.name FROM user_access_role ur
+		JOIN access_role_permission rp ON rp.role_id = ur.role_id
+		JOIN permission p ON p.id = rp.permission_id
+		WHERE ur.user_id = $1 ORDER BY p.name`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("UserPermissions/Query: %w", err)
+	}
+	defer rows.Close()
+
+	permissions := make([]domain.Permission, 0)
+	for rows.Next() {
+		var p domain.Permission
+		err = rows.Scan(&p)
+		if err != nil {
+			return nil, fmt.Errorf("UserPermissions/Scan: %w", err)
+		}
+		permissions = append(permissions, p)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("UserPermissions/Rows: %w", err)
+	}
+	return permissions, nil
+}
+
+func scanAccessRoles(rows pgx.Rows) ([]*domain.AccessRole, error) {
+	defer rows.Close()
+
+	roles := make([]*domain.AccessRole, 0)
+	for rows.Next() {
+		var role domain.AccessRole
+		var permissions []string
+		err := rows.Scan(&role.ID, &role.Name, &role.Description, &role.Builtin, &permissions)
+		if err != nil {
+			return nil, fmt.Errorf("scanAccessRoles/Scan: %w", err)
+		}
+		role.Permissions = make([]domain.Permission, 0, len(permissions))
+		for _, p := range permissions {
+			role.Permissions = append(role.Permissions, domain.Permission(p))
+		}
+		roles = append(roles, &role)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("scanAccessRoles/Rows: %w", err)
+	}
+	return roles, nil
+}
+
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]struct{}, len(values))
+	res := make([]string, 0, len(values))
+	for _, v := range values {
+		if _, ok := seen[v]; ok {
+			continue
+		}
+		seen[v] = struct{}{}
+		res = append(res, v)
+	}
+	return res
+}
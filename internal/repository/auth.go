@@ -26,6 +26,9 @@ const (
 	userEmail = "email"
 	userRole  = "role"
 	userExp   = "exp"
+	// Активная организация добавляется в токен только вне личного пространства
+	userOrg     = "org"
+	userOrgRole = "org_role"
 )
 
 func (m *AuthRepo) Authorization(ctx context.Context, tx redis.Pipeliner, user *domain.AuthData, refreshTTL time.Duration, accessTTL time.Duration, secret string) (string, error) {
@@ -113,6 +116,35 @@ func (m *AuthRepo) RemoveAuthorization(ctx context.Context, tx redis.Pipeliner,
 	return nil
 }
 
+func (m *AuthRepo) RemoveUserAuthorization(ctx context.Context, tx redis.Pipeliner, userID int) error {
+	inc := tx.Del(fmt.Sprint(accessKey, userID), fmt.Sprint(refreshKey, userID))
+	if inc.Err() != nil {
+		return fmt.Errorf("RemoveUserAuthorization/Del: %w", inc.Err())
+	}
+	return nil
+}
+
+// CountAuthorizations Количество пользователей с активной сессией. Ключи перебираются через SCAN, чтобы не блокировать Redis,
+// перебор прерывается, если истек ctx
+func (m *AuthRepo) CountAuthorizations(ctx context.Context, redisClient *redis.Client) (int64, error) {
+	var count int64
+	var cursor uint64
+	for {
+		if ctx.Err() != nil {
+			return 0, fmt.Errorf("CountAuthorizations: %w", ctx.Err())
+		}
+		keys, next, err := redisClient.Scan(cursor, refreshKey+"*", 1000).Result()
+		if err != nil {
+			return 0, fmt.Errorf("CountAuthorizations/Scan: %w", err)
+		}
+		count += int64(len(keys))
+		cursor = next
+		if cursor == 0 {
+			return count, nil
+		}
+	}
+}
+
 func validAccess(redisClient *redis.Client, token string, userID int) error {
 	// Проверяем, есть ли access в redis
 	s := redisClient.Get(fmt.Sprint(accessKey, userID))
@@ -136,12 +168,17 @@ func validAccess(redisClient *redis.Client, token string, userID int) error {
 }
 
 func newToken(user domain.AuthData, duration time.Duration, secret string) (string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+	claims := jwt.MapClaims{
 		userID:    user.ID,
 		userEmail: user.Email,
 		userRole:  user.Role,
 		userExp:   time.Now().Add(duration).Unix(),
-	})
+	}
+	if user.OrgID != 0 {
+		claims[userOrg] = user.OrgID
+		claims[userOrgRole] = user.OrgRole
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	tokenString, err := token.SignedString([]byte(secret))
 	if err != nil {
 		return "", err
@@ -181,9 +218,15 @@ func userData(token string) (user *domain.AuthData, err error) {
 		return nil, TokenInvalidClaims
 	}
 
-	return &domain.AuthData{
+	user = &domain.AuthData{
 		ID:    int(claims[userID].(float64)),
 		Email: fmt.Sprint(claims[userEmail]),
 		Role:  domain.Role(int(claims[userRole].(float64))),
-	}, nil
+	}
+	if org, ok := claims[userOrg].(float64); ok {
+		user.OrgID = int(org)
+		orgRole, _ := claims[userOrgRole].(float64)
+		user.OrgRole = domain.OrgRole(int(orgRole))
+	}
+	return user, nil
 }
@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"auth/internal/config"
+	"auth/internal/domain"
+	"auth/internal/repository/ldap"
+	"context"
+	"errors"
+)
+
+type DirectoryRepos struct {
+	directories []*ldap.Directory
+}
+
+// NewDirectoryRepos Создает клиентов каталогов, ошибка конфигурации каталога останавливает запуск
+func NewDirectoryRepos(ldapCfg *config.LDAPConfig) (Directory, error) {
+	directories := make([]*ldap.Directory, 0, len(ldapCfg.Directories))
+	for _, cfg := range ldapCfg.Directories {
+		directory, err := ldap.New(cfg)
+		if err != nil {
+			return nil, err
+		}
+		directories = append(directories, directory)
+	}
+	return &DirectoryRepos{directories: directories}, nil
+}
+
+func (m *DirectoryRepos) HasDirectory(email string) bool {
+	return m.directory(email) != nil
+}
+
+func (m *DirectoryRepos) Authenticate(ctx context.Context, email string, password string) (*domain.DirectoryUser, error) {
+	directory := m.directory(email)
+	if directory == nil {
+		return nil, DirectoryNotExist
+	}
+	user, err := directory.Authenticate(email, password)
+	if err != nil {
+		if errors.Is(err, ldap.ErrInvalidCredentials) || errors.Is(err, ldap.ErrUserNotFound) {
+			return nil, DirectoryInvalidCredentials
+		}
+		return nil, err
+	}
+	return user, nil
+}
+
+func (m *DirectoryRepos) directory(email string) *ldap.Directory {
+	for _, directory := range m.directories {
+		if directory.Serves(email) {
+			return directory
+		}
+	}
+	return nil
+}
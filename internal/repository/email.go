@@ -2,29 +2,49 @@ package repository
 
 import (
 	"auth/internal/repository/memory"
+	redisRepo "auth/internal/repository/redis"
 	"context"
-	"github.com/Linkify-Company/common_utils/errify"
+	"github.com/go-redis/redis"
+	"time"
 )
 
+// emailStorage Хранилище кодов подтверждения почты
+type emailStorage interface {
+	Set(ctx context.Context, email string, codeHash string, ttl time.Duration) error
+	IsValid(ctx context.Context, email string, codeHash string, attempts int) (bool, error)
+	IsExist(ctx context.Context, email string) (bool, error)
+	AllowSend(ctx context.Context, email string, cooldown time.Duration, dailyLimit int) (time.Duration, error)
+}
+
 type EmailRepos struct {
-	emailMemory *memory.EmailMemory
+	storage emailStorage
+}
+
+func NewEmailRepos(redisClient *redis.Client) Email {
+	return &EmailRepos{
+		storage: redisRepo.NewEmailRedis(redisClient),
+	}
 }
 
-func NewEmailRepos() Email {
+// NewEmailMemoryRepos Хранит коды в памяти процесса, используется только в тестах
+func NewEmailMemoryRepos() Email {
 	return &EmailRepos{
-		emailMemory: memory.NewEmailMemory(),
+		storage: memory.NewEmailMemory(),
 	}
 }
 
-func (m *EmailRepos) IsExist(ctx context.Context, email string) bool {
-	return m.emailMemory.IsExist(email)
+func (m *EmailRepos) IsExist(ctx context.Context, email string) (bool, error) {
+	return m.storage.IsExist(ctx, email)
+}
+
+func (m *EmailRepos) Set(ctx context.Context, email string, codeHash string, ttl time.Duration) error {
+	return m.storage.Set(ctx, email, codeHash, ttl)
 }
 
-func (m *EmailRepos) Set(ctx context.Context, email string, code int) errify.IError {
-	m.emailMemory.Set(email, code)
-	return nil
+func (m *EmailRepos) IsValid(ctx context.Context, email string, codeHash string, attempts int) (bool, error) {
+	return m.storage.IsValid(ctx, email, codeHash, attempts)
 }
 
-func (m *EmailRepos) IsValid(ctx context.Context, email string, code int) bool {
-	return m.emailMemory.IsValid(email, code)
+func (m *EmailRepos) AllowSend(ctx context.Context, email string, cooldown time.Duration, dailyLimit int) (time.Duration, error) {
+	return m.storage.AllowSend(ctx, email, cooldown, dailyLimit)
 }
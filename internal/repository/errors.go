@@ -9,4 +9,36 @@ var (
 	TokenExpired       = errors.New("token expired")
 	TokenNotValid      = errors.New("token not valid")
 	TokenInvalidClaims = errors.New("token invalid claims")
+	CursorNotValid     = errors.New("cursor not valid")
+
+	RoleAlreadyExist       = errors.New("role already exists")
+	RoleNotExist           = errors.New("role not exists")
+	RoleBuiltin            = errors.New("role is builtin")
+	PermissionAlreadyExist = errors.New("permission already exists")
+	PermissionNotExist     = errors.New("permission not exists")
+
+	PolicyNotConfigured = errors.New("policy path not configured")
+
+	OrganizationAlreadyExist = errors.New("organization already exists")
+	OrganizationNotExist     = errors.New("organization not exists")
+	MemberAlreadyExist       = errors.New("member already exists")
+	MemberNotExist           = errors.New("member not exists")
+	InvitationNotExist       = errors.New("invitation not exists")
+
+	IdentityAlreadyExist     = errors.New("identity already exists")
+	IdentityNotExist         = errors.New("identity not exists")
+	ProviderNotExist         = errors.New("oauth provider not exists")
+	OAuthStateNotExist       = errors.New("oauth state not exists")
+	ExternalIdentityNotValid = errors.New("external identity not valid")
+
+	DirectoryNotExist           = errors.New("directory not exists")
+	DirectoryInvalidCredentials = errors.New("directory invalid credentials")
+
+	IdentityProviderNotExist = errors.New("saml identity provider not exists")
+	SAMLStateNotExist        = errors.New("saml state not exists")
+	SAMLAssertionNotValid    = errors.New("saml assertion not valid")
+	SAMLAssertionReplayed    = errors.New("saml assertion already used")
+
+	GroupAlreadyExist = errors.New("group already exists")
+	GroupNotExist     = errors.New("group not exists")
 )
@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"auth/internal/domain"
+	"auth/internal/repository/postgres"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+type IdentityRepos struct{}
+
+func NewIdentityRepos() Identity {
+	return &IdentityRepos{}
+}
+
+const identityColumns = `id, user_id, provider, subject, email, created_at, last_login_at`
+
+func (m *IdentityRepos) AddIdentity(ctx context.Context, tx pgx.Tx, identity *domain.UserIdentity) (int, error) {
+	row := tx.QueryRow(ctx, `INSERT INTO user_identity (user_id, provider, subject, email) VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at`, identity.UserID, identity.Provider, identity.Subject, identity.Email)
+	err := row.Scan(&identity.ID, &identity.CreatedAt)
+	if err != nil {
+		if err, ok := err.(*pgconn.PgError); ok && err.Code == postgres.ErrUniqueViolation {
+			return 0, IdentityAlreadyExist
+		}
+		return 0, fmt.Errorf("AddIdentity/Scan: %w", err)
+	}
+	return identity.ID, nil
+}
+
+func (m *IdentityRepos) IdentityBySubject(ctx context.Context, tx pgx.Tx, provider string, subject string) (*domain.UserIdentity, error) {
+	row := tx.QueryRow(ctx, `SELECT `+identityColumns+` FROM user_identity WHERE provider = $1 AND subject = $2`,
+		provider, subject)
+
+	identity, err := scanIdentity(row)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, IdentityNotExist
+		}
+		return nil, fmt.Errorf("IdentityBySubject/Scan: %w", err)
+	}
+	return identity, nil
+}
+
+func (m *IdentityRepos) UserIdentities(ctx context.Context, tx pgx.Tx, userID int) ([]*domain.UserIdentity, error) {
+	rows, err := tx.Query(ctx, `SELECT `+identityColumns+` FROM user_identity WHERE user_id = $1 ORDER BY provider`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("UserIdentities/Query: %w", err)
+	}
+	defer rows.Close()
+
+	identities := make([]*domain.UserIdentity, 0)
+	for rows.Next() {
+		identity, err := scanIdentity(rows)
+		if err != nil {
+			return nil, fmt.Errorf("UserIdentities/Scan: %w", err)
+		}
+		identities = append(identities, identity)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("UserIdentities/Rows: %w", err)
+	}
+	return identities, nil
+}
+
+func (m *IdentityRepos) TouchIdentity(ctx context.Context, tx pgx.Tx, id int, email string) error {
+	_, err := tx.Exec(ctx, `UPDATE user_identity SET last_login_at = now(), email = $2 WHERE id = $1`, id, email)
+	if err != nil {
+		return fmt.Errorf("TouchIdentity/Exec: %w", err)
+	}
+	return nil
+}
+
+func (m *IdentityRepos) RemoveIdentity(ctx context.Context, tx pgx.Tx, userID int, provider string) error {
+	tag, err := tx.Exec(ctx, `DELETE FROM user_identity WHERE user_id = $1 AND provider = $2`, userID, provider)
+	if err != nil {
+		return fmt.Errorf("RemoveIdentity/Exec: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return IdentityNotExist
+	}
+	return nil
+}
+
+func scanIdentity(row pgx.Row) (*domain.UserIdentity, error) {
+	var identity domain.UserIdentity
+	err := row.Scan(
+		&identity.ID,
+		&identity.UserID,
+		&identity.Provider,
+		&identity.Subject,
+		&identity.Email,
+		&identity.CreatedAt,
+		&identity.LastLoginAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &identity, nil
+}
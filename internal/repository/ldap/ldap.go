@@ -0,0 +1,178 @@
+package ldap
+
+import (
+	"auth/internal/config"
+	"auth/internal/domain"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"github.com/go-ldap/ldap/v3"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidCredentials = errors.New("ldap invalid credentials")
+	ErrUserNotFound       = errors.New("ldap user not found")
+)
+
+const (
+	defaultUserFilter     = "(mail=%s)"
+	defaultGroupAttribute = "memberOf"
+	defaultTimeout        = 5 * time.Second
+)
+
+// Directory Клиент каталога LDAP
+type Directory struct {
+	cfg       config.LDAPDirectoryConfig
+	tlsConfig *tls.Config
+}
+
+func New(cfg config.LDAPDirectoryConfig) (*Directory, error) {
+	if cfg.UserFilter == "" {
+		cfg.UserFilter = defaultUserFilter
+	}
+	if cfg.GroupAttribute == "" {
+		cfg.GroupAttribute = defaultGroupAttribute
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = defaultTimeout
+	}
+	if strings.Count(cfg.UserFilter, "%s") != 1 {
+		return nil, fmt.Errorf("ldap %s: user_filter must contain exactly one %%s", cfg.Name)
+	}
+
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: cfg.InsecureSkipVerify,
+		MinVersion:         tls.VersionTLS12,
+	}
+	if cfg.CACertPath != "" {
+		pem, err := os.ReadFile(cfg.CACertPath)
+		if err != nil {
+			return nil, fmt.Errorf("ldap %s/ReadFile: %w", cfg.Name, err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("ldap %s: ca certificate not valid", cfg.Name)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	return &Directory{cfg: cfg, tlsConfig: tlsConfig}, nil
+}
+
+// Name Имя каталога из конфигурации
+func (d *Directory) Name() string {
+	return d.cfg.Name
+}
+
+// Serves Обслуживает ли каталог почту с этим доменом
+func (d *Directory) Serves(email string) bool {
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return false
+	}
+	emailDomain := email[at+1:]
+	for _, served := range d.cfg.Domains {
+		if strings.EqualFold(served, emailDomain) {
+			return true
+		}
+	}
+	return false
+}
+
+// Authenticate Находит пользователя служебной учетной записью и проверяет его пароль привязкой от его имени
+func (d *Directory) Authenticate(email string, password string) (*domain.DirectoryUser, error) {
+	// Пустой пароль дает анонимную привязку, которую сервер считает успешной
+	if password == "" {
+		return nil, ErrInvalidCredentials
+	}
+	conn, err := d.dial()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if d.cfg.BindDN != "" {
+		err = conn.Bind(d.cfg.BindDN, os.Getenv(d.cfg.BindPasswordEnv))
+		if err != nil {
+			return nil, fmt.Errorf("Authenticate/Bind: %w", err)
+		}
+	}
+
+	result, err := conn.Search(ldap.NewSearchRequest(
+		d.cfg.BaseDN,
+		ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 2, int(d.cfg.Timeout.Seconds()), false,
+		fmt.Sprintf(d.cfg.UserFilter, ldap.EscapeFilter(email)),
+		[]string{"dn", d.cfg.GroupAttribute},
+		nil,
+	))
+	if err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded) {
+			return nil, fmt.Errorf("Authenticate/Search: more than one entry for %s", email)
+		}
+		return nil, fmt.Errorf("Authenticate/Search: %w", err)
+	}
+	if len(result.Entries) == 0 {
+		return nil, ErrUserNotFound
+	}
+	if len(result.Entries) > 1 {
+		return nil, fmt.Errorf("Authenticate/Search: more than one entry for %s", email)
+	}
+	entry := result.Entries[0]
+
+	err = conn.Bind(entry.DN, password)
+	if err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, fmt.Errorf("Authenticate/Bind: %w", err)
+	}
+
+	groups := entry.GetAttributeValues(d.cfg.GroupAttribute)
+	return &domain.DirectoryUser{
+		Directory: d.cfg.Name,
+		DN:        entry.DN,
+		Email:     email,
+		Groups:    groups,
+		Role:      d.role(groups),
+	}, nil
+}
+
+func (d *Directory) dial() (*ldap.Conn, error) {
+	conn, err := ldap.DialURL(d.cfg.URL,
+		ldap.DialWithDialer(&net.Dialer{Timeout: d.cfg.Timeout}),
+		ldap.DialWithTLSConfig(d.tlsConfig),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("dial/DialURL: %w", err)
+	}
+	conn.SetTimeout(d.cfg.Timeout)
+
+	if d.cfg.StartTLS {
+		err = conn.StartTLS(d.tlsConfig)
+		if err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("dial/StartTLS: %w", err)
+		}
+	}
+	return conn, nil
+}
+
+// role Старшая роль среди групп пользователя. DN групп сравниваются без учета регистра
+func (d *Directory) role(groups []string) domain.Role {
+	role := domain.Role(domain.RoleUser)
+	if d.cfg.DefaultRole != nil {
+		role = domain.Role(*d.cfg.DefaultRole)
+	}
+	for _, group := range groups {
+		for dn, r := range d.cfg.GroupRoles {
+			if strings.EqualFold(dn, group) && domain.Role(r) < role {
+				role = domain.Role(r)
+			}
+		}
+	}
+	return role
+}
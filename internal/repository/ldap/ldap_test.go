@@ -0,0 +1,252 @@
+package ldap
+
+import (
+	"auth/internal/config"
+	"auth/internal/domain"
+	"errors"
+	"fmt"
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	testBindDN       = "cn=service,dc=example,dc=com"
+	testBindPassword = "service-secret"
+	testAdminsDN     = "cn=admins,ou=groups,dc=example,dc=com"
+	testModeratorsDN = "cn=moderators,ou=groups,dc=example,dc=com"
+)
+
+// testEntry Запись каталога с паролем для привязки от ее имени
+type testEntry struct {
+	dn       string
+	mail     string
+	password string
+	groups   []string
+}
+
+// fakeServer Минимальный сервер LDAP: простая привязка, поиск по равенству атрибута mail и отключение
+type fakeServer struct {
+	listener net.Listener
+	entries  []testEntry
+}
+
+func newFakeServer(t *testing.T, entries ...testEntry) *fakeServer {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeServer{listener: listener, entries: entries}
+	t.Cleanup(func() { _ = listener.Close() })
+	go s.serve()
+	return s
+}
+
+func (s *fakeServer) url() string {
+	return "ldap://" + s.listener.Addr().String()
+}
+
+func (s *fakeServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *fakeServer) handle(conn net.Conn) {
+	defer conn.Close()
+	for {
+		packet, err := ber.ReadPacket(conn)
+		if err != nil || len(packet.Children) < 2 {
+			return
+		}
+		id := packet.Children[0].Value.(int64)
+		op := packet.Children[1]
+		switch op.Tag {
+		case ldap.ApplicationBindRequest:
+			dn := op.Children[1].Value.(string)
+			password := op.Children[2].Data.String()
+			_, _ = conn.Write(response(id, ldap.ApplicationBindResponse, s.bind(dn, password)).Bytes())
+		case ldap.ApplicationSearchRequest:
+			s.search(conn, id, op)
+		case ldap.ApplicationUnbindRequest:
+			return
+		}
+	}
+}
+
+func (s *fakeServer) bind(dn string, password string) uint16 {
+	if dn == testBindDN && password == testBindPassword {
+		return ldap.LDAPResultSuccess
+	}
+	for _, entry := range s.entries {
+		if strings.EqualFold(entry.dn, dn) && entry.password == password {
+			return ldap.LDAPResultSuccess
+		}
+	}
+	return ldap.LDAPResultInvalidCredentials
+}
+
+// search Отдает не больше sizeLimit записей, при превышении отвечает sizeLimitExceeded, как настоящий сервер
+func (s *fakeServer) search(conn net.Conn, id int64, op *ber.Packet) {
+	sizeLimit := int(op.Children[3].Value.(int64))
+	filter, err := ldap.DecompileFilter(op.Children[6])
+	if err != nil {
+		_, _ = conn.Write(response(id, ldap.ApplicationSearchResultDone, ldap.LDAPResultProtocolError).Bytes())
+		return
+	}
+	var found []testEntry
+	for _, entry := range s.entries {
+		if filter == fmt.Sprintf("(mail=%s)", ldap.EscapeFilter(entry.mail)) {
+			found = append(found, entry)
+		}
+	}
+	code := uint16(ldap.LDAPResultSuccess)
+	if sizeLimit > 0 && len(found) > sizeLimit {
+		found, code = found[:sizeLimit], ldap.LDAPResultSizeLimitExceeded
+	}
+	for _, entry := range found {
+		_, _ = conn.Write(searchEntry(id, entry).Bytes())
+	}
+	_, _ = conn.Write(response(id, ldap.ApplicationSearchResultDone, code).Bytes())
+}
+
+func message(id int64, op *ber.Packet) *ber.Packet {
+	packet := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "LDAP Response")
+	packet.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, id, "Message ID"))
+	packet.AppendChild(op)
+	return packet
+}
+
+func response(id int64, tag ber.Tag, code uint16) *ber.Packet {
+	op := ber.Encode(ber.ClassApplication, ber.TypeConstructed, tag, nil, "Response")
+	op.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, int64(code), "Result Code"))
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "Matched DN"))
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "Diagnostic Message"))
+	return message(id, op)
+}
+
+func searchEntry(id int64, entry testEntry) *ber.Packet {
+	op := ber.Encode(ber.ClassApplication, ber.TypeConstructed, ldap.ApplicationSearchResultEntry, nil, "Search Result Entry")
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, entry.dn, "Object Name"))
+	attributes := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Attributes")
+	attribute := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Attribute")
+	attribute.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, defaultGroupAttribute, "Type"))
+	values := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "Values")
+	for _, group := range entry.groups {
+		values.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, group, "Value"))
+	}
+	attribute.AppendChild(values)
+	attributes.AppendChild(attribute)
+	op.AppendChild(attributes)
+	return message(id, op)
+}
+
+func newTestDirectory(t *testing.T, server *fakeServer, bindPassword string) *Directory {
+	t.Helper()
+	t.Setenv("TEST_LDAP_BIND_PASSWORD", bindPassword)
+	directory, err := New(config.LDAPDirectoryConfig{
+		Name:            "corp",
+		Domains:         []string{"example.com"},
+		URL:             server.url(),
+		BindDN:          testBindDN,
+		BindPasswordEnv: "TEST_LDAP_BIND_PASSWORD",
+		BaseDN:          "dc=example,dc=com",
+		GroupRoles: map[string]int{
+			testAdminsDN:     domain.RoleAdmin,
+			testModeratorsDN: domain.RoleModerator,
+		},
+		Timeout: time.Second,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return directory
+}
+
+func TestAuthenticate(t *testing.T) {
+	server := newFakeServer(t,
+		testEntry{dn: "uid=alice,dc=example,dc=com", mail: "alice@example.com", password: "alice-pw",
+			groups: []string{"CN=Moderators,OU=Groups,DC=example,DC=com", testAdminsDN}},
+		testEntry{dn: "uid=bob,dc=example,dc=com", mail: "bob@example.com", password: "bob-pw",
+			groups: []string{testModeratorsDN, "cn=other,ou=groups,dc=example,dc=com"}},
+		testEntry{dn: "uid=carol,dc=example,dc=com", mail: "carol@example.com", password: "carol-pw"},
+		testEntry{dn: "uid=dup1,dc=example,dc=com", mail: "dup@example.com", password: "dup-pw"},
+		testEntry{dn: "uid=dup2,ou=other,dc=example,dc=com", mail: "dup@example.com", password: "dup-pw"},
+	)
+
+	tests := []struct {
+		name     string
+		email    string
+		password string
+		wantRole domain.Role
+		wantErr  error
+		failed   bool
+	}{
+		{name: "senior group role", email: "alice@example.com", password: "alice-pw", wantRole: domain.RoleAdmin},
+		{name: "mapped group role", email: "bob@example.com", password: "bob-pw", wantRole: domain.RoleModerator},
+		{name: "default role", email: "carol@example.com", password: "carol-pw", wantRole: domain.RoleUser},
+		{name: "wrong password", email: "alice@example.com", password: "wrong", wantErr: ErrInvalidCredentials},
+		{name: "empty password", email: "alice@example.com", password: "", wantErr: ErrInvalidCredentials},
+		{name: "user not found", email: "nobody@example.com", password: "pw", wantErr: ErrUserNotFound},
+		{name: "multiple entries", email: "dup@example.com", password: "dup-pw", failed: true},
+	}
+	directory := newTestDirectory(t, server, testBindPassword)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user, err := directory.Authenticate(tt.email, tt.password)
+			switch {
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Authenticate() error = %v, want %v", err, tt.wantErr)
+				}
+			case tt.failed:
+				if err == nil || errors.Is(err, ErrInvalidCredentials) || errors.Is(err, ErrUserNotFound) {
+					t.Fatalf("Authenticate() error = %v, want directory error", err)
+				}
+			default:
+				if err != nil {
+					t.Fatalf("Authenticate() error = %v", err)
+				}
+				if user.Role != tt.wantRole || user.Email != tt.email || user.Directory != "corp" {
+					t.Fatalf("Authenticate() user = %+v, want role %d", user, tt.wantRole)
+				}
+			}
+		})
+	}
+}
+
+// TestAuthenticateServiceBind Отказ в привязке служебной учетной записи не выдается за неверный пароль пользователя
+func TestAuthenticateServiceBind(t *testing.T) {
+	server := newFakeServer(t, testEntry{dn: "uid=alice,dc=example,dc=com", mail: "alice@example.com", password: "alice-pw"})
+	directory := newTestDirectory(t, server, "wrong-service-secret")
+
+	_, err := directory.Authenticate("alice@example.com", "alice-pw")
+	if err == nil || errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("Authenticate() error = %v, want service bind error", err)
+	}
+}
+
+func TestServes(t *testing.T) {
+	directory, err := New(config.LDAPDirectoryConfig{Name: "corp", Domains: []string{"Example.com"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for email, want := range map[string]bool{
+		"alice@example.com":     true,
+		"alice@EXAMPLE.COM":     true,
+		"alice@sub.example.com": false,
+		"alice":                 false,
+	} {
+		if got := directory.Serves(email); got != want {
+			t.Errorf("Serves(%q) = %v, want %v", email, got, want)
+		}
+	}
+}
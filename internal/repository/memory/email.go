@@ -1,6 +1,8 @@
 package memory
 
 import (
+	"context"
+	"crypto/subtle"
 	"sync"
 	"time"
 )
@@ -8,71 +10,106 @@ import (
 type EmailMemory struct {
 	// Email / *authData
 	emailItems map[string]*authData
-	mx         sync.RWMutex
+	// Email / *sendData
+	sendItems map[string]*sendData
+	mx        sync.RWMutex
 }
 
 const (
-	// Количество времени, за которое должны подтвердить почту
-	timeout time.Duration = time.Minute * 10
-	// Максимальное допустимое количество попыток дозволеных на подтверждение email
-	defaultNumberAttempts int = 3
+	// Окно, в пределах которого действует суточный лимит отправок
+	dailyWindow = 24 * time.Hour
 )
 
 type authData struct {
-	// Время отправки кода на почту
-	timeCreate time.Time
+	// Время, после которого код считается просроченным
+	expiresAt time.Time
 	// Количество неправильных попыток, затраченных на введение кода
-	numberAttempts    int
-	authorizationCode int
+	numberAttempts int
+	codeHash       string
+}
+
+type sendData struct {
+	// Время последней отправки кода на почту
+	lastSend time.Time
+	// Начало текущего суточного окна
+	windowStart time.Time
+	// Количество отправок в текущем суточном окне
+	count int
 }
 
 func NewEmailMemory() *EmailMemory {
 	return &EmailMemory{
 		emailItems: make(map[string]*authData),
+		sendItems:  make(map[string]*sendData),
 		mx:         sync.RWMutex{},
 	}
 }
 
-func (m *EmailMemory) Set(email string, authorizationCode int) {
+func (m *EmailMemory) Set(ctx context.Context, email string, codeHash string, ttl time.Duration) error {
 	m.mx.Lock()
 	defer m.mx.Unlock()
 
 	m.emailItems[email] = &authData{
-		timeCreate:        time.Now(),
-		authorizationCode: authorizationCode,
+		expiresAt: time.Now().Add(ttl),
+		codeHash:  codeHash,
 	}
+	return nil
 }
 
-func (m *EmailMemory) IsValid(email string, authorizationCode int) bool {
+func (m *EmailMemory) IsValid(ctx context.Context, email string, codeHash string, attempts int) (bool, error) {
 	m.mx.Lock()
 	defer m.mx.Unlock()
 
-	if m.emailItems[email] == nil {
-		return false
+	item := m.emailItems[email]
+	if item == nil {
+		return false, nil
 	}
 
-	if m.emailItems[email].timeCreate.Sub(time.Now()) >= timeout {
+	if !time.Now().Before(item.expiresAt) {
 		m.remove(email)
-		return false
+		return false, nil
 	}
-	if m.emailItems[email].authorizationCode != authorizationCode {
-		m.emailItems[email].numberAttempts++
+	if subtle.ConstantTimeCompare([]byte(item.codeHash), []byte(codeHash)) != 1 {
+		item.numberAttempts++
 
-		if m.emailItems[email].numberAttempts >= defaultNumberAttempts {
+		if item.numberAttempts >= attempts {
 			m.remove(email)
 		}
-		return false
+		return false, nil
 	}
 	m.remove(email)
 
-	return true
+	return true, nil
 }
 
-func (m *EmailMemory) IsExist(email string) bool {
+func (m *EmailMemory) IsExist(ctx context.Context, email string) (bool, error) {
 	m.mx.RLock()
 	defer m.mx.RUnlock()
 
-	return m.emailItems[email] != nil
+	item := m.emailItems[email]
+	return item != nil && time.Now().Before(item.expiresAt), nil
+}
+
+func (m *EmailMemory) AllowSend(ctx context.Context, email string, cooldown time.Duration, dailyLimit int) (time.Duration, error) {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	now := time.Now()
+	item := m.sendItems[email]
+	if item == nil || now.Sub(item.windowStart) >= dailyWindow {
+		item = &sendData{windowStart: now}
+		m.sendItems[email] = item
+	}
+	if wait := item.lastSend.Add(cooldown).Sub(now); wait > 0 {
+		return wait, nil
+	}
+	if item.count >= dailyLimit {
+		return item.windowStart.Add(dailyWindow).Sub(now), nil
+	}
+	item.lastSend = now
+	item.count++
+
+	return 0, nil
 }
 
 func (m *EmailMemory) remove(email string) {
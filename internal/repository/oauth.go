@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"auth/internal/config"
+	"auth/internal/domain"
+	"auth/internal/repository/oauth"
+	redisRepo "auth/internal/repository/redis"
+	"context"
+	"errors"
+	"github.com/go-redis/redis"
+	"time"
+)
+
+type OAuthRepos struct {
+	providers *oauth.Providers
+	state     *redisRepo.StateRedis
+}
+
+func NewOAuthRepos(redisClient *redis.Client, oauthCfg *config.OAuthConfig) OAuth {
+	return &OAuthRepos{
+		providers: oauth.New(oauthCfg.Providers, nil),
+		state:     redisRepo.NewStateRedis(redisClient, redisRepo.OAuthStateKey),
+	}
+}
+
+func (m *OAuthRepos) AuthCodeURL(ctx context.Context, provider string, state string, nonce string, verifier string) (string, error) {
+	url, err := m.providers.AuthCodeURL(ctx, provider, state, nonce, verifier)
+	if errors.Is(err, oauth.ErrProviderNotExist) {
+		return "", ProviderNotExist
+	}
+	return url, err
+}
+
+func (m *OAuthRepos) Exchange(ctx context.Context, provider string, code string, verifier string, nonce string) (*domain.ExternalIdentity, error) {
+	identity, err := m.providers.Exchange(ctx, provider, code, verifier, nonce)
+	if err != nil {
+		if errors.Is(err, oauth.ErrProviderNotExist) {
+			return nil, ProviderNotExist
+		}
+		return nil, errors.Join(ExternalIdentityNotValid, err)
+	}
+	return identity, nil
+}
+
+func (m *OAuthRepos) SetOAuthState(ctx context.Context, state string, data *domain.OAuthState, ttl time.Duration) error {
+	return m.state.Set(ctx, state, data, ttl)
+}
+
+func (m *OAuthRepos) PopOAuthState(ctx context.Context, state string) (*domain.OAuthState, error) {
+	var data domain.OAuthState
+	err := m.state.Pop(ctx, state, &data)
+	if err != nil {
+		if errors.Is(err, redisRepo.ErrStateNotExist) {
+			return nil, OAuthStateNotExist
+		}
+		return nil, err
+	}
+	return &data, nil
+}
@@ -0,0 +1,233 @@
+package oauth
+
+import (
+	"auth/internal/config"
+	"auth/internal/domain"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+)
+
+var (
+	ErrProviderNotExist = errors.New("oauth provider not exists")
+	ErrNonceNotValid    = errors.New("oauth nonce not valid")
+	ErrIdentityNotValid = errors.New("oauth identity not valid")
+)
+
+const (
+	defaultSubjectField       = "sub"
+	defaultEmailField         = "email"
+	defaultEmailVerifiedField = "email_verified"
+
+	// maxUserInfoSize Ограничение размера ответа userinfo
+	maxUserInfoSize = 1 << 20
+)
+
+// Providers Клиенты внешних провайдеров входа. OIDC провайдеры инициализируются при первом обращении,
+// чтобы недоступность провайдера не мешала запуску сервиса
+type Providers struct {
+	cfg    map[string]config.OAuthProviderConfig
+	client *http.Client
+
+	mu        sync.Mutex
+	providers map[string]*provider
+}
+
+type provider struct {
+	name     string
+	cfg      config.OAuthProviderConfig
+	oauth    *oauth2.Config
+	verifier *oidc.IDTokenVerifier
+}
+
+// New Создает клиентов провайдеров. client используется для всех запросов к провайдерам, nil - клиент по умолчанию
+func New(cfg map[string]config.OAuthProviderConfig, client *http.Client) *Providers {
+	return &Providers{
+		cfg:       cfg,
+		client:    client,
+		providers: make(map[string]*provider, len(cfg)),
+	}
+}
+
+// AuthCodeURL Возвращает адрес страницы входа провайдера с state, nonce и PKCE
+func (m *Providers) AuthCodeURL(ctx context.Context, name string, state string, nonce string, verifier string) (string, error) {
+	p, err := m.provider(m.clientContext(ctx), name)
+	if err != nil {
+		return "", err
+	}
+	opts := []oauth2.AuthCodeOption{oauth2.S256ChallengeOption(verifier)}
+	if p.verifier != nil {
+		opts = append(opts, oidc.Nonce(nonce))
+	}
+	return p.oauth.AuthCodeURL(state, opts...), nil
+}
+
+// Exchange Обменивает код авторизации на токен и возвращает учетную запись пользователя у провайдера
+func (m *Providers) Exchange(ctx context.Context, name string, code string, verifier string, nonce string) (*domain.ExternalIdentity, error) {
+	ctx = m.clientContext(ctx)
+	p, err := m.provider(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	token, err := p.oauth.Exchange(ctx, code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return nil, fmt.Errorf("Exchange/Exchange: %w", err)
+	}
+
+	var claims map[string]any
+	if p.verifier != nil {
+		rawIDToken, ok := token.Extra("id_token").(string)
+		if !ok {
+			return nil, fmt.Errorf("Exchange/id_token: %w", ErrIdentityNotValid)
+		}
+		idToken, err := p.verifier.Verify(ctx, rawIDToken)
+		if err != nil {
+			return nil, fmt.Errorf("Exchange/Verify: %w", err)
+		}
+		if idToken.Nonce != nonce {
+			return nil, ErrNonceNotValid
+		}
+		err = idToken.Claims(&claims)
+		if err != nil {
+			return nil, fmt.Errorf("Exchange/Claims: %w", err)
+		}
+	} else {
+		claims, err = p.userInfo(ctx, token)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return p.identity(claims)
+}
+
+func (m *Providers) clientContext(ctx context.Context) context.Context {
+	if m.client == nil {
+		return ctx
+	}
+	return oidc.ClientContext(ctx, m.client)
+}
+
+func (m *Providers) provider(ctx context.Context, name string) (*provider, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if p, ok := m.providers[name]; ok {
+		return p, nil
+	}
+	cfg, ok := m.cfg[name]
+	if !ok {
+		return nil, ErrProviderNotExist
+	}
+
+	p := &provider{
+		name: name,
+		cfg:  cfg,
+		oauth: &oauth2.Config{
+			ClientID:     cfg.ClientID,
+			ClientSecret: os.Getenv(cfg.ClientSecretEnv),
+			RedirectURL:  cfg.RedirectURL,
+			Scopes:       cfg.Scopes,
+			Endpoint: oauth2.Endpoint{
+				AuthURL:  cfg.AuthURL,
+				TokenURL: cfg.TokenURL,
+			},
+		},
+	}
+	if cfg.Issuer == "" && (cfg.AuthURL == "" || cfg.TokenURL == "" || cfg.UserInfoURL == "") {
+		return nil, fmt.Errorf("provider %s: auth_url, token_url and userinfo_url are required without issuer", name)
+	}
+	if cfg.Issuer != "" {
+		oidcProvider, err := oidc.NewProvider(ctx, cfg.Issuer)
+		if err != nil {
+			return nil, fmt.Errorf("provider/NewProvider: %w", err)
+		}
+		p.oauth.Endpoint = oidcProvider.Endpoint()
+		p.verifier = oidcProvider.Verifier(&oidc.Config{ClientID: cfg.ClientID})
+	}
+	m.providers[name] = p
+	return p, nil
+}
+
+// userInfo Запрашивает данные пользователя у провайдера без OIDC
+func (p *provider) userInfo(ctx context.Context, token *oauth2.Token) (map[string]any, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.cfg.UserInfoURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("userInfo/NewRequest: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.oauth.Client(ctx, token).Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("userInfo/Do: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxUserInfoSize))
+	if err != nil {
+		return nil, fmt.Errorf("userInfo/ReadAll: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("userInfo: unexpected status %d", resp.StatusCode)
+	}
+
+	var claims map[string]any
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	err = decoder.Decode(&claims)
+	if err != nil {
+		return nil, fmt.Errorf("userInfo/Decode: %w", err)
+	}
+	return claims, nil
+}
+
+func (p *provider) identity(claims map[string]any) (*domain.ExternalIdentity, error) {
+	subject := claimString(claims, field(p.cfg.SubjectField, defaultSubjectField))
+	email := strings.ToLower(claimString(claims, field(p.cfg.EmailField, defaultEmailField)))
+	if subject == "" || email == "" {
+		return nil, ErrIdentityNotValid
+	}
+	verified := p.cfg.TrustEmail
+	if !verified {
+		// Некоторые провайдеры отдают признак подтверждения строкой
+		switch v := claims[field(p.cfg.EmailVerifiedField, defaultEmailVerifiedField)].(type) {
+		case bool:
+			verified = v
+		case string:
+			verified = v == "true"
+		}
+	}
+	return &domain.ExternalIdentity{
+		Provider:      p.name,
+		Subject:       subject,
+		Email:         email,
+		EmailVerified: verified,
+	}, nil
+}
+
+func claimString(claims map[string]any, name string) string {
+	switch v := claims[name].(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case float64:
+		return fmt.Sprintf("%.0f", v)
+	}
+	return ""
+}
+
+func field(value string, def string) string {
+	if value == "" {
+		return def
+	}
+	return value
+}
@@ -0,0 +1,265 @@
+package oauth
+
+import (
+	"auth/internal/config"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"github.com/golang-jwt/jwt/v5"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	testClientID = "srv-auth"
+	testKeyID    = "test-key"
+)
+
+// fakeProvider OIDC провайдер, который выдает id_token, подписанный ключом key, и проверяет PKCE при обмене кода
+type fakeProvider struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	mu    sync.Mutex
+	codes map[string]fakeCode
+
+	// signKey Ключ подписи id_token, по умолчанию key
+	signKey  *rsa.PrivateKey
+	audience string
+	expires  time.Duration
+}
+
+type fakeCode struct {
+	challenge string
+	nonce     string
+}
+
+func newFakeProvider(t *testing.T) *fakeProvider {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &fakeProvider{
+		key:      key,
+		signKey:  key,
+		codes:    make(map[string]fakeCode),
+		audience: testClientID,
+		expires:  time.Hour,
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", p.discovery)
+	mux.HandleFunc("/keys", p.keys)
+	mux.HandleFunc("/token", p.token)
+	p.server = httptest.NewServer(mux)
+	t.Cleanup(p.server.Close)
+	return p
+}
+
+func (p *fakeProvider) discovery(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"issuer":                                p.server.URL,
+		"authorization_endpoint":                p.server.URL + "/authorize",
+		"token_endpoint":                        p.server.URL + "/token",
+		"jwks_uri":                              p.server.URL + "/keys",
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+	})
+}
+
+func (p *fakeProvider) keys(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": testKeyID,
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(p.key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(p.key.E)).Bytes()),
+		}},
+	})
+}
+
+// token Код одноразовый, code_verifier должен соответствовать code_challenge из адреса входа
+func (p *fakeProvider) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+	p.mu.Lock()
+	code, ok := p.codes[r.PostForm.Get("code")]
+	delete(p.codes, r.PostForm.Get("code"))
+	p.mu.Unlock()
+
+	if !ok || s256(r.PostForm.Get("code_verifier")) != code.challenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+	now := time.Now()
+	idToken := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":            p.server.URL,
+		"aud":            p.audience,
+		"sub":            "external-1",
+		"email":          "User@Example.com",
+		"email_verified": true,
+		"nonce":          code.nonce,
+		"iat":            now.Unix(),
+		"exp":            now.Add(p.expires).Unix(),
+	})
+	idToken.Header["kid"] = testKeyID
+	signed, err := idToken.SignedString(p.signKey)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"access_token": "access",
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     signed,
+	})
+}
+
+// authorize Имитирует вход пользователя у провайдера: запоминает PKCE и nonce из адреса входа и выдает код
+func (p *fakeProvider) authorize(t *testing.T, authURL string) string {
+	t.Helper()
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := u.Query()
+	if query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "" {
+		t.Fatalf("auth url without PKCE: %s", authURL)
+	}
+	code := "code-" + query.Get("state")
+	p.mu.Lock()
+	p.codes[code] = fakeCode{challenge: query.Get("code_challenge"), nonce: query.Get("nonce")}
+	p.mu.Unlock()
+	return code
+}
+
+func (p *fakeProvider) providers() *Providers {
+	return New(map[string]config.OAuthProviderConfig{
+		"test": {
+			Issuer:      p.server.URL,
+			ClientID:    testClientID,
+			RedirectURL: "https://auth.example.com/v1/oauth/test/callback",
+			Scopes:      []string{"openid", "email"},
+		},
+	}, p.server.Client())
+}
+
+func s256(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func TestExchange(t *testing.T) {
+	ctx := context.Background()
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		setup    func(p *fakeProvider)
+		verifier string
+		nonce    string
+		wantErr  error
+		failed   bool
+	}{
+		{name: "valid"},
+		{name: "nonce mismatch", nonce: "other-nonce", wantErr: ErrNonceNotValid},
+		{name: "pkce verifier mismatch", verifier: "other-verifier", failed: true},
+		{name: "bad signature", setup: func(p *fakeProvider) { p.signKey = otherKey }, failed: true},
+		{name: "wrong audience", setup: func(p *fakeProvider) { p.audience = "other-client" }, failed: true},
+		{name: "expired id_token", setup: func(p *fakeProvider) { p.expires = -time.Hour }, failed: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newFakeProvider(t)
+			if tt.setup != nil {
+				tt.setup(p)
+			}
+			providers := p.providers()
+
+			const state, nonce, verifier = "state-1", "nonce-1", "verifier-verifier-verifier-verifier-1"
+			authURL, err := providers.AuthCodeURL(ctx, "test", state, nonce, verifier)
+			if err != nil {
+				t.Fatal(err)
+			}
+			code := p.authorize(t, authURL)
+
+			gotVerifier, gotNonce := verifier, nonce
+			if tt.verifier != "" {
+				gotVerifier = tt.verifier
+			}
+			if tt.nonce != "" {
+				gotNonce = tt.nonce
+			}
+			identity, err := providers.Exchange(ctx, "test", code, gotVerifier, gotNonce)
+			switch {
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Exchange() error = %v, want %v", err, tt.wantErr)
+				}
+			case tt.failed:
+				if err == nil {
+					t.Fatal("Exchange() error = nil, want error")
+				}
+			default:
+				if err != nil {
+					t.Fatalf("Exchange() error = %v", err)
+				}
+				if identity.Provider != "test" || identity.Subject != "external-1" ||
+					identity.Email != "user@example.com" || !identity.EmailVerified {
+					t.Fatalf("Exchange() identity = %+v", identity)
+				}
+			}
+		})
+	}
+}
+
+func TestExchangeCodeReplay(t *testing.T) {
+	ctx := context.Background()
+	p := newFakeProvider(t)
+	providers := p.providers()
+
+	const nonce, verifier = "nonce-1", "verifier-verifier-verifier-verifier-1"
+	authURL, err := providers.AuthCodeURL(ctx, "test", "state-1", nonce, verifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code := p.authorize(t, authURL)
+
+	if _, err = providers.Exchange(ctx, "test", code, verifier, nonce); err != nil {
+		t.Fatalf("first Exchange() error = %v", err)
+	}
+	if _, err = providers.Exchange(ctx, "test", code, verifier, nonce); err == nil {
+		t.Fatal("second Exchange() with the same code error = nil, want error")
+	}
+}
+
+func TestProviderNotExist(t *testing.T) {
+	providers := New(map[string]config.OAuthProviderConfig{}, nil)
+
+	_, err := providers.AuthCodeURL(context.Background(), "unknown", "state", "nonce", "verifier")
+	if !errors.Is(err, ErrProviderNotExist) {
+		t.Fatalf("AuthCodeURL() error = %v, want %v", err, ErrProviderNotExist)
+	}
+}
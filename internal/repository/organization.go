@@ -0,0 +1,247 @@
+package repository
+
+import (
+	"auth/internal/domain"
+	"auth/internal/repository/postgres"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+type OrganizationRepos struct{}
+
+func NewOrganizationRepos() Organization {
+	return &OrganizationRepos{}
+}
+
+func (m *OrganizationRepos) AddOrganization(ctx context.Context, tx pgx.Tx, org *domain.Organization) (int, error) {
+	row := tx.QueryRow(ctx, `INSERT INTO organization (name, slug, owner_id) VALUES ($1, $2, $3) RETURNING id`,
+		org.Name, org.Slug, org.OwnerID)
+	var id int
+	err := row.Scan(&id)
+	if err != nil {
+		if err, ok := err.(*pgconn.PgError); ok && err.Code == postgres.ErrUniqueViolation {
+			return 0, OrganizationAlreadyExist
+		}
+		return 0, fmt.Errorf("AddOrganization/Scan: %w", err)
+	}
+	return id, nil
+}
+
+func (m *OrganizationRepos) OrganizationById(ctx context.Context, tx pgx.Tx, id int) (*domain.Organization, error) {
+	row := tx.QueryRow(ctx, `SELECT id, name, slug, owner_id, created_at FROM organization WHERE id = $1`, id)
+
+	var org domain.Organization
+	err := row.Scan(&org.ID, &org.Name, &org.Slug, &org.OwnerID, &org.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, OrganizationNotExist
+		}
+		return nil, fmt.Errorf("OrganizationById/Scan: %w", err)
+	}
+	return &org, nil
+}
+
+func (m *OrganizationRepos) UserOrganizations(ctx context.Context, tx pgx.Tx, userID int) ([]*domain.Organization, error) {
+	rows, err := tx.Query(ctx, `SELECT o.id, o.name, o.slug, o.owner_id, o.created_at, m.role
+		FROM organization o JOIN organization_member m ON m.org_id = o.id
+		WHERE m.user_id = $1 AND m.active ORDER BY o.id`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("UserOrganizations/Query: %w", err)
+	}
+	defer rows.Close()
+
+	orgs := make([]*domain.Organization, 0)
+	for rows.Next() {
+		var org domain.Organization
+		var role domain.OrgRole
+		err = rows.Scan(&org.ID, &org.Name, &org.Slug, &org.OwnerID, &org.CreatedAt, &role)
+		if err != nil {
+			return nil, fmt.Errorf("UserOrganizations/Scan: %w", err)
+		}
+		org.Role = &role
+		orgs = append(orgs, &org)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("UserOrganizations/Rows: %w", err)
+	}
+	return orgs, nil
+}
+
+func (m *OrganizationRepos) AddMember(ctx context.Context, tx pgx.Tx, orgID int, userID int, role domain.OrgRole) error {
+	_, err := tx.Exec(ctx, `INSERT INTO organization_member (org_id, user_id, role) VALUES ($1, $2, $3)`,
+		orgID, userID, role)
+	if err != nil {
+		if err, ok := err.(*pgconn.PgError); ok && err.Code == postgres.ErrUniqueViolation {
+			return MemberAlreadyExist
+		}
+		return fmt.Errorf("AddMember/Exec: %w", err)
+	}
+	return nil
+}
+
+func (m *OrganizationRepos) Member(ctx context.Context, tx pgx.Tx, orgID int, userID int) (*domain.OrgMember, error) {
+	row := tx.QueryRow(ctx, `SELECT `+memberColumns+`
+		FROM organization_member m JOIN "user" u ON u.id = m.user_id
+		WHERE m.org_id = $1 AND m.user_id = $2`, orgID, userID)
+
+	member, err := scanMember(row)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, MemberNotExist
+		}
+		return nil, fmt.Errorf("Member/Scan: %w", err)
+	}
+	return member, nil
+}
+
+func (m *OrganizationRepos) Members(ctx context.Context, tx pgx.Tx, orgID int) ([]*domain.OrgMember, error) {
+	rows, err := tx.Query(ctx, `SELECT `+memberColumns+`
+		FROM organization_member m JOIN "user" u ON u.id = m.user_id
+		WHERE m.org_id = $1 ORDER BY m.created_at, m.user_id`, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("Members/Query: %w", err)
+	}
+	defer rows.Close()
+
+	members := make([]*domain.OrgMember, 0)
+	for rows.Next() {
+		member, err := scanMember(rows)
+		if err != nil {
+			return nil, fmt.Errorf("Members/Scan: %w", err)
+		}
+		members = append(members, member)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("Members/Rows: %w", err)
+	}
+	return members, nil
+}
+
+func (m *OrganizationRepos) SetMemberRole(ctx context.Context, tx pgx.Tx, orgID int, userID int, role domain.OrgRole) error {
+	tag, err := tx.Exec(ctx, `UPDATE organization_member SET role = $1, updated_at = now() WHERE org_id = $2 AND user_id = $3`,
+		role, orgID, userID)
+	if err != nil {
+		return fmt.Errorf("SetMemberRole/Exec: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return MemberNotExist
+	}
+	return nil
+}
+
+func (m *OrganizationRepos) RemoveMember(ctx context.Context, tx pgx.Tx, orgID int, userID int) error {
+	tag, err := tx.Exec(ctx, `DELETE FROM organization_member WHERE org_id = $1 AND user_id = $2`, orgID, userID)
+	if err != nil {
+		return fmt.Errorf("RemoveMember/Exec: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return MemberNotExist
+	}
+	return nil
+}
+
+func (m *OrganizationRepos) AddInvitation(ctx context.Context, tx pgx.Tx, invitation *domain.OrgInvitation) (int, error) {
+	row := tx.QueryRow(ctx, `INSERT INTO organization_invitation (org_id, email, role, token_hash, invited_by, status, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id, created_at`,
+		invitation.OrgID, invitation.Email, invitation.Role, invitation.TokenHash, invitation.InvitedBy,
+		domain.InvitationPending, invitation.ExpiresAt)
+	err := row.Scan(&invitation.ID, &invitation.CreatedAt)
+	if err != nil {
+		return 0, fmt.Errorf("AddInvitation/Scan: %w", err)
+	}
+	invitation.Status = domain.InvitationPending
+	return invitation.ID, nil
+}
+
+const invitationColumns = `id, org_id, email, role, invited_by, status, expires_at, created_at, token_hash`
+
+func (m *OrganizationRepos) InvitationByTokenHash(ctx context.Context, tx pgx.Tx, tokenHash string) (*domain.OrgInvitation, error) {
+	// Блокируем строку, чтобы приглашение нельзя было принять дважды
+	row := tx.QueryRow(ctx, `SELECT `+invitationColumns+` FROM organization_invitation WHERE token_hash = $1 FOR UPDATE`, tokenHash)
+
+	invitation, err := scanInvitation(row)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, InvitationNotExist
+		}
+		return nil, fmt.Errorf("InvitationByTokenHash/Scan: %w", err)
+	}
+	return invitation, nil
+}
+
+func (m *OrganizationRepos) Invitations(ctx context.Context, tx pgx.Tx, orgID int, status domain.InvitationStatus) ([]*domain.OrgInvitation, error) {
+	rows, err := tx.Query(ctx, `SELECT `+invitationColumns+` FROM organization_invitation
+		WHERE org_id = $1 AND status = $2 ORDER BY created_at DESC, id DESC`, orgID, status)
+	if err != nil {
+		return nil, fmt.Errorf("Invitations/Query: %w", err)
+	}
+	defer rows.Close()
+
+	invitations := make([]*domain.OrgInvitation, 0)
+	for rows.Next() {
+		invitation, err := scanInvitation(rows)
+		if err != nil {
+			return nil, fmt.Errorf("Invitations/Scan: %w", err)
+		}
+		invitations = append(invitations, invitation)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("Invitations/Rows: %w", err)
+	}
+	return invitations, nil
+}
+
+func (m *OrganizationRepos) SetInvitationStatus(ctx context.Context, tx pgx.Tx, orgID int, id int, status domain.InvitationStatus) error {
+	tag, err := tx.Exec(ctx, `UPDATE organization_invitation SET status = $1, updated_at = now()
+		WHERE id = $2 AND org_id = $3 AND status = $4`, status, id, orgID, domain.InvitationPending)
+	if err != nil {
+		return fmt.Errorf("SetInvitationStatus/Exec: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return InvitationNotExist
+	}
+	return nil
+}
+
+const memberColumns = `m.org_id, m.user_id, u.email, m.role, m.active, m.external_id, m.provisioned, m.created_at, m.updated_at`
+
+func scanMember(row pgx.Row) (*domain.OrgMember, error) {
+	var member domain.OrgMember
+	err := row.Scan(
+		&member.OrgID,
+		&member.UserID,
+		&member.Email,
+		&member.Role,
+		&member.Active,
+		&member.ExternalID,
+		&member.Provisioned,
+		&member.CreatedAt,
+		&member.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &member, nil
+}
+
+func scanInvitation(row pgx.Row) (*domain.OrgInvitation, error) {
+	var invitation domain.OrgInvitation
+	err := row.Scan(
+		&invitation.ID,
+		&invitation.OrgID,
+		&invitation.Email,
+		&invitation.Role,
+		&invitation.InvitedBy,
+		&invitation.Status,
+		&invitation.ExpiresAt,
+		&invitation.CreatedAt,
+		&invitation.TokenHash,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &invitation, nil
+}
@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"auth/internal/domain"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+)
+
+type PersonalTokenRepos struct{}
+
+func NewPersonalTokenRepos() PersonalToken {
+	return &PersonalTokenRepos{}
+}
+
+const personalTokenColumns = `id, user_id, name, prefix, scopes, expires_at, last_used_at, revoked_at, created_at, token_hash`
+
+func (m *PersonalTokenRepos) AddPersonalToken(ctx context.Context, tx pgx.Tx, token *domain.PersonalToken) (int, error) {
+	scopes := make([]string, 0, len(token.Scopes))
+	for _, s := range token.Scopes {
+		scopes = append(scopes, string(s))
+	}
+	row := tx.QueryRow(ctx, `INSERT INTO personal_access_token (user_id, name, prefix, token_hash, scopes, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, created_at`,
+		token.UserID, token.Name, token.Prefix, token.TokenHash, scopes, token.ExpiresAt)
+	err := row.Scan(&token.ID, &token.CreatedAt)
+	if err != nil {
+		return 0, fmt.Errorf("AddPersonalToken/Scan: %w", err)
+	}
+	return token.ID, nil
+}
+
+func (m *PersonalTokenRepos) PersonalTokens(ctx context.Context, tx pgx.Tx, userID int) ([]*domain.PersonalToken, error) {
+	rows, err := tx.Query(ctx, `SELECT `+personalTokenColumns+` FROM personal_access_token
+		WHERE user_id = $1 AND revoked_at IS NULL ORDER BY created_at DESC, id DESC`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("PersonalTokens/Query: %w", err)
+	}
+	defer rows.Close()
+
+	tokens := make([]*domain.PersonalToken, 0)
+	for rows.Next() {
+		token, err := scanPersonalToken(rows)
+		if err != nil {
+			return nil, fmt.Errorf("PersonalTokens/Scan: %w", err)
+		}
+		tokens = append(tokens, token)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("PersonalTokens/Rows: %w", err)
+	}
+	return tokens, nil
+}
+
+func (m *PersonalTokenRepos) PersonalTokenByHash(ctx context.Context, tx pgx.Tx, tokenHash string) (*domain.PersonalToken, error) {
+	row := tx.QueryRow(ctx, `SELECT `+personalTokenColumns+` FROM personal_access_token WHERE token_hash = $1`, tokenHash)
+
+	token, err := scanPersonalToken(row)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, TokenNotExist
+		}
+		return nil, fmt.Errorf("PersonalTokenByHash/Scan: %w", err)
+	}
+	return token, nil
+}
+
+func (m *PersonalTokenRepos) TouchPersonalToken(ctx context.Context, tx pgx.Tx, id int) error {
+	// Время использования обновляется не чаще раза в минуту, чтобы не писать в базу на каждый запрос
+	_, err := tx.Exec(ctx, `UPDATE personal_access_token SET last_used_at = now()
+		WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < now() - interval '1 minute')`, id)
+	if err != nil {
+		return fmt.Errorf("TouchPersonalToken/Exec: %w", err)
+	}
+	return nil
+}
+
+func (m *PersonalTokenRepos) RevokePersonalToken(ctx context.Context, tx pgx.Tx, userID int, id int) error {
+	tag, err := tx.Exec(ctx, `UPDATE personal_access_token SET revoked_at = now()
+		WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL`, id, userID)
+	if err != nil {
+		return fmt.Errorf("RevokePersonalToken/Exec: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return TokenNotExist
+	}
+	return nil
+}
+
+func scanPersonalToken(row pgx.Row) (*domain.PersonalToken, error) {
+	var token domain.PersonalToken
+	var scopes []string
+	err := row.Scan(
+		&token.ID,
+		&token.UserID,
+		&token.Name,
+		&token.Prefix,
+		&scopes,
+		&token.ExpiresAt,
+		&token.LastUsedAt,
+		&token.RevokedAt,
+		&token.CreatedAt,
+		&token.TokenHash,
+	)
+	if err != nil {
+		return nil, err
+	}
+	token.Scopes = make([]domain.Permission, 0, len(scopes))
+	for _, s := range scopes {
+		token.Scopes = append(token.Scopes, domain.Permission(s))
+	}
+	return &token, nil
+}
@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"auth/internal/domain"
+	"context"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"os"
+	"time"
+)
+
+type PolicyRepos struct {
+	path string
+}
+
+func NewPolicyRepos(path string) Policy {
+	return &PolicyRepos{path: path}
+}
+
+func (m *PolicyRepos) LoadPolicy(ctx context.Context) (*domain.Policy, error) {
+	if m.path == "" {
+		return nil, PolicyNotConfigured
+	}
+	data, err := os.ReadFile(m.path)
+	if err != nil {
+		return nil, fmt.Errorf("LoadPolicy/ReadFile: %w", err)
+	}
+	var policy domain.Policy
+	err = yaml.Unmarshal(data, &policy)
+	if err != nil {
+		return nil, fmt.Errorf("LoadPolicy/Unmarshal: %w", err)
+	}
+	policy.SetDefaults()
+	return &policy, nil
+}
+
+func (m *PolicyRepos) PolicyModTime(ctx context.Context) (time.Time, error) {
+	if m.path == "" {
+		return time.Time{}, PolicyNotConfigured
+	}
+	info, err := os.Stat(m.path)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("PolicyModTime/Stat: %w", err)
+	}
+	return info.ModTime(), nil
+}
@@ -2,27 +2,67 @@ package postgres
 
 import (
 	"auth/internal/config"
+	"auth/internal/requestid"
+	"auth/internal/tracing"
 	"context"
+	"errors"
 	"fmt"
 	"github.com/Linkify-Company/common_utils/errify"
 	"github.com/Linkify-Company/common_utils/logger"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
 	"os"
 	"strconv"
 	"strings"
 )
 
+// tracer Записывает запросы спанами в трассу из контекста запроса, а в режиме отладки еще и логирует SQL
 type tracer struct {
-	log logger.Logger
+	log   logger.Logger
+	debug bool
 }
 
 func (t tracer) TraceQueryStart(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
-	t.log.Debugf(strings.ReplaceAll(data.SQL, "\n", " "))
+	sql := strings.Join(strings.Fields(data.SQL), " ")
+	if t.debug {
+		requestid.Logger(ctx, t.log).Debugf("%s", sql)
+	}
+	// Запросы вне трассы, например при загрузке политик, не начинают собственных трасс
+	if !trace.SpanFromContext(ctx).IsRecording() {
+		return ctx
+	}
+	// Значения параметров в спан не попадают, в SQL только их номера
+	ctx, _ = tracing.Start(ctx, "postgres "+operation(sql),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.DBSystemPostgreSQL, semconv.DBStatement(sql)),
+	)
 	return ctx
 }
 
-func (t tracer) TraceQueryEnd(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryEndData) {}
+func (t tracer) TraceQueryEnd(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryEndData) {
+	span := trace.SpanFromContext(ctx)
+	if !span.IsRecording() {
+		return
+	}
+	span.SetAttributes(attribute.Int64("db.rows_affected", data.CommandTag.RowsAffected()))
+
+	err := data.Err
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = nil
+	}
+	tracing.End(span, err)
+}
+
+// operation Первое слово запроса, например select
+func operation(sql string) string {
+	if i := strings.IndexByte(sql, ' '); i > 0 {
+		sql = sql[:i]
+	}
+	return strings.ToLower(sql)
+}
 
 func New(ctx context.Context, log logger.Logger, debug bool) (*pgxpool.Pool, errify.IError) {
 	port, err := strconv.Atoi(os.Getenv(config.PostgresPort))
@@ -40,9 +80,7 @@ func New(ctx context.Context, log logger.Logger, debug bool) (*pgxpool.Pool, err
 	if err != nil {
 		return nil, errify.NewInternalServerError(err.Error(), "New/ParseConfig")
 	}
-	if debug {
-		cfg.ConnConfig.Tracer = tracer{log: log}
-	}
+	cfg.ConnConfig.Tracer = tracer{log: log, debug: debug}
 
 	pool, err := pgxpool.NewWithConfig(ctx, cfg)
 	if err != nil {
@@ -0,0 +1,137 @@
+package redis
+
+import (
+	"auth/internal/tracing"
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"github.com/go-redis/redis"
+	"strconv"
+	"time"
+)
+
+type EmailRedis struct {
+	client *redis.Client
+}
+
+const (
+	emailCodeKey     = "email_code:"
+	emailCooldownKey = "email_cooldown:"
+	emailDailyKey    = "email_daily:"
+
+	fieldCode     = "code"
+	fieldAttempts = "attempts"
+
+	// Окно, в пределах которого действует суточный лимит отправок
+	dailyWindow = 24 * time.Hour
+	// Количество повторов проверки кода при конкурентном изменении ключа
+	maxWatchRetries = 5
+)
+
+// allowSendScript Проверяет интервал между отправками и суточный лимит.
+// Возвращает 0, если отправка разрешена, иначе количество миллисекунд до следующей возможной отправки.
+var allowSendScript = redis.NewScript(`
+local ttl = redis.call('PTTL', KEYS[1])
+if ttl > 0 then
+	return ttl
+end
+local count = tonumber(redis.call('GET', KEYS[2]) or '0')
+if count >= tonumber(ARGV[2]) then
+	local window = redis.call('PTTL', KEYS[2])
+	if window > 0 then
+		return window
+	end
+	return tonumber(ARGV[3])
+end
+redis.call('SET', KEYS[1], '1', 'PX', ARGV[1])
+if redis.call('INCR', KEYS[2]) == 1 then
+	redis.call('PEXPIRE', KEYS[2], ARGV[3])
+end
+return 0
+`)
+
+func NewEmailRedis(client *redis.Client) *EmailRedis {
+	return &EmailRedis{
+		client: client,
+	}
+}
+
+func (m *EmailRedis) Set(ctx context.Context, email string, codeHash string, ttl time.Duration) error {
+	key := fmt.Sprint(emailCodeKey, email)
+
+	_, err := tracing.Redis(ctx, m.client).TxPipelined(func(tx redis.Pipeliner) error {
+		tx.Del(key)
+		tx.HMSet(key, map[string]interface{}{
+			fieldCode:     codeHash,
+			fieldAttempts: 0,
+		})
+		tx.Expire(key, ttl)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("EmailRedis.Set/TxPipelined: %w", err)
+	}
+	return nil
+}
+
+func (m *EmailRedis) IsValid(ctx context.Context, email string, codeHash string, attempts int) (bool, error) {
+	key := fmt.Sprint(emailCodeKey, email)
+	client := tracing.Redis(ctx, m.client)
+
+	var valid bool
+	for i := 0; i < maxWatchRetries; i++ {
+		err := client.Watch(func(tx *redis.Tx) error {
+			valid = false
+
+			data, err := tx.HGetAll(key).Result()
+			if err != nil {
+				return err
+			}
+			stored, ok := data[fieldCode]
+			if !ok {
+				return nil
+			}
+			numberAttempts, _ := strconv.Atoi(data[fieldAttempts])
+
+			valid = subtle.ConstantTimeCompare([]byte(stored), []byte(codeHash)) == 1
+
+			_, err = tx.Pipelined(func(pipe redis.Pipeliner) error {
+				// Код одноразовый, а после исчерпания попыток его нужно запросить заново
+				if valid || numberAttempts+1 >= attempts {
+					pipe.Del(key)
+					return nil
+				}
+				pipe.HIncrBy(key, fieldAttempts, 1)
+				return nil
+			})
+			return err
+		}, key)
+		if errors.Is(err, redis.TxFailedErr) {
+			continue
+		}
+		if err != nil {
+			return false, fmt.Errorf("EmailRedis.IsValid/Watch: %w", err)
+		}
+		return valid, nil
+	}
+	return false, fmt.Errorf("EmailRedis.IsValid/Watch: %w", redis.TxFailedErr)
+}
+
+func (m *EmailRedis) IsExist(ctx context.Context, email string) (bool, error) {
+	n, err := tracing.Redis(ctx, m.client).Exists(fmt.Sprint(emailCodeKey, email)).Result()
+	if err != nil {
+		return false, fmt.Errorf("EmailRedis.IsExist/Exists: %w", err)
+	}
+	return n > 0, nil
+}
+
+func (m *EmailRedis) AllowSend(ctx context.Context, email string, cooldown time.Duration, dailyLimit int) (time.Duration, error) {
+	ms, err := allowSendScript.Run(tracing.Redis(ctx, m.client),
+		[]string{fmt.Sprint(emailCooldownKey, email), fmt.Sprint(emailDailyKey, email)},
+		cooldown.Milliseconds(), dailyLimit, dailyWindow.Milliseconds()).Int64()
+	if err != nil {
+		return 0, fmt.Errorf("EmailRedis.AllowSend/Run: %w", err)
+	}
+	return time.Duration(ms) * time.Millisecond, nil
+}
@@ -0,0 +1,31 @@
+package redis
+
+import (
+	"auth/internal/tracing"
+	"context"
+	"fmt"
+	"github.com/go-redis/redis"
+	"time"
+)
+
+const samlAssertionKey = "saml_assertion:"
+
+// SAMLAssertionRedis Идентификаторы принятых утверждений SAML для защиты от повторного использования
+type SAMLAssertionRedis struct {
+	client *redis.Client
+}
+
+func NewSAMLAssertionRedis(client *redis.Client) *SAMLAssertionRedis {
+	return &SAMLAssertionRedis{
+		client: client,
+	}
+}
+
+// Use Отмечает утверждение использованным до истечения его срока действия. Возвращает false, если оно уже использовано
+func (m *SAMLAssertionRedis) Use(ctx context.Context, idp string, id string, ttl time.Duration) (bool, error) {
+	ok, err := tracing.Redis(ctx, m.client).SetNX(fmt.Sprint(samlAssertionKey, idp, ":", id), 1, ttl).Result()
+	if err != nil {
+		return false, fmt.Errorf("SAMLAssertionRedis.Use/SetNX: %w", err)
+	}
+	return ok, nil
+}
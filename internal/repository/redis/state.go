@@ -0,0 +1,71 @@
+package redis
+
+import (
+	"auth/internal/tracing"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-redis/redis"
+	"time"
+)
+
+const (
+	OAuthStateKey = "oauth_state:"
+	SAMLStateKey  = "saml_state:"
+)
+
+// ErrStateNotExist Состояние входа не найдено, истекло или уже использовано
+var ErrStateNotExist = errors.New("state not exists")
+
+// StateRedis Одноразовые состояния входа через внешних провайдеров, prefix отделяет ключи разных протоколов
+type StateRedis struct {
+	client *redis.Client
+	prefix string
+}
+
+func NewStateRedis(client *redis.Client, prefix string) *StateRedis {
+	return &StateRedis{
+		client: client,
+		prefix: prefix,
+	}
+}
+
+func (m *StateRedis) Set(ctx context.Context, state string, value any, ttl time.Duration) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("StateRedis.Set/Marshal: %w", err)
+	}
+	err = tracing.Redis(ctx, m.client).Set(fmt.Sprint(m.prefix, state), data, ttl).Err()
+	if err != nil {
+		return fmt.Errorf("StateRedis.Set/Set: %w", err)
+	}
+	return nil
+}
+
+// Pop Возвращает и удаляет состояние, чтобы state нельзя было использовать повторно
+func (m *StateRedis) Pop(ctx context.Context, state string, value any) error {
+	key := fmt.Sprint(m.prefix, state)
+
+	var get *redis.StringCmd
+	_, err := tracing.Redis(ctx, m.client).TxPipelined(func(tx redis.Pipeliner) error {
+		get = tx.Get(key)
+		tx.Del(key)
+		return nil
+	})
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return fmt.Errorf("StateRedis.Pop/TxPipelined: %w", err)
+	}
+	data, err := get.Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return ErrStateNotExist
+		}
+		return fmt.Errorf("StateRedis.Pop/Get: %w", err)
+	}
+	err = json.Unmarshal(data, value)
+	if err != nil {
+		return fmt.Errorf("StateRedis.Pop/Unmarshal: %w", err)
+	}
+	return nil
+}
@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"auth/internal/config"
 	"auth/internal/domain"
 	"context"
 	"github.com/Linkify-Company/common_utils/errify"
@@ -12,7 +13,134 @@ import (
 type User interface {
 	AddUser(ctx context.Context, tx pgx.Tx, email string, role domain.Role, passHash []byte) (int, error)
 	UserById(ctx context.Context, tx pgx.Tx, id int) (*domain.UserFromDB, error)
+	// UserByEmail Пользователь, который не удален, по почте без учета регистра
 	UserByEmail(ctx context.Context, tx pgx.Tx, email string) (*domain.UserFromDB, error)
+	// UsersByIDs Возвращает найденных пользователей, отсутствующие идентификаторы пропускаются
+	UsersByIDs(ctx context.Context, tx pgx.Tx, ids []int) ([]*domain.UserFromDB, error)
+	// Users Возвращает страницу пользователей и курсор следующей страницы
+	Users(ctx context.Context, tx pgx.Tx, filter *domain.UserFilter) ([]*domain.UserFromDB, string, error)
+	UpdateUser(ctx context.Context, tx pgx.Tx, id int, update *domain.UserUpdate) error
+	// DeleteUser Мягкое удаление: запись остается, но помечается deleted_at
+	DeleteUser(ctx context.Context, tx pgx.Tx, id int) error
+}
+
+type Role interface {
+	SetRole(ctx context.Context, tx pgx.Tx, userID int, role domain.Role) error
+	AddRoleChange(ctx context.Context, tx pgx.Tx, change *domain.RoleChange) (int, error)
+	RoleChanges(ctx context.Context, tx pgx.Tx, userID int) ([]*domain.RoleChange, error)
+}
+
+type Access interface {
+	Permissions(ctx context.Context, tx pgx.Tx) ([]*domain.PermissionInfo, error)
+	AddPermission(ctx context.Context, tx pgx.Tx, permission *domain.PermissionInfo) (int, error)
+	AccessRoles(ctx context.Context, tx pgx.Tx) ([]*domain.AccessRole, error)
+	AccessRole(ctx context.Context, tx pgx.Tx, id int) (*domain.AccessRole, error)
+	AddAccessRole(ctx context.Context, tx pgx.Tx, role *domain.AccessRole) (int, error)
+	SetAccessRolePermissions(ctx context.Context, tx pgx.Tx, roleID int, permissions []domain.Permission) error
+	// DeleteAccessRole Удаляет пользовательскую роль, встроенные роли удалить нельзя
+	DeleteAccessRole(ctx context.Context, tx pgx.Tx, id int) error
+	UserAccessRoles(ctx context.Context, tx pgx.Tx, userID int) ([]*domain.AccessRole, error)
+	SetUserAccessRoles(ctx context.Context, tx pgx.Tx, userID int, roleIDs []int) error
+	// UserPermissions Объединение разрешений всех ролей пользователя
+	UserPermissions(ctx context.Context, tx pgx.Tx, userID int) ([]domain.Permission, error)
+}
+
+type Organization interface {
+	AddOrganization(ctx context.Context, tx pgx.Tx, org *domain.Organization) (int, error)
+	OrganizationById(ctx context.Context, tx pgx.Tx, id int) (*domain.Organization, error)
+	// UserOrganizations Организации, в которых состоит пользователь, с его ролью
+	UserOrganizations(ctx context.Context, tx pgx.Tx, userID int) ([]*domain.Organization, error)
+	AddMember(ctx context.Context, tx pgx.Tx, orgID int, userID int, role domain.OrgRole) error
+	Member(ctx context.Context, tx pgx.Tx, orgID int, userID int) (*domain.OrgMember, error)
+	Members(ctx context.Context, tx pgx.Tx, orgID int) ([]*domain.OrgMember, error)
+	SetMemberRole(ctx context.Context, tx pgx.Tx, orgID int, userID int, role domain.OrgRole) error
+	RemoveMember(ctx context.Context, tx pgx.Tx, orgID int, userID int) error
+	AddInvitation(ctx context.Context, tx pgx.Tx, invitation *domain.OrgInvitation) (int, error)
+	InvitationByTokenHash(ctx context.Context, tx pgx.Tx, tokenHash string) (*domain.OrgInvitation, error)
+	Invitations(ctx context.Context, tx pgx.Tx, orgID int, status domain.InvitationStatus) ([]*domain.OrgInvitation, error)
+	// SetInvitationStatus Меняет статус приглашения, ожидающего ответа
+	SetInvitationStatus(ctx context.Context, tx pgx.Tx, orgID int, id int, status domain.InvitationStatus) error
+}
+
+type PersonalToken interface {
+	AddPersonalToken(ctx context.Context, tx pgx.Tx, token *domain.PersonalToken) (int, error)
+	// PersonalTokens Неотозванные токены пользователя
+	PersonalTokens(ctx context.Context, tx pgx.Tx, userID int) ([]*domain.PersonalToken, error)
+	PersonalTokenByHash(ctx context.Context, tx pgx.Tx, tokenHash string) (*domain.PersonalToken, error)
+	// TouchPersonalToken Обновляет время последнего использования токена
+	TouchPersonalToken(ctx context.Context, tx pgx.Tx, id int) error
+	RevokePersonalToken(ctx context.Context, tx pgx.Tx, userID int, id int) error
+}
+
+type SCIM interface {
+	AddSCIMToken(ctx context.Context, tx pgx.Tx, token *domain.SCIMToken) (int, error)
+	// SCIMTokens Неотозванные токены организации
+	SCIMTokens(ctx context.Context, tx pgx.Tx, orgID int) ([]*domain.SCIMToken, error)
+	SCIMTokenByHash(ctx context.Context, tx pgx.Tx, tokenHash string) (*domain.SCIMToken, error)
+	// TouchSCIMToken Обновляет время последнего использования токена
+	TouchSCIMToken(ctx context.Context, tx pgx.Tx, id int) error
+	RevokeSCIMToken(ctx context.Context, tx pgx.Tx, orgID int, id int) error
+	// SCIMMembers Возвращает страницу участников организации и их общее количество
+	SCIMMembers(ctx context.Context, tx pgx.Tx, orgID int, query *domain.SCIMQuery) ([]*domain.OrgMember, int, error)
+	// ProvisionMember Добавляет участника с атрибутами SCIM
+	ProvisionMember(ctx context.Context, tx pgx.Tx, member *domain.OrgMember) error
+	UpdateSCIMMember(ctx context.Context, tx pgx.Tx, orgID int, userID int, externalID *string, active bool) error
+	AddGroup(ctx context.Context, tx pgx.Tx, group *domain.OrgGroup) (int, error)
+	Group(ctx context.Context, tx pgx.Tx, orgID int, id int) (*domain.OrgGroup, error)
+	// Groups Возвращает страницу групп организации с участниками и их общее количество
+	Groups(ctx context.Context, tx pgx.Tx, orgID int, query *domain.SCIMQuery) ([]*domain.OrgGroup, int, error)
+	UpdateGroup(ctx context.Context, tx pgx.Tx, group *domain.OrgGroup) error
+	// SetGroupMembers Заменяет участников группы, участниками могут быть только участники организации
+	SetGroupMembers(ctx context.Context, tx pgx.Tx, orgID int, groupID int, userIDs []int) error
+	DeleteGroup(ctx context.Context, tx pgx.Tx, orgID int, id int) error
+	// MemberGroups Группы участника без списка их участников
+	MemberGroups(ctx context.Context, tx pgx.Tx, orgID int, userID int) ([]*domain.OrgGroup, error)
+}
+
+type Identity interface {
+	AddIdentity(ctx context.Context, tx pgx.Tx, identity *domain.UserIdentity) (int, error)
+	IdentityBySubject(ctx context.Context, tx pgx.Tx, provider string, subject string) (*domain.UserIdentity, error)
+	UserIdentities(ctx context.Context, tx pgx.Tx, userID int) ([]*domain.UserIdentity, error)
+	// TouchIdentity Обновляет время входа и почту, полученную от провайдера
+	TouchIdentity(ctx context.Context, tx pgx.Tx, id int, email string) error
+	RemoveIdentity(ctx context.Context, tx pgx.Tx, userID int, provider string) error
+}
+
+type OAuth interface {
+	// AuthCodeURL Адрес страницы входа провайдера
+	AuthCodeURL(ctx context.Context, provider string, state string, nonce string, verifier string) (string, error)
+	// Exchange Обменивает код от провайдера на учетную запись пользователя у него
+	Exchange(ctx context.Context, provider string, code string, verifier string, nonce string) (*domain.ExternalIdentity, error)
+	SetOAuthState(ctx context.Context, state string, data *domain.OAuthState, ttl time.Duration) error
+	// PopOAuthState Возвращает и удаляет состояние входа, state одноразовый
+	PopOAuthState(ctx context.Context, state string) (*domain.OAuthState, error)
+}
+
+type SAML interface {
+	// SAMLMetadata Метаданные сервиса для регистрации у провайдера
+	SAMLMetadata(ctx context.Context, idp string) ([]byte, error)
+	// SAMLAuthnRequest Запрос аутентификации к провайдеру, relayState возвращается провайдером вместе с ответом
+	SAMLAuthnRequest(ctx context.Context, idp string, relayState string) (*domain.SAMLAuthnRequest, error)
+	// ParseSAMLResponse Проверяет ответ провайдера. Пустой requestIDs - вход, начатый на стороне провайдера
+	ParseSAMLResponse(ctx context.Context, idp string, response string, requestIDs []string) (*domain.SAMLAssertion, error)
+	// UseSAMLAssertion Отмечает утверждение использованным, повторно оно не принимается
+	UseSAMLAssertion(ctx context.Context, idp string, assertion *domain.SAMLAssertion) error
+	SetSAMLState(ctx context.Context, relayState string, data *domain.SAMLState, ttl time.Duration) error
+	// PopSAMLState Возвращает и удаляет состояние запроса, RelayState одноразовый
+	PopSAMLState(ctx context.Context, relayState string) (*domain.SAMLState, error)
+}
+
+type Directory interface {
+	// HasDirectory Проверяется ли почта во внешнем каталоге вместо локального пароля
+	HasDirectory(email string) bool
+	// Authenticate Проверяет учетные данные в каталоге, которому принадлежит домен почты
+	Authenticate(ctx context.Context, email string, password string) (*domain.DirectoryUser, error)
+}
+
+type Policy interface {
+	// LoadPolicy Читает политики авторизации из файла
+	LoadPolicy(ctx context.Context) (*domain.Policy, error)
+	PolicyModTime(ctx context.Context) (time.Time, error)
 }
 
 type Auth interface {
@@ -20,12 +148,18 @@ type Auth interface {
 	RenewalAuthorization(ctx context.Context, redisClient *redis.Client, accessToken string, accessTTL time.Duration, secret string) (string, error)
 	CheckAuthorization(ctx context.Context, redisClient *redis.Client, accessToken string) (*domain.AuthData, error)
 	RemoveAuthorization(ctx context.Context, tx redis.Pipeliner, accessToken string) error
+	// RemoveUserAuthorization Завершает сессию пользователя без его токена
+	RemoveUserAuthorization(ctx context.Context, tx redis.Pipeliner, userID int) error
+	// CountAuthorizations Количество пользователей с активной сессией
+	CountAuthorizations(ctx context.Context, redisClient *redis.Client) (int64, error)
 }
 
 type Email interface {
-	IsExist(ctx context.Context, email string) bool
-	Set(ctx context.Context, email string, code int) errify.IError
-	IsValid(ctx context.Context, email string, code int) bool
+	IsExist(ctx context.Context, email string) (bool, error)
+	Set(ctx context.Context, email string, codeHash string, ttl time.Duration) error
+	IsValid(ctx context.Context, email string, codeHash string, attempts int) (bool, error)
+	// AllowSend Резервирует отправку кода на почту. Возвращает время до следующей возможной отправки, если лимит исчерпан
+	AllowSend(ctx context.Context, email string, cooldown time.Duration, dailyLimit int) (time.Duration, error)
 }
 
 type Transaction interface {
@@ -36,18 +170,51 @@ type Transaction interface {
 	RedisRollback(ctx context.Context, tx redis.Pipeliner) error
 	RedisCommit(tx redis.Pipeliner) error
 	RedisClient(ctx context.Context) *redis.Client
+
+	Ping(ctx context.Context) error
+	RedisPing(ctx context.Context) error
 }
 
 type Repository struct {
 	User
+	Role
+	Access
+	Organization
+	PersonalToken
+	SCIM
+	Identity
+	OAuth
+	SAML
+	Directory
+	Policy
 	Auth
 	Email
 }
 
-func NewRepository() *Repository {
-	return &Repository{
-		User:  NewUserRepos(),
-		Auth:  NewAuthRepo(),
-		Email: NewEmailRepos(),
+func NewRepository(
+	redisClient *redis.Client,
+	authzCfg *config.AuthzConfig,
+	oauthCfg *config.OAuthConfig,
+	ldapCfg *config.LDAPConfig,
+	samlCfg *config.SAMLConfig,
+) (*Repository, errify.IError) {
+	directory, err := NewDirectoryRepos(ldapCfg)
+	if err != nil {
+		return nil, errify.NewInternalServerError(err.Error(), "NewRepository/NewDirectoryRepos")
 	}
+	return &Repository{
+		User:          NewUserRepos(),
+		Role:          NewRoleRepos(),
+		Access:        NewAccessRepos(),
+		Organization:  NewOrganizationRepos(),
+		PersonalToken: NewPersonalTokenRepos(),
+		SCIM:          NewSCIMRepos(),
+		Identity:      NewIdentityRepos(),
+		OAuth:         NewOAuthRepos(redisClient, oauthCfg),
+		SAML:          NewSAMLRepos(redisClient, samlCfg),
+		Directory:     directory,
+		Policy:        NewPolicyRepos(authzCfg.PolicyPath),
+		Auth:          NewAuthRepo(),
+		Email:         NewEmailRepos(redisClient),
+	}, nil
 }
@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"auth/internal/domain"
+	"context"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+)
+
+type RoleRepos struct{}
+
+func NewRoleRepos() Role {
+	return &RoleRepos{}
+}
+
+func (m *RoleRepos) SetRole(ctx context.Context, tx pgx.Tx, userID int, role domain.Role) error {
+	tag, err := tx.Exec(ctx, `UPDATE "user" SET role = $1, updated_at = now() WHERE id = $2 AND deleted_at IS NULL`, role, userID)
+	if err != nil {
+		return fmt.Errorf("SetRole/Exec: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return UserNotExist
+	}
+	return nil
+}
+
+func (m *RoleRepos) AddRoleChange(ctx context.Context, tx pgx.Tx, change *domain.RoleChange) (int, error) {
+	row := tx.QueryRow(ctx, `INSERT INTO role_audit (user_id, old_role, new_role, changed_by, reason) VALUES ($1, $2, $3, $4, $5) RETURNING id`,
+		change.UserID, change.OldRole, change.NewRole, change.ChangedBy, change.Reason)
+	var id int
+	err := row.Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("AddRoleChange/Scan: %w", err)
+	}
+	return id, nil
+}
+
+func (m *RoleRepos) RoleChanges(ctx context.Context, tx pgx.Tx, userID int) ([]*domain.RoleChange, error) {
+	rows, err := tx.Query(ctx, `SELECT id, user_id, old_role, new_role, changed_by, reason, created_at FROM role_audit WHERE user_id = $1 ORDER BY created_at DESC, id DESC`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("RoleChanges/Query: %w", err)
+	}
+	defer rows.Close()
+
+	changes := make([]*domain.RoleChange, 0)
+	for rows.Next() {
+		var change domain.RoleChange
+		err = rows.Scan(
+			&change.ID,
+			&change.UserID,
+			&change.OldRole,
+			&change.NewRole,
+			&change.ChangedBy,
+			&change.Reason,
+			&change.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("RoleChanges/Scan: %w", err)
+		}
+		changes = append(changes, &change)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("RoleChanges/Rows: %w", err)
+	}
+	return changes, nil
+}
@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"auth/internal/config"
+	"auth/internal/domain"
+	redisRepo "auth/internal/repository/redis"
+	"auth/internal/repository/saml"
+	"context"
+	"errors"
+	"github.com/go-redis/redis"
+	"time"
+)
+
+type SAMLRepos struct {
+	providers  *saml.ServiceProviders
+	state      *redisRepo.StateRedis
+	assertions *redisRepo.SAMLAssertionRedis
+}
+
+func NewSAMLRepos(redisClient *redis.Client, samlCfg *config.SAMLConfig) SAML {
+	return &SAMLRepos{
+		providers:  saml.New(samlCfg.IdentityProviders, nil),
+		state:      redisRepo.NewStateRedis(redisClient, redisRepo.SAMLStateKey),
+		assertions: redisRepo.NewSAMLAssertionRedis(redisClient),
+	}
+}
+
+func (m *SAMLRepos) SAMLMetadata(ctx context.Context, idp string) ([]byte, error) {
+	metadata, err := m.providers.Metadata(ctx, idp)
+	if errors.Is(err, saml.ErrIdPNotExist) {
+		return nil, IdentityProviderNotExist
+	}
+	return metadata, err
+}
+
+func (m *SAMLRepos) SAMLAuthnRequest(ctx context.Context, idp string, relayState string) (*domain.SAMLAuthnRequest, error) {
+	request, err := m.providers.AuthnRequest(ctx, idp, relayState)
+	if errors.Is(err, saml.ErrIdPNotExist) {
+		return nil, IdentityProviderNotExist
+	}
+	return request, err
+}
+
+func (m *SAMLRepos) ParseSAMLResponse(ctx context.Context, idp string, response string, requestIDs []string) (*domain.SAMLAssertion, error) {
+	assertion, err := m.providers.ParseResponse(ctx, idp, response, requestIDs)
+	if err != nil {
+		if errors.Is(err, saml.ErrIdPNotExist) {
+			return nil, IdentityProviderNotExist
+		}
+		if errors.Is(err, saml.ErrAssertionNotValid) || errors.Is(err, saml.ErrIdentityNotValid) ||
+			errors.Is(err, saml.ErrIdPInitiatedDenied) {
+			return nil, errors.Join(SAMLAssertionNotValid, err)
+		}
+		return nil, err
+	}
+	return assertion, nil
+}
+
+func (m *SAMLRepos) UseSAMLAssertion(ctx context.Context, idp string, assertion *domain.SAMLAssertion) error {
+	ttl := time.Until(assertion.ExpiresAt)
+	if ttl <= 0 {
+		return SAMLAssertionNotValid
+	}
+	ok, err := m.assertions.Use(ctx, idp, assertion.ID, ttl)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return SAMLAssertionReplayed
+	}
+	return nil
+}
+
+func (m *SAMLRepos) SetSAMLState(ctx context.Context, relayState string, data *domain.SAMLState, ttl time.Duration) error {
+	return m.state.Set(ctx, relayState, data, ttl)
+}
+
+func (m *SAMLRepos) PopSAMLState(ctx context.Context, relayState string) (*domain.SAMLState, error) {
+	var data domain.SAMLState
+	err := m.state.Pop(ctx, relayState, &data)
+	if err != nil {
+		if errors.Is(err, redisRepo.ErrStateNotExist) {
+			return nil, SAMLStateNotExist
+		}
+		return nil, err
+	}
+	return &data, nil
+}
@@ -0,0 +1,346 @@
+package saml
+
+import (
+	"auth/internal/config"
+	"auth/internal/domain"
+	"bytes"
+	"context"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"github.com/crewjam/saml"
+	xrv "github.com/mattermost/xml-roundtrip-validator"
+	dsig "github.com/russellhaering/goxmldsig"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrIdPNotExist         = errors.New("saml identity provider not exists")
+	ErrAssertionNotValid   = errors.New("saml assertion not valid")
+	ErrIdentityNotValid    = errors.New("saml identity not valid")
+	ErrBindingNotSupported = errors.New("saml binding not supported")
+	ErrIdPInitiatedDenied  = errors.New("saml idp initiated login not allowed")
+)
+
+const (
+	BindingRedirect = "redirect"
+	BindingPost     = "post"
+
+	// maxMetadataSize Ограничение размера метаданных провайдера
+	maxMetadataSize = 1 << 20
+)
+
+// ServiceProviders Сервис как SP для каждого провайдера SAML. Метаданные провайдеров загружаются при первом обращении,
+// чтобы недоступность провайдера не мешала запуску сервиса
+type ServiceProviders struct {
+	cfg    map[string]config.SAMLIdentityProviderConfig
+	client *http.Client
+
+	mu        sync.Mutex
+	providers map[string]*provider
+}
+
+type provider struct {
+	name string
+	cfg  config.SAMLIdentityProviderConfig
+	sp   *saml.ServiceProvider
+}
+
+// New Создает SP провайдеров. client используется для загрузки метаданных, nil - клиент по умолчанию
+func New(cfg map[string]config.SAMLIdentityProviderConfig, client *http.Client) *ServiceProviders {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &ServiceProviders{
+		cfg:       cfg,
+		client:    client,
+		providers: make(map[string]*provider, len(cfg)),
+	}
+}
+
+// Metadata Возвращает метаданные SP для регистрации у провайдера
+func (m *ServiceProviders) Metadata(ctx context.Context, name string) ([]byte, error) {
+	p, err := m.provider(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	data, err := xml.MarshalIndent(p.sp.Metadata(), "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("Metadata/MarshalIndent: %w", err)
+	}
+	return append([]byte(xml.Header), data...), nil
+}
+
+// AuthnRequest Создает запрос аутентификации в привязке из конфигурации провайдера
+func (m *ServiceProviders) AuthnRequest(ctx context.Context, name string, relayState string) (*domain.SAMLAuthnRequest, error) {
+	p, err := m.provider(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	binding := saml.HTTPRedirectBinding
+	if p.cfg.Binding == BindingPost {
+		binding = saml.HTTPPostBinding
+	}
+	location := p.sp.GetSSOBindingLocation(binding)
+	if location == "" {
+		return nil, fmt.Errorf("AuthnRequest/GetSSOBindingLocation: %w", ErrBindingNotSupported)
+	}
+	req, err := p.sp.MakeAuthenticationRequest(location, binding, saml.HTTPPostBinding)
+	if err != nil {
+		return nil, fmt.Errorf("AuthnRequest/MakeAuthenticationRequest: %w", err)
+	}
+
+	request := &domain.SAMLAuthnRequest{ID: req.ID}
+	if binding == saml.HTTPPostBinding {
+		request.PostForm = req.Post(relayState)
+		return request, nil
+	}
+	redirect, err := req.Redirect(url.QueryEscape(relayState), p.sp)
+	if err != nil {
+		return nil, fmt.Errorf("AuthnRequest/Redirect: %w", err)
+	}
+	request.RedirectURL = redirect.String()
+	return request, nil
+}
+
+// ParseResponse Проверяет ответ провайдера из привязки HTTP-POST: подпись, получателя, аудиторию, срок действия
+// и InResponseTo. Пустой requestIDs означает вход, начатый на стороне провайдера
+func (m *ServiceProviders) ParseResponse(ctx context.Context, name string, encodedResponse string, requestIDs []string) (assertion *domain.SAMLAssertion, err error) {
+	p, err := m.provider(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if len(requestIDs) == 0 && !p.cfg.AllowIdPInitiated {
+		return nil, ErrIdPInitiatedDenied
+	}
+	data, err := base64.StdEncoding.DecodeString(encodedResponse)
+	if err != nil {
+		return nil, errors.Join(ErrAssertionNotValid, err)
+	}
+
+	// Библиотека не проверяет наличие необязательных элементов подписанного утверждения
+	defer func() {
+		if r := recover(); r != nil {
+			assertion, err = nil, errors.Join(ErrAssertionNotValid, fmt.Errorf("ParseResponse: %v", r))
+		}
+	}()
+	parsed, err := p.sp.ParseXMLResponse(data, requestIDs)
+	if err != nil {
+		var invalid *saml.InvalidResponseError
+		if errors.As(err, &invalid) && invalid.PrivateErr != nil {
+			err = invalid.PrivateErr
+		}
+		return nil, errors.Join(ErrAssertionNotValid, err)
+	}
+	return p.assertion(parsed)
+}
+
+func (m *ServiceProviders) provider(ctx context.Context, name string) (*provider, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if p, ok := m.providers[name]; ok {
+		return p, nil
+	}
+	cfg, ok := m.cfg[name]
+	if !ok {
+		return nil, ErrIdPNotExist
+	}
+
+	keyPair, err := tls.LoadX509KeyPair(cfg.CertPath, cfg.KeyPath)
+	if err != nil {
+		return nil, fmt.Errorf("provider/LoadX509KeyPair: %w", err)
+	}
+	key, ok := keyPair.PrivateKey.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("provider %s: key must be RSA", name)
+	}
+	cert, err := x509.ParseCertificate(keyPair.Certificate[0])
+	if err != nil {
+		return nil, fmt.Errorf("provider/ParseCertificate: %w", err)
+	}
+	metadataURL, err := url.Parse(cfg.MetadataURL)
+	if err != nil {
+		return nil, fmt.Errorf("provider/Parse: %w", err)
+	}
+	acsURL, err := url.Parse(cfg.ACSURL)
+	if err != nil {
+		return nil, fmt.Errorf("provider/Parse: %w", err)
+	}
+	idpMetadata, err := m.idpMetadata(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	sp := &saml.ServiceProvider{
+		EntityID:          cfg.EntityID,
+		Key:               key,
+		Certificate:       cert,
+		HTTPClient:        m.client,
+		MetadataURL:       *metadataURL,
+		AcsURL:            *acsURL,
+		IDPMetadata:       idpMetadata,
+		AuthnNameIDFormat: saml.UnspecifiedNameIDFormat,
+		AllowIDPInitiated: cfg.AllowIdPInitiated,
+	}
+	if cfg.SignRequests {
+		sp.SignatureMethod = dsig.RSASHA256SignatureMethod
+	}
+	p := &provider{
+		name: name,
+		cfg:  cfg,
+		sp:   sp,
+	}
+	m.providers[name] = p
+	return p, nil
+}
+
+// idpMetadata Читает метаданные провайдера из файла или загружает по адресу
+func (m *ServiceProviders) idpMetadata(ctx context.Context, cfg config.SAMLIdentityProviderConfig) (*saml.EntityDescriptor, error) {
+	var data []byte
+	var err error
+	switch {
+	case cfg.IdPMetadataPath != "":
+		data, err = os.ReadFile(cfg.IdPMetadataPath)
+		if err != nil {
+			return nil, fmt.Errorf("idpMetadata/ReadFile: %w", err)
+		}
+	case cfg.IdPMetadataURL != "":
+		data, err = m.fetchMetadata(ctx, cfg.IdPMetadataURL)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errors.New("idp_metadata_path or idp_metadata_url is required")
+	}
+
+	err = xrv.Validate(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("idpMetadata/Validate: %w", err)
+	}
+	var entity saml.EntityDescriptor
+	err = xml.Unmarshal(data, &entity)
+	if err != nil {
+		return nil, fmt.Errorf("idpMetadata/Unmarshal: %w", err)
+	}
+	if len(entity.IDPSSODescriptors) == 0 {
+		return nil, errors.New("idpMetadata: no IDPSSODescriptor")
+	}
+	return &entity, nil
+}
+
+func (m *ServiceProviders) fetchMetadata(ctx context.Context, metadataURL string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, metadataURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("fetchMetadata/NewRequest: %w", err)
+	}
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetchMetadata/Do: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetchMetadata: unexpected status %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxMetadataSize))
+	if err != nil {
+		return nil, fmt.Errorf("fetchMetadata/ReadAll: %w", err)
+	}
+	return data, nil
+}
+
+// assertion Сопоставляет атрибуты утверждения с учетной записью и ролью пользователя
+func (p *provider) assertion(parsed *saml.Assertion) (*domain.SAMLAssertion, error) {
+	attributes := make(map[string][]string)
+	for _, statement := range parsed.AttributeStatements {
+		for _, attribute := range statement.Attributes {
+			values := make([]string, 0, len(attribute.Values))
+			for _, value := range attribute.Values {
+				values = append(values, strings.TrimSpace(value.Value))
+			}
+			attributes[attribute.Name] = append(attributes[attribute.Name], values...)
+			if attribute.FriendlyName != "" && attribute.FriendlyName != attribute.Name {
+				attributes[attribute.FriendlyName] = append(attributes[attribute.FriendlyName], values...)
+			}
+		}
+	}
+	var nameID string
+	if parsed.Subject != nil && parsed.Subject.NameID != nil {
+		nameID = strings.TrimSpace(parsed.Subject.NameID.Value)
+	}
+
+	subject := attributeValue(attributes, p.cfg.SubjectAttribute, nameID)
+	email := strings.ToLower(attributeValue(attributes, p.cfg.EmailAttribute, nameID))
+	if parsed.ID == "" || subject == "" || email == "" {
+		return nil, ErrIdentityNotValid
+	}
+
+	assertion := &domain.SAMLAssertion{
+		ID: parsed.ID,
+		Identity: &domain.ExternalIdentity{
+			Provider:      domain.SAMLIdentityPrefix + p.name,
+			Subject:       subject,
+			Email:         email,
+			EmailVerified: p.cfg.TrustEmail,
+		},
+		ExpiresAt: expiresAt(parsed),
+	}
+	if p.cfg.RoleAttribute != "" {
+		role := p.role(attributes[p.cfg.RoleAttribute])
+		assertion.Role = &role
+	}
+	return assertion, nil
+}
+
+// role Старшая роль среди значений атрибута роли
+func (p *provider) role(values []string) domain.Role {
+	role := domain.Role(domain.RoleUser)
+	if p.cfg.DefaultRole != nil {
+		role = domain.Role(*p.cfg.DefaultRole)
+	}
+	for _, value := range values {
+		if r, ok := p.cfg.AttributeRoles[value]; ok && domain.Role(r) < role {
+			role = domain.Role(r)
+		}
+	}
+	return role
+}
+
+// expiresAt Самый поздний срок, до которого утверждение может быть принято с учетом допустимого расхождения часов
+func expiresAt(parsed *saml.Assertion) time.Time {
+	expires := parsed.IssueInstant.Add(saml.MaxIssueDelay)
+	if parsed.Conditions != nil && parsed.Conditions.NotOnOrAfter.After(expires) {
+		expires = parsed.Conditions.NotOnOrAfter
+	}
+	if parsed.Subject != nil {
+		for _, confirmation := range parsed.Subject.SubjectConfirmations {
+			if data := confirmation.SubjectConfirmationData; data != nil && data.NotOnOrAfter.After(expires) {
+				expires = data.NotOnOrAfter
+			}
+		}
+	}
+	return expires.Add(saml.MaxClockSkew)
+}
+
+func attributeValue(attributes map[string][]string, name string, def string) string {
+	if name == "" {
+		return def
+	}
+	for _, value := range attributes[name] {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,288 @@
+package saml
+
+import (
+	"auth/internal/config"
+	"auth/internal/domain"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"encoding/xml"
+	"errors"
+	"github.com/beevik/etree"
+	"github.com/crewjam/saml"
+	dsig "github.com/russellhaering/goxmldsig"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const (
+	testIdPEntityID = "https://idp.example.com/metadata"
+	testSPEntityID  = "https://auth.example.com/v1/saml/test/metadata"
+	testACSURL      = "https://auth.example.com/v1/saml/test/acs"
+	testRequestID   = "id-request-1"
+)
+
+// testIdP Провайдер с собственной ключевой парой, который выпускает подписанные ответы
+type testIdP struct {
+	cert tls.Certificate
+}
+
+func newTestIdP(t *testing.T) *testIdP {
+	t.Helper()
+	return &testIdP{cert: newKeyPair(t, "idp.example.com")}
+}
+
+func newKeyPair(t *testing.T, name string) tls.Certificate {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+// metadata Метаданные провайдера с сертификатом подписи
+func (idp *testIdP) metadata(t *testing.T) []byte {
+	t.Helper()
+	entity := saml.EntityDescriptor{
+		EntityID: testIdPEntityID,
+		IDPSSODescriptors: []saml.IDPSSODescriptor{{
+			SSODescriptor: saml.SSODescriptor{
+				RoleDescriptor: saml.RoleDescriptor{
+					ProtocolSupportEnumeration: "urn:oasis:names:tc:SAML:2.0:protocol",
+					KeyDescriptors: []saml.KeyDescriptor{{
+						Use: "signing",
+						KeyInfo: saml.KeyInfo{X509Data: saml.X509Data{X509Certificates: []saml.X509Certificate{
+							{Data: base64.StdEncoding.EncodeToString(idp.cert.Certificate[0])},
+						}}},
+					}},
+				},
+			},
+			SingleSignOnServices: []saml.Endpoint{
+				{Binding: saml.HTTPRedirectBinding, Location: "https://idp.example.com/sso"},
+			},
+		}},
+	}
+	data, err := xml.Marshal(entity)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// responseOptions Отличия ответа от действительного
+type responseOptions struct {
+	inResponseTo string
+	audience     string
+	issueInstant time.Time
+	notOnOrAfter time.Time
+	// signer Ключевая пара, которой подписывается утверждение, nil - ключ провайдера
+	signer   *tls.Certificate
+	unsigned bool
+}
+
+// response Ответ провайдера в кодировке привязки HTTP-POST с подписанным утверждением
+func (idp *testIdP) response(t *testing.T, opts responseOptions) string {
+	t.Helper()
+	now := saml.TimeNow()
+	if opts.inResponseTo == "" {
+		opts.inResponseTo = testRequestID
+	}
+	if opts.audience == "" {
+		opts.audience = testSPEntityID
+	}
+	if opts.issueInstant.IsZero() {
+		opts.issueInstant = now
+	}
+	if opts.notOnOrAfter.IsZero() {
+		opts.notOnOrAfter = now.Add(5 * time.Minute)
+	}
+
+	assertion := &saml.Assertion{
+		ID:           "id-assertion-1",
+		IssueInstant: opts.issueInstant,
+		Version:      "2.0",
+		Issuer:       saml.Issuer{Format: "urn:oasis:names:tc:SAML:2.0:nameid-format:entity", Value: testIdPEntityID},
+		Subject: &saml.Subject{
+			NameID: &saml.NameID{Format: string(saml.EmailAddressNameIDFormat), Value: "Alice@Example.com"},
+			SubjectConfirmations: []saml.SubjectConfirmation{{
+				Method: "urn:oasis:names:tc:SAML:2.0:cm:bearer",
+				SubjectConfirmationData: &saml.SubjectConfirmationData{
+					InResponseTo: opts.inResponseTo,
+					NotOnOrAfter: opts.notOnOrAfter,
+					Recipient:    testACSURL,
+				},
+			}},
+		},
+		Conditions: &saml.Conditions{
+			NotBefore:            opts.issueInstant.Add(-time.Minute),
+			NotOnOrAfter:         opts.notOnOrAfter,
+			AudienceRestrictions: []saml.AudienceRestriction{{Audience: saml.Audience{Value: opts.audience}}},
+		},
+		AttributeStatements: []saml.AttributeStatement{{
+			Attributes: []saml.Attribute{{
+				Name:   "groups",
+				Values: []saml.AttributeValue{{Type: "xs:string", Value: "staff"}, {Type: "xs:string", Value: "admins"}},
+			}},
+		}},
+	}
+	assertionEl := assertion.Element()
+	if !opts.unsigned {
+		signer := &idp.cert
+		if opts.signer != nil {
+			signer = opts.signer
+		}
+		signingContext := dsig.NewDefaultSigningContext(dsig.TLSCertKeyStore(*signer))
+		signingContext.Canonicalizer = dsig.MakeC14N10ExclusiveCanonicalizerWithPrefixList("")
+		if err := signingContext.SetSignatureMethod(dsig.RSASHA256SignatureMethod); err != nil {
+			t.Fatal(err)
+		}
+		signed, err := signingContext.SignEnveloped(assertionEl)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Подпись должна следовать за Issuer, поэтому утверждение собирается заново вместе с ней
+		assertion.Signature = signed.Child[len(signed.Child)-1].(*etree.Element)
+		assertionEl = assertion.Element()
+	}
+
+	response := &saml.Response{
+		ID:           "id-response-1",
+		InResponseTo: opts.inResponseTo,
+		Version:      "2.0",
+		IssueInstant: opts.issueInstant,
+		Destination:  testACSURL,
+		Issuer:       &saml.Issuer{Format: "urn:oasis:names:tc:SAML:2.0:nameid-format:entity", Value: testIdPEntityID},
+		Status:       saml.Status{StatusCode: saml.StatusCode{Value: saml.StatusSuccess}},
+	}
+	responseEl := response.Element()
+	responseEl.AddChild(assertionEl)
+
+	doc := etree.NewDocument()
+	doc.SetRoot(responseEl)
+	data, err := doc.WriteToBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.StdEncoding.EncodeToString(data)
+}
+
+// newTestProviders SP с ключевой парой и метаданными провайдера во временных файлах
+func newTestProviders(t *testing.T, idp *testIdP, allowIdPInitiated bool) *ServiceProviders {
+	t.Helper()
+	dir := t.TempDir()
+	sp := newKeyPair(t, "auth.example.com")
+	certPath := filepath.Join(dir, "sp.crt")
+	keyPath := filepath.Join(dir, "sp.key")
+	metadataPath := filepath.Join(dir, "idp.xml")
+	writeFile(t, certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: sp.Certificate[0]}))
+	writeFile(t, keyPath, pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(sp.PrivateKey.(*rsa.PrivateKey))}))
+	writeFile(t, metadataPath, idp.metadata(t))
+
+	return New(map[string]config.SAMLIdentityProviderConfig{
+		"test": {
+			EntityID:          testSPEntityID,
+			MetadataURL:       testSPEntityID,
+			ACSURL:            testACSURL,
+			CertPath:          certPath,
+			KeyPath:           keyPath,
+			IdPMetadataPath:   metadataPath,
+			AllowIdPInitiated: allowIdPInitiated,
+			RoleAttribute:     "groups",
+			AttributeRoles:    map[string]int{"admins": domain.RoleAdmin, "staff": domain.RoleModerator},
+		},
+	}, nil)
+}
+
+func writeFile(t *testing.T, path string, data []byte) {
+	t.Helper()
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParseResponse(t *testing.T) {
+	ctx := context.Background()
+	idp := newTestIdP(t)
+	providers := newTestProviders(t, idp, false)
+	other := newKeyPair(t, "attacker.example.com")
+
+	tests := []struct {
+		name       string
+		opts       responseOptions
+		requestIDs []string
+		wantErr    error
+	}{
+		{name: "valid", requestIDs: []string{testRequestID}},
+		{name: "bad signature", opts: responseOptions{signer: &other}, requestIDs: []string{testRequestID}, wantErr: ErrAssertionNotValid},
+		{name: "unsigned", opts: responseOptions{unsigned: true}, requestIDs: []string{testRequestID}, wantErr: ErrAssertionNotValid},
+		{name: "wrong audience", opts: responseOptions{audience: "https://other.example.com"}, requestIDs: []string{testRequestID}, wantErr: ErrAssertionNotValid},
+		{name: "expired", opts: responseOptions{
+			issueInstant: saml.TimeNow().Add(-time.Hour),
+			notOnOrAfter: saml.TimeNow().Add(-30 * time.Minute),
+		}, requestIDs: []string{testRequestID}, wantErr: ErrAssertionNotValid},
+		{name: "InResponseTo mismatch", opts: responseOptions{inResponseTo: "id-other"}, requestIDs: []string{testRequestID}, wantErr: ErrAssertionNotValid},
+		{name: "idp initiated not allowed", wantErr: ErrIdPInitiatedDenied},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertion, err := providers.ParseResponse(ctx, "test", idp.response(t, tt.opts), tt.requestIDs)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("ParseResponse() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseResponse() error = %v", err)
+			}
+			if assertion.ID != "id-assertion-1" || assertion.Identity.Email != "alice@example.com" ||
+				assertion.Identity.Provider != domain.SAMLIdentityPrefix+"test" {
+				t.Fatalf("ParseResponse() assertion = %+v, identity = %+v", assertion, assertion.Identity)
+			}
+			if assertion.Role == nil || *assertion.Role != domain.RoleAdmin {
+				t.Fatalf("ParseResponse() role = %v, want %d", assertion.Role, domain.RoleAdmin)
+			}
+			if !assertion.ExpiresAt.After(saml.TimeNow()) {
+				t.Fatalf("ParseResponse() expires at %s", assertion.ExpiresAt)
+			}
+		})
+	}
+}
+
+func TestParseResponseIdPInitiated(t *testing.T) {
+	idp := newTestIdP(t)
+	providers := newTestProviders(t, idp, true)
+
+	_, err := providers.ParseResponse(context.Background(), "test", idp.response(t, responseOptions{}), nil)
+	if err != nil {
+		t.Fatalf("ParseResponse() error = %v", err)
+	}
+}
+
+func TestIdPNotExist(t *testing.T) {
+	_, err := New(nil, nil).ParseResponse(context.Background(), "unknown", "", []string{testRequestID})
+	if !errors.Is(err, ErrIdPNotExist) {
+		t.Fatalf("ParseResponse() error = %v, want %v", err, ErrIdPNotExist)
+	}
+}
@@ -0,0 +1,376 @@
+package repository
+
+import (
+	"auth/internal/domain"
+	"auth/internal/repository/postgres"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"strings"
+)
+
+type SCIMRepos struct{}
+
+func NewSCIMRepos() SCIM {
+	return &SCIMRepos{}
+}
+
+func (m *SCIMRepos) AddSCIMToken(ctx context.Context, tx pgx.Tx, token *domain.SCIMToken) (int, error) {
+	row := tx.QueryRow(ctx, `INSERT INTO scim_token (org_id, name, prefix, token_hash, created_by)
+		VALUES ($1, $2, $3, $4, $5) RETURNING id, created_at`,
+		token.OrgID, token.Name, token.Prefix, token.TokenHash, token.CreatedBy)
+	err := row.Scan(&token.ID, &token.CreatedAt)
+	if err != nil {
+		return 0, fmt.Errorf("AddSCIMToken/Scan: %w", err)
+	}
+	return token.ID, nil
+}
+
+const scimTokenColumns = `id, org_id, name, prefix, created_by, last_used_at, revoked_at, created_at, token_hash`
+
+func (m *SCIMRepos) SCIMTokens(ctx context.Context, tx pgx.Tx, orgID int) ([]*domain.SCIMToken, error) {
+	rows, err := tx.Query(ctx, `SELECT `+scimTokenColumns+` FROM scim_token
+		WHERE org_id = $1 AND revoked_at IS NULL ORDER BY created_at DESC, id DESC`, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("SCIMTokens/Query: %w", err)
+	}
+	defer rows.Close()
+
+	tokens := make([]*domain.SCIMToken, 0)
+	for rows.Next() {
+		token, err := scanSCIMToken(rows)
+		if err != nil {
+			return nil, fmt.Errorf("SCIMTokens/Scan: %w", err)
+		}
+		tokens = append(tokens, token)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("SCIMTokens/Rows: %w", err)
+	}
+	return tokens, nil
+}
+
+func (m *SCIMRepos) SCIMTokenByHash(ctx context.Context, tx pgx.Tx, tokenHash string) (*domain.SCIMToken, error) {
+	row := tx.QueryRow(ctx, `SELECT `+scimTokenColumns+` FROM scim_token WHERE token_hash = $1`, tokenHash)
+
+	token, err := scanSCIMToken(row)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, TokenNotExist
+		}
+		return nil, fmt.Errorf("SCIMTokenByHash/Scan: %w", err)
+	}
+	return token, nil
+}
+
+func (m *SCIMRepos) TouchSCIMToken(ctx context.Context, tx pgx.Tx, id int) error {
+	// Время использования обновляется не чаще раза в минуту, чтобы не писать в базу на каждый запрос
+	_, err := tx.Exec(ctx, `UPDATE scim_token SET last_used_at = now()
+		WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < now() - interval '1 minute')`, id)
+	if err != nil {
+		return fmt.Errorf("TouchSCIMToken/Exec: %w", err)
+	}
+	return nil
+}
+
+func (m *SCIMRepos) RevokeSCIMToken(ctx context.Context, tx pgx.Tx, orgID int, id int) error {
+	tag, err := tx.Exec(ctx, `UPDATE scim_token SET revoked_at = now()
+		WHERE id = $1 AND org_id = $2 AND revoked_at IS NULL`, id, orgID)
+	if err != nil {
+		return fmt.Errorf("RevokeSCIMToken/Exec: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return TokenNotExist
+	}
+	return nil
+}
+
+func (m *SCIMRepos) SCIMMembers(ctx context.Context, tx pgx.Tx, orgID int, query *domain.SCIMQuery) ([]*domain.OrgMember, int, error) {
+	where := `m.org_id = $1`
+	args := []any{orgID}
+	if query.Filter != nil {
+		switch query.Filter.Attribute {
+		case "userName":
+			where += ` AND u.email = $2`
+			args = append(args, strings.ToLower(query.Filter.Value))
+		case "externalId":
+			where += ` AND m.external_id = $2`
+			args = append(args, query.Filter.Value)
+		}
+	}
+	from := ` FROM organization_member m JOIN "user" u ON u.id = m.user_id WHERE ` + where
+
+	var total int
+	err := tx.QueryRow(ctx, `SELECT count(*)`+from, args...).Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("SCIMMembers/Count: %w", err)
+	}
+	rows, err := tx.Query(ctx, `SELECT `+memberColumns+from+
+		fmt.Sprintf(` ORDER BY m.user_id OFFSET %d LIMIT %d`, query.StartIndex-1, query.Count), args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("SCIMMembers/Query: %w", err)
+	}
+	defer rows.Close()
+
+	members := make([]*domain.OrgMember, 0)
+	for rows.Next() {
+		member, err := scanMember(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("SCIMMembers/Scan: %w", err)
+		}
+		members = append(members, member)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("SCIMMembers/Rows: %w", err)
+	}
+	return members, total, nil
+}
+
+func (m *SCIMRepos) ProvisionMember(ctx context.Context, tx pgx.Tx, member *domain.OrgMember) error {
+	_, err := tx.Exec(ctx, `INSERT INTO organization_member (org_id, user_id, role, active, external_id, provisioned)
+		VALUES ($1, $2, $3, $4, $5, $6)`,
+		member.OrgID, member.UserID, member.Role, member.Active, member.ExternalID, member.Provisioned)
+	if err != nil {
+		if err, ok := err.(*pgconn.PgError); ok && err.Code == postgres.ErrUniqueViolation {
+			return MemberAlreadyExist
+		}
+		return fmt.Errorf("ProvisionMember/Exec: %w", err)
+	}
+	return nil
+}
+
+func (m *SCIMRepos) UpdateSCIMMember(ctx context.Context, tx pgx.Tx, orgID int, userID int, externalID *string, active bool) error {
+	tag, err := tx.Exec(ctx, `UPDATE organization_member SET external_id = $1, active = $2, updated_at = now()
+		WHERE org_id = $3 AND user_id = $4`, externalID, active, orgID, userID)
+	if err != nil {
+		if err, ok := err.(*pgconn.PgError); ok && err.Code == postgres.ErrUniqueViolation {
+			return MemberAlreadyExist
+		}
+		return fmt.Errorf("UpdateSCIMMember/Exec: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return MemberNotExist
+	}
+	return nil
+}
+
+func (m *SCIMRepos) AddGroup(ctx context.Context, tx pgx.Tx, group *domain.OrgGroup) (int, error) {
+	row := tx.QueryRow(ctx, `INSERT INTO scim_group (org_id, display_name, external_id) VALUES ($1, $2, $3)
+		RETURNING id, created_at`, group.OrgID, group.DisplayName, group.ExternalID)
+	err := row.Scan(&group.ID, &group.CreatedAt)
+	if err != nil {
+		if err, ok := err.(*pgconn.PgError); ok && err.Code == postgres.ErrUniqueViolation {
+			return 0, GroupAlreadyExist
+		}
+		return 0, fmt.Errorf("AddGroup/Scan: %w", err)
+	}
+	return group.ID, nil
+}
+
+const groupColumns = `id, org_id, display_name, external_id, created_at, updated_at`
+
+func (m *SCIMRepos) Group(ctx context.Context, tx pgx.Tx, orgID int, id int) (*domain.OrgGroup, error) {
+	row := tx.QueryRow(ctx, `SELECT `+groupColumns+` FROM scim_group WHERE id = $1 AND org_id = $2`, id, orgID)
+
+	group, err := scanGroup(row)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, GroupNotExist
+		}
+		return nil, fmt.Errorf("Group/Scan: %w", err)
+	}
+	err = m.loadGroupMembers(ctx, tx, []*domain.OrgGroup{group})
+	if err != nil {
+		return nil, err
+	}
+	return group, nil
+}
+
+func (m *SCIMRepos) Groups(ctx context.Context, tx pgx.Tx, orgID int, query *domain.SCIMQuery) ([]*domain.OrgGroup, int, error) {
+	where := `org_id = $1`
+	args := []any{orgID}
+	if query.Filter != nil {
+		switch query.Filter.Attribute {
+		case "displayName":
+			where += ` AND display_name = $2`
+			args = append(args, query.Filter.Value)
+		case "externalId":
+			where += ` AND external_id = $2`
+			args = append(args, query.Filter.Value)
+		}
+	}
+
+	var total int
+	err := tx.QueryRow(ctx, `SELECT count(*) FROM scim_group WHERE `+where, args...).Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("Groups/Count: %w", err)
+	}
+	rows, err := tx.Query(ctx, `SELECT `+groupColumns+` FROM scim_group WHERE `+where+
+		fmt.Sprintf(` ORDER BY id OFFSET %d LIMIT %d`, query.StartIndex-1, query.Count), args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("Groups/Query: %w", err)
+	}
+	defer rows.Close()
+
+	groups := make([]*domain.OrgGroup, 0)
+	for rows.Next() {
+		group, err := scanGroup(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("Groups/Scan: %w", err)
+		}
+		groups = append(groups, group)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("Groups/Rows: %w", err)
+	}
+	err = m.loadGroupMembers(ctx, tx, groups)
+	if err != nil {
+		return nil, 0, err
+	}
+	return groups, total, nil
+}
+
+func (m *SCIMRepos) UpdateGroup(ctx context.Context, tx pgx.Tx, group *domain.OrgGroup) error {
+	tag, err := tx.Exec(ctx, `UPDATE scim_group SET display_name = $1, external_id = $2, updated_at = now()
+		WHERE id = $3 AND org_id = $4`, group.DisplayName, group.ExternalID, group.ID, group.OrgID)
+	if err != nil {
+		if err, ok := err.(*pgconn.PgError); ok && err.Code == postgres.ErrUniqueViolation {
+			return GroupAlreadyExist
+		}
+		return fmt.Errorf("UpdateGroup/Exec: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return GroupNotExist
+	}
+	return nil
+}
+
+func (m *SCIMRepos) SetGroupMembers(ctx context.Context, tx pgx.Tx, orgID int, groupID int, userIDs []int) error {
+	_, err := tx.Exec(ctx, `DELETE FROM scim_group_member WHERE group_id = $1 AND NOT (user_id = ANY($2))`, groupID, userIDs)
+	if err != nil {
+		return fmt.Errorf("SetGroupMembers/Delete: %w", err)
+	}
+	_, err = tx.Exec(ctx, `INSERT INTO scim_group_member (group_id, org_id, user_id)
+		SELECT $1, $2, unnest($3::INTEGER[]) ON CONFLICT DO NOTHING`, groupID, orgID, userIDs)
+	if err != nil {
+		// Участником группы может быть только участник организации
+		if err, ok := err.(*pgconn.PgError); ok && err.Code == postgres.ErrForeignKeyViolation {
+			return MemberNotExist
+		}
+		return fmt.Errorf("SetGroupMembers/Insert: %w", err)
+	}
+	return nil
+}
+
+func (m *SCIMRepos) DeleteGroup(ctx context.Context, tx pgx.Tx, orgID int, id int) error {
+	tag, err := tx.Exec(ctx, `DELETE FROM scim_group WHERE id = $1 AND org_id = $2`, id, orgID)
+	if err != nil {
+		return fmt.Errorf("DeleteGroup/Exec: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return GroupNotExist
+	}
+	return nil
+}
+
+func (m *SCIMRepos) MemberGroups(ctx context.Context, tx pgx.Tx, orgID int, userID int) ([]*domain.OrgGroup, error) {
+	rows, err := tx.Query(ctx, `SELECT g.id, g.org_id, g.display_name, g.external_id, g.created_at, g.updated_at
+		FROM scim_group g JOIN scim_group_member gm ON gm.group_id = g.id
+		WHERE gm.org_id = $1 AND gm.user_id = $2 ORDER BY g.id`, orgID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("MemberGroups/Query: %w", err)
+	}
+	defer rows.Close()
+
+	groups := make([]*domain.OrgGroup, 0)
+	for rows.Next() {
+		group, err := scanGroup(rows)
+		if err != nil {
+			return nil, fmt.Errorf("MemberGroups/Scan: %w", err)
+		}
+		groups = append(groups, group)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("MemberGroups/Rows: %w", err)
+	}
+	return groups, nil
+}
+
+// loadGroupMembers Загружает участников групп одним запросом
+func (m *SCIMRepos) loadGroupMembers(ctx context.Context, tx pgx.Tx, groups []*domain.OrgGroup) error {
+	if len(groups) == 0 {
+		return nil
+	}
+	ids := make([]int, 0, len(groups))
+	byID := make(map[int]*domain.OrgGroup, len(groups))
+	for _, group := range groups {
+		group.Members = make([]*domain.OrgMember, 0)
+		ids = append(ids, group.ID)
+		byID[group.ID] = group
+	}
+
+	rows, err := tx.Query(ctx, `SELECT gm.group_id, `+memberColumns+`
+		FROM scim_group_member gm
+		JOIN organization_member m ON m.org_id = gm.org_id AND m.user_id = gm.user_id
+		JOIN "user" u ON u.id = m.user_id
+		WHERE gm.group_id = ANY($1) ORDER BY gm.group_id, m.user_id`, ids)
+	if err != nil {
+		return fmt.Errorf("loadGroupMembers/Query: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var groupID int
+		var member domain.OrgMember
+		err = rows.Scan(
+			&groupID,
+			&member.OrgID,
+			&member.UserID,
+			&member.Email,
+			&member.Role,
+			&member.Active,
+			&member.ExternalID,
+			&member.Provisioned,
+			&member.CreatedAt,
+			&member.UpdatedAt,
+		)
+		if err != nil {
+			return fmt.Errorf("loadGroupMembers/Scan: %w", err)
+		}
+		byID[groupID].Members = append(byID[groupID].Members, &member)
+	}
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("loadGroupMembers/Rows: %w", err)
+	}
+	return nil
+}
+
+func scanSCIMToken(row pgx.Row) (*domain.SCIMToken, error) {
+	var token domain.SCIMToken
+	err := row.Scan(
+		&token.ID,
+		&token.OrgID,
+		&token.Name,
+		&token.Prefix,
+		&token.CreatedBy,
+		&token.LastUsedAt,
+		&token.RevokedAt,
+		&token.CreatedAt,
+		&token.TokenHash,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+func scanGroup(row pgx.Row) (*domain.OrgGroup, error) {
+	var group domain.OrgGroup
+	err := row.Scan(&group.ID, &group.OrgID, &group.DisplayName, &group.ExternalID, &group.CreatedAt, &group.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &group, nil
+}
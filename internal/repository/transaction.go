@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"auth/internal/tracing"
 	"context"
 	"github.com/go-redis/redis"
 	"github.com/jackc/pgx/v5"
@@ -29,7 +30,7 @@ func (r *TransactionRepos) Rollback(ctx context.Context, tx pgx.Tx) error {
 }
 
 func (r *TransactionRepos) RedisTx(ctx context.Context) (redis.Pipeliner, error) {
-	clientCtx := r.redisClient.WithContext(ctx)
+	clientCtx := tracing.Redis(ctx, r.redisClient)
 	return clientCtx.TxPipeline(), nil
 }
 func (r *TransactionRepos) RedisRollback(ctx context.Context, tx redis.Pipeliner) error {
@@ -44,5 +45,15 @@ func (r *TransactionRepos) RedisCommit(tx redis.Pipeliner) error {
 }
 
 func (r *TransactionRepos) RedisClient(ctx context.Context) *redis.Client {
-	return r.redisClient.WithContext(ctx)
+	return tracing.Redis(ctx, r.redisClient)
+}
+
+// Ping Проверяет соединение с Postgres
+func (r *TransactionRepos) Ping(ctx context.Context) error {
+	return r.pool.Ping(ctx)
+}
+
+// RedisPing Проверяет соединение с Redis
+func (r *TransactionRepos) RedisPing(ctx context.Context) error {
+	return tracing.Redis(ctx, r.redisClient).Ping().Err()
 }
@@ -4,10 +4,14 @@ import (
 	"auth/internal/domain"
 	"auth/internal/repository/postgres"
 	"context"
+	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
+	"strings"
+	"time"
 )
 
 type UserRepos struct {
@@ -31,40 +35,245 @@ func (m *UserRepos) AddUser(ctx context.Context, tx pgx.Tx, email string, role d
 	return id, nil
 }
 
+const userColumns = `id, email, pass_hash, role, verified, locked, created_at, updated_at, deleted_at`
+
 func (m *UserRepos) UserById(ctx context.Context, tx pgx.Tx, id int) (*domain.UserFromDB, error) {
-	row := tx.QueryRow(ctx, `SELECT email, pass_hash, role FROM "user" WHERE id = $1`, id)
+	row := tx.QueryRow(ctx, `SELECT `+userColumns+` FROM "user" WHERE id = $1`, id)
 
-	var user domain.UserFromDB
-	err := row.Scan(
-		&user.Email,
-		&user.HashPassword,
-		&user.Role,
-	)
+	user, err := scanUser(row)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, UserNotExist
 		}
 		return nil, fmt.Errorf("UserById/Scan: %w", err)
 	}
-	user.ID = id
-	return &user, nil
+	return user, nil
 }
 
 func (m *UserRepos) UserByEmail(ctx context.Context, tx pgx.Tx, email string) (*domain.UserFromDB, error) {
-	row := tx.QueryRow(ctx, `SELECT id, pass_hash, role FROM "user" WHERE email = $1`, email)
+	row := tx.QueryRow(ctx, `SELECT `+userColumns+` FROM "user" WHERE lower(email) = lower($1) AND deleted_at IS NULL`, email)
+
+	user, err := scanUser(row)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, UserNotExist
+		}
+		return nil, fmt.Errorf("UserByEmail/Scan: %w", err)
+	}
+	return user, nil
+}
+
+func (m *UserRepos) UsersByIDs(ctx context.Context, tx pgx.Tx, ids []int) ([]*domain.UserFromDB, error) {
+	rows, err := tx.Query(ctx, `SELECT `+userColumns+` FROM "user" WHERE id = ANY($1) ORDER BY id`, ids)
+	if err != nil {
+		return nil, fmt.Errorf("UsersByIDs/Query: %w", err)
+	}
+	defer rows.Close()
+
+	users := make([]*domain.UserFromDB, 0, len(ids))
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, fmt.Errorf("UsersByIDs/Scan: %w", err)
+		}
+		users = append(users, user)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("UsersByIDs/Rows: %w", err)
+	}
+	return users, nil
+}
+
+func (m *UserRepos) Users(ctx context.Context, tx pgx.Tx, filter *domain.UserFilter) ([]*domain.UserFromDB, string, error) {
+	var where []string
+	var args []any
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if filter.Role != nil {
+		where = append(where, "role = "+arg(*filter.Role))
+	}
+	if filter.CreatedFrom != nil {
+		where = append(where, "created_at >= "+arg(*filter.CreatedFrom))
+	}
+	if filter.CreatedTo != nil {
+		where = append(where, "created_at < "+arg(*filter.CreatedTo))
+	}
+	if filter.Verified != nil {
+		where = append(where, "verified = "+arg(*filter.Verified))
+	}
+	if filter.Locked != nil {
+		where = append(where, "locked = "+arg(*filter.Locked))
+	}
+	if filter.Deleted != nil {
+		if *filter.Deleted {
+			where = append(where, "deleted_at IS NOT NULL")
+		} else {
+			where = append(where, "deleted_at IS NULL")
+		}
+	}
+	if filter.EmailPrefix != "" {
+		where = append(where, "lower(email) LIKE "+arg(likeEscaper.Replace(strings.ToLower(filter.EmailPrefix))+"%"))
+	}
+
+	op, order := ">", "ASC"
+	if filter.Desc {
+		op, order = "<", "DESC"
+	}
+	if filter.Cursor != "" {
+		c, err := decodeUserCursor(filter.Cursor)
+		if err != nil || c.Sort != filter.Sort {
+			return nil, "", CursorNotValid
+		}
+		switch filter.Sort {
+		case domain.UserSortEmail:
+			where = append(where, fmt.Sprintf("(email, id) %s (%s, %s)", op, arg(c.Email), arg(c.ID)))
+		case domain.UserSortCreatedAt:
+			where = append(where, fmt.Sprintf("(created_at, id) %s (%s, %s)", op, arg(c.CreatedAt), arg(c.ID)))
+		default:
+			where = append(where, fmt.Sprintf("id %s %s", op, arg(c.ID)))
+		}
+	}
+
+	query := `SELECT ` + userColumns + ` FROM "user"`
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	if filter.Sort == domain.UserSortID {
+		query += fmt.Sprintf(" ORDER BY id %s", order)
+	} else {
+		query += fmt.Sprintf(" ORDER BY %s %s, id %s", filter.Sort, order, order)
+	}
+	// Запрашиваем на одну запись больше, чтобы понять, есть ли следующая страница
+	query += " LIMIT " + arg(filter.Limit+1)
+
+	rows, err := tx.Query(ctx, query, args...)
+	if err != nil {
+		return nil, "", fmt.Errorf("Users/Query: %w", err)
+	}
+	defer rows.Close()
 
+	users := make([]*domain.UserFromDB, 0, filter.Limit+1)
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, "", fmt.Errorf("Users/Scan: %w", err)
+		}
+		users = append(users, user)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, "", fmt.Errorf("Users/Rows: %w", err)
+	}
+
+	var next string
+	if len(users) > filter.Limit {
+		users = users[:filter.Limit]
+		last := users[len(users)-1]
+		next, err = encodeUserCursor(userCursor{
+			Sort:      filter.Sort,
+			ID:        last.ID,
+			Email:     last.Email,
+			CreatedAt: last.CreatedAt,
+		})
+		if err != nil {
+			return nil, "", fmt.Errorf("Users/encodeUserCursor: %w", err)
+		}
+	}
+	return users, next, nil
+}
+
+func (m *UserRepos) UpdateUser(ctx context.Context, tx pgx.Tx, id int, update *domain.UserUpdate) error {
+	var args []any
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	sets := []string{"updated_at = now()"}
+	if update.Email != nil {
+		sets = append(sets, "email = "+arg(*update.Email))
+	}
+	if update.Verified != nil {
+		sets = append(sets, "verified = "+arg(*update.Verified))
+	}
+	if update.Locked != nil {
+		sets = append(sets, "locked = "+arg(*update.Locked))
+	}
+
+	tag, err := tx.Exec(ctx, `UPDATE "user" SET `+strings.Join(sets, ", ")+
+		` WHERE id = `+arg(id)+` AND deleted_at IS NULL`, args...)
+	if err != nil {
+		if err, ok := err.(*pgconn.PgError); ok && err.Code == postgres.ErrUniqueViolation {
+			return UserAlreadyExist
+		}
+		return fmt.Errorf("UpdateUser/Exec: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return UserNotExist
+	}
+	return nil
+}
+
+func (m *UserRepos) DeleteUser(ctx context.Context, tx pgx.Tx, id int) error {
+	tag, err := tx.Exec(ctx, `UPDATE "user" SET deleted_at = now(), updated_at = now() WHERE id = $1 AND deleted_at IS NULL`, id)
+	if err != nil {
+		return fmt.Errorf("DeleteUser/Exec: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return UserNotExist
+	}
+	return nil
+}
+
+func scanUser(row pgx.Row) (*domain.UserFromDB, error) {
 	var user domain.UserFromDB
 	err := row.Scan(
 		&user.ID,
+		&user.Email,
 		&user.HashPassword,
 		&user.Role,
+		&user.Verified,
+		&user.Locked,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+		&user.DeletedAt,
 	)
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, UserNotExist
-		}
-		return nil, fmt.Errorf("UserById/Scan: %w", err)
+		return nil, err
 	}
-	user.Email = email
 	return &user, nil
 }
+
+// likeEscaper Экранирует спецсимволы шаблона LIKE
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// userCursor Позиция последней записи страницы для keyset-пагинации
+type userCursor struct {
+	Sort      domain.UserSort `json:"s"`
+	ID        int             `json:"i"`
+	Email     string          `json:"e,omitempty"`
+	CreatedAt time.Time       `json:"c,omitempty"`
+}
+
+func encodeUserCursor(c userCursor) (string, error) {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func decodeUserCursor(s string) (*userCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	var c userCursor
+	err = json.Unmarshal(data, &c)
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
@@ -0,0 +1,91 @@
+// Package requestid Идентификатор запроса, по которому ответ клиенту связывается с записями в логе
+package requestid
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"github.com/Linkify-Company/common_utils/errify"
+	"github.com/Linkify-Company/common_utils/logger"
+)
+
+// Header Заголовок, в котором идентификатор приходит от клиента или прокси и возвращается в ответе
+const Header = "X-Request-ID"
+
+// maxLength Более длинный идентификатор от клиента заменяется новым, чтобы не раздувать лог
+const maxLength = 128
+
+type ctxKey struct{}
+
+// FromHeader Идентификатор из заголовка, если он допустим, иначе новый
+func FromHeader(value string) string {
+	if valid(value) {
+		return value
+	}
+	return New()
+}
+
+// New Случайный идентификатор из 32 шестнадцатеричных символов
+func New() string {
+	b := make([]byte, 16)
+	// Ошибка crypto/rand означает неисправность системы, а идентификатор нужен только для поиска в логе
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, ctxKey{}, id)
+}
+
+// FromContext Идентификатор запроса или пустая строка вне запроса
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(ctxKey{}).(string)
+	return id
+}
+
+// Logger Логгер, который добавляет идентификатор запроса из ctx к каждой записи. Вне запроса возвращает log
+func Logger(ctx context.Context, log logger.Logger) logger.Logger {
+	id := FromContext(ctx)
+	if id == "" {
+		return log
+	}
+	return &requestLogger{log: log, id: id}
+}
+
+// requestLogger Логгер не встраивается, чтобы новый метод logger.Logger не попадал в лог без идентификатора:
+// без его обертки тип перестанет удовлетворять интерфейсу
+type requestLogger struct {
+	log logger.Logger
+	id  string
+}
+
+var _ logger.Logger = (*requestLogger)(nil)
+
+// Error Идентификатор становится началом цепочки мест ошибки
+func (l *requestLogger) Error(err errify.IError) {
+	l.log.Error(err.JoinLoc("request_id=" + l.id))
+}
+
+func (l *requestLogger) Infof(format string, args ...any) {
+	l.log.Infof("request_id=%s "+format, append([]any{l.id}, args...)...)
+}
+
+func (l *requestLogger) Debugf(format string, args ...any) {
+	l.log.Debugf("request_id=%s "+format, append([]any{l.id}, args...)...)
+}
+
+// valid Допускаются латинские буквы, цифры и -_.: , чтобы значение от клиента нельзя было использовать для подделки записей лога
+func valid(value string) bool {
+	if value == "" || len(value) > maxLength {
+		return false
+	}
+	for _, c := range value {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '_', c == '.', c == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
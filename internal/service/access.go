@@ -0,0 +1,350 @@
+package service
+
+import (
+	"auth/internal/domain"
+	"auth/internal/repository"
+	"auth/internal/tracing"
+	"context"
+	"errors"
+	"github.com/Linkify-Company/common_utils/errify"
+	"github.com/Linkify-Company/common_utils/logger"
+	"github.com/jackc/pgx/v5"
+	"slices"
+	"strings"
+)
+
+type AccessService struct {
+	log         logger.Logger
+	transaction repository.Transaction
+	userRepos   repository.User
+	roleRepos   repository.Role
+	accessRepos repository.Access
+	authRepos   repository.Auth
+}
+
+func NewAccessService(
+	log logger.Logger,
+	transaction repository.Transaction,
+	userRepos repository.User,
+	roleRepos repository.Role,
+	accessRepos repository.Access,
+	authRepos repository.Auth,
+) Access {
+	return &AccessService{
+		log:         log,
+		transaction: transaction,
+		userRepos:   userRepos,
+		roleRepos:   roleRepos,
+		accessRepos: accessRepos,
+		authRepos:   authRepos,
+	}
+}
+
+func (m *AccessService) Permissions(ctx context.Context) ([]*domain.PermissionInfo, errify.IError) {
+	ctx, span := tracing.Start(ctx, "AccessService.Permissions")
+	defer span.End()
+
+	tx, err := m.transaction.Begin(ctx)
+	if err != nil {
+		return nil, errify.NewInternalServerError(err.Error(), "Permissions/Begin")
+	}
+	defer m.transaction.Rollback(ctx, tx)
+
+	permissions, err := m.accessRepos.Permissions(ctx, tx)
+	if err != nil {
+		return nil, errify.NewInternalServerError(err.Error(), "Permissions/Permissions")
+	}
+	return permissions, nil
+}
+
+func (m *AccessService) AddPermission(ctx context.Context, permission *domain.PermissionInfo) (int, errify.IError) {
+	ctx, span := tracing.Start(ctx, "AccessService.AddPermission")
+	defer span.End()
+
+	tx, err := m.transaction.Begin(ctx)
+	if err != nil {
+		return 0, errify.NewInternalServerError(err.Error(), "AddPermission/Begin")
+	}
+	defer m.transaction.Rollback(ctx, tx)
+
+	id, err := m.accessRepos.AddPermission(ctx, tx, permission)
+	if err != nil {
+		if errors.Is(err, repository.PermissionAlreadyExist) {
+			return 0, errify.NewBadRequestError(err.Error(), PermissionIsAlreadyExist.Error(), "AddPermission/AddPermission")
+		}
+		return 0, errify.NewInternalServerError(err.Error(), "AddPermission/AddPermission")
+	}
+	err = tx.Commit(ctx)
+	if err != nil {
+		return 0, errify.NewInternalServerError(err.Error(), "AddPermission/Commit")
+	}
+	return id, nil
+}
+
+func (m *AccessService) AccessRoles(ctx context.Context) ([]*domain.AccessRole, errify.IError) {
+	ctx, span := tracing.Start(ctx, "AccessService.AccessRoles")
+	defer span.End()
+
+	tx, err := m.transaction.Begin(ctx)
+	if err != nil {
+		return nil, errify.NewInternalServerError(err.Error(), "AccessRoles/Begin")
+	}
+	defer m.transaction.Rollback(ctx, tx)
+
+	roles, err := m.accessRepos.AccessRoles(ctx, tx)
+	if err != nil {
+		return nil, errify.NewInternalServerError(err.Error(), "AccessRoles/AccessRoles")
+	}
+	return roles, nil
+}
+
+func (m *AccessService) AddAccessRole(ctx context.Context, role *domain.AccessRole) (*domain.AccessRole, errify.IError) {
+	ctx, span := tracing.Start(ctx, "AccessService.AddAccessRole")
+	defer span.End()
+
+	tx, err := m.transaction.Begin(ctx)
+	if err != nil {
+		return nil, errify.NewInternalServerError(err.Error(), "AddAccessRole/Begin")
+	}
+	defer m.transaction.Rollback(ctx, tx)
+
+	id, err := m.accessRepos.AddAccessRole(ctx, tx, role)
+	if err != nil {
+		if errors.Is(err, repository.RoleAlreadyExist) {
+			return nil, errify.NewBadRequestError(err.Error(), RoleIsAlreadyExist.Error(), "AddAccessRole/AddAccessRole")
+		}
+		return nil, errify.NewInternalServerError(err.Error(), "AddAccessRole/AddAccessRole")
+	}
+	e := m.setPermissions(ctx, tx, id, role.Permissions)
+	if e != nil {
+		return nil, e.JoinLoc("AddAccessRole")
+	}
+	created, err := m.accessRepos.AccessRole(ctx, tx, id)
+	if err != nil {
+		return nil, errify.NewInternalServerError(err.Error(), "AddAccessRole/AccessRole")
+	}
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, errify.NewInternalServerError(err.Error(), "AddAccessRole/Commit")
+	}
+	return created, nil
+}
+
+func (m *AccessService) SetAccessRolePermissions(ctx context.Context, id int, permissions []domain.Permission) (*domain.AccessRole, errify.IError) {
+	ctx, span := tracing.Start(ctx, "AccessService.SetAccessRolePermissions")
+	defer span.End()
+
+	tx, err := m.transaction.Begin(ctx)
+	if err != nil {
+		return nil, errify.NewInternalServerError(err.Error(), "SetAccessRolePermissions/Begin")
+	}
+	defer m.transaction.Rollback(ctx, tx)
+
+	role, err := m.accessRepos.AccessRole(ctx, tx, id)
+	if err != nil {
+		if errors.Is(err, repository.RoleNotExist) {
+			return nil, errify.NewBadRequestError(err.Error(), RoleNotExist.Error(), "SetAccessRolePermissions/AccessRole")
+		}
+		return nil, errify.NewInternalServerError(err.Error(), "SetAccessRolePermissions/AccessRole")
+	}
+	if role.Builtin {
+		return nil, errify.NewBadRequestError(ErrRoleBuiltin.Error(), ErrRoleBuiltin.Error(), "SetAccessRolePermissions/Builtin")
+	}
+	e := m.setPermissions(ctx, tx, id, permissions)
+	if e != nil {
+		return nil, e.JoinLoc("SetAccessRolePermissions")
+	}
+	role, err = m.accessRepos.AccessRole(ctx, tx, id)
+	if err != nil {
+		return nil, errify.NewInternalServerError(err.Error(), "SetAccessRolePermissions/AccessRole")
+	}
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, errify.NewInternalServerError(err.Error(), "SetAccessRolePermissions/Commit")
+	}
+	return role, nil
+}
+
+func (m *AccessService) DeleteAccessRole(ctx context.Context, id int) errify.IError {
+	ctx, span := tracing.Start(ctx, "AccessService.DeleteAccessRole")
+	defer span.End()
+
+	tx, err := m.transaction.Begin(ctx)
+	if err != nil {
+		return errify.NewInternalServerError(err.Error(), "DeleteAccessRole/Begin")
+	}
+	defer m.transaction.Rollback(ctx, tx)
+
+	err = m.accessRepos.DeleteAccessRole(ctx, tx, id)
+	if err != nil {
+		if errors.Is(err, repository.RoleNotExist) {
+			return errify.NewBadRequestError(err.Error(), RoleNotExist.Error(), "DeleteAccessRole/DeleteAccessRole")
+		}
+		if errors.Is(err, repository.RoleBuiltin) {
+			return errify.NewBadRequestError(err.Error(), ErrRoleBuiltin.Error(), "DeleteAccessRole/DeleteAccessRole")
+		}
+		return errify.NewInternalServerError(err.Error(), "DeleteAccessRole/DeleteAccessRole")
+	}
+	err = tx.Commit(ctx)
+	if err != nil {
+		return errify.NewInternalServerError(err.Error(), "DeleteAccessRole/Commit")
+	}
+	return nil
+}
+
+func (m *AccessService) UserAccessRoles(ctx context.Context, userID int) ([]*domain.AccessRole, errify.IError) {
+	ctx, span := tracing.Start(ctx, "AccessService.UserAccessRoles")
+	defer span.End()
+
+	tx, err := m.transaction.Begin(ctx)
+	if err != nil {
+		return nil, errify.NewInternalServerError(err.Error(), "UserAccessRoles/Begin")
+	}
+	defer m.transaction.Rollback(ctx, tx)
+
+	roles, err := m.accessRepos.UserAccessRoles(ctx, tx, userID)
+	if err != nil {
+		return nil, errify.NewInternalServerError(err.Error(), "UserAccessRoles/UserAccessRoles")
+	}
+	return roles, nil
+}
+
+func (m *AccessService) SetUserAccessRoles(ctx context.Context, userID int, roleIDs []int, changedBy *int) ([]*domain.AccessRole, errify.IError) {
+	ctx, span := tracing.Start(ctx, "AccessService.SetUserAccessRoles")
+	defer span.End()
+
+	tx, err := m.transaction.Begin(ctx)
+	if err != nil {
+		return nil, errify.NewInternalServerError(err.Error(), "SetUserAccessRoles/Begin")
+	}
+	defer m.transaction.Rollback(ctx, tx)
+
+	user, err := m.userRepos.UserById(ctx, tx, userID)
+	if err != nil {
+		if errors.Is(err, repository.UserNotExist) {
+			return nil, errify.NewBadRequestError(err.Error(), UserNotExist.Error(), "SetUserAccessRoles/UserById")
+		}
+		return nil, errify.NewInternalServerError(err.Error(), "SetUserAccessRoles/UserById")
+	}
+	if user.DeletedAt != nil {
+		return nil, errify.NewBadRequestError(repository.UserNotExist.Error(), UserNotExist.Error(), "SetUserAccessRoles/DeletedAt")
+	}
+	allRoles, err := m.accessRepos.AccessRoles(ctx, tx)
+	if err != nil {
+		return nil, errify.NewInternalServerError(err.Error(), "SetUserAccessRoles/AccessRoles")
+	}
+	newRole, names := legacyRole(user.Role, allRoles, roleIDs)
+
+	// Колонка "user".role меняется до набора ролей: триггер синхронизации удаляет прежнюю встроенную роль,
+	// а набор ниже заменяет роли пользователя целиком
+	if newRole != user.Role {
+		err = m.roleRepos.SetRole(ctx, tx, userID, newRole)
+		if err != nil {
+			return nil, errify.NewInternalServerError(err.Error(), "SetUserAccessRoles/SetRole")
+		}
+	}
+	err = m.accessRepos.SetUserAccessRoles(ctx, tx, userID, roleIDs)
+	if err != nil {
+		if errors.Is(err, repository.RoleNotExist) {
+			return nil, errify.NewBadRequestError(err.Error(), RoleNotExist.Error(), "SetUserAccessRoles/SetUserAccessRoles")
+		}
+		return nil, errify.NewInternalServerError(err.Error(), "SetUserAccessRoles/SetUserAccessRoles")
+	}
+	_, err = m.roleRepos.AddRoleChange(ctx, tx, &domain.RoleChange{
+		UserID:    userID,
+		OldRole:   user.Role,
+		NewRole:   newRole,
+		ChangedBy: changedBy,
+		Reason:    "access roles: " + strings.Join(names, ", "),
+	})
+	if err != nil {
+		return nil, errify.NewInternalServerError(err.Error(), "SetUserAccessRoles/AddRoleChange")
+	}
+	roles, err := m.accessRepos.UserAccessRoles(ctx, tx, userID)
+	if err != nil {
+		return nil, errify.NewInternalServerError(err.Error(), "SetUserAccessRoles/UserAccessRoles")
+	}
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, errify.NewInternalServerError(err.Error(), "SetUserAccessRoles/Commit")
+	}
+
+	// Роль зашита в токен, поэтому при ее изменении сессии пользователя завершаются, как в SetRole
+	if newRole != user.Role {
+		e := m.removeSessions(ctx, userID)
+		if e != nil {
+			return nil, e.JoinLoc("SetUserAccessRoles")
+		}
+	}
+	return roles, nil
+}
+
+// legacyRole Роль для колонки "user".role: самая старшая из назначаемых встроенных ролей.
+// Без встроенных ролей прежняя роль сохраняется. Также возвращает имена назначаемых ролей для аудита
+func legacyRole(current domain.Role, roles []*domain.AccessRole, roleIDs []int) (domain.Role, []string) {
+	role, found := current, false
+	names := make([]string, 0, len(roleIDs))
+	for _, r := range roles {
+		if !slices.Contains(roleIDs, r.ID) {
+			continue
+		}
+		names = append(names, r.Name)
+		if !r.Builtin {
+			continue
+		}
+		builtin, err := domain.ParseRole(r.Name)
+		if err == nil && (!found || builtin < role) {
+			role, found = builtin, true
+		}
+	}
+	return role, names
+}
+
+func (m *AccessService) removeSessions(ctx context.Context, id int) errify.IError {
+	redisTx, err := m.transaction.RedisTx(ctx)
+	if err != nil {
+		return errify.NewInternalServerError(err.Error(), "removeSessions/RedisTx")
+	}
+	defer m.transaction.RedisRollback(ctx, redisTx)
+
+	err = m.authRepos.RemoveUserAuthorization(ctx, redisTx, id)
+	if err != nil {
+		return errify.NewInternalServerError(err.Error(), "removeSessions/RemoveUserAuthorization")
+	}
+	err = m.transaction.RedisCommit(redisTx)
+	if err != nil {
+		return errify.NewInternalServerError(err.Error(), "removeSessions/RedisCommit")
+	}
+	return nil
+}
+
+func (m *AccessService) UserPermissions(ctx context.Context, userID int) ([]domain.Permission, errify.IError) {
+	ctx, span := tracing.Start(ctx, "AccessService.UserPermissions")
+	defer span.End()
+
+	tx, err := m.transaction.Begin(ctx)
+	if err != nil {
+		return nil, errify.NewInternalServerError(err.Error(), "UserPermissions/Begin")
+	}
+	defer m.transaction.Rollback(ctx, tx)
+
+	permissions, err := m.accessRepos.UserPermissions(ctx, tx, userID)
+	if err != nil {
+		return nil, errify.NewInternalServerError(err.Error(), "UserPermissions/UserPermissions")
+	}
+	return permissions, nil
+}
+
+func (m *AccessService) setPermissions(ctx context.Context, tx pgx.Tx, roleID int, permissions []domain.Permission) errify.IError {
+	err := m.accessRepos.SetAccessRolePermissions(ctx, tx, roleID, permissions)
+	if err != nil {
+		if errors.Is(err, repository.PermissionNotExist) {
+			return errify.NewBadRequestError(err.Error(), PermissionNotExist.Error(), "setPermissions/SetAccessRolePermissions")
+		}
+		if errors.Is(err, repository.RoleNotExist) {
+			return errify.NewBadRequestError(err.Error(), RoleNotExist.Error(), "setPermissions/SetAccessRolePermissions")
+		}
+		return errify.NewInternalServerError(err.Error(), "setPermissions/SetAccessRolePermissions")
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package service
+
+import (
+	"auth/internal/domain"
+	"slices"
+	"testing"
+)
+
+func TestLegacyRole(t *testing.T) {
+	roles := []*domain.AccessRole{
+		{ID: 1, Name: "admin", Builtin: true},
+		{ID: 2, Name: "moderator", Builtin: true},
+		{ID: 3, Name: "user", Builtin: true},
+		{ID: 4, Name: "billing"},
+	}
+	tests := []struct {
+		name      string
+		current   domain.Role
+		roleIDs   []int
+		wantRole  domain.Role
+		wantNames []string
+	}{
+		{name: "most senior builtin", current: domain.RoleUser, roleIDs: []int{3, 2, 4}, wantRole: domain.RoleModerator, wantNames: []string{"moderator", "user", "billing"}},
+		{name: "demotion", current: domain.RoleAdmin, roleIDs: []int{3}, wantRole: domain.RoleUser, wantNames: []string{"user"}},
+		{name: "no builtin keeps role", current: domain.RoleModerator, roleIDs: []int{4}, wantRole: domain.RoleModerator, wantNames: []string{"billing"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			role, names := legacyRole(tt.current, roles, tt.roleIDs)
+			if role != tt.wantRole || !slices.Equal(names, tt.wantNames) {
+				t.Fatalf("legacyRole() = %d, %v, want %d, %v", role, names, tt.wantRole, tt.wantNames)
+			}
+		})
+	}
+}
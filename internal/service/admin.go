@@ -0,0 +1,248 @@
+package service
+
+import (
+	"auth/internal/domain"
+	"auth/internal/repository"
+	"auth/internal/tracing"
+	"context"
+	"errors"
+	"github.com/Linkify-Company/common_utils/errify"
+	"github.com/Linkify-Company/common_utils/logger"
+)
+
+type AdminService struct {
+	log         logger.Logger
+	transaction repository.Transaction
+	userRepos   repository.User
+	roleRepos   repository.Role
+	authRepos   repository.Auth
+}
+
+func NewAdminService(
+	log logger.Logger,
+	transaction repository.Transaction,
+	userRepos repository.User,
+	roleRepos repository.Role,
+	authRepos repository.Auth,
+) Admin {
+	return &AdminService{
+		log:         log,
+		transaction: transaction,
+		userRepos:   userRepos,
+		roleRepos:   roleRepos,
+		authRepos:   authRepos,
+	}
+}
+
+func (m *AdminService) Users(ctx context.Context, filter *domain.UserFilter) (*domain.UserPage, errify.IError) {
+	ctx, span := tracing.Start(ctx, "AdminService.Users")
+	defer span.End()
+
+	tx, err := m.transaction.Begin(ctx)
+	if err != nil {
+		return nil, errify.NewInternalServerError(err.Error(), "Users/Begin")
+	}
+	defer m.transaction.Rollback(ctx, tx)
+
+	users, next, err := m.userRepos.Users(ctx, tx, filter)
+	if err != nil {
+		if errors.Is(err, repository.CursorNotValid) {
+			return nil, errify.NewBadRequestError(err.Error(), ErrCursorNotValid.Error(), "Users/Users")
+		}
+		return nil, errify.NewInternalServerError(err.Error(), "Users/Users")
+	}
+
+	page := &domain.UserPage{
+		Users:      make([]*domain.UserInfo, 0, len(users)),
+		NextCursor: next,
+	}
+	for _, user := range users {
+		page.Users = append(page.Users, domain.NewUserInfo(user))
+	}
+	return page, nil
+}
+
+func (m *AdminService) UpdateUser(ctx context.Context, id int, update *domain.UserUpdate) (*domain.UserInfo, errify.IError) {
+	ctx, span := tracing.Start(ctx, "AdminService.UpdateUser")
+	defer span.End()
+
+	tx, err := m.transaction.Begin(ctx)
+	if err != nil {
+		return nil, errify.NewInternalServerError(err.Error(), "UpdateUser/Begin")
+	}
+	defer m.transaction.Rollback(ctx, tx)
+
+	err = m.userRepos.UpdateUser(ctx, tx, id, update)
+	if err != nil {
+		if errors.Is(err, repository.UserNotExist) {
+			return nil, errify.NewBadRequestError(err.Error(), UserNotExist.Error(), "UpdateUser/UpdateUser")
+		}
+		if errors.Is(err, repository.UserAlreadyExist) {
+			return nil, errify.NewBadRequestError(err.Error(), UserIsAlreadyExist.Error(), "UpdateUser/UpdateUser")
+		}
+		return nil, errify.NewInternalServerError(err.Error(), "UpdateUser/UpdateUser")
+	}
+	user, err := m.userRepos.UserById(ctx, tx, id)
+	if err != nil {
+		return nil, errify.NewInternalServerError(err.Error(), "UpdateUser/UserById")
+	}
+
+	// Email зашит в токен, а заблокированный пользователь не должен оставаться авторизованным
+	if update.Email != nil || user.Locked {
+		e := m.removeSessions(ctx, id)
+		if e != nil {
+			return nil, e.JoinLoc("UpdateUser")
+		}
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, errify.NewInternalServerError(err.Error(), "UpdateUser/Commit")
+	}
+	return domain.NewUserInfo(user), nil
+}
+
+func (m *AdminService) DeleteUser(ctx context.Context, id int) errify.IError {
+	ctx, span := tracing.Start(ctx, "AdminService.DeleteUser")
+	defer span.End()
+
+	tx, err := m.transaction.Begin(ctx)
+	if err != nil {
+		return errify.NewInternalServerError(err.Error(), "DeleteUser/Begin")
+	}
+	defer m.transaction.Rollback(ctx, tx)
+
+	err = m.userRepos.DeleteUser(ctx, tx, id)
+	if err != nil {
+		if errors.Is(err, repository.UserNotExist) {
+			return errify.NewBadRequestError(err.Error(), UserNotExist.Error(), "DeleteUser/DeleteUser")
+		}
+		return errify.NewInternalServerError(err.Error(), "DeleteUser/DeleteUser")
+	}
+	err = tx.Commit(ctx)
+	if err != nil {
+		return errify.NewInternalServerError(err.Error(), "DeleteUser/Commit")
+	}
+
+	// Сессии завершаются только после фиксации, чтобы неудачное удаление не разлогинило пользователя
+	e := m.removeSessions(ctx, id)
+	if e != nil {
+		return e.JoinLoc("DeleteUser")
+	}
+	return nil
+}
+
+func (m *AdminService) RevokeSessions(ctx context.Context, id int) errify.IError {
+	ctx, span := tracing.Start(ctx, "AdminService.RevokeSessions")
+	defer span.End()
+
+	tx, err := m.transaction.Begin(ctx)
+	if err != nil {
+		return errify.NewInternalServerError(err.Error(), "RevokeSessions/Begin")
+	}
+	defer m.transaction.Rollback(ctx, tx)
+
+	_, err = m.userRepos.UserById(ctx, tx, id)
+	if err != nil {
+		if errors.Is(err, repository.UserNotExist) {
+			return errify.NewBadRequestError(err.Error(), UserNotExist.Error(), "RevokeSessions/UserById")
+		}
+		return errify.NewInternalServerError(err.Error(), "RevokeSessions/UserById")
+	}
+	e := m.removeSessions(ctx, id)
+	if e != nil {
+		return e.JoinLoc("RevokeSessions")
+	}
+	return nil
+}
+
+func (m *AdminService) SetRole(ctx context.Context, id int, update *domain.RoleUpdate, changedBy *int) (*domain.UserInfo, errify.IError) {
+	ctx, span := tracing.Start(ctx, "AdminService.SetRole")
+	defer span.End()
+
+	tx, err := m.transaction.Begin(ctx)
+	if err != nil {
+		return nil, errify.NewInternalServerError(err.Error(), "SetRole/Begin")
+	}
+	defer m.transaction.Rollback(ctx, tx)
+
+	user, err := m.userRepos.UserById(ctx, tx, id)
+	if err != nil {
+		if errors.Is(err, repository.UserNotExist) {
+			return nil, errify.NewBadRequestError(err.Error(), UserNotExist.Error(), "SetRole/UserById")
+		}
+		return nil, errify.NewInternalServerError(err.Error(), "SetRole/UserById")
+	}
+	if user.DeletedAt != nil {
+		return nil, errify.NewBadRequestError(repository.UserNotExist.Error(), UserNotExist.Error(), "SetRole/DeletedAt")
+	}
+	if user.Role == *update.Role {
+		return domain.NewUserInfo(user), nil
+	}
+
+	err = m.roleRepos.SetRole(ctx, tx, id, *update.Role)
+	if err != nil {
+		if errors.Is(err, repository.UserNotExist) {
+			return nil, errify.NewBadRequestError(err.Error(), UserNotExist.Error(), "SetRole/SetRole")
+		}
+		return nil, errify.NewInternalServerError(err.Error(), "SetRole/SetRole")
+	}
+	_, err = m.roleRepos.AddRoleChange(ctx, tx, &domain.RoleChange{
+		UserID:    id,
+		OldRole:   user.Role,
+		NewRole:   *update.Role,
+		ChangedBy: changedBy,
+		Reason:    update.Reason,
+	})
+	if err != nil {
+		return nil, errify.NewInternalServerError(err.Error(), "SetRole/AddRoleChange")
+	}
+
+	// Роль зашита в токен, поэтому сессии пользователя завершаются, чтобы изменение вступило в силу сразу
+	e := m.removeSessions(ctx, id)
+	if e != nil {
+		return nil, e.JoinLoc("SetRole")
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, errify.NewInternalServerError(err.Error(), "SetRole/Commit")
+	}
+	user.Role = *update.Role
+	return domain.NewUserInfo(user), nil
+}
+
+func (m *AdminService) RoleChanges(ctx context.Context, id int) ([]*domain.RoleChange, errify.IError) {
+	ctx, span := tracing.Start(ctx, "AdminService.RoleChanges")
+	defer span.End()
+
+	tx, err := m.transaction.Begin(ctx)
+	if err != nil {
+		return nil, errify.NewInternalServerError(err.Error(), "RoleChanges/Begin")
+	}
+	defer m.transaction.Rollback(ctx, tx)
+
+	changes, err := m.roleRepos.RoleChanges(ctx, tx, id)
+	if err != nil {
+		return nil, errify.NewInternalServerError(err.Error(), "RoleChanges/RoleChanges")
+	}
+	return changes, nil
+}
+
+func (m *AdminService) removeSessions(ctx context.Context, id int) errify.IError {
+	redisTx, err := m.transaction.RedisTx(ctx)
+	if err != nil {
+		return errify.NewInternalServerError(err.Error(), "removeSessions/RedisTx")
+	}
+	defer m.transaction.RedisRollback(ctx, redisTx)
+
+	err = m.authRepos.RemoveUserAuthorization(ctx, redisTx, id)
+	if err != nil {
+		return errify.NewInternalServerError(err.Error(), "removeSessions/RemoveUserAuthorization")
+	}
+	err = m.transaction.RedisCommit(redisTx)
+	if err != nil {
+		return errify.NewInternalServerError(err.Error(), "removeSessions/RedisCommit")
+	}
+	return nil
+}
@@ -3,21 +3,35 @@ package service
 import (
 	"auth/internal/config"
 	"auth/internal/domain"
+	"auth/internal/metrics"
 	"auth/internal/repository"
+	"auth/internal/tracing"
 	"context"
 	"errors"
 	"github.com/Linkify-Company/common_utils/errify"
 	"github.com/Linkify-Company/common_utils/logger"
 	"golang.org/x/crypto/bcrypt"
 	"os"
+	"time"
 )
 
+// dummyHash Хеш, с которым сравнивается пароль несуществующего пользователя, чтобы время ответа не выдавало наличие почты
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("dummy-password"), bcrypt.DefaultCost)
+
 type AuthService struct {
 	log         logger.Logger
 	transaction repository.Transaction
 	userRepos   repository.User
 	authRepos   repository.Auth
+	accessRepos repository.Access
+	tokenRepos  repository.PersonalToken
 	emailRepos  repository.Email
+	securityCfg config.SecurityConfig
+
+	local     authBackend
+	directory authBackend
+	// directoryRepos Определяет, проверяется ли почта в каталоге
+	directoryRepos repository.Directory
 }
 
 func NewAuthService(
@@ -25,34 +39,68 @@ func NewAuthService(
 	transaction repository.Transaction,
 	userRepos repository.User,
 	authRepos repository.Auth,
+	accessRepos repository.Access,
+	tokenRepos repository.PersonalToken,
 	emailRepos repository.Email,
+	roleRepos repository.Role,
+	directoryRepos repository.Directory,
+	securityCfg config.SecurityConfig,
 ) Auth {
 	return &AuthService{
 		log:         log,
 		transaction: transaction,
 		userRepos:   userRepos,
 		authRepos:   authRepos,
+		accessRepos: accessRepos,
+		tokenRepos:  tokenRepos,
 		emailRepos:  emailRepos,
+		securityCfg: securityCfg,
+		local: &localBackend{
+			userRepos:   userRepos,
+			securityCfg: securityCfg,
+		},
+		directory: &directoryBackend{
+			userRepos:      userRepos,
+			roleRepos:      roleRepos,
+			directoryRepos: directoryRepos,
+		},
+		directoryRepos: directoryRepos,
+	}
+}
+
+// backend Способ проверки учетных данных: домены каталогов проверяются только в каталоге
+func (m *AuthService) backend(email string) authBackend {
+	if m.directoryRepos.HasDirectory(email) {
+		return m.directory
 	}
+	return m.local
 }
 
 func (m *AuthService) Authorization(ctx context.Context, auth *domain.Auth, cfg config.TokenConfig) (string, errify.IError) {
+	ctx, span := tracing.Start(ctx, "AuthService.Authorization")
+	defer span.End()
+
+	token, err := m.authorization(ctx, auth, cfg)
+	metrics.Login(metrics.LoginPassword, loginFailureReason(err))
+	return token, err
+}
+
+func (m *AuthService) authorization(ctx context.Context, auth *domain.Auth, cfg config.TokenConfig) (string, errify.IError) {
 	tx, err := m.transaction.Begin(ctx)
 	if err != nil {
 		return "", errify.NewInternalServerError(err.Error(), "Authorization/Begin")
 	}
 	defer m.transaction.Rollback(ctx, tx)
 
-	user, err := m.userRepos.UserByEmail(ctx, tx, auth.Email)
-	if err != nil {
-		if errors.Is(err, repository.UserNotExist) {
-			return "", errify.NewBadRequestError(err.Error(), UserNotExist.Error(), "Authorization/UserByEmail")
-		}
-		return "", errify.NewInternalServerError(err.Error(), "Authorization/UserByEmail")
+	user, e := m.backend(auth.Email).authenticate(ctx, tx, auth)
+	if e != nil {
+		return "", e.JoinLoc("Authorization")
 	}
-	err = bcrypt.CompareHashAndPassword(user.HashPassword, []byte(auth.Password))
-	if err != nil {
-		return "", errify.NewBadRequestError(err.Error(), ErrInvalidCredentials.Error(), "Authorization/CompareHashAndPassword")
+	if user.DeletedAt != nil {
+		return "", errify.NewBadRequestError(ErrInvalidCredentials.Error(), ErrInvalidCredentials.Error(), "Authorization/DeletedAt")
+	}
+	if user.Locked {
+		return "", errify.NewBadRequestError(ErrUserLocked.Error(), ErrUserLocked.Error(), "Authorization/Locked")
 	}
 
 	redisTx, err := m.transaction.RedisTx(ctx)
@@ -69,6 +117,11 @@ func (m *AuthService) Authorization(ctx context.Context, auth *domain.Auth, cfg
 	if err != nil {
 		return "", errify.NewInternalServerError(err.Error(), "Authorization/authRepos.Authorization")
 	}
+	// Фиксирует пользователя, созданного или обновленного при входе через каталог
+	err = tx.Commit(ctx)
+	if err != nil {
+		return "", errify.NewInternalServerError(err.Error(), "Authorization/Commit")
+	}
 	err = m.transaction.RedisCommit(redisTx)
 	if err != nil {
 		return "", errify.NewInternalServerError(err.Error(), "Authorization/RedisCommit")
@@ -77,6 +130,16 @@ func (m *AuthService) Authorization(ctx context.Context, auth *domain.Auth, cfg
 }
 
 func (m *AuthService) CheckAuthorization(ctx context.Context, accessToken string) (*domain.AuthData, errify.IError) {
+	ctx, span := tracing.Start(ctx, "AuthService.CheckAuthorization")
+	defer span.End()
+
+	if domain.IsPersonalToken(accessToken) {
+		user, err := m.checkPersonalToken(ctx, accessToken)
+		if err != nil {
+			return nil, err.JoinLoc("CheckAuthorization")
+		}
+		return user, nil
+	}
 	redisClient := m.transaction.RedisClient(ctx)
 
 	user, err := m.authRepos.CheckAuthorization(ctx, redisClient, accessToken)
@@ -92,10 +155,78 @@ func (m *AuthService) CheckAuthorization(ctx context.Context, accessToken string
 		}
 		return nil, errify.NewInternalServerError(err.Error(), "CheckAuthorization/CheckAuthorization")
 	}
+
+	// Разрешения не зашиваются в токен, чтобы изменения ролей применялись сразу
+	tx, err := m.transaction.Begin(ctx)
+	if err != nil {
+		return nil, errify.NewInternalServerError(err.Error(), "CheckAuthorization/Begin")
+	}
+	defer m.transaction.Rollback(ctx, tx)
+
+	user.Permissions, err = m.accessRepos.UserPermissions(ctx, tx, user.ID)
+	if err != nil {
+		return nil, errify.NewInternalServerError(err.Error(), "CheckAuthorization/UserPermissions")
+	}
 	return user, nil
 }
 
+// checkPersonalToken Проверяет персональный токен. Разрешения токена ограничены его областями и текущими разрешениями пользователя
+func (m *AuthService) checkPersonalToken(ctx context.Context, value string) (*domain.AuthData, errify.IError) {
+	tx, err := m.transaction.Begin(ctx)
+	if err != nil {
+		return nil, errify.NewInternalServerError(err.Error(), "checkPersonalToken/Begin")
+	}
+	defer m.transaction.Rollback(ctx, tx)
+
+	token, err := m.tokenRepos.PersonalTokenByHash(ctx, tx, hashToken(value))
+	if err != nil {
+		if errors.Is(err, repository.TokenNotExist) {
+			return nil, errify.NewUnauthorizedError(err.Error(), ErrInvalidCredentials.Error(), "checkPersonalToken/PersonalTokenByHash")
+		}
+		return nil, errify.NewInternalServerError(err.Error(), "checkPersonalToken/PersonalTokenByHash")
+	}
+	if !token.Active(time.Now()) {
+		return nil, errify.NewUnauthorizedError(repository.TokenNotValid.Error(), ErrInvalidCredentials.Error(), "checkPersonalToken/Active")
+	}
+	user, err := m.userRepos.UserById(ctx, tx, token.UserID)
+	if err != nil {
+		return nil, errify.NewInternalServerError(err.Error(), "checkPersonalToken/UserById")
+	}
+	if user.DeletedAt != nil || user.Locked {
+		return nil, errify.NewUnauthorizedError(ErrUserLocked.Error(), ErrInvalidCredentials.Error(), "checkPersonalToken/Locked")
+	}
+	permissions, err := m.accessRepos.UserPermissions(ctx, tx, user.ID)
+	if err != nil {
+		return nil, errify.NewInternalServerError(err.Error(), "checkPersonalToken/UserPermissions")
+	}
+	err = m.tokenRepos.TouchPersonalToken(ctx, tx, token.ID)
+	if err != nil {
+		return nil, errify.NewInternalServerError(err.Error(), "checkPersonalToken/TouchPersonalToken")
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, errify.NewInternalServerError(err.Error(), "checkPersonalToken/Commit")
+	}
+	return &domain.AuthData{
+		ID:              user.ID,
+		Email:           user.Email,
+		Role:            user.Role,
+		Permissions:     token.Permissions(permissions),
+		PersonalTokenID: token.ID,
+	}, nil
+}
+
 func (m *AuthService) RenewAuthorization(ctx context.Context, accessToken string, cfg config.TokenConfig) (string, errify.IError) {
+	ctx, span := tracing.Start(ctx, "AuthService.RenewAuthorization")
+	defer span.End()
+
+	token, err := m.renewAuthorization(ctx, accessToken, cfg)
+	metrics.Renewal(err == nil)
+	return token, err
+}
+
+func (m *AuthService) renewAuthorization(ctx context.Context, accessToken string, cfg config.TokenConfig) (string, errify.IError) {
 	redisClient := m.transaction.RedisClient(ctx)
 
 	token, err := m.authRepos.RenewalAuthorization(ctx, redisClient, accessToken, cfg.RefreshTTL, os.Getenv(config.Secret))
@@ -111,7 +242,25 @@ func (m *AuthService) RenewAuthorization(ctx context.Context, accessToken string
 	return token, nil
 }
 
+func (m *AuthService) ActiveSessions(ctx context.Context) (int64, errify.IError) {
+	ctx, span := tracing.Start(ctx, "AuthService.ActiveSessions")
+	defer span.End()
+
+	count, err := m.authRepos.CountAuthorizations(ctx, m.transaction.RedisClient(ctx))
+	if err != nil {
+		return 0, errify.NewInternalServerError(err.Error(), "ActiveSessions/CountAuthorizations")
+	}
+	return count, nil
+}
+
 func (m *AuthService) Logout(ctx context.Context, accessToken string) errify.IError {
+	ctx, span := tracing.Start(ctx, "AuthService.Logout")
+	defer span.End()
+
+	// Персональный токен не является сессией, он отзывается отдельно
+	if domain.IsPersonalToken(accessToken) {
+		return errify.NewBadRequestError(ErrPersonalToken.Error(), ErrPersonalToken.Error(), "Logout/IsPersonalToken")
+	}
 	tx, err := m.transaction.RedisTx(ctx)
 	if err != nil {
 		return errify.NewInternalServerError(err.Error(), "Logout/RedisTx")
@@ -128,3 +277,17 @@ func (m *AuthService) Logout(ctx context.Context, accessToken string) errify.IEr
 	}
 	return nil
 }
+
+// loginFailureReason Причина отказа во входе для метрик, пустая строка при успешном входе
+func loginFailureReason(err errify.IError) string {
+	if err == nil {
+		return ""
+	}
+	if _, ok := err.(*errify.InternalServerError); ok {
+		return metrics.ReasonError
+	}
+	if err.Error() == ErrUserLocked.Error() {
+		return metrics.ReasonLocked
+	}
+	return metrics.ReasonInvalidCredentials
+}
@@ -0,0 +1,101 @@
+package service
+
+import (
+	"auth/internal/config"
+	"auth/internal/domain"
+	"auth/internal/repository"
+	"context"
+	"errors"
+	"github.com/Linkify-Company/common_utils/errify"
+	"github.com/jackc/pgx/v5"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// authBackend Способ проверки учетных данных при входе по паролю
+type authBackend interface {
+	// authenticate Проверяет учетные данные и возвращает локального пользователя
+	authenticate(ctx context.Context, tx pgx.Tx, auth *domain.Auth) (*domain.UserFromDB, errify.IError)
+}
+
+// localBackend Проверка пароля, хранящегося в базе
+type localBackend struct {
+	userRepos   repository.User
+	securityCfg config.SecurityConfig
+}
+
+func (b *localBackend) authenticate(ctx context.Context, tx pgx.Tx, auth *domain.Auth) (*domain.UserFromDB, errify.IError) {
+	user, err := b.userRepos.UserByEmail(ctx, tx, auth.Email)
+	if err != nil {
+		if errors.Is(err, repository.UserNotExist) {
+			if b.securityCfg.EnumerationSafe {
+				_ = bcrypt.CompareHashAndPassword(dummyHash, []byte(auth.Password))
+				return nil, errify.NewBadRequestError(err.Error(), ErrInvalidCredentials.Error(), "localBackend/UserByEmail")
+			}
+			return nil, errify.NewBadRequestError(err.Error(), UserNotExist.Error(), "localBackend/UserByEmail")
+		}
+		return nil, errify.NewInternalServerError(err.Error(), "localBackend/UserByEmail")
+	}
+	err = bcrypt.CompareHashAndPassword(user.HashPassword, []byte(auth.Password))
+	if err != nil {
+		return nil, errify.NewBadRequestError(err.Error(), ErrInvalidCredentials.Error(), "localBackend/CompareHashAndPassword")
+	}
+	return user, nil
+}
+
+// directoryBackend Проверка учетных данных в каталоге LDAP. Локальный пользователь создается при первом входе,
+// а его роль синхронизируется с группами каталога при каждом входе
+type directoryBackend struct {
+	userRepos      repository.User
+	roleRepos      repository.Role
+	directoryRepos repository.Directory
+}
+
+func (b *directoryBackend) authenticate(ctx context.Context, tx pgx.Tx, auth *domain.Auth) (*domain.UserFromDB, errify.IError) {
+	directoryUser, err := b.directoryRepos.Authenticate(ctx, auth.Email, auth.Password)
+	if err != nil {
+		if errors.Is(err, repository.DirectoryInvalidCredentials) {
+			return nil, errify.NewBadRequestError(err.Error(), ErrInvalidCredentials.Error(), "directoryBackend/Authenticate")
+		}
+		return nil, errify.NewInternalServerError(err.Error(), "directoryBackend/Authenticate")
+	}
+
+	user, err := b.userRepos.UserByEmail(ctx, tx, auth.Email)
+	if err != nil {
+		if !errors.Is(err, repository.UserNotExist) {
+			return nil, errify.NewInternalServerError(err.Error(), "directoryBackend/UserByEmail")
+		}
+		user, err = addExternalUser(ctx, tx, b.userRepos, directoryUser.Email, directoryUser.Role)
+		if err != nil {
+			return nil, errify.NewInternalServerError(err.Error(), "directoryBackend/addExternalUser")
+		}
+		return user, nil
+	}
+
+	err = syncRole(ctx, tx, b.roleRepos, user, directoryUser.Role, "directory group sync: "+directoryUser.Directory)
+	if err != nil {
+		return nil, errify.NewInternalServerError(err.Error(), "directoryBackend/syncRole")
+	}
+	return user, nil
+}
+
+// syncRole Устанавливает пользователю роль из внешнего источника и записывает изменение в аудит
+func syncRole(ctx context.Context, tx pgx.Tx, roleRepos repository.Role, user *domain.UserFromDB, role domain.Role, reason string) error {
+	if user.Role == role || user.DeletedAt != nil {
+		return nil
+	}
+	err := roleRepos.SetRole(ctx, tx, user.ID, role)
+	if err != nil {
+		return err
+	}
+	_, err = roleRepos.AddRoleChange(ctx, tx, &domain.RoleChange{
+		UserID:  user.ID,
+		OldRole: user.Role,
+		NewRole: role,
+		Reason:  reason,
+	})
+	if err != nil {
+		return err
+	}
+	user.Role = role
+	return nil
+}
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"auth/internal/domain"
+	"auth/internal/repository"
+	"context"
+	"errors"
+	"github.com/Linkify-Company/common_utils/errify"
+	"github.com/jackc/pgx/v5"
+	"testing"
+)
+
+// fakeDirectory Каталог, который отвечает заранее заданным пользователем или ошибкой
+type fakeDirectory struct {
+	user *domain.DirectoryUser
+	err  error
+}
+
+func (m *fakeDirectory) HasDirectory(string) bool {
+	return true
+}
+
+func (m *fakeDirectory) Authenticate(context.Context, string, string) (*domain.DirectoryUser, error) {
+	return m.user, m.err
+}
+
+// fakeUsers Пользователи в памяти. Не используемые входом методы репозитория остаются без реализации
+type fakeUsers struct {
+	repository.User
+	users map[string]*domain.UserFromDB
+}
+
+func (m *fakeUsers) UserByEmail(_ context.Context, _ pgx.Tx, email string) (*domain.UserFromDB, error) {
+	if user, ok := m.users[email]; ok {
+		return user, nil
+	}
+	return nil, repository.UserNotExist
+}
+
+func (m *fakeUsers) AddUser(_ context.Context, _ pgx.Tx, email string, role domain.Role, _ []byte) (int, error) {
+	id := len(m.users) + 1
+	m.users[email] = &domain.UserFromDB{ID: id, Email: email, Role: role}
+	return id, nil
+}
+
+func (m *fakeUsers) UserById(_ context.Context, _ pgx.Tx, id int) (*domain.UserFromDB, error) {
+	for _, user := range m.users {
+		if user.ID == id {
+			return user, nil
+		}
+	}
+	return nil, repository.UserNotExist
+}
+
+// fakeRoles Запоминает изменения ролей
+type fakeRoles struct {
+	repository.Role
+	changes []*domain.RoleChange
+}
+
+func (m *fakeRoles) SetRole(context.Context, pgx.Tx, int, domain.Role) error {
+	return nil
+}
+
+func (m *fakeRoles) AddRoleChange(_ context.Context, _ pgx.Tx, change *domain.RoleChange) (int, error) {
+	m.changes = append(m.changes, change)
+	return len(m.changes), nil
+}
+
+func TestDirectoryBackendErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		badRequest bool
+	}{
+		{name: "bind failure", err: repository.DirectoryInvalidCredentials, badRequest: true},
+		{name: "directory unavailable", err: errors.New("dial/DialURL: connection refused")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &directoryBackend{
+				userRepos:      &fakeUsers{users: map[string]*domain.UserFromDB{}},
+				roleRepos:      &fakeRoles{},
+				directoryRepos: &fakeDirectory{err: tt.err},
+			}
+			_, err := b.authenticate(context.Background(), nil, &domain.Auth{Email: "alice@example.com", Password: "pw"})
+			switch err.(type) {
+			case *errify.BadRequestError:
+				if !tt.badRequest {
+					t.Fatalf("authenticate() error = %v, want internal", err)
+				}
+			case *errify.InternalServerError:
+				if tt.badRequest {
+					t.Fatalf("authenticate() error = %v, want bad request", err)
+				}
+			default:
+				t.Fatalf("authenticate() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestDirectoryBackendRoles(t *testing.T) {
+	ctx := context.Background()
+	users := &fakeUsers{users: map[string]*domain.UserFromDB{}}
+	roles := &fakeRoles{}
+	directory := &fakeDirectory{user: &domain.DirectoryUser{Directory: "corp", Email: "alice@example.com", Role: domain.RoleModerator}}
+	b := &directoryBackend{userRepos: users, roleRepos: roles, directoryRepos: directory}
+	auth := &domain.Auth{Email: "alice@example.com", Password: "pw"}
+
+	user, err := b.authenticate(ctx, nil, auth)
+	if err != nil {
+		t.Fatalf("first authenticate() error = %v", err)
+	}
+	if user.Role != domain.RoleModerator || len(roles.changes) != 0 {
+		t.Fatalf("created user role = %d, role changes = %d", user.Role, len(roles.changes))
+	}
+
+	directory.user.Role = domain.RoleAdmin
+	user, err = b.authenticate(ctx, nil, auth)
+	if err != nil {
+		t.Fatalf("second authenticate() error = %v", err)
+	}
+	if user.Role != domain.RoleAdmin || len(roles.changes) != 1 ||
+		roles.changes[0].OldRole != domain.RoleModerator || roles.changes[0].NewRole != domain.RoleAdmin {
+		t.Fatalf("synced user role = %d, role changes = %+v", user.Role, roles.changes)
+	}
+
+	_, err = b.authenticate(ctx, nil, auth)
+	if err != nil || len(roles.changes) != 1 {
+		t.Fatalf("unchanged role was synced again: error = %v, role changes = %d", err, len(roles.changes))
+	}
+}
@@ -0,0 +1,152 @@
+package service
+
+import (
+	"auth/internal/domain"
+	"auth/internal/repository"
+	"auth/internal/requestid"
+	"auth/internal/tracing"
+	"context"
+	"errors"
+	"github.com/Linkify-Company/common_utils/errify"
+	"github.com/Linkify-Company/common_utils/logger"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// denyAll Политика, действующая, пока файл политик не загружен
+var denyAll = &domain.Policy{Default: domain.EffectDeny}
+
+type AuthzService struct {
+	log         logger.Logger
+	transaction repository.Transaction
+	userRepos   repository.User
+	policyRepos repository.Policy
+
+	policy  atomic.Pointer[domain.Policy]
+	mx      sync.Mutex
+	modTime time.Time
+}
+
+func NewAuthzService(
+	log logger.Logger,
+	transaction repository.Transaction,
+	userRepos repository.User,
+	policyRepos repository.Policy,
+) Authz {
+	m := &AuthzService{
+		log:         log,
+		transaction: transaction,
+		userRepos:   userRepos,
+		policyRepos: policyRepos,
+	}
+	m.policy.Store(denyAll)
+	return m
+}
+
+func (m *AuthzService) CheckAccess(ctx context.Context, subject *domain.AuthData, req *domain.AuthzRequest) (*domain.AuthzDecision, errify.IError) {
+	ctx, span := tracing.Start(ctx, "AuthzService.CheckAccess")
+	defer span.End()
+
+	attributes, err := m.subjectAttributes(ctx, subject)
+	if err != nil {
+		return nil, err.JoinLoc("CheckAccess")
+	}
+	req.Subject = attributes
+
+	policy := m.policy.Load()
+	if req.Policy != nil {
+		policy = req.Policy
+	}
+	decision := policy.Evaluate(req, req.Explain)
+	decision.DryRun = req.Policy != nil
+	return decision, nil
+}
+
+// subjectAttributes Атрибуты субъекта из токена и из базы. Блокировка берется из базы, так как токен выпущен до нее
+func (m *AuthzService) subjectAttributes(ctx context.Context, subject *domain.AuthData) (map[string]any, errify.IError) {
+	tx, err := m.transaction.Begin(ctx)
+	if err != nil {
+		return nil, errify.NewInternalServerError(err.Error(), "subjectAttributes/Begin")
+	}
+	defer m.transaction.Rollback(ctx, tx)
+
+	user, err := m.userRepos.UserById(ctx, tx, subject.ID)
+	if err != nil {
+		if errors.Is(err, repository.UserNotExist) {
+			return nil, errify.NewUnauthorizedError(err.Error(), ErrInvalidCredentials.Error(), "subjectAttributes/UserById")
+		}
+		return nil, errify.NewInternalServerError(err.Error(), "subjectAttributes/UserById")
+	}
+	permissions := make([]any, 0, len(subject.Permissions))
+	for _, p := range subject.Permissions {
+		permissions = append(permissions, string(p))
+	}
+	return map[string]any{
+		"id":          subject.ID,
+		"email":       subject.Email,
+		"role":        int(subject.Role),
+		"permissions": permissions,
+		"locked":      user.Locked,
+		"deleted":     user.DeletedAt != nil,
+	}, nil
+}
+
+func (m *AuthzService) ReloadPolicy(ctx context.Context) errify.IError {
+	ctx, span := tracing.Start(ctx, "AuthzService.ReloadPolicy")
+	defer span.End()
+
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	modTime, err := m.policyRepos.PolicyModTime(ctx)
+	if err != nil {
+		if errors.Is(err, repository.PolicyNotConfigured) {
+			return nil
+		}
+		return errify.NewInternalServerError(err.Error(), "ReloadPolicy/PolicyModTime")
+	}
+	policy, err := m.policyRepos.LoadPolicy(ctx)
+	if err != nil {
+		return errify.NewInternalServerError(err.Error(), "ReloadPolicy/LoadPolicy")
+	}
+	err = policy.Valid()
+	if err != nil {
+		return errify.NewInternalServerError(err.Error(), "ReloadPolicy/Valid")
+	}
+	m.policy.Store(policy)
+	m.modTime = modTime
+
+	requestid.Logger(ctx, m.log).Infof("authorization policy loaded: %d rules", len(policy.Rules))
+	return nil
+}
+
+func (m *AuthzService) WatchPolicy(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			modTime, err := m.policyRepos.PolicyModTime(ctx)
+			if err != nil {
+				if !errors.Is(err, repository.PolicyNotConfigured) {
+					m.log.Error(errify.NewInternalServerError(err.Error(), "WatchPolicy/PolicyModTime"))
+				}
+				continue
+			}
+			m.mx.Lock()
+			changed := !modTime.Equal(m.modTime)
+			m.mx.Unlock()
+			if !changed {
+				continue
+			}
+			// При ошибке продолжает действовать предыдущая версия политик
+			if e := m.ReloadPolicy(ctx); e != nil {
+				m.log.Error(e.JoinLoc("WatchPolicy"))
+			}
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package service
+
+import (
+	"auth/internal/config"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"os"
+)
+
+const (
+	minAuthorizationCode = 1000000
+	maxAuthorizationCode = minAuthorizationCode * 10
+)
+
+// generateCode Генерирует семизначный код подтверждения криптографически стойким генератором
+func generateCode() (int, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(maxAuthorizationCode-minAuthorizationCode))
+	if err != nil {
+		return 0, err
+	}
+	return int(n.Int64()) + minAuthorizationCode, nil
+}
+
+// hashCode Возвращает HMAC кода, привязанный к адресу почты, чтобы код не хранился в открытом виде
+func hashCode(email string, code int) string {
+	mac := hmac.New(sha256.New, []byte(os.Getenv(config.Secret)))
+	mac.Write([]byte(fmt.Sprintf("%s:%d", email, code)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// generateToken Генерирует случайный токен для приглашений и персональных токенов доступа
+func generateToken() (string, error) {
+	b := make([]byte, 32)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashToken Хеш токена для хранения в базе. Токен случайный, поэтому соль не нужна
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
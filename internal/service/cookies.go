@@ -1,40 +1,233 @@
 package service
 
 import (
+	"auth/internal/config"
+	"crypto/subtle"
+	"errors"
+	"github.com/Linkify-Company/common_utils/errify"
 	"net/http"
-	"time"
+	"net/url"
+	"slices"
+	"strings"
 )
 
-type CookiesService struct{}
+// hostPrefix Браузер принимает такую cookie только с Secure, без Domain и с путем /
+const hostPrefix = "__Host-"
 
-func NewCookiesService() Cookies {
-	return &CookiesService{}
+type CookiesService struct {
+	cfg      config.CookieConfig
+	csrfCfg  config.CSRFConfig
+	tokenCfg config.TokenConfig
+	oauthCfg config.OAuthConfig
+	sameSite http.SameSite
+}
+
+func NewCookiesService(cfg config.CookieConfig, csrfCfg config.CSRFConfig, tokenCfg config.TokenConfig, oauthCfg config.OAuthConfig) Cookies {
+	if cfg.Name == "" {
+		cfg.Name = Authorization
+	}
+	if cfg.OAuthStateName == "" {
+		cfg.OAuthStateName = oauthState
+	}
+	if cfg.Path == "" {
+		cfg.Path = "/"
+	}
+	// Префикс добавляется после значений по умолчанию, пустое имя остается пустым
+	if cfg.HostPrefix {
+		cfg.Name = withPrefix(hostPrefix, cfg.Name)
+		csrfCfg.CookieName = withPrefix(hostPrefix, csrfCfg.CookieName)
+		cfg.OAuthStateName = withPrefix(hostPrefix, cfg.OAuthStateName)
+		cfg.Domain = ""
+		cfg.Path = "/"
+		cfg.Secure = true
+	}
+	m := &CookiesService{
+		cfg:      cfg,
+		csrfCfg:  csrfCfg,
+		tokenCfg: tokenCfg,
+		oauthCfg: oauthCfg,
+		sameSite: http.SameSiteLaxMode,
+	}
+	switch strings.ToLower(cfg.SameSite) {
+	case "strict":
+		m.sameSite = http.SameSiteStrictMode
+	case "none":
+		// Браузеры отклоняют SameSite=None без Secure
+		if cfg.Secure {
+			m.sameSite = http.SameSiteNoneMode
+		}
+	}
+	return m
 }
 
 const (
 	Authorization = "Authorization"
+	oauthState    = "oauth_state"
 )
 
+func withPrefix(prefix string, name string) string {
+	if name == "" {
+		return ""
+	}
+	return prefix + strings.TrimPrefix(name, prefix)
+}
+
+// SetToken Сохраняет токен в cookie. Сессия продлевается по истекшему токену доступа, поэтому cookie живет
+// до конца сессии, а не до истечения токена
 func (m *CookiesService) SetToken(w http.ResponseWriter, token string) {
-	http.SetCookie(w, &http.Cookie{
-		Name:     Authorization,
-		Value:    token,
-		Path:     "/",
-		HttpOnly: true,
-		Domain:   "localhost",
-		Expires:  time.Now().Add(365 * 24 * time.Hour),
-	})
+	http.SetCookie(w, m.cookie(m.cfg.Name, m.cfg.Path, token, int(m.tokenCfg.RefreshTTL.Seconds())))
+	// Новая сессия получает новый CSRF токен. Если его не удалось создать, клиент получит его через CSRFToken
+	if csrf, err := generateToken(); err == nil {
+		m.setCSRF(w, csrf)
+	}
+}
+
+// ClearToken Удаляет cookie сессии и CSRF cookie, например при выходе
+func (m *CookiesService) ClearToken(w http.ResponseWriter) {
+	http.SetCookie(w, m.cookie(m.cfg.Name, m.cfg.Path, "", -1))
+	if m.csrfCfg.Enabled {
+		csrf := m.cookie(m.csrfCfg.CookieName, "/", "", -1)
+		csrf.HttpOnly = false
+		http.SetCookie(w, csrf)
+	}
 }
 
 func (m *CookiesService) GetToken(r *http.Request) (string, error) {
-	token := r.Header.Get(Authorization)
+	// Заголовок принимается как с типом Bearer, так и без него
+	token := strings.TrimPrefix(r.Header.Get(Authorization), "Bearer ")
 	if token != "" {
 		return token, nil
 	}
 
-	c, err := r.Cookie(Authorization)
+	c, err := r.Cookie(m.cfg.Name)
 	if err != nil {
 		return "", err
 	}
 	return c.Value, nil
 }
+
+// CSRFToken Текущий CSRF токен из cookie. Если его нет, выпускает новый и сохраняет в cookie
+func (m *CookiesService) CSRFToken(w http.ResponseWriter, r *http.Request) (string, error) {
+	if c, err := r.Cookie(m.csrfCfg.CookieName); err == nil && c.Value != "" {
+		return c.Value, nil
+	}
+	token, err := generateToken()
+	if err != nil {
+		return "", err
+	}
+	m.setCSRF(w, token)
+	return token, nil
+}
+
+// CheckCSRF Проверяет изменяющий запрос, если токен берется из cookie и браузер мог отправить его сам.
+// Запросы с заголовком Authorization и без cookie авторизации не проверяются
+func (m *CookiesService) CheckCSRF(r *http.Request) errify.IError {
+	if !m.csrfCfg.Enabled || !m.fromCookie(r) {
+		return nil
+	}
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return nil
+	}
+	err := m.checkOrigin(r)
+	if err != nil {
+		return errify.NewUnauthorizedError(err.Error(), ErrOriginNotTrusted.Error(), "CheckCSRF/checkOrigin")
+	}
+	c, err := r.Cookie(m.csrfCfg.CookieName)
+	if err != nil || c.Value == "" {
+		return errify.NewUnauthorizedError("csrf cookie empty", ErrCSRFNotValid.Error(), "CheckCSRF/Cookie")
+	}
+	header := r.Header.Get(m.csrfCfg.HeaderName)
+	if header == "" || subtle.ConstantTimeCompare([]byte(header), []byte(c.Value)) != 1 {
+		return errify.NewUnauthorizedError("csrf header does not match cookie", ErrCSRFNotValid.Error(), "CheckCSRF")
+	}
+	return nil
+}
+
+// fromCookie Токен запроса будет взят из cookie
+func (m *CookiesService) fromCookie(r *http.Request) bool {
+	if r.Header.Get(Authorization) != "" {
+		return false
+	}
+	c, err := r.Cookie(m.cfg.Name)
+	return err == nil && c.Value != ""
+}
+
+// checkOrigin Источник запроса из Origin, а без него из Referer, должен совпадать с адресом сервиса или быть доверенным.
+// Без обоих заголовков запрос защищает только CSRF токен
+func (m *CookiesService) checkOrigin(r *http.Request) error {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		referer := r.Header.Get("Referer")
+		if referer == "" {
+			return nil
+		}
+		u, err := url.Parse(referer)
+		if err != nil || u.Host == "" {
+			return errors.New("referer not valid")
+		}
+		origin = u.Scheme + "://" + u.Host
+	}
+	if strings.EqualFold(origin, requestOrigin(r)) || slices.Contains(m.csrfCfg.TrustedOrigins, origin) {
+		return nil
+	}
+	return errors.New("origin " + origin + " is not trusted")
+}
+
+// requestOrigin Адрес сервиса, на который пришел запрос, с учетом заголовков обратного прокси
+func requestOrigin(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	}
+	host := r.Host
+	if forwarded := r.Header.Get("X-Forwarded-Host"); forwarded != "" {
+		host = forwarded
+	}
+	return scheme + "://" + host
+}
+
+func (m *CookiesService) SetOAuthState(w http.ResponseWriter, state string) {
+	c := m.cookie(m.cfg.OAuthStateName, "/", state, int(m.oauthCfg.StateTTL.Seconds()))
+	// Провайдер возвращает пользователя переходом с другого сайта, с SameSite=Strict браузер не отправил бы cookie
+	c.SameSite = http.SameSiteLaxMode
+	http.SetCookie(w, c)
+}
+
+func (m *CookiesService) CheckOAuthState(w http.ResponseWriter, r *http.Request, state string) errify.IError {
+	c, err := r.Cookie(m.cfg.OAuthStateName)
+	if err != nil || c.Value == "" {
+		return errify.NewBadRequestError("oauth state cookie empty", ErrOAuthStateNotValid.Error(), "CheckOAuthState/Cookie")
+	}
+	http.SetCookie(w, m.cookie(m.cfg.OAuthStateName, "/", "", -1))
+	if subtle.ConstantTimeCompare([]byte(state), []byte(c.Value)) != 1 {
+		return errify.NewBadRequestError("oauth state does not match cookie", ErrOAuthStateNotValid.Error(), "CheckOAuthState")
+	}
+	return nil
+}
+
+// setCSRF CSRF cookie доступна скрипту страницы, чтобы он мог повторить ее значение в заголовке
+func (m *CookiesService) setCSRF(w http.ResponseWriter, token string) {
+	if !m.csrfCfg.Enabled {
+		return
+	}
+	c := m.cookie(m.csrfCfg.CookieName, "/", token, int(m.tokenCfg.RefreshTTL.Seconds()))
+	c.HttpOnly = false
+	http.SetCookie(w, c)
+}
+
+func (m *CookiesService) cookie(name string, path string, value string, maxAge int) *http.Cookie {
+	return &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     path,
+		Domain:   m.cfg.Domain,
+		MaxAge:   maxAge,
+		Secure:   m.cfg.Secure,
+		HttpOnly: true,
+		SameSite: m.sameSite,
+	}
+}
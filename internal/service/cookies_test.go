@@ -0,0 +1,18 @@
+package service
+
+import (
+	"auth/internal/config"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCookiesHostPrefixDefaults(t *testing.T) {
+	m := NewCookiesService(config.CookieConfig{HostPrefix: true}, config.CSRFConfig{}, config.TokenConfig{}, config.OAuthConfig{})
+
+	w := httptest.NewRecorder()
+	m.SetToken(w, "token")
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != "__Host-"+Authorization {
+		t.Fatalf("cookies = %+v, want only __Host-%s", cookies, Authorization)
+	}
+}
@@ -2,20 +2,33 @@ package service
 
 import (
 	"auth/internal/config"
+	"auth/internal/metrics"
 	"auth/internal/repository"
+	"auth/internal/requestid"
+	"auth/internal/tracing"
 	"context"
 	"crypto/tls"
 	"fmt"
 	"github.com/Linkify-Company/common_utils/errify"
 	"github.com/Linkify-Company/common_utils/logger"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
 	"net/smtp"
 	"os"
+	"sync"
+	"time"
 )
 
+// asyncSendTimeout Ограничение на отправку письма, результат которой не ждет запрос
+const asyncSendTimeout = time.Minute
+
 type EmailService struct {
 	log        logger.Logger
 	emailRepos repository.Email
 	cfg        config.EmailServiceConfig
+
+	// sending Отправки, которые еще не завершились, их дожидается остановка сервиса
+	sending sync.WaitGroup
 }
 
 func NewEmailService(
@@ -31,10 +44,31 @@ func NewEmailService(
 }
 
 func (m *EmailService) Send(ctx context.Context, title string, toEmail string, message string) errify.IError {
+	ctx, span := tracing.Start(ctx, "smtp send",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.ServerAddress(m.cfg.SmtpServer), semconv.ServerPort(m.cfg.SmtpPort)),
+	)
+	err := m.send(ctx, title, toEmail, message)
+	tracing.End(span, err)
+	return err
+}
+
+func (m *EmailService) send(ctx context.Context, title string, toEmail string, message string) errify.IError {
 	var errCh = make(chan errify.IError, 1)
-	var ok = make(chan struct{})
+	// Буфер нужен, чтобы отправка завершилась, даже если вызывающий перестал ждать результат
+	var ok = make(chan struct{}, 1)
 
+	m.sending.Add(1)
 	go func() {
+		defer m.sending.Done()
+
+		// Результат учитывается по завершении отправки, даже если вызывающий перестал его ждать
+		start := time.Now()
+		sent := false
+		defer func() {
+			metrics.EmailSend(sent, time.Since(start))
+		}()
+
 		tlsConfig := &tls.Config{
 			ServerName: m.cfg.SmtpServer,
 		}
@@ -75,7 +109,6 @@ func (m *EmailService) Send(ctx context.Context, title string, toEmail string, m
 			errCh <- errify.NewInternalServerError(err.Error(), "Send/Data").SetDetails("Error getting writers")
 			return
 		}
-		defer w.Close()
 
 		_, err = w.Write([]byte(fmt.Sprintf("From: %s\r\n", os.Getenv(config.AuthEmail)) +
 			fmt.Sprintf("To: %s\r\n", toEmail) +
@@ -88,6 +121,13 @@ func (m *EmailService) Send(ctx context.Context, title string, toEmail string, m
 			errCh <- errify.NewInternalServerError(err.Error(), "Send/Write").SetDetails("Error write message")
 			return
 		}
+		// Сервер принимает письмо только в ответ на завершение DATA, поэтому ошибка Close означает, что письмо не отправлено
+		err = w.Close()
+		if err != nil {
+			errCh <- errify.NewInternalServerError(err.Error(), "Send/Close").SetDetails("Error write message")
+			return
+		}
+		sent = true
 		ok <- struct{}{}
 	}()
 
@@ -97,7 +137,40 @@ func (m *EmailService) Send(ctx context.Context, title string, toEmail string, m
 	case err := <-errCh:
 		return err
 	case <-ok:
-		m.log.Debugf("Send message successfully")
+		requestid.Logger(ctx, m.log).Debugf("Send message successfully")
+		return nil
+	}
+}
+
+// SendAsync Отправляет письмо в фоне, ошибка только логируется. Отправка остается в трассе из ctx,
+// но не прерывается, когда ctx отменяется с завершением запроса
+func (m *EmailService) SendAsync(ctx context.Context, title string, toEmail string, message string) {
+	m.sending.Add(1)
+	go func() {
+		defer m.sending.Done()
+
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), asyncSendTimeout)
+		defer cancel()
+
+		err := m.Send(ctx, title, toEmail, message)
+		if err != nil {
+			requestid.Logger(ctx, m.log).Error(err.JoinLoc("SendAsync"))
+		}
+	}()
+}
+
+// Wait Ждет завершения начатых отправок, но не дольше, чем действует ctx
+func (m *EmailService) Wait(ctx context.Context) errify.IError {
+	done := make(chan struct{})
+	go func() {
+		m.sending.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-ctx.Done():
+		return errify.NewInternalServerError(ctx.Err().Error(), "Wait")
+	case <-done:
 		return nil
 	}
 }
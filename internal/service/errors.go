@@ -1,11 +1,95 @@
 package service
 
-import "errors"
+import (
+	"errors"
+	"github.com/Linkify-Company/common_utils/errify"
+	"time"
+)
 
 var (
-	UserIsAlreadyExist    = errors.New("user is already exist")
-	UserNotExist          = errors.New("user is not exist")
-	ErrInvalidCredentials = errors.New("invalid credentials")
-	ErrTokenExpired       = errors.New("token expired")
-	MailConfirmationError = errors.New("mail confirmation error")
+	UserIsAlreadyExist       = errors.New("user is already exist")
+	UserNotExist             = errors.New("user is not exist")
+	ErrInvalidCredentials    = errors.New("invalid credentials")
+	ErrTokenExpired          = errors.New("token expired")
+	MailConfirmationError    = errors.New("mail confirmation error")
+	ErrTooManyRequests       = errors.New("too many requests, try again later")
+	ErrAccessDenied          = errors.New("access denied")
+	ErrCSRFNotValid          = errors.New("csrf token not valid")
+	ErrOriginNotTrusted      = errors.New("request origin is not trusted")
+	ErrUserLocked            = errors.New("user is locked")
+	ErrCursorNotValid        = errors.New("cursor not valid")
+	RoleIsAlreadyExist       = errors.New("role is already exist")
+	RoleNotExist             = errors.New("role is not exist")
+	ErrRoleBuiltin           = errors.New("builtin role can not be changed")
+	PermissionIsAlreadyExist = errors.New("permission is already exist")
+	PermissionNotExist       = errors.New("permission is not exist")
+
+	OrganizationIsAlreadyExist = errors.New("organization with this slug is already exist")
+	MemberIsAlreadyExist       = errors.New("user is already a member of the organization")
+	MemberNotExist             = errors.New("member is not exist")
+	InvitationNotValid         = errors.New("invitation is not valid or expired")
+	ErrOwnerCanNotLeave        = errors.New("organization owner can not be removed")
+
+	PersonalTokenNotExist = errors.New("personal access token is not exist")
+	ErrPersonalToken      = errors.New("action is not available with a personal access token")
+
+	ProviderNotExist       = errors.New("login provider is not exist")
+	ErrOAuthStateNotValid  = errors.New("login state is not valid or expired")
+	ErrOAuthLinkNotValid   = errors.New("account linking was started by another user")
+	ErrEmailNotVerified    = errors.New("email is not verified by the login provider")
+	IdentityIsAlreadyExist = errors.New("account of the login provider is already linked")
+	IdentityNotExist       = errors.New("linked account is not exist")
+
+	ErrSAMLAssertionNotValid = errors.New("saml assertion is not valid")
+	ErrSAMLAssertionReplayed = errors.New("saml assertion is already used")
+
+	SCIMTokenNotExist       = errors.New("scim token is not exist")
+	ErrSCIMResourceNotExist = errors.New("scim resource is not exist")
+	ErrSCIMResourceConflict = errors.New("scim resource is already exist")
+	ErrSCIMUserNotManaged   = errors.New("userName of a user not provisioned by the organization can not be changed")
 )
+
+// ForbiddenError Пользователь известен, но действие ему запрещено. В errify такого типа нет,
+// поэтому обработчики отвечают на него кодом 403 сами
+type ForbiddenError struct {
+	errify.IError
+}
+
+// NewForbiddenError Ошибка доступа, клиент получает ErrAccessDenied
+func NewForbiddenError(msg string, loc string) errify.IError {
+	return &ForbiddenError{IError: errify.NewUnauthorizedError(msg, ErrAccessDenied.Error(), loc)}
+}
+
+func (e *ForbiddenError) JoinLoc(loc string) errify.IError {
+	e.IError = e.IError.JoinLoc(loc)
+	return e
+}
+
+func (e *ForbiddenError) SetDetails(details string) errify.IError {
+	e.IError = e.IError.SetDetails(details)
+	return e
+}
+
+// TooManyRequestsError Лимит запросов исчерпан, повторить можно через RetryAfter. Обработчики отвечают на нее кодом 429
+type TooManyRequestsError struct {
+	errify.IError
+	RetryAfter time.Duration
+}
+
+// NewTooManyRequestsError Ошибка лимита, клиент получает ErrTooManyRequests
+func NewTooManyRequestsError(retryAfter time.Duration, loc string) errify.IError {
+	return &TooManyRequestsError{
+		IError:     errify.NewBadRequestError(ErrTooManyRequests.Error(), ErrTooManyRequests.Error(), loc),
+		RetryAfter: retryAfter,
+	}
+}
+
+func (e *TooManyRequestsError) JoinLoc(loc string) errify.IError {
+	e.IError = e.IError.JoinLoc(loc)
+	return e
+}
+
+func (e *TooManyRequestsError) SetDetails(details string) errify.IError {
+	e.IError = e.IError.SetDetails(details)
+	return e
+}
@@ -0,0 +1,122 @@
+package service
+
+import (
+	"auth/internal/config"
+	"auth/internal/domain"
+	"auth/internal/repository"
+	"context"
+	"fmt"
+	"github.com/Linkify-Company/common_utils/logger"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type HealthService struct {
+	log         logger.Logger
+	transaction repository.Transaction
+	emailCfg    config.EmailServiceConfig
+	cfg         config.HealthConfig
+
+	draining atomic.Bool
+}
+
+func NewHealthService(
+	log logger.Logger,
+	transaction repository.Transaction,
+	emailCfg config.EmailServiceConfig,
+	cfg config.HealthConfig,
+) Health {
+	return &HealthService{
+		log:         log,
+		transaction: transaction,
+		emailCfg:    emailCfg,
+		cfg:         cfg,
+	}
+}
+
+type dependencyCheck struct {
+	name  string
+	check func(ctx context.Context) error
+}
+
+// Readiness Проверяет зависимости параллельно, каждую не дольше HealthConfig.Timeout
+func (m *HealthService) Readiness(ctx context.Context) *domain.Readiness {
+	checks := []dependencyCheck{
+		{name: "postgres", check: m.transaction.Ping},
+		{name: "redis", check: m.transaction.RedisPing},
+	}
+	if m.cfg.CheckSMTP {
+		checks = append(checks, dependencyCheck{name: "smtp", check: m.smtpPing})
+	}
+
+	res := &domain.Readiness{
+		Status:       domain.HealthOK,
+		Dependencies: make([]*domain.DependencyHealth, len(checks)),
+	}
+	var wg sync.WaitGroup
+	for i, dependency := range checks {
+		wg.Add(1)
+		go func(i int, dependency dependencyCheck) {
+			defer wg.Done()
+			res.Dependencies[i] = m.checkDependency(ctx, dependency)
+		}(i, dependency)
+	}
+	wg.Wait()
+
+	for _, dependency := range res.Dependencies {
+		if dependency.Status != domain.HealthOK {
+			res.Status = domain.HealthFail
+		}
+	}
+	if m.draining.Load() {
+		res.Status = domain.HealthDraining
+	}
+	return res
+}
+
+// SetDraining Помечает сервис как останавливающийся, после этого /readyz отвечает, что сервис не готов
+func (m *HealthService) SetDraining() {
+	m.draining.Store(true)
+}
+
+func (m *HealthService) checkDependency(ctx context.Context, dependency dependencyCheck) *domain.DependencyHealth {
+	ctx, cancel := context.WithTimeout(ctx, m.cfg.Timeout)
+	defer cancel()
+
+	start := time.Now()
+	errCh := make(chan error, 1)
+
+	// Клиент Redis не прерывает запрос по контексту, поэтому ожидание ограничивается здесь
+	go func() {
+		errCh <- dependency.check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-errCh:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	res := &domain.DependencyHealth{
+		Name:      dependency.name,
+		Status:    domain.HealthOK,
+		LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		res.Status = domain.HealthFail
+		res.Error = err.Error()
+	}
+	return res
+}
+
+// smtpPing Проверяет, что почтовый сервер принимает соединения
+func (m *HealthService) smtpPing(ctx context.Context) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", fmt.Sprintf("%s:%d", m.emailCfg.SmtpServer, m.emailCfg.SmtpPort))
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
@@ -0,0 +1,107 @@
+package service
+
+import (
+	"auth/internal/domain"
+	"auth/internal/repository"
+	"context"
+	"errors"
+	"github.com/Linkify-Company/common_utils/errify"
+	"github.com/jackc/pgx/v5"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// identityResolver Сопоставляет учетные записи внешних провайдеров входа (OAuth, SAML) с пользователями
+type identityResolver struct {
+	identityRepos repository.Identity
+	userRepos     repository.User
+}
+
+// identityUser Находит пользователя по привязанной учетной записи. Если привязки нет,
+// учетная запись привязывается к пользователю с той же подтвержденной почтой или создается новый пользователь
+func (m *identityResolver) identityUser(ctx context.Context, tx pgx.Tx, external *domain.ExternalIdentity) (*domain.UserFromDB, errify.IError) {
+	identity, err := m.identityRepos.IdentityBySubject(ctx, tx, external.Provider, external.Subject)
+	if err == nil {
+		err = m.identityRepos.TouchIdentity(ctx, tx, identity.ID, external.Email)
+		if err != nil {
+			return nil, errify.NewInternalServerError(err.Error(), "identityUser/TouchIdentity")
+		}
+		user, err := m.userRepos.UserById(ctx, tx, identity.UserID)
+		if err != nil {
+			return nil, errify.NewInternalServerError(err.Error(), "identityUser/UserById")
+		}
+		return user, nil
+	}
+	if !errors.Is(err, repository.IdentityNotExist) {
+		return nil, errify.NewInternalServerError(err.Error(), "identityUser/IdentityBySubject")
+	}
+
+	// Без подтвержденной почты нельзя ни привязать существующего пользователя, ни создать нового
+	if !external.EmailVerified {
+		return nil, errify.NewBadRequestError(ErrEmailNotVerified.Error(), ErrEmailNotVerified.Error(), "identityUser/EmailVerified")
+	}
+	user, err := m.userRepos.UserByEmail(ctx, tx, external.Email)
+	if err != nil {
+		if !errors.Is(err, repository.UserNotExist) {
+			return nil, errify.NewInternalServerError(err.Error(), "identityUser/UserByEmail")
+		}
+		user, err = addExternalUser(ctx, tx, m.userRepos, external.Email, domain.RoleUser)
+		if err != nil {
+			return nil, errify.NewInternalServerError(err.Error(), "identityUser/addExternalUser")
+		}
+	}
+	e := m.addIdentity(ctx, tx, user.ID, external)
+	if e != nil {
+		return nil, e.JoinLoc("identityUser")
+	}
+	return user, nil
+}
+
+// linkIdentity Привязывает учетную запись провайдера к авторизованному пользователю
+func (m *identityResolver) linkIdentity(ctx context.Context, tx pgx.Tx, userID int, external *domain.ExternalIdentity) (*domain.UserFromDB, errify.IError) {
+	user, err := m.userRepos.UserById(ctx, tx, userID)
+	if err != nil {
+		if errors.Is(err, repository.UserNotExist) {
+			return nil, errify.NewBadRequestError(err.Error(), UserNotExist.Error(), "linkIdentity/UserById")
+		}
+		return nil, errify.NewInternalServerError(err.Error(), "linkIdentity/UserById")
+	}
+	e := m.addIdentity(ctx, tx, user.ID, external)
+	if e != nil {
+		return nil, e.JoinLoc("linkIdentity")
+	}
+	return user, nil
+}
+
+func (m *identityResolver) addIdentity(ctx context.Context, tx pgx.Tx, userID int, external *domain.ExternalIdentity) errify.IError {
+	_, err := m.identityRepos.AddIdentity(ctx, tx, &domain.UserIdentity{
+		UserID:   userID,
+		Provider: external.Provider,
+		Subject:  external.Subject,
+		Email:    external.Email,
+	})
+	if err != nil {
+		if errors.Is(err, repository.IdentityAlreadyExist) {
+			return errify.NewBadRequestError(err.Error(), IdentityIsAlreadyExist.Error(), "addIdentity/AddIdentity")
+		}
+		return errify.NewInternalServerError(err.Error(), "addIdentity/AddIdentity")
+	}
+	return nil
+}
+
+// addExternalUser Создает пользователя, который входит через внешний источник. Пароль случайный,
+// войти по паролю пользователь сможет после его восстановления
+func addExternalUser(ctx context.Context, tx pgx.Tx, userRepos repository.User, email string, role domain.Role) (*domain.UserFromDB, error) {
+	password, err := generateToken()
+	if err != nil {
+		return nil, err
+	}
+	passHash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+	id, err := userRepos.AddUser(ctx, tx, email, role, passHash)
+	if err != nil {
+		return nil, err
+	}
+	return userRepos.UserById(ctx, tx, id)
+}
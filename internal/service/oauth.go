@@ -0,0 +1,210 @@
+package service
+
+import (
+	"auth/internal/config"
+	"auth/internal/domain"
+	"auth/internal/metrics"
+	"auth/internal/repository"
+	"auth/internal/tracing"
+	"context"
+	"errors"
+	"github.com/Linkify-Company/common_utils/errify"
+	"github.com/Linkify-Company/common_utils/logger"
+	"os"
+)
+
+type OAuthService struct {
+	identityResolver
+
+	log           logger.Logger
+	transaction   repository.Transaction
+	oauthRepos    repository.OAuth
+	identityRepos repository.Identity
+	authRepos     repository.Auth
+	oauthCfg      config.OAuthConfig
+}
+
+func NewOAuthService(
+	log logger.Logger,
+	transaction repository.Transaction,
+	oauthRepos repository.OAuth,
+	identityRepos repository.Identity,
+	userRepos repository.User,
+	authRepos repository.Auth,
+	oauthCfg config.OAuthConfig,
+) OAuth {
+	return &OAuthService{
+		identityResolver: identityResolver{
+			identityRepos: identityRepos,
+			userRepos:     userRepos,
+		},
+		log:           log,
+		transaction:   transaction,
+		oauthRepos:    oauthRepos,
+		identityRepos: identityRepos,
+		authRepos:     authRepos,
+		oauthCfg:      oauthCfg,
+	}
+}
+
+func (m *OAuthService) OAuthLoginURL(ctx context.Context, provider string, linkUserID int) (string, string, errify.IError) {
+	ctx, span := tracing.Start(ctx, "OAuthService.OAuthLoginURL")
+	defer span.End()
+
+	state, err := generateToken()
+	if err != nil {
+		return "", "", errify.NewInternalServerError(err.Error(), "OAuthLoginURL/generateToken")
+	}
+	nonce, err := generateToken()
+	if err != nil {
+		return "", "", errify.NewInternalServerError(err.Error(), "OAuthLoginURL/generateToken")
+	}
+	verifier, err := generateToken()
+	if err != nil {
+		return "", "", errify.NewInternalServerError(err.Error(), "OAuthLoginURL/generateToken")
+	}
+
+	url, err := m.oauthRepos.AuthCodeURL(ctx, provider, state, nonce, verifier)
+	if err != nil {
+		if errors.Is(err, repository.ProviderNotExist) {
+			return "", "", errify.NewBadRequestError(err.Error(), ProviderNotExist.Error(), "OAuthLoginURL/AuthCodeURL")
+		}
+		return "", "", errify.NewInternalServerError(err.Error(), "OAuthLoginURL/AuthCodeURL")
+	}
+	err = m.oauthRepos.SetOAuthState(ctx, state, &domain.OAuthState{
+		Provider:   provider,
+		Nonce:      nonce,
+		Verifier:   verifier,
+		LinkUserID: linkUserID,
+	}, m.oauthCfg.StateTTL)
+	if err != nil {
+		return "", "", errify.NewInternalServerError(err.Error(), "OAuthLoginURL/SetOAuthState")
+	}
+	return url, state, nil
+}
+
+func (m *OAuthService) OAuthCallback(ctx context.Context, provider string, state string, code string, userID int, cfg config.TokenConfig) (string, errify.IError) {
+	ctx, span := tracing.Start(ctx, "OAuthService.OAuthCallback")
+	defer span.End()
+
+	token, err := m.oauthCallback(ctx, provider, state, code, userID, cfg)
+	metrics.Login(metrics.LoginOAuth, loginFailureReason(err))
+	return token, err
+}
+
+func (m *OAuthService) oauthCallback(ctx context.Context, provider string, state string, code string, userID int, cfg config.TokenConfig) (string, errify.IError) {
+	data, err := m.oauthRepos.PopOAuthState(ctx, state)
+	if err != nil {
+		if errors.Is(err, repository.OAuthStateNotExist) {
+			return "", errify.NewBadRequestError(err.Error(), ErrOAuthStateNotValid.Error(), "OAuthCallback/PopOAuthState")
+		}
+		return "", errify.NewInternalServerError(err.Error(), "OAuthCallback/PopOAuthState")
+	}
+	if data.Provider != provider {
+		return "", errify.NewBadRequestError(ErrOAuthStateNotValid.Error(), ErrOAuthStateNotValid.Error(), "OAuthCallback/Provider")
+	}
+	// Иначе ссылка возврата, выданная одному пользователю, привязала бы учетную запись провайдера к чужому аккаунту
+	if data.LinkUserID != 0 && data.LinkUserID != userID {
+		return "", errify.NewUnauthorizedError(ErrOAuthLinkNotValid.Error(), ErrOAuthLinkNotValid.Error(), "OAuthCallback/LinkUserID")
+	}
+	external, err := m.oauthRepos.Exchange(ctx, provider, code, data.Verifier, data.Nonce)
+	if err != nil {
+		if errors.Is(err, repository.ProviderNotExist) {
+			return "", errify.NewBadRequestError(err.Error(), ProviderNotExist.Error(), "OAuthCallback/Exchange")
+		}
+		if errors.Is(err, repository.ExternalIdentityNotValid) {
+			return "", errify.NewUnauthorizedError(err.Error(), ErrInvalidCredentials.Error(), "OAuthCallback/Exchange")
+		}
+		return "", errify.NewInternalServerError(err.Error(), "OAuthCallback/Exchange")
+	}
+
+	tx, err := m.transaction.Begin(ctx)
+	if err != nil {
+		return "", errify.NewInternalServerError(err.Error(), "OAuthCallback/Begin")
+	}
+	defer m.transaction.Rollback(ctx, tx)
+
+	var user *domain.UserFromDB
+	var e errify.IError
+	if data.LinkUserID != 0 {
+		user, e = m.linkIdentity(ctx, tx, data.LinkUserID, external)
+	} else {
+		user, e = m.identityUser(ctx, tx, external)
+	}
+	if e != nil {
+		return "", e.JoinLoc("OAuthCallback")
+	}
+	if user.DeletedAt != nil {
+		return "", errify.NewBadRequestError(ErrInvalidCredentials.Error(), ErrInvalidCredentials.Error(), "OAuthCallback/DeletedAt")
+	}
+	if user.Locked {
+		return "", errify.NewBadRequestError(ErrUserLocked.Error(), ErrUserLocked.Error(), "OAuthCallback/Locked")
+	}
+
+	redisTx, err := m.transaction.RedisTx(ctx)
+	if err != nil {
+		return "", errify.NewInternalServerError(err.Error(), "OAuthCallback/RedisTx")
+	}
+	defer m.transaction.RedisRollback(ctx, redisTx)
+
+	token, err := m.authRepos.Authorization(ctx, redisTx, &domain.AuthData{
+		ID:    user.ID,
+		Email: user.Email,
+		Role:  user.Role,
+	}, cfg.RefreshTTL, cfg.AccessTTL, os.Getenv(config.Secret))
+	if err != nil {
+		return "", errify.NewInternalServerError(err.Error(), "OAuthCallback/Authorization")
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return "", errify.NewInternalServerError(err.Error(), "OAuthCallback/Commit")
+	}
+	err = m.transaction.RedisCommit(redisTx)
+	if err != nil {
+		return "", errify.NewInternalServerError(err.Error(), "OAuthCallback/RedisCommit")
+	}
+	return token, nil
+}
+
+func (m *OAuthService) UserIdentities(ctx context.Context, userID int) ([]*domain.UserIdentity, errify.IError) {
+	ctx, span := tracing.Start(ctx, "OAuthService.UserIdentities")
+	defer span.End()
+
+	tx, err := m.transaction.Begin(ctx)
+	if err != nil {
+		return nil, errify.NewInternalServerError(err.Error(), "UserIdentities/Begin")
+	}
+	defer m.transaction.Rollback(ctx, tx)
+
+	identities, err := m.identityRepos.UserIdentities(ctx, tx, userID)
+	if err != nil {
+		return nil, errify.NewInternalServerError(err.Error(), "UserIdentities/UserIdentities")
+	}
+	return identities, nil
+}
+
+func (m *OAuthService) RemoveIdentity(ctx context.Context, userID int, provider string) errify.IError {
+	ctx, span := tracing.Start(ctx, "OAuthService.RemoveIdentity")
+	defer span.End()
+
+	tx, err := m.transaction.Begin(ctx)
+	if err != nil {
+		return errify.NewInternalServerError(err.Error(), "RemoveIdentity/Begin")
+	}
+	defer m.transaction.Rollback(ctx, tx)
+
+	err = m.identityRepos.RemoveIdentity(ctx, tx, userID, provider)
+	if err != nil {
+		if errors.Is(err, repository.IdentityNotExist) {
+			return errify.NewBadRequestError(err.Error(), IdentityNotExist.Error(), "RemoveIdentity/RemoveIdentity")
+		}
+		return errify.NewInternalServerError(err.Error(), "RemoveIdentity/RemoveIdentity")
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return errify.NewInternalServerError(err.Error(), "RemoveIdentity/Commit")
+	}
+	return nil
+}
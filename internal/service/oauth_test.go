@@ -0,0 +1,186 @@
+package service
+
+import (
+	"auth/internal/config"
+	"auth/internal/domain"
+	"auth/internal/repository"
+	"context"
+	"github.com/Linkify-Company/common_utils/errify"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// fakeOAuthRepos Хранит состояния входа в памяти и запоминает, с чем вызывался обмен кода
+type fakeOAuthRepos struct {
+	states map[string]*domain.OAuthState
+
+	exchanges   int
+	gotVerifier string
+	gotNonce    string
+}
+
+func newFakeOAuthRepos() *fakeOAuthRepos {
+	return &fakeOAuthRepos{states: make(map[string]*domain.OAuthState)}
+}
+
+func (m *fakeOAuthRepos) AuthCodeURL(_ context.Context, provider string, state string, _ string, _ string) (string, error) {
+	if provider != "test" {
+		return "", repository.ProviderNotExist
+	}
+	return "https://idp.example.com/authorize?state=" + url.QueryEscape(state), nil
+}
+
+// Exchange Отклоняет код, чтобы вызов завершился до транзакции
+func (m *fakeOAuthRepos) Exchange(_ context.Context, _ string, _ string, verifier string, nonce string) (*domain.ExternalIdentity, error) {
+	m.exchanges++
+	m.gotVerifier, m.gotNonce = verifier, nonce
+	return nil, repository.ExternalIdentityNotValid
+}
+
+func (m *fakeOAuthRepos) SetOAuthState(_ context.Context, state string, data *domain.OAuthState, _ time.Duration) error {
+	m.states[state] = data
+	return nil
+}
+
+func (m *fakeOAuthRepos) PopOAuthState(_ context.Context, state string) (*domain.OAuthState, error) {
+	data, ok := m.states[state]
+	if !ok {
+		return nil, repository.OAuthStateNotExist
+	}
+	delete(m.states, state)
+	return data, nil
+}
+
+func newTestOAuthService(repos *fakeOAuthRepos) *OAuthService {
+	return &OAuthService{
+		oauthRepos: repos,
+		oauthCfg:   config.OAuthConfig{StateTTL: time.Minute},
+	}
+}
+
+func TestOAuthLoginURL(t *testing.T) {
+	ctx := context.Background()
+	repos := newFakeOAuthRepos()
+	m := newTestOAuthService(repos)
+
+	_, state, err := m.OAuthLoginURL(ctx, "test", 0)
+	if err != nil {
+		t.Fatalf("OAuthLoginURL() error = %v", err)
+	}
+	data := repos.states[state]
+	if data == nil || data.Provider != "test" || data.Nonce == "" || data.Verifier == "" {
+		t.Fatalf("stored state = %+v", data)
+	}
+	if _, second, _ := m.OAuthLoginURL(ctx, "test", 0); second == state {
+		t.Fatal("OAuthLoginURL() returned the same state twice")
+	}
+
+	_, _, err = m.OAuthLoginURL(ctx, "unknown", 0)
+	if _, ok := err.(*errify.BadRequestError); !ok {
+		t.Fatalf("OAuthLoginURL() with unknown provider error = %v, want bad request", err)
+	}
+}
+
+func TestOAuthCallbackState(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("unknown state", func(t *testing.T) {
+		m := newTestOAuthService(newFakeOAuthRepos())
+
+		_, err := m.OAuthCallback(ctx, "test", "unknown", "code", 0, config.TokenConfig{})
+		if _, ok := err.(*errify.BadRequestError); !ok {
+			t.Fatalf("OAuthCallback() error = %v, want bad request", err)
+		}
+	})
+
+	t.Run("state replay", func(t *testing.T) {
+		repos := newFakeOAuthRepos()
+		m := newTestOAuthService(repos)
+		_, state, _ := m.OAuthLoginURL(ctx, "test", 0)
+		data := *repos.states[state]
+
+		_, err := m.OAuthCallback(ctx, "test", state, "code", 0, config.TokenConfig{})
+		if _, ok := err.(*errify.UnauthorizedError); !ok {
+			t.Fatalf("first OAuthCallback() error = %v, want unauthorized from exchange", err)
+		}
+		if repos.gotVerifier != data.Verifier || repos.gotNonce != data.Nonce {
+			t.Fatal("Exchange() did not get the PKCE verifier and nonce of the state")
+		}
+
+		_, err = m.OAuthCallback(ctx, "test", state, "code", 0, config.TokenConfig{})
+		if _, ok := err.(*errify.BadRequestError); !ok {
+			t.Fatalf("replayed OAuthCallback() error = %v, want bad request", err)
+		}
+		if repos.exchanges != 1 {
+			t.Fatalf("Exchange() called %d times, want 1", repos.exchanges)
+		}
+	})
+
+	t.Run("provider mismatch", func(t *testing.T) {
+		repos := newFakeOAuthRepos()
+		m := newTestOAuthService(repos)
+		_, state, _ := m.OAuthLoginURL(ctx, "test", 0)
+
+		_, err := m.OAuthCallback(ctx, "other", state, "code", 0, config.TokenConfig{})
+		if _, ok := err.(*errify.BadRequestError); !ok {
+			t.Fatalf("OAuthCallback() error = %v, want bad request", err)
+		}
+		if repos.exchanges != 0 {
+			t.Fatal("Exchange() called for a state of another provider")
+		}
+	})
+
+	t.Run("link by another user", func(t *testing.T) {
+		for _, userID := range []int{0, 2} {
+			repos := newFakeOAuthRepos()
+			m := newTestOAuthService(repos)
+			_, state, _ := m.OAuthLoginURL(ctx, "test", 1)
+
+			_, err := m.OAuthCallback(ctx, "test", state, "code", userID, config.TokenConfig{})
+			if _, ok := err.(*errify.UnauthorizedError); !ok {
+				t.Fatalf("OAuthCallback() by user %d error = %v, want unauthorized", userID, err)
+			}
+			if repos.exchanges != 0 {
+				t.Fatalf("Exchange() called for a link started by another user")
+			}
+		}
+	})
+}
+
+func TestCheckOAuthState(t *testing.T) {
+	m := NewCookiesService(config.CookieConfig{OAuthStateName: "oauth_state"}, config.CSRFConfig{},
+		config.TokenConfig{}, config.OAuthConfig{StateTTL: time.Minute})
+
+	login := httptest.NewRecorder()
+	m.SetOAuthState(login, "state-1")
+	cookies := login.Result().Cookies()
+	if len(cookies) != 1 || !cookies[0].HttpOnly || cookies[0].SameSite != http.SameSiteLaxMode {
+		t.Fatalf("state cookie = %+v", cookies)
+	}
+
+	tests := []struct {
+		name   string
+		cookie *http.Cookie
+		state  string
+		valid  bool
+	}{
+		{name: "same browser", cookie: cookies[0], state: "state-1", valid: true},
+		{name: "no cookie", state: "state-1"},
+		{name: "other state", cookie: cookies[0], state: "state-2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/v1/oauth/test/callback?state="+tt.state, nil)
+			if tt.cookie != nil {
+				r.AddCookie(tt.cookie)
+			}
+			err := m.CheckOAuthState(httptest.NewRecorder(), r, tt.state)
+			if tt.valid != (err == nil) {
+				t.Fatalf("CheckOAuthState() error = %v, want valid %v", err, tt.valid)
+			}
+		})
+	}
+}
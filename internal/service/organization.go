@@ -0,0 +1,462 @@
+package service
+
+import (
+	"auth/internal/config"
+	"auth/internal/domain"
+	"auth/internal/repository"
+	"auth/internal/tracing"
+	"auth/pkg/html_template"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Linkify-Company/common_utils/errify"
+	"github.com/Linkify-Company/common_utils/logger"
+	"github.com/jackc/pgx/v5"
+	"os"
+	"strings"
+	"time"
+)
+
+type OrganizationService struct {
+	log         logger.Logger
+	transaction repository.Transaction
+	orgRepos    repository.Organization
+	userRepos   repository.User
+	authRepos   repository.Auth
+	orgCfg      config.OrganizationConfig
+}
+
+func NewOrganizationService(
+	log logger.Logger,
+	transaction repository.Transaction,
+	orgRepos repository.Organization,
+	userRepos repository.User,
+	authRepos repository.Auth,
+	orgCfg config.OrganizationConfig,
+) Organization {
+	return &OrganizationService{
+		log:         log,
+		transaction: transaction,
+		orgRepos:    orgRepos,
+		userRepos:   userRepos,
+		authRepos:   authRepos,
+		orgCfg:      orgCfg,
+	}
+}
+
+func (m *OrganizationService) AddOrganization(ctx context.Context, userID int, org *domain.Organization) (*domain.Organization, errify.IError) {
+	ctx, span := tracing.Start(ctx, "OrganizationService.AddOrganization")
+	defer span.End()
+
+	tx, err := m.transaction.Begin(ctx)
+	if err != nil {
+		return nil, errify.NewInternalServerError(err.Error(), "AddOrganization/Begin")
+	}
+	defer m.transaction.Rollback(ctx, tx)
+
+	org.OwnerID = userID
+	id, err := m.orgRepos.AddOrganization(ctx, tx, org)
+	if err != nil {
+		if errors.Is(err, repository.OrganizationAlreadyExist) {
+			return nil, errify.NewBadRequestError(err.Error(), OrganizationIsAlreadyExist.Error(), "AddOrganization/AddOrganization")
+		}
+		return nil, errify.NewInternalServerError(err.Error(), "AddOrganization/AddOrganization")
+	}
+	err = m.orgRepos.AddMember(ctx, tx, id, userID, domain.OrgRoleOwner)
+	if err != nil {
+		return nil, errify.NewInternalServerError(err.Error(), "AddOrganization/AddMember")
+	}
+	created, err := m.orgRepos.OrganizationById(ctx, tx, id)
+	if err != nil {
+		return nil, errify.NewInternalServerError(err.Error(), "AddOrganization/OrganizationById")
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, errify.NewInternalServerError(err.Error(), "AddOrganization/Commit")
+	}
+	role := domain.OrgRoleOwner
+	created.Role = &role
+	return created, nil
+}
+
+func (m *OrganizationService) UserOrganizations(ctx context.Context, userID int) ([]*domain.Organization, errify.IError) {
+	ctx, span := tracing.Start(ctx, "OrganizationService.UserOrganizations")
+	defer span.End()
+
+	tx, err := m.transaction.Begin(ctx)
+	if err != nil {
+		return nil, errify.NewInternalServerError(err.Error(), "UserOrganizations/Begin")
+	}
+	defer m.transaction.Rollback(ctx, tx)
+
+	orgs, err := m.orgRepos.UserOrganizations(ctx, tx, userID)
+	if err != nil {
+		return nil, errify.NewInternalServerError(err.Error(), "UserOrganizations/UserOrganizations")
+	}
+	return orgs, nil
+}
+
+func (m *OrganizationService) Members(ctx context.Context, orgID int, userID int) ([]*domain.OrgMember, errify.IError) {
+	ctx, span := tracing.Start(ctx, "OrganizationService.Members")
+	defer span.End()
+
+	tx, err := m.transaction.Begin(ctx)
+	if err != nil {
+		return nil, errify.NewInternalServerError(err.Error(), "Members/Begin")
+	}
+	defer m.transaction.Rollback(ctx, tx)
+
+	_, e := activeOrgMember(ctx, tx, m.orgRepos, orgID, userID)
+	if e != nil {
+		return nil, e.JoinLoc("Members")
+	}
+	members, err := m.orgRepos.Members(ctx, tx, orgID)
+	if err != nil {
+		return nil, errify.NewInternalServerError(err.Error(), "Members/Members")
+	}
+	return members, nil
+}
+
+func (m *OrganizationService) SetMemberRole(ctx context.Context, orgID int, userID int, memberID int, role domain.OrgRole) (*domain.OrgMember, errify.IError) {
+	ctx, span := tracing.Start(ctx, "OrganizationService.SetMemberRole")
+	defer span.End()
+
+	tx, err := m.transaction.Begin(ctx)
+	if err != nil {
+		return nil, errify.NewInternalServerError(err.Error(), "SetMemberRole/Begin")
+	}
+	defer m.transaction.Rollback(ctx, tx)
+
+	manager, e := orgManager(ctx, tx, m.orgRepos, orgID, userID)
+	if e != nil {
+		return nil, e.JoinLoc("SetMemberRole")
+	}
+	member, e := orgMember(ctx, tx, m.orgRepos, orgID, memberID)
+	if e != nil {
+		return nil, e.JoinLoc("SetMemberRole")
+	}
+	// Владелец единственный и не меняется, а администратор не может назначать администраторов
+	if member.Role == domain.OrgRoleOwner || role == domain.OrgRoleOwner ||
+		(manager.Role != domain.OrgRoleOwner && (member.Role == domain.OrgRoleAdmin || role == domain.OrgRoleAdmin)) {
+		return nil, NewForbiddenError(ErrAccessDenied.Error(), "SetMemberRole/Role")
+	}
+	if member.Role == role {
+		return member, nil
+	}
+
+	err = m.orgRepos.SetMemberRole(ctx, tx, orgID, memberID, role)
+	if err != nil {
+		if errors.Is(err, repository.MemberNotExist) {
+			return nil, errify.NewBadRequestError(err.Error(), MemberNotExist.Error(), "SetMemberRole/SetMemberRole")
+		}
+		return nil, errify.NewInternalServerError(err.Error(), "SetMemberRole/SetMemberRole")
+	}
+	// Роль в организации зашита в токен
+	e = m.removeSessions(ctx, memberID)
+	if e != nil {
+		return nil, e.JoinLoc("SetMemberRole")
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, errify.NewInternalServerError(err.Error(), "SetMemberRole/Commit")
+	}
+	member.Role = role
+	return member, nil
+}
+
+func (m *OrganizationService) RemoveMember(ctx context.Context, orgID int, userID int, memberID int) errify.IError {
+	ctx, span := tracing.Start(ctx, "OrganizationService.RemoveMember")
+	defer span.End()
+
+	tx, err := m.transaction.Begin(ctx)
+	if err != nil {
+		return errify.NewInternalServerError(err.Error(), "RemoveMember/Begin")
+	}
+	defer m.transaction.Rollback(ctx, tx)
+
+	member, e := orgMember(ctx, tx, m.orgRepos, orgID, memberID)
+	if e != nil {
+		return e.JoinLoc("RemoveMember")
+	}
+	if member.Role == domain.OrgRoleOwner {
+		return errify.NewBadRequestError(ErrOwnerCanNotLeave.Error(), ErrOwnerCanNotLeave.Error(), "RemoveMember/Owner")
+	}
+	// Участник может сам покинуть организацию
+	if userID != memberID {
+		manager, e := orgManager(ctx, tx, m.orgRepos, orgID, userID)
+		if e != nil {
+			return e.JoinLoc("RemoveMember")
+		}
+		if manager.Role != domain.OrgRoleOwner && member.Role == domain.OrgRoleAdmin {
+			return NewForbiddenError(ErrAccessDenied.Error(), "RemoveMember/Role")
+		}
+	}
+
+	err = m.orgRepos.RemoveMember(ctx, tx, orgID, memberID)
+	if err != nil {
+		if errors.Is(err, repository.MemberNotExist) {
+			return errify.NewBadRequestError(err.Error(), MemberNotExist.Error(), "RemoveMember/RemoveMember")
+		}
+		return errify.NewInternalServerError(err.Error(), "RemoveMember/RemoveMember")
+	}
+	e = m.removeSessions(ctx, memberID)
+	if e != nil {
+		return e.JoinLoc("RemoveMember")
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return errify.NewInternalServerError(err.Error(), "RemoveMember/Commit")
+	}
+	return nil
+}
+
+func (m *OrganizationService) Invite(ctx context.Context, emailService Email, userID int, invitation *domain.OrgInvitation) (*domain.OrgInvitation, errify.IError) {
+	ctx, span := tracing.Start(ctx, "OrganizationService.Invite")
+	defer span.End()
+
+	tx, err := m.transaction.Begin(ctx)
+	if err != nil {
+		return nil, errify.NewInternalServerError(err.Error(), "Invite/Begin")
+	}
+	defer m.transaction.Rollback(ctx, tx)
+
+	manager, e := orgManager(ctx, tx, m.orgRepos, invitation.OrgID, userID)
+	if e != nil {
+		return nil, e.JoinLoc("Invite")
+	}
+	if manager.Role != domain.OrgRoleOwner && invitation.Role == domain.OrgRoleAdmin {
+		return nil, NewForbiddenError(ErrAccessDenied.Error(), "Invite/Role")
+	}
+	org, err := m.orgRepos.OrganizationById(ctx, tx, invitation.OrgID)
+	if err != nil {
+		return nil, errify.NewInternalServerError(err.Error(), "Invite/OrganizationById")
+	}
+
+	token, err := generateToken()
+	if err != nil {
+		return nil, errify.NewInternalServerError(err.Error(), "Invite/generateToken")
+	}
+	invitation.InvitedBy = userID
+	invitation.TokenHash = hashToken(token)
+	invitation.ExpiresAt = time.Now().Add(m.orgCfg.InvitationTTL)
+
+	_, err = m.orgRepos.AddInvitation(ctx, tx, invitation)
+	if err != nil {
+		return nil, errify.NewInternalServerError(err.Error(), "Invite/AddInvitation")
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, errify.NewInternalServerError(err.Error(), "Invite/Commit")
+	}
+
+	// Письмо уходит после фиксации, чтобы ссылка из него всегда вела на сохраненное приглашение
+	emailService.SendAsync(ctx, "Приглашение в организацию Linkify", invitation.Email,
+		fmt.Sprintf(html_template.OrganizationInvitation, manager.Email, org.Name, token))
+	return invitation, nil
+}
+
+func (m *OrganizationService) Invitations(ctx context.Context, orgID int, userID int) ([]*domain.OrgInvitation, errify.IError) {
+	ctx, span := tracing.Start(ctx, "OrganizationService.Invitations")
+	defer span.End()
+
+	tx, err := m.transaction.Begin(ctx)
+	if err != nil {
+		return nil, errify.NewInternalServerError(err.Error(), "Invitations/Begin")
+	}
+	defer m.transaction.Rollback(ctx, tx)
+
+	_, e := orgManager(ctx, tx, m.orgRepos, orgID, userID)
+	if e != nil {
+		return nil, e.JoinLoc("Invitations")
+	}
+	invitations, err := m.orgRepos.Invitations(ctx, tx, orgID, domain.InvitationPending)
+	if err != nil {
+		return nil, errify.NewInternalServerError(err.Error(), "Invitations/Invitations")
+	}
+	return invitations, nil
+}
+
+func (m *OrganizationService) RevokeInvitation(ctx context.Context, orgID int, userID int, id int) errify.IError {
+	ctx, span := tracing.Start(ctx, "OrganizationService.RevokeInvitation")
+	defer span.End()
+
+	tx, err := m.transaction.Begin(ctx)
+	if err != nil {
+		return errify.NewInternalServerError(err.Error(), "RevokeInvitation/Begin")
+	}
+	defer m.transaction.Rollback(ctx, tx)
+
+	_, e := orgManager(ctx, tx, m.orgRepos, orgID, userID)
+	if e != nil {
+		return e.JoinLoc("RevokeInvitation")
+	}
+	err = m.orgRepos.SetInvitationStatus(ctx, tx, orgID, id, domain.InvitationRevoked)
+	if err != nil {
+		if errors.Is(err, repository.InvitationNotExist) {
+			return errify.NewBadRequestError(err.Error(), InvitationNotValid.Error(), "RevokeInvitation/SetInvitationStatus")
+		}
+		return errify.NewInternalServerError(err.Error(), "RevokeInvitation/SetInvitationStatus")
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return errify.NewInternalServerError(err.Error(), "RevokeInvitation/Commit")
+	}
+	return nil
+}
+
+func (m *OrganizationService) RespondInvitation(ctx context.Context, user *domain.AuthData, token string, accept bool) (*domain.Organization, errify.IError) {
+	ctx, span := tracing.Start(ctx, "OrganizationService.RespondInvitation")
+	defer span.End()
+
+	tx, err := m.transaction.Begin(ctx)
+	if err != nil {
+		return nil, errify.NewInternalServerError(err.Error(), "RespondInvitation/Begin")
+	}
+	defer m.transaction.Rollback(ctx, tx)
+
+	invitation, err := m.orgRepos.InvitationByTokenHash(ctx, tx, hashToken(token))
+	if err != nil {
+		if errors.Is(err, repository.InvitationNotExist) {
+			return nil, errify.NewBadRequestError(err.Error(), InvitationNotValid.Error(), "RespondInvitation/InvitationByTokenHash")
+		}
+		return nil, errify.NewInternalServerError(err.Error(), "RespondInvitation/InvitationByTokenHash")
+	}
+	// Приглашение может принять только владелец почты, на которую оно отправлено. Регистр адреса не важен
+	if invitation.Status != domain.InvitationPending || !strings.EqualFold(invitation.Email, user.Email) || time.Now().After(invitation.ExpiresAt) {
+		return nil, errify.NewBadRequestError(InvitationNotValid.Error(), InvitationNotValid.Error(), "RespondInvitation/Status")
+	}
+
+	status := domain.InvitationDeclined
+	if accept {
+		status = domain.InvitationAccepted
+		err = m.orgRepos.AddMember(ctx, tx, invitation.OrgID, user.ID, invitation.Role)
+		if err != nil {
+			if errors.Is(err, repository.MemberAlreadyExist) {
+				return nil, errify.NewBadRequestError(err.Error(), MemberIsAlreadyExist.Error(), "RespondInvitation/AddMember")
+			}
+			return nil, errify.NewInternalServerError(err.Error(), "RespondInvitation/AddMember")
+		}
+	}
+	err = m.orgRepos.SetInvitationStatus(ctx, tx, invitation.OrgID, invitation.ID, status)
+	if err != nil {
+		return nil, errify.NewInternalServerError(err.Error(), "RespondInvitation/SetInvitationStatus")
+	}
+	org, err := m.orgRepos.OrganizationById(ctx, tx, invitation.OrgID)
+	if err != nil {
+		return nil, errify.NewInternalServerError(err.Error(), "RespondInvitation/OrganizationById")
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, errify.NewInternalServerError(err.Error(), "RespondInvitation/Commit")
+	}
+	if accept {
+		org.Role = &invitation.Role
+	}
+	return org, nil
+}
+
+func (m *OrganizationService) SwitchOrganization(ctx context.Context, accessToken string, user *domain.AuthData, orgID int, cfg config.TokenConfig) (string, errify.IError) {
+	ctx, span := tracing.Start(ctx, "OrganizationService.SwitchOrganization")
+	defer span.End()
+
+	data := &domain.AuthData{
+		ID:    user.ID,
+		Email: user.Email,
+		Role:  user.Role,
+	}
+	// orgID 0 возвращает пользователя в личное пространство
+	if orgID != 0 {
+		tx, err := m.transaction.Begin(ctx)
+		if err != nil {
+			return "", errify.NewInternalServerError(err.Error(), "SwitchOrganization/Begin")
+		}
+		defer m.transaction.Rollback(ctx, tx)
+
+		member, e := activeOrgMember(ctx, tx, m.orgRepos, orgID, user.ID)
+		if e != nil {
+			return "", e.JoinLoc("SwitchOrganization")
+		}
+		data.OrgID, data.OrgRole = orgID, member.Role
+	}
+
+	redisTx, err := m.transaction.RedisTx(ctx)
+	if err != nil {
+		return "", errify.NewInternalServerError(err.Error(), "SwitchOrganization/RedisTx")
+	}
+	defer m.transaction.RedisRollback(ctx, redisTx)
+
+	err = m.authRepos.RemoveAuthorization(ctx, redisTx, accessToken)
+	if err != nil {
+		return "", errify.NewInternalServerError(err.Error(), "SwitchOrganization/RemoveAuthorization")
+	}
+	token, err := m.authRepos.Authorization(ctx, redisTx, data, cfg.RefreshTTL, cfg.AccessTTL, os.Getenv(config.Secret))
+	if err != nil {
+		return "", errify.NewInternalServerError(err.Error(), "SwitchOrganization/Authorization")
+	}
+	err = m.transaction.RedisCommit(redisTx)
+	if err != nil {
+		return "", errify.NewInternalServerError(err.Error(), "SwitchOrganization/RedisCommit")
+	}
+	return token, nil
+}
+
+// orgMember Возвращает участника организации, остальным пользователям доступ запрещен
+func orgMember(ctx context.Context, tx pgx.Tx, orgRepos repository.Organization, orgID int, userID int) (*domain.OrgMember, errify.IError) {
+	member, err := orgRepos.Member(ctx, tx, orgID, userID)
+	if err != nil {
+		if errors.Is(err, repository.MemberNotExist) {
+			return nil, NewForbiddenError(err.Error(), "orgMember/Member")
+		}
+		return nil, errify.NewInternalServerError(err.Error(), "orgMember/Member")
+	}
+	return member, nil
+}
+
+// activeOrgMember Возвращает участника, который имеет доступ к организации. Отключенные через SCIM участники доступа не имеют
+func activeOrgMember(ctx context.Context, tx pgx.Tx, orgRepos repository.Organization, orgID int, userID int) (*domain.OrgMember, errify.IError) {
+	member, e := orgMember(ctx, tx, orgRepos, orgID, userID)
+	if e != nil {
+		return nil, e.JoinLoc("activeOrgMember")
+	}
+	if !member.Active {
+		return nil, NewForbiddenError(ErrAccessDenied.Error(), "activeOrgMember/Active")
+	}
+	return member, nil
+}
+
+// orgManager Возвращает участника, который может управлять организацией.
+// Роль берется из базы, а не из токена, чтобы понижение роли применялось сразу
+func orgManager(ctx context.Context, tx pgx.Tx, orgRepos repository.Organization, orgID int, userID int) (*domain.OrgMember, errify.IError) {
+	member, e := activeOrgMember(ctx, tx, orgRepos, orgID, userID)
+	if e != nil {
+		return nil, e.JoinLoc("orgManager")
+	}
+	if !member.Role.CanManage() {
+		return nil, NewForbiddenError(ErrAccessDenied.Error(), "orgManager/CanManage")
+	}
+	return member, nil
+}
+
+func (m *OrganizationService) removeSessions(ctx context.Context, id int) errify.IError {
+	redisTx, err := m.transaction.RedisTx(ctx)
+	if err != nil {
+		return errify.NewInternalServerError(err.Error(), "removeSessions/RedisTx")
+	}
+	defer m.transaction.RedisRollback(ctx, redisTx)
+
+	err = m.authRepos.RemoveUserAuthorization(ctx, redisTx, id)
+	if err != nil {
+		return errify.NewInternalServerError(err.Error(), "removeSessions/RemoveUserAuthorization")
+	}
+	err = m.transaction.RedisCommit(redisTx)
+	if err != nil {
+		return errify.NewInternalServerError(err.Error(), "removeSessions/RedisCommit")
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package service
+
+import (
+	"auth/internal/domain"
+	"auth/internal/repository"
+	"auth/internal/tracing"
+	"context"
+	"errors"
+	"github.com/Linkify-Company/common_utils/errify"
+	"github.com/Linkify-Company/common_utils/logger"
+)
+
+// personalTokenPrefixLen Длина начала токена, которое хранится открыто для отображения в списке
+const personalTokenPrefixLen = len(domain.PersonalTokenPrefix) + 6
+
+type PersonalTokenService struct {
+	log         logger.Logger
+	transaction repository.Transaction
+	tokenRepos  repository.PersonalToken
+}
+
+func NewPersonalTokenService(
+	log logger.Logger,
+	transaction repository.Transaction,
+	tokenRepos repository.PersonalToken,
+) PersonalToken {
+	return &PersonalTokenService{
+		log:         log,
+		transaction: transaction,
+		tokenRepos:  tokenRepos,
+	}
+}
+
+func (m *PersonalTokenService) AddPersonalToken(ctx context.Context, userID int, req *domain.PersonalTokenCreate) (*domain.PersonalTokenCreated, errify.IError) {
+	ctx, span := tracing.Start(ctx, "PersonalTokenService.AddPersonalToken")
+	defer span.End()
+
+	secret, err := generateToken()
+	if err != nil {
+		return nil, errify.NewInternalServerError(err.Error(), "AddPersonalToken/generateToken")
+	}
+	value := domain.PersonalTokenPrefix + secret
+
+	tx, err := m.transaction.Begin(ctx)
+	if err != nil {
+		return nil, errify.NewInternalServerError(err.Error(), "AddPersonalToken/Begin")
+	}
+	defer m.transaction.Rollback(ctx, tx)
+
+	token := &domain.PersonalToken{
+		UserID:    userID,
+		Name:      req.Name,
+		Prefix:    value[:personalTokenPrefixLen],
+		Scopes:    req.Scopes,
+		ExpiresAt: req.ExpiresAt,
+		TokenHash: hashToken(value),
+	}
+	_, err = m.tokenRepos.AddPersonalToken(ctx, tx, token)
+	if err != nil {
+		return nil, errify.NewInternalServerError(err.Error(), "AddPersonalToken/AddPersonalToken")
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, errify.NewInternalServerError(err.Error(), "AddPersonalToken/Commit")
+	}
+	return &domain.PersonalTokenCreated{PersonalToken: token, Token: value}, nil
+}
+
+func (m *PersonalTokenService) PersonalTokens(ctx context.Context, userID int) ([]*domain.PersonalToken, errify.IError) {
+	ctx, span := tracing.Start(ctx, "PersonalTokenService.PersonalTokens")
+	defer span.End()
+
+	tx, err := m.transaction.Begin(ctx)
+	if err != nil {
+		return nil, errify.NewInternalServerError(err.Error(), "PersonalTokens/Begin")
+	}
+	defer m.transaction.Rollback(ctx, tx)
+
+	tokens, err := m.tokenRepos.PersonalTokens(ctx, tx, userID)
+	if err != nil {
+		return nil, errify.NewInternalServerError(err.Error(), "PersonalTokens/PersonalTokens")
+	}
+	return tokens, nil
+}
+
+func (m *PersonalTokenService) RevokePersonalToken(ctx context.Context, userID int, id int) errify.IError {
+	ctx, span := tracing.Start(ctx, "PersonalTokenService.RevokePersonalToken")
+	defer span.End()
+
+	tx, err := m.transaction.Begin(ctx)
+	if err != nil {
+		return errify.NewInternalServerError(err.Error(), "RevokePersonalToken/Begin")
+	}
+	defer m.transaction.Rollback(ctx, tx)
+
+	err = m.tokenRepos.RevokePersonalToken(ctx, tx, userID, id)
+	if err != nil {
+		if errors.Is(err, repository.TokenNotExist) {
+			return errify.NewBadRequestError(err.Error(), PersonalTokenNotExist.Error(), "RevokePersonalToken/RevokePersonalToken")
+		}
+		return errify.NewInternalServerError(err.Error(), "RevokePersonalToken/RevokePersonalToken")
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return errify.NewInternalServerError(err.Error(), "RevokePersonalToken/Commit")
+	}
+	return nil
+}
@@ -0,0 +1,184 @@
+package service
+
+import (
+	"auth/internal/config"
+	"auth/internal/domain"
+	"auth/internal/metrics"
+	"auth/internal/repository"
+	"auth/internal/tracing"
+	"context"
+	"errors"
+	"github.com/Linkify-Company/common_utils/errify"
+	"github.com/Linkify-Company/common_utils/logger"
+	"os"
+)
+
+type SAMLService struct {
+	identityResolver
+
+	log         logger.Logger
+	transaction repository.Transaction
+	samlRepos   repository.SAML
+	roleRepos   repository.Role
+	authRepos   repository.Auth
+	samlCfg     config.SAMLConfig
+}
+
+func NewSAMLService(
+	log logger.Logger,
+	transaction repository.Transaction,
+	samlRepos repository.SAML,
+	identityRepos repository.Identity,
+	userRepos repository.User,
+	roleRepos repository.Role,
+	authRepos repository.Auth,
+	samlCfg config.SAMLConfig,
+) SAML {
+	return &SAMLService{
+		identityResolver: identityResolver{
+			identityRepos: identityRepos,
+			userRepos:     userRepos,
+		},
+		log:         log,
+		transaction: transaction,
+		samlRepos:   samlRepos,
+		roleRepos:   roleRepos,
+		authRepos:   authRepos,
+		samlCfg:     samlCfg,
+	}
+}
+
+func (m *SAMLService) SAMLMetadata(ctx context.Context, idp string) ([]byte, errify.IError) {
+	ctx, span := tracing.Start(ctx, "SAMLService.SAMLMetadata")
+	defer span.End()
+
+	metadata, err := m.samlRepos.SAMLMetadata(ctx, idp)
+	if err != nil {
+		if errors.Is(err, repository.IdentityProviderNotExist) {
+			return nil, errify.NewBadRequestError(err.Error(), ProviderNotExist.Error(), "SAMLMetadata/SAMLMetadata")
+		}
+		return nil, errify.NewInternalServerError(err.Error(), "SAMLMetadata/SAMLMetadata")
+	}
+	return metadata, nil
+}
+
+func (m *SAMLService) SAMLLogin(ctx context.Context, idp string) (*domain.SAMLAuthnRequest, errify.IError) {
+	ctx, span := tracing.Start(ctx, "SAMLService.SAMLLogin")
+	defer span.End()
+
+	relayState, err := generateToken()
+	if err != nil {
+		return nil, errify.NewInternalServerError(err.Error(), "SAMLLogin/generateToken")
+	}
+	request, err := m.samlRepos.SAMLAuthnRequest(ctx, idp, relayState)
+	if err != nil {
+		if errors.Is(err, repository.IdentityProviderNotExist) {
+			return nil, errify.NewBadRequestError(err.Error(), ProviderNotExist.Error(), "SAMLLogin/SAMLAuthnRequest")
+		}
+		return nil, errify.NewInternalServerError(err.Error(), "SAMLLogin/SAMLAuthnRequest")
+	}
+	err = m.samlRepos.SetSAMLState(ctx, relayState, &domain.SAMLState{
+		IdP:       idp,
+		RequestID: request.ID,
+	}, m.samlCfg.RequestTTL)
+	if err != nil {
+		return nil, errify.NewInternalServerError(err.Error(), "SAMLLogin/SetSAMLState")
+	}
+	return request, nil
+}
+
+func (m *SAMLService) SAMLAssertionConsumer(ctx context.Context, idp string, response string, relayState string, cfg config.TokenConfig) (string, errify.IError) {
+	ctx, span := tracing.Start(ctx, "SAMLService.SAMLAssertionConsumer")
+	defer span.End()
+
+	token, err := m.samlAssertionConsumer(ctx, idp, response, relayState, cfg)
+	metrics.Login(metrics.LoginSAML, loginFailureReason(err))
+	return token, err
+}
+
+func (m *SAMLService) samlAssertionConsumer(ctx context.Context, idp string, response string, relayState string, cfg config.TokenConfig) (string, errify.IError) {
+	// Без сохраненного запроса ответ считается входом, начатым на стороне провайдера,
+	// и принимается, только если это разрешено для провайдера
+	var requestIDs []string
+	if relayState != "" {
+		state, err := m.samlRepos.PopSAMLState(ctx, relayState)
+		if err != nil && !errors.Is(err, repository.SAMLStateNotExist) {
+			return "", errify.NewInternalServerError(err.Error(), "SAMLAssertionConsumer/PopSAMLState")
+		}
+		if state != nil {
+			if state.IdP != idp {
+				return "", errify.NewBadRequestError(ErrOAuthStateNotValid.Error(), ErrOAuthStateNotValid.Error(), "SAMLAssertionConsumer/IdP")
+			}
+			requestIDs = []string{state.RequestID}
+		}
+	}
+
+	assertion, err := m.samlRepos.ParseSAMLResponse(ctx, idp, response, requestIDs)
+	if err != nil {
+		if errors.Is(err, repository.IdentityProviderNotExist) {
+			return "", errify.NewBadRequestError(err.Error(), ProviderNotExist.Error(), "SAMLAssertionConsumer/ParseSAMLResponse")
+		}
+		if errors.Is(err, repository.SAMLAssertionNotValid) {
+			return "", errify.NewUnauthorizedError(err.Error(), ErrSAMLAssertionNotValid.Error(), "SAMLAssertionConsumer/ParseSAMLResponse")
+		}
+		return "", errify.NewInternalServerError(err.Error(), "SAMLAssertionConsumer/ParseSAMLResponse")
+	}
+	err = m.samlRepos.UseSAMLAssertion(ctx, idp, assertion)
+	if err != nil {
+		if errors.Is(err, repository.SAMLAssertionReplayed) {
+			return "", errify.NewUnauthorizedError(err.Error(), ErrSAMLAssertionReplayed.Error(), "SAMLAssertionConsumer/UseSAMLAssertion")
+		}
+		if errors.Is(err, repository.SAMLAssertionNotValid) {
+			return "", errify.NewUnauthorizedError(err.Error(), ErrSAMLAssertionNotValid.Error(), "SAMLAssertionConsumer/UseSAMLAssertion")
+		}
+		return "", errify.NewInternalServerError(err.Error(), "SAMLAssertionConsumer/UseSAMLAssertion")
+	}
+
+	tx, err := m.transaction.Begin(ctx)
+	if err != nil {
+		return "", errify.NewInternalServerError(err.Error(), "SAMLAssertionConsumer/Begin")
+	}
+	defer m.transaction.Rollback(ctx, tx)
+
+	user, e := m.identityUser(ctx, tx, assertion.Identity)
+	if e != nil {
+		return "", e.JoinLoc("SAMLAssertionConsumer")
+	}
+	if user.DeletedAt != nil {
+		return "", errify.NewBadRequestError(ErrInvalidCredentials.Error(), ErrInvalidCredentials.Error(), "SAMLAssertionConsumer/DeletedAt")
+	}
+	if user.Locked {
+		return "", errify.NewBadRequestError(ErrUserLocked.Error(), ErrUserLocked.Error(), "SAMLAssertionConsumer/Locked")
+	}
+	if assertion.Role != nil {
+		err = syncRole(ctx, tx, m.roleRepos, user, *assertion.Role, "saml attribute sync: "+idp)
+		if err != nil {
+			return "", errify.NewInternalServerError(err.Error(), "SAMLAssertionConsumer/syncRole")
+		}
+	}
+
+	redisTx, err := m.transaction.RedisTx(ctx)
+	if err != nil {
+		return "", errify.NewInternalServerError(err.Error(), "SAMLAssertionConsumer/RedisTx")
+	}
+	defer m.transaction.RedisRollback(ctx, redisTx)
+
+	token, err := m.authRepos.Authorization(ctx, redisTx, &domain.AuthData{
+		ID:    user.ID,
+		Email: user.Email,
+		Role:  user.Role,
+	}, cfg.RefreshTTL, cfg.AccessTTL, os.Getenv(config.Secret))
+	if err != nil {
+		return "", errify.NewInternalServerError(err.Error(), "SAMLAssertionConsumer/Authorization")
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return "", errify.NewInternalServerError(err.Error(), "SAMLAssertionConsumer/Commit")
+	}
+	err = m.transaction.RedisCommit(redisTx)
+	if err != nil {
+		return "", errify.NewInternalServerError(err.Error(), "SAMLAssertionConsumer/RedisCommit")
+	}
+	return token, nil
+}
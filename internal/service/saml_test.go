@@ -0,0 +1,137 @@
+package service
+
+import (
+	"auth/internal/config"
+	"auth/internal/domain"
+	"auth/internal/repository"
+	"context"
+	"errors"
+	"github.com/Linkify-Company/common_utils/errify"
+	"github.com/jackc/pgx/v5"
+	"slices"
+	"testing"
+	"time"
+)
+
+// fakeSAMLRepos Принимает любой ответ провайдера и, как Redis, помнит использованные утверждения
+type fakeSAMLRepos struct {
+	repository.SAML
+	states        map[string]*domain.SAMLState
+	used          map[string]bool
+	gotRequestIDs []string
+}
+
+func newFakeSAMLRepos() *fakeSAMLRepos {
+	return &fakeSAMLRepos{states: make(map[string]*domain.SAMLState), used: make(map[string]bool)}
+}
+
+func (m *fakeSAMLRepos) SAMLAuthnRequest(context.Context, string, string) (*domain.SAMLAuthnRequest, error) {
+	return &domain.SAMLAuthnRequest{ID: "id-request-1", RedirectURL: "https://idp.example.com/sso"}, nil
+}
+
+func (m *fakeSAMLRepos) ParseSAMLResponse(_ context.Context, _ string, _ string, requestIDs []string) (*domain.SAMLAssertion, error) {
+	m.gotRequestIDs = requestIDs
+	return &domain.SAMLAssertion{
+		ID:        "id-assertion-1",
+		Identity:  &domain.ExternalIdentity{Provider: "saml:test", Subject: "alice", Email: "alice@example.com"},
+		ExpiresAt: time.Now().Add(time.Minute),
+	}, nil
+}
+
+func (m *fakeSAMLRepos) UseSAMLAssertion(_ context.Context, idp string, assertion *domain.SAMLAssertion) error {
+	key := idp + ":" + assertion.ID
+	if m.used[key] {
+		return repository.SAMLAssertionReplayed
+	}
+	m.used[key] = true
+	return nil
+}
+
+func (m *fakeSAMLRepos) SetSAMLState(_ context.Context, relayState string, data *domain.SAMLState, _ time.Duration) error {
+	m.states[relayState] = data
+	return nil
+}
+
+func (m *fakeSAMLRepos) PopSAMLState(_ context.Context, relayState string) (*domain.SAMLState, error) {
+	data, ok := m.states[relayState]
+	if !ok {
+		return nil, repository.SAMLStateNotExist
+	}
+	delete(m.states, relayState)
+	return data, nil
+}
+
+// unavailableTransaction База недоступна, поэтому вход останавливается сразу после проверки утверждения
+type unavailableTransaction struct {
+	repository.Transaction
+}
+
+func (unavailableTransaction) Begin(context.Context) (pgx.Tx, error) {
+	return nil, errors.New("database unavailable")
+}
+
+func newTestSAMLService(repos *fakeSAMLRepos) *SAMLService {
+	return &SAMLService{
+		transaction: unavailableTransaction{},
+		samlRepos:   repos,
+		samlCfg:     config.SAMLConfig{RequestTTL: time.Minute},
+	}
+}
+
+// relayState RelayState последнего запроса аутентификации
+func (m *fakeSAMLRepos) relayState() string {
+	for relayState := range m.states {
+		return relayState
+	}
+	return ""
+}
+
+func TestSAMLAssertionConsumerRequestID(t *testing.T) {
+	ctx := context.Background()
+	repos := newFakeSAMLRepos()
+	m := newTestSAMLService(repos)
+
+	if _, err := m.SAMLLogin(ctx, "test"); err != nil {
+		t.Fatalf("SAMLLogin() error = %v", err)
+	}
+	relayState := repos.relayState()
+
+	_, _ = m.SAMLAssertionConsumer(ctx, "test", "response", relayState, config.TokenConfig{})
+	if !slices.Equal(repos.gotRequestIDs, []string{"id-request-1"}) {
+		t.Fatalf("ParseSAMLResponse() request ids = %v, want the id of the login request", repos.gotRequestIDs)
+	}
+
+	// RelayState одноразовый: повторный ответ проверяется как вход, начатый провайдером
+	_, _ = m.SAMLAssertionConsumer(ctx, "test", "response", relayState, config.TokenConfig{})
+	if len(repos.gotRequestIDs) != 0 {
+		t.Fatalf("ParseSAMLResponse() request ids = %v for a used relay state", repos.gotRequestIDs)
+	}
+}
+
+func TestSAMLAssertionConsumerOtherIdP(t *testing.T) {
+	ctx := context.Background()
+	repos := newFakeSAMLRepos()
+	m := newTestSAMLService(repos)
+	if _, err := m.SAMLLogin(ctx, "test"); err != nil {
+		t.Fatalf("SAMLLogin() error = %v", err)
+	}
+
+	_, err := m.SAMLAssertionConsumer(ctx, "other", "response", repos.relayState(), config.TokenConfig{})
+	if _, ok := err.(*errify.BadRequestError); !ok {
+		t.Fatalf("SAMLAssertionConsumer() error = %v, want bad request", err)
+	}
+}
+
+func TestSAMLAssertionConsumerReplay(t *testing.T) {
+	ctx := context.Background()
+	m := newTestSAMLService(newFakeSAMLRepos())
+
+	_, err := m.SAMLAssertionConsumer(ctx, "test", "response", "", config.TokenConfig{})
+	if _, ok := err.(*errify.InternalServerError); !ok {
+		t.Fatalf("first SAMLAssertionConsumer() error = %v, want the database error", err)
+	}
+	_, err = m.SAMLAssertionConsumer(ctx, "test", "response", "", config.TokenConfig{})
+	if _, ok := err.(*errify.UnauthorizedError); !ok {
+		t.Fatalf("replayed SAMLAssertionConsumer() error = %v, want unauthorized", err)
+	}
+}
@@ -0,0 +1,774 @@
+package service
+
+import (
+	"auth/internal/config"
+	"auth/internal/domain"
+	"auth/internal/repository"
+	"auth/internal/tracing"
+	"context"
+	"errors"
+	"github.com/Linkify-Company/common_utils/errify"
+	"github.com/Linkify-Company/common_utils/logger"
+	"github.com/jackc/pgx/v5"
+	"strings"
+)
+
+// scimTokenPrefixLen Длина начала токена, которое хранится открыто для отображения в списке
+const scimTokenPrefixLen = len(domain.SCIMTokenPrefix) + 6
+
+type SCIMService struct {
+	log         logger.Logger
+	transaction repository.Transaction
+	scimRepos   repository.SCIM
+	orgRepos    repository.Organization
+	userRepos   repository.User
+	authRepos   repository.Auth
+	scimCfg     config.SCIMConfig
+}
+
+func NewSCIMService(
+	log logger.Logger,
+	transaction repository.Transaction,
+	scimRepos repository.SCIM,
+	orgRepos repository.Organization,
+	userRepos repository.User,
+	authRepos repository.Auth,
+	scimCfg config.SCIMConfig,
+) SCIM {
+	return &SCIMService{
+		log:         log,
+		transaction: transaction,
+		scimRepos:   scimRepos,
+		orgRepos:    orgRepos,
+		userRepos:   userRepos,
+		authRepos:   authRepos,
+		scimCfg:     scimCfg,
+	}
+}
+
+func (m *SCIMService) AddSCIMToken(ctx context.Context, orgID int, userID int, req *domain.SCIMTokenCreate) (*domain.SCIMTokenCreated, errify.IError) {
+	ctx, span := tracing.Start(ctx, "SCIMService.AddSCIMToken")
+	defer span.End()
+
+	secret, err := generateToken()
+	if err != nil {
+		return nil, errify.NewInternalServerError(err.Error(), "AddSCIMToken/generateToken")
+	}
+	value := domain.SCIMTokenPrefix + secret
+
+	tx, err := m.transaction.Begin(ctx)
+	if err != nil {
+		return nil, errify.NewInternalServerError(err.Error(), "AddSCIMToken/Begin")
+	}
+	defer m.transaction.Rollback(ctx, tx)
+
+	_, e := orgManager(ctx, tx, m.orgRepos, orgID, userID)
+	if e != nil {
+		return nil, e.JoinLoc("AddSCIMToken")
+	}
+	token := &domain.SCIMToken{
+		OrgID:     orgID,
+		Name:      req.Name,
+		Prefix:    value[:scimTokenPrefixLen],
+		CreatedBy: userID,
+		TokenHash: hashToken(value),
+	}
+	_, err = m.scimRepos.AddSCIMToken(ctx, tx, token)
+	if err != nil {
+		return nil, errify.NewInternalServerError(err.Error(), "AddSCIMToken/AddSCIMToken")
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, errify.NewInternalServerError(err.Error(), "AddSCIMToken/Commit")
+	}
+	return &domain.SCIMTokenCreated{SCIMToken: token, Token: value}, nil
+}
+
+func (m *SCIMService) SCIMTokens(ctx context.Context, orgID int, userID int) ([]*domain.SCIMToken, errify.IError) {
+	ctx, span := tracing.Start(ctx, "SCIMService.SCIMTokens")
+	defer span.End()
+
+	tx, err := m.transaction.Begin(ctx)
+	if err != nil {
+		return nil, errify.NewInternalServerError(err.Error(), "SCIMTokens/Begin")
+	}
+	defer m.transaction.Rollback(ctx, tx)
+
+	_, e := orgManager(ctx, tx, m.orgRepos, orgID, userID)
+	if e != nil {
+		return nil, e.JoinLoc("SCIMTokens")
+	}
+	tokens, err := m.scimRepos.SCIMTokens(ctx, tx, orgID)
+	if err != nil {
+		return nil, errify.NewInternalServerError(err.Error(), "SCIMTokens/SCIMTokens")
+	}
+	return tokens, nil
+}
+
+func (m *SCIMService) RevokeSCIMToken(ctx context.Context, orgID int, userID int, id int) errify.IError {
+	ctx, span := tracing.Start(ctx, "SCIMService.RevokeSCIMToken")
+	defer span.End()
+
+	tx, err := m.transaction.Begin(ctx)
+	if err != nil {
+		return errify.NewInternalServerError(err.Error(), "RevokeSCIMToken/Begin")
+	}
+	defer m.transaction.Rollback(ctx, tx)
+
+	_, e := orgManager(ctx, tx, m.orgRepos, orgID, userID)
+	if e != nil {
+		return e.JoinLoc("RevokeSCIMToken")
+	}
+	err = m.scimRepos.RevokeSCIMToken(ctx, tx, orgID, id)
+	if err != nil {
+		if errors.Is(err, repository.TokenNotExist) {
+			return errify.NewBadRequestError(err.Error(), SCIMTokenNotExist.Error(), "RevokeSCIMToken/RevokeSCIMToken")
+		}
+		return errify.NewInternalServerError(err.Error(), "RevokeSCIMToken/RevokeSCIMToken")
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return errify.NewInternalServerError(err.Error(), "RevokeSCIMToken/Commit")
+	}
+	return nil
+}
+
+func (m *SCIMService) SCIMTenant(ctx context.Context, value string) (int, errify.IError) {
+	ctx, span := tracing.Start(ctx, "SCIMService.SCIMTenant")
+	defer span.End()
+
+	if !strings.HasPrefix(value, domain.SCIMTokenPrefix) {
+		return 0, errify.NewUnauthorizedError(repository.TokenNotValid.Error(), ErrInvalidCredentials.Error(), "SCIMTenant/HasPrefix")
+	}
+	tx, err := m.transaction.Begin(ctx)
+	if err != nil {
+		return 0, errify.NewInternalServerError(err.Error(), "SCIMTenant/Begin")
+	}
+	defer m.transaction.Rollback(ctx, tx)
+
+	token, err := m.scimRepos.SCIMTokenByHash(ctx, tx, hashToken(value))
+	if err != nil {
+		if errors.Is(err, repository.TokenNotExist) {
+			return 0, errify.NewUnauthorizedError(err.Error(), ErrInvalidCredentials.Error(), "SCIMTenant/SCIMTokenByHash")
+		}
+		return 0, errify.NewInternalServerError(err.Error(), "SCIMTenant/SCIMTokenByHash")
+	}
+	if token.RevokedAt != nil {
+		return 0, errify.NewUnauthorizedError(repository.TokenNotValid.Error(), ErrInvalidCredentials.Error(), "SCIMTenant/RevokedAt")
+	}
+	err = m.scimRepos.TouchSCIMToken(ctx, tx, token.ID)
+	if err != nil {
+		return 0, errify.NewInternalServerError(err.Error(), "SCIMTenant/TouchSCIMToken")
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return 0, errify.NewInternalServerError(err.Error(), "SCIMTenant/Commit")
+	}
+	return token.OrgID, nil
+}
+
+func (m *SCIMService) SCIMUsers(ctx context.Context, orgID int, query *domain.SCIMQuery) (*domain.SCIMListResponse, errify.IError) {
+	ctx, span := tracing.Start(ctx, "SCIMService.SCIMUsers")
+	defer span.End()
+
+	tx, err := m.transaction.Begin(ctx)
+	if err != nil {
+		return nil, errify.NewInternalServerError(err.Error(), "SCIMUsers/Begin")
+	}
+	defer m.transaction.Rollback(ctx, tx)
+
+	members, total, err := m.scimRepos.SCIMMembers(ctx, tx, orgID, query)
+	if err != nil {
+		return nil, errify.NewInternalServerError(err.Error(), "SCIMUsers/SCIMMembers")
+	}
+	users := make([]*domain.SCIMUser, 0, len(members))
+	for _, member := range members {
+		groups, err := m.scimRepos.MemberGroups(ctx, tx, orgID, member.UserID)
+		if err != nil {
+			return nil, errify.NewInternalServerError(err.Error(), "SCIMUsers/MemberGroups")
+		}
+		users = append(users, domain.NewSCIMUser(member, groups))
+	}
+	return domain.NewSCIMListResponse(users, len(users), total, query.StartIndex), nil
+}
+
+func (m *SCIMService) SCIMUser(ctx context.Context, orgID int, userID int) (*domain.SCIMUser, errify.IError) {
+	ctx, span := tracing.Start(ctx, "SCIMService.SCIMUser")
+	defer span.End()
+
+	tx, err := m.transaction.Begin(ctx)
+	if err != nil {
+		return nil, errify.NewInternalServerError(err.Error(), "SCIMUser/Begin")
+	}
+	defer m.transaction.Rollback(ctx, tx)
+
+	user, e := m.scimUser(ctx, tx, orgID, userID)
+	if e != nil {
+		return nil, e.JoinLoc("SCIMUser")
+	}
+	return user, nil
+}
+
+// AddSCIMUser Создает пользователя и добавляет его в организацию. Существующий пользователь
+// принимается под управление SCIM, только если уже состоит в организации
+func (m *SCIMService) AddSCIMUser(ctx context.Context, orgID int, user *domain.SCIMUser) (*domain.SCIMUser, errify.IError) {
+	ctx, span := tracing.Start(ctx, "SCIMService.AddSCIMUser")
+	defer span.End()
+
+	tx, err := m.transaction.Begin(ctx)
+	if err != nil {
+		return nil, errify.NewInternalServerError(err.Error(), "AddSCIMUser/Begin")
+	}
+	defer m.transaction.Rollback(ctx, tx)
+
+	email := user.Email()
+	existing, err := m.userRepos.UserByEmail(ctx, tx, email)
+	if err != nil && !errors.Is(err, repository.UserNotExist) {
+		return nil, errify.NewInternalServerError(err.Error(), "AddSCIMUser/UserByEmail")
+	}
+
+	var userID int
+	if existing != nil {
+		member, err := m.orgRepos.Member(ctx, tx, orgID, existing.ID)
+		if err != nil {
+			if errors.Is(err, repository.MemberNotExist) {
+				return nil, errify.NewBadRequestError(ErrSCIMResourceConflict.Error(), ErrSCIMResourceConflict.Error(), "AddSCIMUser/Member")
+			}
+			return nil, errify.NewInternalServerError(err.Error(), "AddSCIMUser/Member")
+		}
+		if member.ExternalID != nil {
+			return nil, errify.NewBadRequestError(ErrSCIMResourceConflict.Error(), ErrSCIMResourceConflict.Error(), "AddSCIMUser/ExternalID")
+		}
+		e := m.updateUser(ctx, tx, member, user)
+		if e != nil {
+			return nil, e.JoinLoc("AddSCIMUser")
+		}
+		userID = member.UserID
+	} else {
+		created, err := addExternalUser(ctx, tx, m.userRepos, email, domain.RoleUser)
+		if err != nil {
+			return nil, errify.NewInternalServerError(err.Error(), "AddSCIMUser/addExternalUser")
+		}
+		if !user.IsActive() {
+			locked := true
+			err = m.userRepos.UpdateUser(ctx, tx, created.ID, &domain.UserUpdate{Locked: &locked})
+			if err != nil {
+				return nil, errify.NewInternalServerError(err.Error(), "AddSCIMUser/UpdateUser")
+			}
+		}
+		err = m.scimRepos.ProvisionMember(ctx, tx, &domain.OrgMember{
+			OrgID:       orgID,
+			UserID:      created.ID,
+			Role:        domain.OrgRoleMember,
+			Active:      user.IsActive(),
+			ExternalID:  externalID(user.ExternalID),
+			Provisioned: true,
+		})
+		if err != nil {
+			if errors.Is(err, repository.MemberAlreadyExist) {
+				return nil, errify.NewBadRequestError(ErrSCIMResourceConflict.Error(), ErrSCIMResourceConflict.Error(), "AddSCIMUser/ProvisionMember")
+			}
+			return nil, errify.NewInternalServerError(err.Error(), "AddSCIMUser/ProvisionMember")
+		}
+		userID = created.ID
+	}
+
+	result, e := m.scimUser(ctx, tx, orgID, userID)
+	if e != nil {
+		return nil, e.JoinLoc("AddSCIMUser")
+	}
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, errify.NewInternalServerError(err.Error(), "AddSCIMUser/Commit")
+	}
+	return result, nil
+}
+
+func (m *SCIMService) ReplaceSCIMUser(ctx context.Context, orgID int, userID int, user *domain.SCIMUser) (*domain.SCIMUser, errify.IError) {
+	ctx, span := tracing.Start(ctx, "SCIMService.ReplaceSCIMUser")
+	defer span.End()
+
+	tx, err := m.transaction.Begin(ctx)
+	if err != nil {
+		return nil, errify.NewInternalServerError(err.Error(), "ReplaceSCIMUser/Begin")
+	}
+	defer m.transaction.Rollback(ctx, tx)
+
+	member, e := m.member(ctx, tx, orgID, userID)
+	if e != nil {
+		return nil, e.JoinLoc("ReplaceSCIMUser")
+	}
+	e = m.updateUser(ctx, tx, member, user)
+	if e != nil {
+		return nil, e.JoinLoc("ReplaceSCIMUser")
+	}
+	result, e := m.scimUser(ctx, tx, orgID, userID)
+	if e != nil {
+		return nil, e.JoinLoc("ReplaceSCIMUser")
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, errify.NewInternalServerError(err.Error(), "ReplaceSCIMUser/Commit")
+	}
+	return result, nil
+}
+
+func (m *SCIMService) PatchSCIMUser(ctx context.Context, orgID int, userID int, patch *domain.SCIMPatch) (*domain.SCIMUser, errify.IError) {
+	ctx, span := tracing.Start(ctx, "SCIMService.PatchSCIMUser")
+	defer span.End()
+
+	tx, err := m.transaction.Begin(ctx)
+	if err != nil {
+		return nil, errify.NewInternalServerError(err.Error(), "PatchSCIMUser/Begin")
+	}
+	defer m.transaction.Rollback(ctx, tx)
+
+	member, e := m.member(ctx, tx, orgID, userID)
+	if e != nil {
+		return nil, e.JoinLoc("PatchSCIMUser")
+	}
+	user := domain.NewSCIMUser(member, nil)
+	err = user.Patch(patch.Operations)
+	if err == nil {
+		err = user.Valid()
+	}
+	if err != nil {
+		return nil, errify.NewBadRequestError(err.Error(), err.Error(), "PatchSCIMUser/Patch")
+	}
+	e = m.updateUser(ctx, tx, member, user)
+	if e != nil {
+		return nil, e.JoinLoc("PatchSCIMUser")
+	}
+	result, e := m.scimUser(ctx, tx, orgID, userID)
+	if e != nil {
+		return nil, e.JoinLoc("PatchSCIMUser")
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, errify.NewInternalServerError(err.Error(), "PatchSCIMUser/Commit")
+	}
+	return result, nil
+}
+
+// DeleteSCIMUser Исключает пользователя из организации и завершает его сессии.
+// Учетная запись, созданная организацией через SCIM, удаляется
+func (m *SCIMService) DeleteSCIMUser(ctx context.Context, orgID int, userID int) errify.IError {
+	ctx, span := tracing.Start(ctx, "SCIMService.DeleteSCIMUser")
+	defer span.End()
+
+	tx, err := m.transaction.Begin(ctx)
+	if err != nil {
+		return errify.NewInternalServerError(err.Error(), "DeleteSCIMUser/Begin")
+	}
+	defer m.transaction.Rollback(ctx, tx)
+
+	member, e := m.member(ctx, tx, orgID, userID)
+	if e != nil {
+		return e.JoinLoc("DeleteSCIMUser")
+	}
+	if member.Role == domain.OrgRoleOwner {
+		return errify.NewBadRequestError(ErrOwnerCanNotLeave.Error(), ErrOwnerCanNotLeave.Error(), "DeleteSCIMUser/Owner")
+	}
+	err = m.orgRepos.RemoveMember(ctx, tx, orgID, userID)
+	if err != nil {
+		return errify.NewInternalServerError(err.Error(), "DeleteSCIMUser/RemoveMember")
+	}
+	if member.Provisioned {
+		err = m.userRepos.DeleteUser(ctx, tx, userID)
+		if err != nil && !errors.Is(err, repository.UserNotExist) {
+			return errify.NewInternalServerError(err.Error(), "DeleteSCIMUser/DeleteUser")
+		}
+	}
+	e = m.removeSessions(ctx, userID)
+	if e != nil {
+		return e.JoinLoc("DeleteSCIMUser")
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return errify.NewInternalServerError(err.Error(), "DeleteSCIMUser/Commit")
+	}
+	return nil
+}
+
+func (m *SCIMService) SCIMGroups(ctx context.Context, orgID int, query *domain.SCIMQuery) (*domain.SCIMListResponse, errify.IError) {
+	ctx, span := tracing.Start(ctx, "SCIMService.SCIMGroups")
+	defer span.End()
+
+	tx, err := m.transaction.Begin(ctx)
+	if err != nil {
+		return nil, errify.NewInternalServerError(err.Error(), "SCIMGroups/Begin")
+	}
+	defer m.transaction.Rollback(ctx, tx)
+
+	groups, total, err := m.scimRepos.Groups(ctx, tx, orgID, query)
+	if err != nil {
+		return nil, errify.NewInternalServerError(err.Error(), "SCIMGroups/Groups")
+	}
+	resources := make([]*domain.SCIMGroup, 0, len(groups))
+	for _, group := range groups {
+		resources = append(resources, domain.NewSCIMGroup(group))
+	}
+	return domain.NewSCIMListResponse(resources, len(resources), total, query.StartIndex), nil
+}
+
+func (m *SCIMService) SCIMGroup(ctx context.Context, orgID int, id int) (*domain.SCIMGroup, errify.IError) {
+	ctx, span := tracing.Start(ctx, "SCIMService.SCIMGroup")
+	defer span.End()
+
+	tx, err := m.transaction.Begin(ctx)
+	if err != nil {
+		return nil, errify.NewInternalServerError(err.Error(), "SCIMGroup/Begin")
+	}
+	defer m.transaction.Rollback(ctx, tx)
+
+	group, e := m.group(ctx, tx, orgID, id)
+	if e != nil {
+		return nil, e.JoinLoc("SCIMGroup")
+	}
+	return domain.NewSCIMGroup(group), nil
+}
+
+func (m *SCIMService) AddSCIMGroup(ctx context.Context, orgID int, group *domain.SCIMGroup) (*domain.SCIMGroup, errify.IError) {
+	ctx, span := tracing.Start(ctx, "SCIMService.AddSCIMGroup")
+	defer span.End()
+
+	tx, err := m.transaction.Begin(ctx)
+	if err != nil {
+		return nil, errify.NewInternalServerError(err.Error(), "AddSCIMGroup/Begin")
+	}
+	defer m.transaction.Rollback(ctx, tx)
+
+	id, err := m.scimRepos.AddGroup(ctx, tx, &domain.OrgGroup{
+		OrgID:       orgID,
+		DisplayName: group.DisplayName,
+		ExternalID:  externalID(group.ExternalID),
+	})
+	if err != nil {
+		if errors.Is(err, repository.GroupAlreadyExist) {
+			return nil, errify.NewBadRequestError(ErrSCIMResourceConflict.Error(), ErrSCIMResourceConflict.Error(), "AddSCIMGroup/AddGroup")
+		}
+		return nil, errify.NewInternalServerError(err.Error(), "AddSCIMGroup/AddGroup")
+	}
+	e := m.setGroupMembers(ctx, tx, orgID, id, group, nil)
+	if e != nil {
+		return nil, e.JoinLoc("AddSCIMGroup")
+	}
+	result, e := m.group(ctx, tx, orgID, id)
+	if e != nil {
+		return nil, e.JoinLoc("AddSCIMGroup")
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, errify.NewInternalServerError(err.Error(), "AddSCIMGroup/Commit")
+	}
+	return domain.NewSCIMGroup(result), nil
+}
+
+func (m *SCIMService) ReplaceSCIMGroup(ctx context.Context, orgID int, id int, group *domain.SCIMGroup) (*domain.SCIMGroup, errify.IError) {
+	ctx, span := tracing.Start(ctx, "SCIMService.ReplaceSCIMGroup")
+	defer span.End()
+
+	tx, err := m.transaction.Begin(ctx)
+	if err != nil {
+		return nil, errify.NewInternalServerError(err.Error(), "ReplaceSCIMGroup/Begin")
+	}
+	defer m.transaction.Rollback(ctx, tx)
+
+	current, e := m.group(ctx, tx, orgID, id)
+	if e != nil {
+		return nil, e.JoinLoc("ReplaceSCIMGroup")
+	}
+	result, e := m.updateGroup(ctx, tx, current, group)
+	if e != nil {
+		return nil, e.JoinLoc("ReplaceSCIMGroup")
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, errify.NewInternalServerError(err.Error(), "ReplaceSCIMGroup/Commit")
+	}
+	return result, nil
+}
+
+func (m *SCIMService) PatchSCIMGroup(ctx context.Context, orgID int, id int, patch *domain.SCIMPatch) (*domain.SCIMGroup, errify.IError) {
+	ctx, span := tracing.Start(ctx, "SCIMService.PatchSCIMGroup")
+	defer span.End()
+
+	tx, err := m.transaction.Begin(ctx)
+	if err != nil {
+		return nil, errify.NewInternalServerError(err.Error(), "PatchSCIMGroup/Begin")
+	}
+	defer m.transaction.Rollback(ctx, tx)
+
+	current, e := m.group(ctx, tx, orgID, id)
+	if e != nil {
+		return nil, e.JoinLoc("PatchSCIMGroup")
+	}
+	group := domain.NewSCIMGroup(current)
+	err = group.Patch(patch.Operations)
+	if err == nil {
+		err = group.Valid()
+	}
+	if err != nil {
+		return nil, errify.NewBadRequestError(err.Error(), err.Error(), "PatchSCIMGroup/Patch")
+	}
+	result, e := m.updateGroup(ctx, tx, current, group)
+	if e != nil {
+		return nil, e.JoinLoc("PatchSCIMGroup")
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, errify.NewInternalServerError(err.Error(), "PatchSCIMGroup/Commit")
+	}
+	return result, nil
+}
+
+func (m *SCIMService) DeleteSCIMGroup(ctx context.Context, orgID int, id int) errify.IError {
+	ctx, span := tracing.Start(ctx, "SCIMService.DeleteSCIMGroup")
+	defer span.End()
+
+	tx, err := m.transaction.Begin(ctx)
+	if err != nil {
+		return errify.NewInternalServerError(err.Error(), "DeleteSCIMGroup/Begin")
+	}
+	defer m.transaction.Rollback(ctx, tx)
+
+	group, e := m.group(ctx, tx, orgID, id)
+	if e != nil {
+		return e.JoinLoc("DeleteSCIMGroup")
+	}
+	err = m.scimRepos.DeleteGroup(ctx, tx, orgID, id)
+	if err != nil {
+		return errify.NewInternalServerError(err.Error(), "DeleteSCIMGroup/DeleteGroup")
+	}
+	e = m.syncRoles(ctx, tx, orgID, memberIDs(group.Members))
+	if e != nil {
+		return e.JoinLoc("DeleteSCIMGroup")
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return errify.NewInternalServerError(err.Error(), "DeleteSCIMGroup/Commit")
+	}
+	return nil
+}
+
+// member Участник организации. Пользователи других организаций для SCIM не существуют
+func (m *SCIMService) member(ctx context.Context, tx pgx.Tx, orgID int, userID int) (*domain.OrgMember, errify.IError) {
+	member, err := m.orgRepos.Member(ctx, tx, orgID, userID)
+	if err != nil {
+		if errors.Is(err, repository.MemberNotExist) {
+			return nil, errify.NewBadRequestError(ErrSCIMResourceNotExist.Error(), ErrSCIMResourceNotExist.Error(), "member/Member")
+		}
+		return nil, errify.NewInternalServerError(err.Error(), "member/Member")
+	}
+	return member, nil
+}
+
+func (m *SCIMService) scimUser(ctx context.Context, tx pgx.Tx, orgID int, userID int) (*domain.SCIMUser, errify.IError) {
+	member, e := m.member(ctx, tx, orgID, userID)
+	if e != nil {
+		return nil, e.JoinLoc("scimUser")
+	}
+	groups, err := m.scimRepos.MemberGroups(ctx, tx, orgID, userID)
+	if err != nil {
+		return nil, errify.NewInternalServerError(err.Error(), "scimUser/MemberGroups")
+	}
+	return domain.NewSCIMUser(member, groups), nil
+}
+
+// updateUser Применяет атрибуты ресурса к участнику. Почта и блокировка меняются только у учетных записей,
+// созданных организацией. Отключение участника завершает его сессии
+func (m *SCIMService) updateUser(ctx context.Context, tx pgx.Tx, member *domain.OrgMember, user *domain.SCIMUser) errify.IError {
+	email := user.Email()
+	active := user.IsActive()
+	update := &domain.UserUpdate{}
+	if email != member.Email {
+		if !member.Provisioned {
+			return errify.NewBadRequestError(ErrSCIMUserNotManaged.Error(), ErrSCIMUserNotManaged.Error(), "updateUser/Provisioned")
+		}
+		update.Email = &email
+	}
+	if member.Provisioned && active != member.Active {
+		locked := !active
+		update.Locked = &locked
+	}
+	if update.Email != nil || update.Locked != nil {
+		err := m.userRepos.UpdateUser(ctx, tx, member.UserID, update)
+		if err != nil {
+			if errors.Is(err, repository.UserAlreadyExist) {
+				return errify.NewBadRequestError(ErrSCIMResourceConflict.Error(), ErrSCIMResourceConflict.Error(), "updateUser/UpdateUser")
+			}
+			return errify.NewInternalServerError(err.Error(), "updateUser/UpdateUser")
+		}
+	}
+
+	err := m.scimRepos.UpdateSCIMMember(ctx, tx, member.OrgID, member.UserID, externalID(user.ExternalID), active)
+	if err != nil {
+		if errors.Is(err, repository.MemberAlreadyExist) {
+			return errify.NewBadRequestError(ErrSCIMResourceConflict.Error(), ErrSCIMResourceConflict.Error(), "updateUser/UpdateSCIMMember")
+		}
+		if errors.Is(err, repository.MemberNotExist) {
+			return errify.NewBadRequestError(ErrSCIMResourceNotExist.Error(), ErrSCIMResourceNotExist.Error(), "updateUser/UpdateSCIMMember")
+		}
+		return errify.NewInternalServerError(err.Error(), "updateUser/UpdateSCIMMember")
+	}
+
+	// Почта зашита в токен, а отключенный участник не должен оставаться авторизованным
+	if update.Email != nil || (member.Active && !active) {
+		e := m.removeSessions(ctx, member.UserID)
+		if e != nil {
+			return e.JoinLoc("updateUser")
+		}
+	}
+	return nil
+}
+
+func (m *SCIMService) group(ctx context.Context, tx pgx.Tx, orgID int, id int) (*domain.OrgGroup, errify.IError) {
+	group, err := m.scimRepos.Group(ctx, tx, orgID, id)
+	if err != nil {
+		if errors.Is(err, repository.GroupNotExist) {
+			return nil, errify.NewBadRequestError(ErrSCIMResourceNotExist.Error(), ErrSCIMResourceNotExist.Error(), "group/Group")
+		}
+		return nil, errify.NewInternalServerError(err.Error(), "group/Group")
+	}
+	return group, nil
+}
+
+func (m *SCIMService) updateGroup(ctx context.Context, tx pgx.Tx, current *domain.OrgGroup, group *domain.SCIMGroup) (*domain.SCIMGroup, errify.IError) {
+	err := m.scimRepos.UpdateGroup(ctx, tx, &domain.OrgGroup{
+		ID:          current.ID,
+		OrgID:       current.OrgID,
+		DisplayName: group.DisplayName,
+		ExternalID:  externalID(group.ExternalID),
+	})
+	if err != nil {
+		if errors.Is(err, repository.GroupAlreadyExist) {
+			return nil, errify.NewBadRequestError(ErrSCIMResourceConflict.Error(), ErrSCIMResourceConflict.Error(), "updateGroup/UpdateGroup")
+		}
+		return nil, errify.NewInternalServerError(err.Error(), "updateGroup/UpdateGroup")
+	}
+	e := m.setGroupMembers(ctx, tx, current.OrgID, current.ID, group, current.Members)
+	if e != nil {
+		return nil, e.JoinLoc("updateGroup")
+	}
+	result, e := m.group(ctx, tx, current.OrgID, current.ID)
+	if e != nil {
+		return nil, e.JoinLoc("updateGroup")
+	}
+	return domain.NewSCIMGroup(result), nil
+}
+
+// setGroupMembers Заменяет участников группы и пересчитывает роли прежних и новых участников
+func (m *SCIMService) setGroupMembers(ctx context.Context, tx pgx.Tx, orgID int, groupID int, group *domain.SCIMGroup, previous []*domain.OrgMember) errify.IError {
+	ids, err := group.MemberIDs()
+	if err != nil {
+		return errify.NewBadRequestError(err.Error(), err.Error(), "setGroupMembers/MemberIDs")
+	}
+	err = m.scimRepos.SetGroupMembers(ctx, tx, orgID, groupID, ids)
+	if err != nil {
+		if errors.Is(err, repository.MemberNotExist) {
+			return errify.NewBadRequestError(err.Error(), MemberNotExist.Error(), "setGroupMembers/SetGroupMembers")
+		}
+		return errify.NewInternalServerError(err.Error(), "setGroupMembers/SetGroupMembers")
+	}
+	e := m.syncRoles(ctx, tx, orgID, append(ids, memberIDs(previous)...))
+	if e != nil {
+		return e.JoinLoc("setGroupMembers")
+	}
+	return nil
+}
+
+// syncRoles Назначает участникам старшую роль среди их групп. Роль владельца не меняется
+func (m *SCIMService) syncRoles(ctx context.Context, tx pgx.Tx, orgID int, userIDs []int) errify.IError {
+	seen := make(map[int]bool, len(userIDs))
+	for _, userID := range userIDs {
+		if seen[userID] {
+			continue
+		}
+		seen[userID] = true
+
+		member, err := m.orgRepos.Member(ctx, tx, orgID, userID)
+		if err != nil {
+			if errors.Is(err, repository.MemberNotExist) {
+				continue
+			}
+			return errify.NewInternalServerError(err.Error(), "syncRoles/Member")
+		}
+		if member.Role == domain.OrgRoleOwner {
+			continue
+		}
+		groups, err := m.scimRepos.MemberGroups(ctx, tx, orgID, userID)
+		if err != nil {
+			return errify.NewInternalServerError(err.Error(), "syncRoles/MemberGroups")
+		}
+		role := m.groupRole(groups)
+		if role == member.Role {
+			continue
+		}
+		err = m.orgRepos.SetMemberRole(ctx, tx, orgID, userID, role)
+		if err != nil {
+			return errify.NewInternalServerError(err.Error(), "syncRoles/SetMemberRole")
+		}
+		// Роль в организации зашита в токен
+		e := m.removeSessions(ctx, userID)
+		if e != nil {
+			return e.JoinLoc("syncRoles")
+		}
+	}
+	return nil
+}
+
+func (m *SCIMService) groupRole(groups []*domain.OrgGroup) domain.OrgRole {
+	role := domain.OrgRoleMember
+	for _, group := range groups {
+		for name, r := range m.scimCfg.GroupRoles {
+			groupRole := domain.OrgRole(r)
+			if strings.EqualFold(name, group.DisplayName) && groupRole.Valid() && groupRole != domain.OrgRoleOwner && groupRole < role {
+				role = groupRole
+			}
+		}
+	}
+	return role
+}
+
+func (m *SCIMService) removeSessions(ctx context.Context, id int) errify.IError {
+	redisTx, err := m.transaction.RedisTx(ctx)
+	if err != nil {
+		return errify.NewInternalServerError(err.Error(), "removeSessions/RedisTx")
+	}
+	defer m.transaction.RedisRollback(ctx, redisTx)
+
+	err = m.authRepos.RemoveUserAuthorization(ctx, redisTx, id)
+	if err != nil {
+		return errify.NewInternalServerError(err.Error(), "removeSessions/RemoveUserAuthorization")
+	}
+	err = m.transaction.RedisCommit(redisTx)
+	if err != nil {
+		return errify.NewInternalServerError(err.Error(), "removeSessions/RedisCommit")
+	}
+	return nil
+}
+
+func externalID(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
+
+func memberIDs(members []*domain.OrgMember) []int {
+	ids := make([]int, 0, len(members))
+	for _, member := range members {
+		ids = append(ids, member.UserID)
+	}
+	return ids
+}
@@ -10,12 +10,16 @@ import (
 	"github.com/go-redis/redis"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"net/http"
+	"time"
 )
 
 type User interface {
 	AddUser(ctx context.Context, emailService Email, user *domain.User) (int, errify.IError)
 	GetUserByID(ctx context.Context, id int) (*domain.User, errify.IError)
 	GetUserByEmail(ctx context.Context, email string) (*domain.User, errify.IError)
+	// GetUsersByIDs Возвращает найденных пользователей, отсутствующие и удаленные пропускаются
+	GetUsersByIDs(ctx context.Context, ids []int) ([]*domain.User, errify.IError)
+	// PushCodeInEmail Отправляет код подтверждения на почту. Если отправка ограничена, возвращает TooManyRequestsError
 	PushCodeInEmail(ctx context.Context, emailService Email, email string) errify.IError
 }
 
@@ -24,22 +28,145 @@ type Auth interface {
 	CheckAuthorization(ctx context.Context, accessToken string) (*domain.AuthData, errify.IError)
 	RenewAuthorization(ctx context.Context, accessToken string, cfg config.TokenConfig) (string, errify.IError)
 	Logout(ctx context.Context, accessToken string) errify.IError
+	// ActiveSessions Количество пользователей с активной сессией
+	ActiveSessions(ctx context.Context) (int64, errify.IError)
+}
+
+type Admin interface {
+	Users(ctx context.Context, filter *domain.UserFilter) (*domain.UserPage, errify.IError)
+	UpdateUser(ctx context.Context, id int, update *domain.UserUpdate) (*domain.UserInfo, errify.IError)
+	DeleteUser(ctx context.Context, id int) errify.IError
+	// RevokeSessions Завершает все сессии пользователя
+	RevokeSessions(ctx context.Context, id int) errify.IError
+	// SetRole Изменяет роль пользователя, записывает изменение в аудит и завершает его сессии
+	SetRole(ctx context.Context, id int, update *domain.RoleUpdate, changedBy *int) (*domain.UserInfo, errify.IError)
+	RoleChanges(ctx context.Context, id int) ([]*domain.RoleChange, errify.IError)
+}
+
+type Access interface {
+	Permissions(ctx context.Context) ([]*domain.PermissionInfo, errify.IError)
+	AddPermission(ctx context.Context, permission *domain.PermissionInfo) (int, errify.IError)
+	AccessRoles(ctx context.Context) ([]*domain.AccessRole, errify.IError)
+	AddAccessRole(ctx context.Context, role *domain.AccessRole) (*domain.AccessRole, errify.IError)
+	SetAccessRolePermissions(ctx context.Context, id int, permissions []domain.Permission) (*domain.AccessRole, errify.IError)
+	DeleteAccessRole(ctx context.Context, id int) errify.IError
+	UserAccessRoles(ctx context.Context, userID int) ([]*domain.AccessRole, errify.IError)
+	// SetUserAccessRoles Заменяет роли пользователя, записывает изменение в аудит и синхронизирует колонку "user".role
+	SetUserAccessRoles(ctx context.Context, userID int, roleIDs []int, changedBy *int) ([]*domain.AccessRole, errify.IError)
+	UserPermissions(ctx context.Context, userID int) ([]domain.Permission, errify.IError)
+}
+
+type Authz interface {
+	// CheckAccess Вычисляет решение по активной политике или по политике из запроса (dry-run).
+	// Атрибуты subject заполняются по данным субъекта и его записи в базе
+	CheckAccess(ctx context.Context, subject *domain.AuthData, req *domain.AuthzRequest) (*domain.AuthzDecision, errify.IError)
+	ReloadPolicy(ctx context.Context) errify.IError
+	// WatchPolicy Перечитывает файл политик при его изменении, пока не отменен контекст
+	WatchPolicy(ctx context.Context, interval time.Duration)
+}
+
+type Organization interface {
+	// AddOrganization Создает организацию, создатель становится ее владельцем
+	AddOrganization(ctx context.Context, userID int, org *domain.Organization) (*domain.Organization, errify.IError)
+	UserOrganizations(ctx context.Context, userID int) ([]*domain.Organization, errify.IError)
+	Members(ctx context.Context, orgID int, userID int) ([]*domain.OrgMember, errify.IError)
+	SetMemberRole(ctx context.Context, orgID int, userID int, memberID int, role domain.OrgRole) (*domain.OrgMember, errify.IError)
+	RemoveMember(ctx context.Context, orgID int, userID int, memberID int) errify.IError
+	// Invite Отправляет приглашение в организацию на почту
+	Invite(ctx context.Context, emailService Email, userID int, invitation *domain.OrgInvitation) (*domain.OrgInvitation, errify.IError)
+	Invitations(ctx context.Context, orgID int, userID int) ([]*domain.OrgInvitation, errify.IError)
+	RevokeInvitation(ctx context.Context, orgID int, userID int, id int) errify.IError
+	// RespondInvitation Принимает или отклоняет приглашение по токену из письма
+	RespondInvitation(ctx context.Context, user *domain.AuthData, token string, accept bool) (*domain.Organization, errify.IError)
+	// SwitchOrganization Выпускает новый токен с активной организацией вместо текущего
+	SwitchOrganization(ctx context.Context, accessToken string, user *domain.AuthData, orgID int, cfg config.TokenConfig) (string, errify.IError)
+}
+
+type PersonalToken interface {
+	// AddPersonalToken Создает персональный токен доступа. Значение токена возвращается только здесь
+	AddPersonalToken(ctx context.Context, userID int, req *domain.PersonalTokenCreate) (*domain.PersonalTokenCreated, errify.IError)
+	PersonalTokens(ctx context.Context, userID int) ([]*domain.PersonalToken, errify.IError)
+	RevokePersonalToken(ctx context.Context, userID int, id int) errify.IError
+}
+
+type OAuth interface {
+	// OAuthLoginURL Адрес входа у провайдера и state, который сохраняется в cookie браузера.
+	// При linkUserID отличном от 0 учетная запись привязывается к этому пользователю
+	OAuthLoginURL(ctx context.Context, provider string, linkUserID int) (url string, state string, err errify.IError)
+	// OAuthCallback Завершает вход через провайдера и выпускает токен. userID - пользователь, авторизованный
+	// в запросе возврата, или 0. Привязка учетной записи завершается только для того, кто ее начал
+	OAuthCallback(ctx context.Context, provider string, state string, code string, userID int, cfg config.TokenConfig) (string, errify.IError)
+	UserIdentities(ctx context.Context, userID int) ([]*domain.UserIdentity, errify.IError)
+	RemoveIdentity(ctx context.Context, userID int, provider string) errify.IError
+}
+
+type SAML interface {
+	// SAMLMetadata Метаданные сервиса для регистрации у провайдера SAML
+	SAMLMetadata(ctx context.Context, idp string) ([]byte, errify.IError)
+	// SAMLLogin Создает запрос аутентификации к провайдеру SAML
+	SAMLLogin(ctx context.Context, idp string) (*domain.SAMLAuthnRequest, errify.IError)
+	// SAMLAssertionConsumer Проверяет ответ провайдера SAML и выпускает токен
+	SAMLAssertionConsumer(ctx context.Context, idp string, response string, relayState string, cfg config.TokenConfig) (string, errify.IError)
+}
+
+type SCIM interface {
+	// AddSCIMToken Создает токен SCIM организации. Значение токена возвращается только здесь
+	AddSCIMToken(ctx context.Context, orgID int, userID int, req *domain.SCIMTokenCreate) (*domain.SCIMTokenCreated, errify.IError)
+	SCIMTokens(ctx context.Context, orgID int, userID int) ([]*domain.SCIMToken, errify.IError)
+	RevokeSCIMToken(ctx context.Context, orgID int, userID int, id int) errify.IError
+	// SCIMTenant Проверяет токен SCIM и возвращает организацию, к которой он выпущен
+	SCIMTenant(ctx context.Context, token string) (int, errify.IError)
+	SCIMUsers(ctx context.Context, orgID int, query *domain.SCIMQuery) (*domain.SCIMListResponse, errify.IError)
+	SCIMUser(ctx context.Context, orgID int, userID int) (*domain.SCIMUser, errify.IError)
+	AddSCIMUser(ctx context.Context, orgID int, user *domain.SCIMUser) (*domain.SCIMUser, errify.IError)
+	ReplaceSCIMUser(ctx context.Context, orgID int, userID int, user *domain.SCIMUser) (*domain.SCIMUser, errify.IError)
+	PatchSCIMUser(ctx context.Context, orgID int, userID int, patch *domain.SCIMPatch) (*domain.SCIMUser, errify.IError)
+	DeleteSCIMUser(ctx context.Context, orgID int, userID int) errify.IError
+	SCIMGroups(ctx context.Context, orgID int, query *domain.SCIMQuery) (*domain.SCIMListResponse, errify.IError)
+	SCIMGroup(ctx context.Context, orgID int, id int) (*domain.SCIMGroup, errify.IError)
+	AddSCIMGroup(ctx context.Context, orgID int, group *domain.SCIMGroup) (*domain.SCIMGroup, errify.IError)
+	ReplaceSCIMGroup(ctx context.Context, orgID int, id int, group *domain.SCIMGroup) (*domain.SCIMGroup, errify.IError)
+	PatchSCIMGroup(ctx context.Context, orgID int, id int, patch *domain.SCIMPatch) (*domain.SCIMGroup, errify.IError)
+	DeleteSCIMGroup(ctx context.Context, orgID int, id int) errify.IError
 }
 
 type Cookies interface {
 	SetToken(w http.ResponseWriter, token string)
+	ClearToken(w http.ResponseWriter)
 	GetToken(r *http.Request) (string, error)
+	CSRFToken(w http.ResponseWriter, r *http.Request) (string, error)
+	CheckCSRF(r *http.Request) errify.IError
+	// SetOAuthState Привязывает state входа через провайдера к браузеру, который начал вход
+	SetOAuthState(w http.ResponseWriter, state string)
+	// CheckOAuthState Проверяет, что state вернулся в тот же браузер, и удаляет cookie
+	CheckOAuthState(w http.ResponseWriter, r *http.Request, state string) errify.IError
+}
+
+type Health interface {
+	Readiness(ctx context.Context) *domain.Readiness
+	SetDraining()
 }
 
 type Email interface {
 	Send(ctx context.Context, title string, toEmail string, message string) errify.IError
+	SendAsync(ctx context.Context, title string, toEmail string, message string)
+	Wait(ctx context.Context) errify.IError
 }
 
 type Service struct {
 	User
 	Auth
+	Admin
+	Access
+	Authz
+	Organization
+	PersonalToken
+	OAuth
+	SAML
+	SCIM
 	Cookies
 	Email
+	Health
 
 	log logger.Logger
 }
@@ -50,14 +177,32 @@ func NewService(
 	redisClient *redis.Client,
 	repos *repository.Repository,
 	emailConfig *config.EmailServiceConfig,
+	securityConfig *config.SecurityConfig,
+	orgConfig *config.OrganizationConfig,
+	oauthConfig *config.OAuthConfig,
+	samlConfig *config.SAMLConfig,
+	scimConfig *config.SCIMConfig,
+	cookieConfig *config.CookieConfig,
+	csrfConfig *config.CSRFConfig,
+	tokenConfig *config.TokenConfig,
+	healthConfig *config.HealthConfig,
 ) *Service {
 	transaction := repository.NewTransactionsRepos(pool, redisClient)
 
 	return &Service{
-		User:    NewUserService(log, transaction, repos, repos),
-		Auth:    NewAuthService(log, transaction, repos, repos, repos),
-		Cookies: NewCookiesService(),
-		Email:   NewEmailService(log, repos, *emailConfig),
-		log:     log,
+		User:          NewUserService(log, transaction, repos, repos, *emailConfig, *securityConfig),
+		Auth:          NewAuthService(log, transaction, repos, repos, repos, repos, repos, repos, repos, *securityConfig),
+		Admin:         NewAdminService(log, transaction, repos, repos, repos),
+		Access:        NewAccessService(log, transaction, repos, repos, repos, repos),
+		Authz:         NewAuthzService(log, transaction, repos, repos),
+		Organization:  NewOrganizationService(log, transaction, repos, repos, repos, *orgConfig),
+		PersonalToken: NewPersonalTokenService(log, transaction, repos),
+		OAuth:         NewOAuthService(log, transaction, repos, repos, repos, repos, *oauthConfig),
+		SAML:          NewSAMLService(log, transaction, repos, repos, repos, repos, repos, *samlConfig),
+		SCIM:          NewSCIMService(log, transaction, repos, repos, repos, repos, *scimConfig),
+		Cookies:       NewCookiesService(*cookieConfig, *csrfConfig, *tokenConfig, *oauthConfig),
+		Email:         NewEmailService(log, repos, *emailConfig),
+		Health:        NewHealthService(log, transaction, *emailConfig, *healthConfig),
+		log:           log,
 	}
 }
@@ -1,8 +1,10 @@
 package service
 
 import (
+	"auth/internal/config"
 	"auth/internal/domain"
 	"auth/internal/repository"
+	"auth/internal/tracing"
 	"auth/pkg/html_template"
 	"context"
 	"errors"
@@ -10,8 +12,6 @@ import (
 	"github.com/Linkify-Company/common_utils/errify"
 	"github.com/Linkify-Company/common_utils/logger"
 	"golang.org/x/crypto/bcrypt"
-	"math/rand"
-	"time"
 )
 
 type UserService struct {
@@ -19,6 +19,8 @@ type UserService struct {
 	transaction repository.Transaction
 	userRepos   repository.User
 	emailRepos  repository.Email
+	emailCfg    config.EmailServiceConfig
+	securityCfg config.SecurityConfig
 }
 
 func NewUserService(
@@ -26,31 +28,43 @@ func NewUserService(
 	transaction repository.Transaction,
 	userRepos repository.User,
 	emailRepos repository.Email,
+	emailCfg config.EmailServiceConfig,
+	securityCfg config.SecurityConfig,
 ) User {
 	return &UserService{
 		log:         log,
 		transaction: transaction,
 		userRepos:   userRepos,
 		emailRepos:  emailRepos,
+		emailCfg:    emailCfg,
+		securityCfg: securityCfg,
 	}
 }
 
 func (m *UserService) AddUser(ctx context.Context, emailService Email, user *domain.User) (int, errify.IError) {
+	ctx, span := tracing.Start(ctx, "UserService.AddUser")
+	defer span.End()
+
 	tx, err := m.transaction.Begin(ctx)
 	if err != nil {
 		return 0, errify.NewInternalServerError(err.Error(), "AddUser/Begin")
 	}
 	defer m.transaction.Rollback(ctx, tx)
 
+	// Код проверяется раньше существования пользователя: без кода нельзя узнать, зарегистрирована ли почта
+	valid, err := m.emailRepos.IsValid(ctx, user.Email, hashCode(user.Email, user.AuthorizationCode), m.emailCfg.CodeAttempts)
+	if err != nil {
+		return 0, errify.NewInternalServerError(err.Error(), "AddUser/IsValid")
+	}
+	if !valid {
+		return 0, errify.NewBadRequestError(MailConfirmationError.Error(), MailConfirmationError.Error(), "AddUser/IsValid")
+	}
 	userExist, err := m.userRepos.UserByEmail(ctx, tx, user.Email)
 	if err != nil && !errors.Is(err, repository.UserNotExist) {
 		return 0, errify.NewInternalServerError(err.Error(), "AddUser/UserByEmail")
 	}
 	if userExist != nil {
-		return 0, errify.NewBadRequestError(err.Error(), UserIsAlreadyExist.Error(), "AddUser/UserByEmail")
-	}
-	if !m.emailRepos.IsValid(ctx, user.Email, user.AuthorizationCode) {
-		return 0, errify.NewBadRequestError(err.Error(), MailConfirmationError.Error(), "AddUser/IsValid")
+		return 0, errify.NewBadRequestError(UserIsAlreadyExist.Error(), UserIsAlreadyExist.Error(), "AddUser/UserByEmail")
 	}
 
 	passHash, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
@@ -71,19 +85,15 @@ func (m *UserService) AddUser(ctx context.Context, emailService Email, user *dom
 		return 0, errify.NewInternalServerError(err.Error(), "AddUser/Commit")
 	}
 
-	go func() {
-		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
-		defer cancel()
-		err = emailService.Send(ctx, "Успешная регистрация в Linkify", user.Email, fmt.Sprintf(html_template.RegistgrationSuccessfully, user.Email))
-		if err != nil {
-			m.log.Error(err.(errify.IError).JoinLoc("AddUser"))
-		}
-	}()
+	emailService.SendAsync(ctx, "Успешная регистрация в Linkify", user.Email, fmt.Sprintf(html_template.RegistgrationSuccessfully, user.Email))
 
 	return id, nil
 }
 
 func (m *UserService) PushCodeInEmail(ctx context.Context, emailService Email, email string) errify.IError {
+	ctx, span := tracing.Start(ctx, "UserService.PushCodeInEmail")
+	defer span.End()
+
 	tx, err := m.transaction.Begin(ctx)
 	if err != nil {
 		return errify.NewInternalServerError(err.Error(), "PushCodeInEmail/Begin")
@@ -94,19 +104,38 @@ func (m *UserService) PushCodeInEmail(ctx context.Context, emailService Email, e
 	if err != nil && !errors.Is(err, repository.UserNotExist) {
 		return errify.NewInternalServerError(err.Error(), "PushCodeInEmail/UserByEmail")
 	}
-	if userExist != nil {
+	if userExist != nil && !m.securityCfg.EnumerationSafe {
 		return errify.NewBadRequestError(UserIsAlreadyExist.Error(), UserIsAlreadyExist.Error(), "PushCodeInEmail/UserByEmail")
 	}
-	var mins = 1000000
-	var maxs = mins * 10
 
-	var authorizationCode = rand.Intn(maxs-mins) + mins
+	retryAfter, err := m.emailRepos.AllowSend(ctx, email, m.emailCfg.ResendCooldown, m.emailCfg.DailyLimit)
+	if err != nil {
+		return errify.NewInternalServerError(err.Error(), "PushCodeInEmail/AllowSend")
+	}
+	if retryAfter > 0 {
+		return NewTooManyRequestsError(retryAfter, "PushCodeInEmail/AllowSend")
+	}
+
+	if userExist != nil {
+		// Владелец почты получает предупреждение вместо кода, а ответ не отличается от ответа для новой почты
+		err = emailService.Send(ctx, "Попытка регистрации в Linkify", email, fmt.Sprintf(html_template.RegistrationAttempt, email))
+		if err != nil {
+			return err.(errify.IError).JoinLoc("PushCodeInEmail")
+		}
+		return nil
+	}
 
-	err = emailService.Send(ctx, "Подтверждение регистрации в Linkify", email, fmt.Sprintf(html_template.PushAuthCode, authorizationCode, authorizationCode))
+	authorizationCode, err := generateCode()
 	if err != nil {
-		return err.(errify.IError).JoinLoc("PushCodeInEmail")
+		return errify.NewInternalServerError(err.Error(), "PushCodeInEmail/generateCode")
 	}
-	err = m.emailRepos.Set(ctx, email, authorizationCode)
+	// Сохраняем код до отправки, чтобы пользователь не получил код, который не сможет подтвердить
+	err = m.emailRepos.Set(ctx, email, hashCode(email, authorizationCode), m.emailCfg.CodeTTL)
+	if err != nil {
+		return errify.NewInternalServerError(err.Error(), "PushCodeInEmail/Set")
+	}
+
+	err = emailService.Send(ctx, "Подтверждение регистрации в Linkify", email, fmt.Sprintf(html_template.PushAuthCode, authorizationCode, authorizationCode))
 	if err != nil {
 		return err.(errify.IError).JoinLoc("PushCodeInEmail")
 	}
@@ -114,6 +143,9 @@ func (m *UserService) PushCodeInEmail(ctx context.Context, emailService Email, e
 }
 
 func (m *UserService) GetUserByID(ctx context.Context, id int) (*domain.User, errify.IError) {
+	ctx, span := tracing.Start(ctx, "UserService.GetUserByID")
+	defer span.End()
+
 	tx, err := m.transaction.Begin(ctx)
 	if err != nil {
 		return nil, errify.NewInternalServerError(err.Error(), "GetUserByID/Begin")
@@ -127,6 +159,9 @@ func (m *UserService) GetUserByID(ctx context.Context, id int) (*domain.User, er
 		}
 		return nil, errify.NewInternalServerError(err.Error(), "GetUserByID/UserById")
 	}
+	if user.DeletedAt != nil {
+		return nil, errify.NewBadRequestError(repository.UserNotExist.Error(), UserNotExist.Error(), "GetUserByID/DeletedAt")
+	}
 	return &domain.User{
 		ID:    user.ID,
 		Email: user.Email,
@@ -135,6 +170,9 @@ func (m *UserService) GetUserByID(ctx context.Context, id int) (*domain.User, er
 }
 
 func (m *UserService) GetUserByEmail(ctx context.Context, email string) (*domain.User, errify.IError) {
+	ctx, span := tracing.Start(ctx, "UserService.GetUserByEmail")
+	defer span.End()
+
 	tx, err := m.transaction.Begin(ctx)
 	if err != nil {
 		return nil, errify.NewInternalServerError(err.Error(), "GetUserByEmail/Begin")
@@ -148,9 +186,40 @@ func (m *UserService) GetUserByEmail(ctx context.Context, email string) (*domain
 		}
 		return nil, errify.NewInternalServerError(err.Error(), "GetUserByEmail/UserByEmail")
 	}
+	if user.DeletedAt != nil {
+		return nil, errify.NewBadRequestError(repository.UserNotExist.Error(), UserNotExist.Error(), "GetUserByEmail/DeletedAt")
+	}
 	return &domain.User{
 		ID:    user.ID,
 		Email: user.Email,
 		Role:  user.Role,
 	}, nil
 }
+
+func (m *UserService) GetUsersByIDs(ctx context.Context, ids []int) ([]*domain.User, errify.IError) {
+	ctx, span := tracing.Start(ctx, "UserService.GetUsersByIDs")
+	defer span.End()
+
+	tx, err := m.transaction.Begin(ctx)
+	if err != nil {
+		return nil, errify.NewInternalServerError(err.Error(), "GetUsersByIDs/Begin")
+	}
+	defer m.transaction.Rollback(ctx, tx)
+
+	users, err := m.userRepos.UsersByIDs(ctx, tx, ids)
+	if err != nil {
+		return nil, errify.NewInternalServerError(err.Error(), "GetUsersByIDs/UsersByIDs")
+	}
+	result := make([]*domain.User, 0, len(users))
+	for _, user := range users {
+		if user.DeletedAt != nil {
+			continue
+		}
+		result = append(result, &domain.User{
+			ID:    user.ID,
+			Email: user.Email,
+			Role:  user.Role,
+		})
+	}
+	return result, nil
+}
@@ -0,0 +1,54 @@
+package tracing
+
+import (
+	"context"
+	"github.com/go-redis/redis"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+	"strings"
+)
+
+// Redis Клиент с контекстом ctx, команды которого записываются спанами в трассу из ctx. Аргументы команд в спан
+// не попадают, так как среди них токены и коды подтверждения
+func Redis(ctx context.Context, client *redis.Client) *redis.Client {
+	client = client.WithContext(ctx)
+	if !trace.SpanFromContext(ctx).IsRecording() {
+		return client
+	}
+	client.WrapProcess(func(next func(cmd redis.Cmder) error) func(cmd redis.Cmder) error {
+		return func(cmd redis.Cmder) error {
+			name := strings.ToLower(cmd.Name())
+			_, span := Start(ctx, "redis "+name,
+				trace.WithSpanKind(trace.SpanKindClient),
+				trace.WithAttributes(semconv.DBSystemRedis, semconv.DBOperation(name)),
+			)
+			err := next(cmd)
+			End(span, redisError(err))
+			return err
+		}
+	})
+	client.WrapProcessPipeline(func(next func(cmds []redis.Cmder) error) func(cmds []redis.Cmder) error {
+		return func(cmds []redis.Cmder) error {
+			names := make([]string, 0, len(cmds))
+			for _, cmd := range cmds {
+				names = append(names, strings.ToLower(cmd.Name()))
+			}
+			_, span := Start(ctx, "redis pipeline",
+				trace.WithSpanKind(trace.SpanKindClient),
+				trace.WithAttributes(semconv.DBSystemRedis, semconv.DBOperation(strings.Join(names, " "))),
+			)
+			err := next(cmds)
+			End(span, redisError(err))
+			return err
+		}
+	})
+	return client
+}
+
+// redisError Отсутствующий ключ не считается ошибкой
+func redisError(err error) error {
+	if err == redis.Nil {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,80 @@
+// Package tracing Трассировка OpenTelemetry: настройка экспорта спанов и помощники для спанов сервиса
+package tracing
+
+import (
+	"auth/internal/config"
+	"context"
+	"github.com/Linkify-Company/common_utils/errify"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Экспортеры спанов
+const (
+	ExporterOTLP   = "otlp"
+	ExporterStdout = "stdout"
+)
+
+const tracerName = "auth"
+
+// New Настраивает глобальный провайдер трассировки и распространение W3C trace context. Возвращает функцию,
+// которая отправляет накопленные спаны и останавливает экспорт. Без экспортера спаны не записываются,
+// но контекст трассировки из входящих запросов по-прежнему передается дальше
+func New(ctx context.Context, cfg config.TracingConfig) (func(ctx context.Context) error, errify.IError) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch cfg.Exporter {
+	case "":
+		return func(ctx context.Context) error { return nil }, nil
+	case ExporterOTLP:
+		opts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(cfg.Endpoint)}
+		if cfg.Insecure {
+			opts = append(opts, otlptracegrpc.WithInsecure())
+		}
+		// Соединение с коллектором устанавливается в фоне, поэтому недоступный коллектор не мешает запуску
+		exporter, err = otlptracegrpc.New(ctx, opts...)
+	case ExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithPrettyPrint())
+	default:
+		return nil, errify.NewInternalServerError("unknown tracing exporter "+cfg.Exporter, "tracing.New")
+	}
+	if err != nil {
+		return nil, errify.NewInternalServerError(err.Error(), "tracing.New/exporter")
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(cfg.ServiceName)))
+	if err != nil {
+		return nil, errify.NewInternalServerError(err.Error(), "tracing.New/Merge")
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		// Трассы, начатые вызывающим, следуют его решению, а новые записываются с долей SampleRatio
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// Start Начинает спан внутри текущей трассы из ctx
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, opts...)
+}
+
+// End Завершает спан, отмечая в нем ошибку, если она есть
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,9 @@
+-- Области персонального токена для действий со своей учетной записью, у пользователя они есть всегда
+INSERT INTO permission (name, description) VALUES
+    ('orgs:read', 'view own organizations, members and invitations'),
+    ('orgs:write', 'manage own organizations and answer invitations'),
+    ('identities:read', 'view linked accounts'),
+    ('identities:write', 'unlink accounts'),
+    ('tokens:read', 'view personal access tokens'),
+    ('tokens:write', 'revoke personal access tokens')
+ON CONFLICT (name) DO NOTHING;
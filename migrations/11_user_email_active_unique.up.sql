@@ -0,0 +1,3 @@
+-- Почта удаленного пользователя освобождается для повторной регистрации и SCIM, поэтому уникальна только среди активных
+ALTER TABLE "user" DROP CONSTRAINT IF EXISTS user_email_key;
+CREATE UNIQUE INDEX IF NOT EXISTS user_email_active_idx ON "user" (lower(email)) WHERE deleted_at IS NULL;
@@ -0,0 +1,6 @@
+ALTER TABLE "user" ADD COLUMN IF NOT EXISTS verified BOOLEAN NOT NULL DEFAULT true;
+ALTER TABLE "user" ADD COLUMN IF NOT EXISTS locked BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE "user" ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS created_at_idx ON "user" (created_at, id);
+CREATE INDEX IF NOT EXISTS email_prefix_idx ON "user" (lower(email) text_pattern_ops);
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS role_audit (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES "user" (id),
+    old_role INTEGER NOT NULL REFERENCES role (id),
+    new_role INTEGER NOT NULL REFERENCES role (id),
+    changed_by INTEGER REFERENCES "user" (id),
+    reason TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS role_audit_user_id_idx ON role_audit (user_id, created_at);
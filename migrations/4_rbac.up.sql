@@ -0,0 +1,77 @@
+CREATE TABLE IF NOT EXISTS permission (
+    id SERIAL PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE,
+    description TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS access_role (
+    id SERIAL PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE,
+    description TEXT NOT NULL DEFAULT '',
+    -- Встроенные роли соответствуют старым идентификаторам из таблицы role и не удаляются
+    builtin BOOLEAN NOT NULL DEFAULT false,
+    legacy_id INTEGER UNIQUE REFERENCES role (id),
+    created_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS access_role_permission (
+    role_id INTEGER NOT NULL REFERENCES access_role (id) ON DELETE CASCADE,
+    permission_id INTEGER NOT NULL REFERENCES permission (id) ON DELETE CASCADE,
+    PRIMARY KEY (role_id, permission_id)
+);
+
+CREATE TABLE IF NOT EXISTS user_access_role (
+    user_id INTEGER NOT NULL REFERENCES "user" (id) ON DELETE CASCADE,
+    role_id INTEGER NOT NULL REFERENCES access_role (id) ON DELETE CASCADE,
+    PRIMARY KEY (user_id, role_id)
+);
+CREATE INDEX IF NOT EXISTS user_access_role_role_id_idx ON user_access_role (role_id);
+
+INSERT INTO permission (name, description) VALUES
+    ('*', 'all permissions'),
+    ('users:read', 'view users'),
+    ('users:write', 'modify and delete users'),
+    ('roles:read', 'view roles and permissions'),
+    ('roles:write', 'manage roles and permissions'),
+    ('links:read', 'view links'),
+    ('links:write', 'create and modify links')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO access_role (name, description, builtin, legacy_id) VALUES
+    ('admin', 'administrator', true, 0),
+    ('moderator', 'moderator', true, 1),
+    ('user', 'regular user', true, 2)
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO access_role_permission (role_id, permission_id)
+SELECT r.id, p.id FROM access_role r, permission p
+WHERE (r.name = 'admin' AND p.name = '*')
+   OR (r.name = 'moderator' AND p.name IN ('users:read', 'roles:read', 'links:read', 'links:write'))
+   OR (r.name = 'user' AND p.name IN ('links:read', 'links:write'))
+ON CONFLICT DO NOTHING;
+
+-- Переносим существующие роли пользователей
+INSERT INTO user_access_role (user_id, role_id)
+SELECT u.id, r.id FROM "user" u JOIN access_role r ON r.legacy_id = u.role
+ON CONFLICT DO NOTHING;
+
+-- Поддерживаем встроенную роль в соответствии с колонкой "user".role
+CREATE OR REPLACE FUNCTION sync_legacy_role() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'UPDATE' THEN
+        IF NEW.role = OLD.role THEN
+            RETURN NEW;
+        END IF;
+        DELETE FROM user_access_role
+        WHERE user_id = NEW.id AND role_id IN (SELECT id FROM access_role WHERE legacy_id = OLD.role);
+    END IF;
+    INSERT INTO user_access_role (user_id, role_id)
+    SELECT NEW.id, id FROM access_role WHERE legacy_id = NEW.role
+    ON CONFLICT DO NOTHING;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS user_legacy_role ON "user";
+CREATE TRIGGER user_legacy_role AFTER INSERT OR UPDATE OF role ON "user"
+    FOR EACH ROW EXECUTE FUNCTION sync_legacy_role();
@@ -0,0 +1,30 @@
+CREATE TABLE IF NOT EXISTS organization (
+    id SERIAL PRIMARY KEY,
+    name TEXT NOT NULL,
+    slug TEXT NOT NULL UNIQUE,
+    owner_id INTEGER NOT NULL REFERENCES "user" (id),
+    created_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS organization_member (
+    org_id INTEGER NOT NULL REFERENCES organization (id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES "user" (id) ON DELETE CASCADE,
+    role INTEGER NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT now(),
+    PRIMARY KEY (org_id, user_id)
+);
+CREATE INDEX IF NOT EXISTS organization_member_user_id_idx ON organization_member (user_id);
+
+CREATE TABLE IF NOT EXISTS organization_invitation (
+    id SERIAL PRIMARY KEY,
+    org_id INTEGER NOT NULL REFERENCES organization (id) ON DELETE CASCADE,
+    email TEXT NOT NULL,
+    role INTEGER NOT NULL,
+    token_hash TEXT NOT NULL UNIQUE,
+    invited_by INTEGER NOT NULL REFERENCES "user" (id),
+    status TEXT NOT NULL DEFAULT 'pending',
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT now(),
+    updated_at TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS organization_invitation_org_id_idx ON organization_invitation (org_id, status);
@@ -0,0 +1,39 @@
+CREATE TABLE IF NOT EXISTS scim_token (
+    id SERIAL PRIMARY KEY,
+    org_id INTEGER NOT NULL REFERENCES organization (id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    prefix TEXT NOT NULL,
+    token_hash TEXT NOT NULL UNIQUE,
+    created_by INTEGER NOT NULL REFERENCES "user" (id),
+    last_used_at TIMESTAMP,
+    revoked_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS scim_token_org_id_idx ON scim_token (org_id);
+
+ALTER TABLE organization_member ADD COLUMN IF NOT EXISTS active BOOLEAN NOT NULL DEFAULT true;
+ALTER TABLE organization_member ADD COLUMN IF NOT EXISTS external_id TEXT;
+ALTER TABLE organization_member ADD COLUMN IF NOT EXISTS provisioned BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE organization_member ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP;
+CREATE UNIQUE INDEX IF NOT EXISTS organization_member_external_id_idx ON organization_member (org_id, external_id)
+    WHERE external_id IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS scim_group (
+    id SERIAL PRIMARY KEY,
+    org_id INTEGER NOT NULL REFERENCES organization (id) ON DELETE CASCADE,
+    display_name TEXT NOT NULL,
+    external_id TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT now(),
+    updated_at TIMESTAMP,
+    UNIQUE (org_id, display_name)
+);
+
+-- Участник группы всегда участник организации: при выходе из организации он удаляется из ее групп
+CREATE TABLE IF NOT EXISTS scim_group_member (
+    group_id INTEGER NOT NULL REFERENCES scim_group (id) ON DELETE CASCADE,
+    org_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    PRIMARY KEY (group_id, user_id),
+    FOREIGN KEY (org_id, user_id) REFERENCES organization_member (org_id, user_id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS scim_group_member_user_idx ON scim_group_member (org_id, user_id);
@@ -0,0 +1,3 @@
+INSERT INTO permission (name, description) VALUES
+    ('authz:check', 'request authorization decisions')
+ON CONFLICT (name) DO NOTHING;
@@ -0,0 +1,173 @@
+package authclient
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Users Страница пользователей по фильтру
+func (c *Client) Users(ctx context.Context, filter *UserFilter) (*UserPage, error) {
+	path := "/admin/users"
+	if query := userQuery(filter).Encode(); query != "" {
+		path += "?" + query
+	}
+	var page UserPage
+	err := c.do(ctx, http.MethodGet, path, nil, &page)
+	if err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// UpdateUser Изменяет email, подтверждение или блокировку пользователя
+func (c *Client) UpdateUser(ctx context.Context, id int, update *UserUpdate) (*UserInfo, error) {
+	var user UserInfo
+	err := c.do(ctx, http.MethodPatch, fmt.Sprintf("/admin/users/%d", id), update, &user)
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// DeleteUser Удаляет пользователя
+func (c *Client) DeleteUser(ctx context.Context, id int) error {
+	return c.do(ctx, http.MethodDelete, fmt.Sprintf("/admin/users/%d", id), nil, nil)
+}
+
+// SetRole Меняет роль пользователя
+func (c *Client) SetRole(ctx context.Context, id int, update *RoleUpdate) (*UserInfo, error) {
+	var user UserInfo
+	err := c.do(ctx, http.MethodPut, fmt.Sprintf("/admin/users/%d/role", id), update, &user)
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// RoleChanges История смены роли пользователя
+func (c *Client) RoleChanges(ctx context.Context, id int) ([]*RoleChange, error) {
+	var changes []*RoleChange
+	err := c.do(ctx, http.MethodGet, fmt.Sprintf("/admin/users/%d/role/audit", id), nil, &changes)
+	return changes, err
+}
+
+// Permissions Все разрешения
+func (c *Client) Permissions(ctx context.Context) ([]*PermissionInfo, error) {
+	var permissions []*PermissionInfo
+	err := c.do(ctx, http.MethodGet, "/admin/permissions", nil, &permissions)
+	return permissions, err
+}
+
+// AddPermission Создает разрешение, возвращает его id
+func (c *Client) AddPermission(ctx context.Context, permission *PermissionInfo) (int, error) {
+	var id int
+	err := c.do(ctx, http.MethodPost, "/admin/permissions", permission, &id)
+	return id, err
+}
+
+// AccessRoles Все роли доступа
+func (c *Client) AccessRoles(ctx context.Context) ([]*AccessRole, error) {
+	var roles []*AccessRole
+	err := c.do(ctx, http.MethodGet, "/admin/roles", nil, &roles)
+	return roles, err
+}
+
+// AddAccessRole Создает роль доступа
+func (c *Client) AddAccessRole(ctx context.Context, role *AccessRole) (*AccessRole, error) {
+	var created AccessRole
+	err := c.do(ctx, http.MethodPost, "/admin/roles", role, &created)
+	if err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+// SetAccessRolePermissions Заменяет разрешения роли доступа
+func (c *Client) SetAccessRolePermissions(ctx context.Context, id int, permissions []Permission) (*AccessRole, error) {
+	body := struct {
+		Permissions []Permission `json:"permissions"`
+	}{Permissions: permissions}
+
+	var role AccessRole
+	err := c.do(ctx, http.MethodPut, fmt.Sprintf("/admin/roles/%d/permissions", id), body, &role)
+	if err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
+
+// DeleteAccessRole Удаляет роль доступа
+func (c *Client) DeleteAccessRole(ctx context.Context, id int) error {
+	return c.do(ctx, http.MethodDelete, fmt.Sprintf("/admin/roles/%d", id), nil, nil)
+}
+
+// UserAccessRoles Роли доступа пользователя
+func (c *Client) UserAccessRoles(ctx context.Context, id int) ([]*AccessRole, error) {
+	var roles []*AccessRole
+	err := c.do(ctx, http.MethodGet, fmt.Sprintf("/admin/users/%d/roles", id), nil, &roles)
+	return roles, err
+}
+
+// SetUserAccessRoles Заменяет роли доступа пользователя
+func (c *Client) SetUserAccessRoles(ctx context.Context, id int, roles []int) ([]*AccessRole, error) {
+	body := struct {
+		Roles []int `json:"roles"`
+	}{Roles: roles}
+
+	var res []*AccessRole
+	err := c.do(ctx, http.MethodPut, fmt.Sprintf("/admin/users/%d/roles", id), body, &res)
+	return res, err
+}
+
+// UserPermissions Разрешения всех ролей пользователя
+func (c *Client) UserPermissions(ctx context.Context, id int) ([]Permission, error) {
+	var permissions []Permission
+	err := c.do(ctx, http.MethodGet, fmt.Sprintf("/admin/users/%d/permissions", id), nil, &permissions)
+	return permissions, err
+}
+
+// userQuery Параметры запроса списка пользователей в том виде, в котором их разбирает сервис
+func userQuery(filter *UserFilter) url.Values {
+	query := url.Values{}
+	if filter == nil {
+		return query
+	}
+	if filter.EmailPrefix != "" {
+		query.Set("email", filter.EmailPrefix)
+	}
+	if filter.Sort != "" {
+		query.Set("sort", string(filter.Sort))
+	}
+	if filter.Desc {
+		query.Set("order", "desc")
+	}
+	if filter.Cursor != "" {
+		query.Set("cursor", filter.Cursor)
+	}
+	if filter.Limit != 0 {
+		query.Set("limit", strconv.Itoa(filter.Limit))
+	}
+	if filter.Role != nil {
+		query.Set("role", strconv.Itoa(int(*filter.Role)))
+	}
+	if filter.CreatedFrom != nil {
+		query.Set("created_from", filter.CreatedFrom.Format(time.RFC3339))
+	}
+	if filter.CreatedTo != nil {
+		query.Set("created_to", filter.CreatedTo.Format(time.RFC3339))
+	}
+	for key, value := range map[string]*bool{
+		"verified": filter.Verified,
+		"locked":   filter.Locked,
+		"deleted":  filter.Deleted,
+	} {
+		if value != nil {
+			query.Set(key, strconv.FormatBool(*value))
+		}
+	}
+	return query
+}
@@ -0,0 +1,79 @@
+package authclient
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// AddUser Регистрирует пользователя по коду из письма, возвращает его id
+func (c *Client) AddUser(ctx context.Context, user *User) (int, error) {
+	var id int
+	err := c.do(ctx, http.MethodPost, "/user", user, &id)
+	return id, err
+}
+
+// GetUserByID Пользователь по id
+func (c *Client) GetUserByID(ctx context.Context, id int) (*User, error) {
+	return c.getUser(ctx, strconv.Itoa(id))
+}
+
+// GetUserByEmail Пользователь по email
+func (c *Client) GetUserByEmail(ctx context.Context, email string) (*User, error) {
+	return c.getUser(ctx, email)
+}
+
+func (c *Client) getUser(ctx context.Context, value string) (*User, error) {
+	var user User
+	err := c.do(ctx, http.MethodGet, "/user/"+url.PathEscape(value), nil, &user)
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// PushCode Отправляет код подтверждения на email
+func (c *Client) PushCode(ctx context.Context, email string) error {
+	body := struct {
+		Email string `json:"email"`
+	}{Email: email}
+	return c.do(ctx, http.MethodPost, "/email/push_auth", body, nil)
+}
+
+// Login Вход по email и паролю, возвращает токен доступа
+func (c *Client) Login(ctx context.Context, auth *Auth) (string, error) {
+	var token string
+	err := c.do(ctx, http.MethodPost, "/auth/login", auth, &token)
+	return token, err
+}
+
+// Logout Завершает сессию токена
+func (c *Client) Logout(ctx context.Context, token string) error {
+	return c.doWithToken(ctx, token, http.MethodDelete, "/auth/logout", nil, nil)
+}
+
+// CheckAuth Проверяет токен и возвращает данные пользователя с его разрешениями
+func (c *Client) CheckAuth(ctx context.Context, token string) (*CheckResult, error) {
+	var res CheckResult
+	err := c.doWithToken(ctx, token, http.MethodGet, "/auth/check", nil, &res)
+	if err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// CheckAccess Проверяет доступ по политике авторизации
+func (c *Client) CheckAccess(ctx context.Context, req *AuthzRequest) (*AuthzDecision, error) {
+	var decision AuthzDecision
+	err := c.do(ctx, http.MethodPost, "/authz/check", req, &decision)
+	if err != nil {
+		return nil, err
+	}
+	return &decision, nil
+}
+
+// ReloadPolicy Перечитывает политику авторизации из файла
+func (c *Client) ReloadPolicy(ctx context.Context) error {
+	return c.do(ctx, http.MethodPost, "/authz/reload", nil, nil)
+}
@@ -0,0 +1,214 @@
+// Package authclient Клиент HTTP API сервиса авторизации и middleware проверки токена для сервисов, которые его используют.
+// Входы через внешних провайдеров (OAuth, SAML) выполняются браузером, а SCIM API предназначен для систем
+// управления учетными записями, поэтому в клиент они не входят
+package authclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	apiPrefix = "/srv-auth/api/v1"
+
+	DefaultTimeout      = 3 * time.Second
+	DefaultRetries      = 2
+	DefaultRetryBackoff = 100 * time.Millisecond
+)
+
+// ErrUnauthorized Токен отсутствует, недействителен или у него нет доступа
+var ErrUnauthorized = errors.New("unauthorized")
+
+// Error Ошибка, которую вернул сервис авторизации
+type Error struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("auth service: %d %s", e.StatusCode, e.Message)
+}
+
+func (e *Error) Is(target error) bool {
+	return target == ErrUnauthorized && e.StatusCode == http.StatusUnauthorized
+}
+
+// envelope Ответ сервиса в формате common_utils/response: данные в data, описание результата или ошибки в message
+type envelope struct {
+	Data    json.RawMessage `json:"data,omitempty"`
+	Message string          `json:"message"`
+	Status  int             `json:"status"`
+}
+
+type Client struct {
+	baseURL      string
+	httpClient   *http.Client
+	token        string
+	timeout      time.Duration
+	retries      int
+	retryBackoff time.Duration
+}
+
+type Option func(c *Client)
+
+// WithHTTPClient Клиент, через который выполняются запросы, по умолчанию http.DefaultClient
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithTimeout Ограничение на одну попытку запроса
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Client) {
+		c.timeout = timeout
+	}
+}
+
+// WithRetries Количество повторов при сетевых ошибках и недоступности сервиса.
+// Повторяются только идемпотентные запросы, пауза удваивается после каждой попытки
+func WithRetries(retries int, backoff time.Duration) Option {
+	return func(c *Client) {
+		c.retries = retries
+		c.retryBackoff = backoff
+	}
+}
+
+// WithToken Токен, с которым выполняются запросы, например персональный токен сервиса
+func WithToken(token string) Option {
+	return func(c *Client) {
+		c.token = token
+	}
+}
+
+// New Создает клиент. baseURL - адрес сервиса без префикса API, например http://auth:8091
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:      strings.TrimRight(baseURL, "/"),
+		httpClient:   http.DefaultClient,
+		timeout:      DefaultTimeout,
+		retries:      DefaultRetries,
+		retryBackoff: DefaultRetryBackoff,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// WithToken Копия клиента, выполняющая запросы с другим токеном, например с токеном пользователя из входящего запроса
+func (c *Client) WithToken(token string) *Client {
+	clone := *c
+	clone.token = token
+	return &clone
+}
+
+func (c *Client) do(ctx context.Context, method string, path string, body any, out any) error {
+	return c.doWithToken(ctx, c.token, method, path, body, out)
+}
+
+func (c *Client) doWithToken(ctx context.Context, token string, method string, path string, body any, out any) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		payload, err = json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("authclient: marshal request: %w", err)
+		}
+	}
+	attempts := 1
+	if idempotent(method) {
+		attempts += c.retries
+	}
+	backoff := c.retryBackoff
+
+	var err error
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+		}
+		var retry bool
+		retry, err = c.attempt(ctx, token, method, path, payload, out)
+		if !retry {
+			return err
+		}
+	}
+	return err
+}
+
+// attempt Выполняет одну попытку запроса и сообщает, имеет ли смысл ее повторить
+func (c *Client) attempt(ctx context.Context, token string, method string, path string, payload []byte, out any) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+apiPrefix+path, body)
+	if err != nil {
+		return false, fmt.Errorf("authclient: new request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		// Отмена или истечение контекста вызывающего не повторяется
+		if ctx.Err() != nil && ctx.Err() != context.DeadlineExceeded {
+			return false, err
+		}
+		return true, fmt.Errorf("authclient: %s %s: %w", method, path, err)
+	}
+	defer res.Body.Close()
+
+	var env envelope
+	if err = json.NewDecoder(res.Body).Decode(&env); err != nil && !errors.Is(err, io.EOF) {
+		return retryable(res.StatusCode), fmt.Errorf("authclient: decode response: %w", err)
+	}
+	if res.StatusCode >= http.StatusBadRequest {
+		if env.Message == "" {
+			env.Message = http.StatusText(res.StatusCode)
+		}
+		return retryable(res.StatusCode), &Error{StatusCode: res.StatusCode, Message: env.Message}
+	}
+	if out != nil && len(env.Data) > 0 {
+		if err = json.Unmarshal(env.Data, out); err != nil {
+			return false, fmt.Errorf("authclient: decode data: %w", err)
+		}
+	}
+	return false, nil
+}
+
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+func retryable(status int) bool {
+	switch status {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
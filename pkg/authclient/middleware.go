@@ -0,0 +1,142 @@
+package authclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// authorization Заголовок и cookie, в которых сервис авторизации передает токен
+const authorization = "Authorization"
+
+type ctxKey int
+
+const authDataKey ctxKey = iota
+
+type middleware struct {
+	verifier     Verifier
+	cookieName   string
+	errorHandler func(w http.ResponseWriter, r *http.Request, err error)
+	renewed      func(w http.ResponseWriter, token string)
+}
+
+type MiddlewareOption func(m *middleware)
+
+// WithErrorHandler Ответ на запрос без действительного токена, по умолчанию 401 или 503, если сервис недоступен
+func WithErrorHandler(handler func(w http.ResponseWriter, r *http.Request, err error)) MiddlewareOption {
+	return func(m *middleware) {
+		m.errorHandler = handler
+	}
+}
+
+// WithCookieName Имя cookie с токеном, если в сервисе авторизации оно изменено настройкой cookie.name
+func WithCookieName(name string) MiddlewareOption {
+	return func(m *middleware) {
+		m.cookieName = name
+	}
+}
+
+// WithRenewedToken Передача клиенту продленного токена, по умолчанию заголовок ответа Authorization: Bearer <token>
+func WithRenewedToken(renewed func(w http.ResponseWriter, token string)) MiddlewareOption {
+	return func(m *middleware) {
+		m.renewed = renewed
+	}
+}
+
+// Middleware Проверяет токен запроса и кладет данные пользователя в контекст, откуда их достает FromContext
+func Middleware(verifier Verifier, opts ...MiddlewareOption) func(http.Handler) http.Handler {
+	m := &middleware{
+		verifier:     verifier,
+		cookieName:   authorization,
+		errorHandler: writeError,
+		renewed: func(w http.ResponseWriter, token string) {
+			w.Header().Set(authorization, "Bearer "+token)
+		},
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token := tokenFromRequest(r, m.cookieName)
+			if token == "" {
+				m.errorHandler(w, r, ErrUnauthorized)
+				return
+			}
+			res, err := m.verifier.Verify(r.Context(), token)
+			if err != nil {
+				m.errorHandler(w, r, err)
+				return
+			}
+			if res.Token != "" {
+				m.renewed(w, res.Token)
+			}
+			next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), res.AuthData)))
+		})
+	}
+}
+
+// RequirePermission Пропускает запрос, только если у пользователя из контекста есть все разрешения.
+// Используется после Middleware
+func RequirePermission(permissions ...Permission) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user, ok := FromContext(r.Context())
+			if !ok {
+				writeError(w, r, ErrUnauthorized)
+				return
+			}
+			if !user.HasPermission(permissions...) {
+				writeJSON(w, http.StatusForbidden, "access denied")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// TokenFromRequest Токен из заголовка Authorization (с типом Bearer или без него) или из одноименной cookie
+func TokenFromRequest(r *http.Request) string {
+	return tokenFromRequest(r, authorization)
+}
+
+func tokenFromRequest(r *http.Request, cookieName string) string {
+	token := strings.TrimPrefix(r.Header.Get(authorization), "Bearer ")
+	if token != "" {
+		return token
+	}
+	c, err := r.Cookie(cookieName)
+	if err != nil {
+		return ""
+	}
+	return c.Value
+}
+
+// NewContext Контекст с данными пользователя
+func NewContext(ctx context.Context, user *AuthData) context.Context {
+	return context.WithValue(ctx, authDataKey, user)
+}
+
+// FromContext Данные пользователя, которые положил Middleware
+func FromContext(ctx context.Context) (*AuthData, bool) {
+	user, ok := ctx.Value(authDataKey).(*AuthData)
+	return user, ok && user != nil
+}
+
+func writeError(w http.ResponseWriter, _ *http.Request, err error) {
+	var apiErr *Error
+	if errors.Is(err, ErrUnauthorized) || errors.As(err, &apiErr) && apiErr.StatusCode < http.StatusInternalServerError {
+		writeJSON(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	writeJSON(w, http.StatusServiceUnavailable, "auth service unavailable")
+}
+
+// writeJSON Ответ в том же формате, что и у сервиса авторизации
+func writeJSON(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(envelope{Message: message, Status: status})
+}
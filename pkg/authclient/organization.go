@@ -0,0 +1,126 @@
+package authclient
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// AddOrganization Создает организацию, владельцем становится пользователь токена
+func (c *Client) AddOrganization(ctx context.Context, org *Organization) (*Organization, error) {
+	var created Organization
+	err := c.do(ctx, http.MethodPost, "/orgs", org, &created)
+	if err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+// Organizations Организации пользователя токена
+func (c *Client) Organizations(ctx context.Context) ([]*Organization, error) {
+	var orgs []*Organization
+	err := c.do(ctx, http.MethodGet, "/orgs", nil, &orgs)
+	return orgs, err
+}
+
+// SwitchOrganization Переключает активную организацию, возвращает новый токен. 0 - личное пространство
+func (c *Client) SwitchOrganization(ctx context.Context, orgID int) (string, error) {
+	body := struct {
+		OrgID int `json:"org_id"`
+	}{OrgID: orgID}
+
+	var token string
+	err := c.do(ctx, http.MethodPost, "/orgs/switch", body, &token)
+	return token, err
+}
+
+// AcceptInvitation Принимает приглашение в организацию
+func (c *Client) AcceptInvitation(ctx context.Context, token string) (*Organization, error) {
+	var org Organization
+	err := c.do(ctx, http.MethodPost, "/orgs/invitations/accept", invitationToken(token), &org)
+	if err != nil {
+		return nil, err
+	}
+	return &org, nil
+}
+
+// DeclineInvitation Отклоняет приглашение в организацию
+func (c *Client) DeclineInvitation(ctx context.Context, token string) error {
+	return c.do(ctx, http.MethodPost, "/orgs/invitations/decline", invitationToken(token), nil)
+}
+
+// OrgMembers Участники организации
+func (c *Client) OrgMembers(ctx context.Context, orgID int) ([]*OrgMember, error) {
+	var members []*OrgMember
+	err := c.do(ctx, http.MethodGet, fmt.Sprintf("/orgs/%d/members", orgID), nil, &members)
+	return members, err
+}
+
+// SetOrgMemberRole Меняет роль участника организации
+func (c *Client) SetOrgMemberRole(ctx context.Context, orgID int, userID int, role OrgRole) (*OrgMember, error) {
+	body := struct {
+		Role *OrgRole `json:"role"`
+	}{Role: &role}
+
+	var member OrgMember
+	err := c.do(ctx, http.MethodPatch, fmt.Sprintf("/orgs/%d/members/%d", orgID, userID), body, &member)
+	if err != nil {
+		return nil, err
+	}
+	return &member, nil
+}
+
+// RemoveOrgMember Исключает участника из организации
+func (c *Client) RemoveOrgMember(ctx context.Context, orgID int, userID int) error {
+	return c.do(ctx, http.MethodDelete, fmt.Sprintf("/orgs/%d/members/%d", orgID, userID), nil, nil)
+}
+
+// InviteMember Отправляет приглашение в организацию
+func (c *Client) InviteMember(ctx context.Context, orgID int, invitation *OrgInvitation) (*OrgInvitation, error) {
+	var created OrgInvitation
+	err := c.do(ctx, http.MethodPost, fmt.Sprintf("/orgs/%d/invitations", orgID), invitation, &created)
+	if err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+// OrgInvitations Приглашения организации
+func (c *Client) OrgInvitations(ctx context.Context, orgID int) ([]*OrgInvitation, error) {
+	var invitations []*OrgInvitation
+	err := c.do(ctx, http.MethodGet, fmt.Sprintf("/orgs/%d/invitations", orgID), nil, &invitations)
+	return invitations, err
+}
+
+// RevokeInvitation Отзывает приглашение
+func (c *Client) RevokeInvitation(ctx context.Context, orgID int, invitationID int) error {
+	return c.do(ctx, http.MethodDelete, fmt.Sprintf("/orgs/%d/invitations/%d", orgID, invitationID), nil, nil)
+}
+
+// AddSCIMToken Выпускает токен SCIM для организации. Значение токена возвращается только здесь
+func (c *Client) AddSCIMToken(ctx context.Context, orgID int, token *SCIMTokenCreate) (*SCIMTokenCreated, error) {
+	var created SCIMTokenCreated
+	err := c.do(ctx, http.MethodPost, fmt.Sprintf("/orgs/%d/scim/tokens", orgID), token, &created)
+	if err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+// SCIMTokens Токены SCIM организации
+func (c *Client) SCIMTokens(ctx context.Context, orgID int) ([]*SCIMToken, error) {
+	var tokens []*SCIMToken
+	err := c.do(ctx, http.MethodGet, fmt.Sprintf("/orgs/%d/scim/tokens", orgID), nil, &tokens)
+	return tokens, err
+}
+
+// RevokeSCIMToken Отзывает токен SCIM
+func (c *Client) RevokeSCIMToken(ctx context.Context, orgID int, tokenID int) error {
+	return c.do(ctx, http.MethodDelete, fmt.Sprintf("/orgs/%d/scim/tokens/%d", orgID, tokenID), nil, nil)
+}
+
+func invitationToken(token string) any {
+	return struct {
+		Token string `json:"token"`
+	}{Token: token}
+}
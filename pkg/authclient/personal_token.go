@@ -0,0 +1,42 @@
+package authclient
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// AddPersonalToken Выпускает персональный токен. Значение токена возвращается только здесь
+func (c *Client) AddPersonalToken(ctx context.Context, token *PersonalTokenCreate) (*PersonalTokenCreated, error) {
+	var created PersonalTokenCreated
+	err := c.do(ctx, http.MethodPost, "/tokens", token, &created)
+	if err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+// PersonalTokens Персональные токены пользователя
+func (c *Client) PersonalTokens(ctx context.Context) ([]*PersonalToken, error) {
+	var tokens []*PersonalToken
+	err := c.do(ctx, http.MethodGet, "/tokens", nil, &tokens)
+	return tokens, err
+}
+
+// RevokePersonalToken Отзывает персональный токен
+func (c *Client) RevokePersonalToken(ctx context.Context, id int) error {
+	return c.do(ctx, http.MethodDelete, fmt.Sprintf("/tokens/%d", id), nil, nil)
+}
+
+// UserIdentities Внешние аккаунты, привязанные к пользователю
+func (c *Client) UserIdentities(ctx context.Context) ([]*UserIdentity, error) {
+	var identities []*UserIdentity
+	err := c.do(ctx, http.MethodGet, "/oauth/identities", nil, &identities)
+	return identities, err
+}
+
+// RemoveIdentity Отвязывает внешний аккаунт провайдера
+func (c *Client) RemoveIdentity(ctx context.Context, provider string) error {
+	return c.do(ctx, http.MethodDelete, "/oauth/identities/"+url.PathEscape(provider), nil, nil)
+}
@@ -0,0 +1,39 @@
+package authclient
+
+import "auth/internal/domain"
+
+// Типы запросов и ответов совпадают с моделями сервиса, поэтому клиент не расходится с API при их изменении
+type (
+	AuthData             = domain.AuthData
+	Auth                 = domain.Auth
+	User                 = domain.User
+	Role                 = domain.Role
+	Permission           = domain.Permission
+	UserFilter           = domain.UserFilter
+	UserInfo             = domain.UserInfo
+	UserPage             = domain.UserPage
+	UserUpdate           = domain.UserUpdate
+	RoleUpdate           = domain.RoleUpdate
+	RoleChange           = domain.RoleChange
+	PermissionInfo       = domain.PermissionInfo
+	AccessRole           = domain.AccessRole
+	AuthzRequest         = domain.AuthzRequest
+	AuthzDecision        = domain.AuthzDecision
+	Organization         = domain.Organization
+	OrgRole              = domain.OrgRole
+	OrgMember            = domain.OrgMember
+	OrgInvitation        = domain.OrgInvitation
+	PersonalToken        = domain.PersonalToken
+	PersonalTokenCreate  = domain.PersonalTokenCreate
+	PersonalTokenCreated = domain.PersonalTokenCreated
+	SCIMToken            = domain.SCIMToken
+	SCIMTokenCreate      = domain.SCIMTokenCreate
+	SCIMTokenCreated     = domain.SCIMTokenCreated
+	UserIdentity         = domain.UserIdentity
+)
+
+// CheckResult Результат проверки токена. Token заполнен, если истекший токен был продлен и его нужно заменить
+type CheckResult struct {
+	*AuthData
+	Token string `json:"token,omitempty"`
+}
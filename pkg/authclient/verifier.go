@@ -0,0 +1,105 @@
+package authclient
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+// Verifier Проверяет токен доступа и возвращает данные пользователя. Токены подписываются секретом сервиса,
+// который не публикуется, поэтому проверка всегда выполняется сервисом через /auth/check
+type Verifier interface {
+	Verify(ctx context.Context, token string) (*CheckResult, error)
+}
+
+type remoteVerifier struct {
+	client     *Client
+	ttl        time.Duration
+	maxEntries int
+
+	mu    sync.Mutex
+	cache map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	res       *CheckResult
+	expiresAt time.Time
+}
+
+// NewRemoteVerifier Проверяет токен через /auth/check и держит успешные результаты в памяти не дольше ttl.
+// Пока результат в кеше, отзыв сессии или изменение разрешений на нем не отражаются. ttl 0 отключает кеш
+func NewRemoteVerifier(client *Client, ttl time.Duration, maxEntries int) Verifier {
+	return &remoteVerifier{
+		client:     client,
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		cache:      make(map[string]cacheEntry),
+	}
+}
+
+func (v *remoteVerifier) Verify(ctx context.Context, token string) (*CheckResult, error) {
+	key := tokenKey(token)
+	if res, ok := v.cached(key); ok {
+		return res, nil
+	}
+	res, err := v.client.CheckAuth(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	// Продленный токен нужно вернуть клиенту, поэтому такой результат не кешируется
+	if res.Token == "" {
+		v.store(key, res)
+	}
+	return res, nil
+}
+
+func (v *remoteVerifier) cached(key string) (*CheckResult, bool) {
+	if v.ttl <= 0 {
+		return nil, false
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	entry, ok := v.cache[key]
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(entry.expiresAt) {
+		delete(v.cache, key)
+		return nil, false
+	}
+	return entry.res, true
+}
+
+func (v *remoteVerifier) store(key string, res *CheckResult) {
+	if v.ttl <= 0 || v.maxEntries <= 0 {
+		return
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	now := time.Now()
+	if len(v.cache) >= v.maxEntries {
+		for k, entry := range v.cache {
+			if now.After(entry.expiresAt) {
+				delete(v.cache, k)
+			}
+		}
+	}
+	// Если устаревших записей нет, вытесняется произвольная
+	for k := range v.cache {
+		if len(v.cache) < v.maxEntries {
+			break
+		}
+		delete(v.cache, k)
+	}
+	v.cache[key] = cacheEntry{res: res, expiresAt: now.Add(v.ttl)}
+}
+
+// tokenKey Токены не хранятся в памяти в открытом виде
+func tokenKey(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
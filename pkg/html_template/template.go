@@ -106,3 +106,50 @@ var RegistgrationSuccessfully = `<!DOCTYPE html>
 </body>
 </html>
 `
+
+var RegistrationAttempt = `<!DOCTYPE html>
+<html lang="ru">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>Попытка регистрации</title>
+</head>
+<body style="font-family: Arial, sans-serif; background-color: #f4f4f4; padding: 20px;">
+
+    <div style="max-width: 600px; margin: 0 auto; background-color: #ffffff; padding: 20px; border-radius: 10px; box-shadow: 0px 0px 10px rgba(0, 0, 0, 0.1);">
+        <h2 style="color: #4CAF50; text-align: center; margin-bottom: 30px;">Попытка регистрации</h2>
+        <p style="color: #333333;">Здравствуйте,</p>
+        <p style="color: #333333;">Кто-то попытался зарегистрироваться в Linkify, указав вашу почту <strong>%s</strong>.</p>
+        <p style="color: #333333;">У вас уже есть учетная запись, поэтому повторная регистрация не требуется. Если вы забыли пароль, воспользуйтесь восстановлением доступа.</p>
+        <p style="color: #333333;">Если это были не вы, просто проигнорируйте это письмо.</p>
+        <p style="color: #333333; margin: 0; text-align: right;">С уважением,</p>
+        <p style="color: #4CAF50; margin: 0; text-align: right;"><strong>Linkify Company</strong></p>
+    </div>
+
+</body>
+</html>
+`
+
+var OrganizationInvitation = `<!DOCTYPE html>
+<html lang="ru">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>Приглашение в организацию</title>
+</head>
+<body style="font-family: Arial, sans-serif; background-color: #f4f4f4; padding: 20px;">
+
+    <div style="max-width: 600px; margin: 0 auto; background-color: #ffffff; padding: 20px; border-radius: 10px; box-shadow: 0px 0px 10px rgba(0, 0, 0, 0.1);">
+        <h2 style="color: #4CAF50; text-align: center; margin-bottom: 30px;">Приглашение в организацию</h2>
+        <p style="color: #333333;">Здравствуйте,</p>
+        <p style="color: #333333;">Пользователь <strong>%s</strong> приглашает вас в организацию <strong>%s</strong> в Linkify.</p>
+        <p style="color: #333333;">Чтобы принять или отклонить приглашение, войдите в Linkify с этой почтой и используйте код приглашения:</p>
+        <p style="color: #333333; text-align: center; word-break: break-all;"><strong>%s</strong></p>
+        <p style="color: #333333;">Если вы не ожидали это письмо, просто проигнорируйте его.</p>
+        <p style="color: #333333; margin: 0; text-align: right;">С уважением,</p>
+        <p style="color: #4CAF50; margin: 0; text-align: right;"><strong>Linkify Company</strong></p>
+    </div>
+
+</body>
+</html>
+`